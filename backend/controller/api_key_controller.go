@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"better-form-doc-backend/usecase"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyController will hold the dependencies for the API key admin handlers
+type APIKeyController struct {
+	apiKeyUseCase usecase.APIKeyUseCaseInterface
+}
+
+// NewAPIKeyController creates a new instance of APIKeyController
+func NewAPIKeyController(apiKeyUseCase usecase.APIKeyUseCaseInterface) *APIKeyController {
+	return &APIKeyController{
+		apiKeyUseCase: apiKeyUseCase,
+	}
+}
+
+// MintAPIKeyRequest is the body of POST /admin/api-keys.
+type MintAPIKeyRequest struct {
+	Name string `json:"name" binding:"required"`
+	// OwnerID is the user the key acts as; defaults to the caller.
+	OwnerID string `json:"ownerId,omitempty"`
+	// Scopes granted to the key: chat, forms and/or admin.
+	Scopes []string `json:"scopes" binding:"required"`
+	// ExpiresAt is optional; keys without it do not expire.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// MintAPIKey godoc
+// @Summary      Mint an API key
+// @Description  Creates a long-lived API key for machine clients. The key is only returned in this response; store it securely. Requires the admin scope.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        key  body      MintAPIKeyRequest  true  "Key to create"
+// @Success      201  {object}  usecase.MintedAPIKey
+// @Failure      400  {object}  ErrorResponse  "INVALID_REQUEST"
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      403  {object}  ErrorResponse  "FORBIDDEN"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /admin/api-keys [post]
+func (kc *APIKeyController) MintAPIKey(c *gin.Context) {
+	var request MintAPIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		_ = c.Error(invalidRequest("%v", err))
+		return
+	}
+	ownerID := request.OwnerID
+	if ownerID == "" {
+		ownerID = currentUserID(c)
+	}
+
+	key, err := kc.apiKeyUseCase.MintKey(usecase.MintAPIKeyInput{
+		OwnerID:   ownerID,
+		Name:      request.Name,
+		Scopes:    request.Scopes,
+		ExpiresAt: request.ExpiresAt,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+// ListAPIKeys godoc
+// @Summary      List API keys
+// @Description  Lists API keys (without their secrets), newest first. Requires the admin scope.
+// @Tags         admin
+// @Produce      json
+// @Param        ownerId  query     string  false  "Only list the keys of this user"
+// @Success      200      {array}   domain.APIKey
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      403  {object}  ErrorResponse  "FORBIDDEN"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /admin/api-keys [get]
+func (kc *APIKeyController) ListAPIKeys(c *gin.Context) {
+	keys, err := kc.apiKeyUseCase.ListKeys(c.Query("ownerId"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary      Revoke an API key
+// @Description  Revokes a key immediately. Revoked keys stay listed. Requires the admin scope.
+// @Tags         admin
+// @Param        id  path  string  true  "API key ID"
+// @Success      204
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      403  {object}  ErrorResponse  "FORBIDDEN"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND: API key not found"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /admin/api-keys/{id} [delete]
+func (kc *APIKeyController) RevokeAPIKey(c *gin.Context) {
+	if err := kc.apiKeyUseCase.RevokeKey(c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
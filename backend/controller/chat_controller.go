@@ -1,12 +1,24 @@
 package controller
 
 import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/jsonpatch"
 	"better-form-doc-backend/usecase"
+	"better-form-doc-backend/validation"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// statusClientClosedRequest is logged when the client went away before the
+// response was ready (the nginx convention).
+const statusClientClosedRequest = 499
+
 // ChatController will hold the dependencies for the chat handlers
 type ChatController struct {
 	chatUseCase usecase.ChatUseCaseInterface
@@ -22,20 +34,67 @@ func NewChatController(chatUseCase usecase.ChatUseCaseInterface) *ChatController
 // ChatRequest defines the structure of the incoming JSON request
 type ChatRequest struct {
 	Prompt string `json:"prompt" binding:"required"`
+	// ConversationID continues a previous conversation returned by this API
+	// to the same user.
+	ConversationID string `json:"conversationId,omitempty"`
+	// History lets the client send prior turns itself (roles "user"/"assistant").
+	History []domain.Message `json:"history,omitempty"`
+	// FormID refines a stored form and saves the result as a new version.
+	FormID string `json:"formId,omitempty"`
+	// CurrentForm is the FormConfig being refined, including any manual
+	// edits. It is decoded as strictly as generated forms.
+	CurrentForm json.RawMessage `json:"currentForm,omitempty" swaggertype:"object"`
+	// Patch is an RFC 6902 JSON Patch of user edits, applied server-side to the
+	// form being refined before the model is called.
+	Patch jsonpatch.Patch `json:"patch,omitempty"`
+	// PromptVersion selects the prompt templates among those the deployment
+	// makes selectable; the deployment default is used when omitted.
+	PromptVersion string `json:"promptVersion,omitempty"`
+}
+
+// toInput converts the request into the use case input. A
+// "Cache-Control: no-cache" header bypasses the form cache. An invalid
+// currentForm is reported as *usecase.InvalidFormError.
+func (r ChatRequest) toInput(c *gin.Context) (usecase.ChatInput, error) {
+	currentForm, err := parseFormConfig(r.CurrentForm)
+	if err != nil {
+		return usecase.ChatInput{}, err
+	}
+	return usecase.ChatInput{
+		Prompt:         r.Prompt,
+		ConversationID: r.ConversationID,
+		FormID:         r.FormID,
+		UserID:         currentUserID(c),
+		History:        r.History,
+		CurrentForm:    currentForm,
+		Patch:          r.Patch,
+		PromptVersion:  r.PromptVersion,
+		BypassCache:    strings.Contains(strings.ToLower(c.GetHeader("Cache-Control")), "no-cache"),
+	}, nil
 }
 
 // GenerateChatResponse godoc
 // @Summary      Generate a chat response from the AI
-// @Description  Accepts a user prompt and returns a JSON response from the Gemini AI model.
+// @Description  Accepts a user prompt and returns a JSON response from the Gemini AI model. Pass the returned conversationId (or the history / currentForm) to refine the form in follow-up requests. With formId the stored form is refined and the result saved as a new version. New forms are cached by normalized prompt; X-Cache tells whether the answer came from the cache.
 // @Tags         chat
 // @Accept       json
 // @Produce      json
-// @Param        prompt  body      ChatRequest  true  "User's prompt for the AI"
-// @Success      200     {object}  map[string]interface{}
-// @Failure      400 {string}  "Invalid request"
-// @Failure      401 {string}  "Unauthorized"
-// @Failure      500 {string}  "Server error"
+// @Param        prompt         body      ChatRequest  true   "User's prompt for the AI"
+// @Param        Cache-Control  header    string       false  "no-cache generates the form again instead of serving it from the cache"
+// @Success      200     {object}  usecase.GenerationResult
+// @Header       200     {string}  X-Cache  "HIT or MISS; absent for requests that are not cacheable"
+// @Failure      400     {object}  ErrorResponse  "INVALID_REQUEST, e.g. an empty or too long prompt or an unknown promptVersion"
+// @Failure      401     {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      404     {object}  ErrorResponse  "NOT_FOUND: stored form not found"
+// @Failure      409     {object}  ErrorResponse  "CONFLICT: stored form was modified concurrently"
+// @Failure      422     {object}  ErrorResponse  "INVALID_FORM_CONFIG for an invalid currentForm, IRRELEVANT_PROMPT, MODEL_OUTPUT_INVALID (details: InvalidFormDetails) or SAFETY_BLOCKED"
+// @Failure      429     {object}  ErrorResponse  "RATE_LIMITED, QUOTA_EXCEEDED or UPSTREAM_RATE_LIMITED (see Retry-After)"
+// @Failure      500     {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Failure      502     {object}  ErrorResponse  "UPSTREAM_ERROR"
+// @Failure      503     {object}  ErrorResponse  "UPSTREAM_UNAVAILABLE (see Retry-After)"
+// @Failure      504     {object}  ErrorResponse  "TIMEOUT"
 // @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Router       /chat [post]
 func (cc *ChatController) GenerateChatResponse(c *gin.Context) {
 	var request ChatRequest
@@ -43,18 +102,119 @@ func (cc *ChatController) GenerateChatResponse(c *gin.Context) {
 	// Bind the incoming JSON to the ChatRequest struct.
 	// If the "prompt" field is missing or not a string, it will return an error.
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		_ = c.Error(invalidRequest("%v", err))
+		return
+	}
+	input, err := request.toInput(c)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
 
 	// Call the use case layer with the user's prompt
-	response, err := cc.chatUseCase.GenerateChatResponse(request.Prompt)
+	// The request context is canceled when the client disconnects, which
+	// abandons the model call.
+	response, err := cc.chatUseCase.GenerateChatResponse(c.Request.Context(), input)
+	// Tokens spent on failed requests count against the quota as well.
+	var usageErr *usecase.UsageError
+	if errors.As(err, &usageErr) {
+		recordUsage(c, usageErr.Usage)
+	}
+	if errors.Is(err, context.Canceled) {
+		// Nobody is listening any more.
+		c.AbortWithStatus(statusClientClosedRequest)
+		return
+	}
 	if err != nil {
-		// If the use case returns an error (e.g., Gemini API is down), send a 500 error.
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate AI response", "details": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
 	// Send the successful response from the use case back to the client.
+	recordUsage(c, response.Metadata.Usage)
+	if response.Metadata.Cache != "" {
+		c.Header("X-Cache", response.Metadata.Cache)
+	}
 	c.JSON(http.StatusOK, response)
 }
+
+// RepairEvent is sent on the stream before an invalid answer is sent back to
+// the model for correction.
+type RepairEvent struct {
+	Attempt int                `json:"attempt"`
+	Issues  []validation.Issue `json:"issues"`
+}
+
+// StreamChatResponse godoc
+// @Summary      Stream a form generation over Server-Sent Events
+// @Description  Accepts a user prompt and streams the generation as SSE events: `token` ({"text"}) for every partial chunk, `repair` (RepairEvent) when an invalid answer is sent back for correction, then a final `form` (usecase.GenerationResult) or `error` (ErrorResponse, with the same codes as POST /chat).
+// @Tags         chat
+// @Accept       json
+// @Produce      text/event-stream
+// @Param        prompt  body      ChatRequest  true  "User's prompt for the AI"
+// @Success      200     {string}  string  "Event stream"
+// @Failure      400     {object}  ErrorResponse  "INVALID_REQUEST"
+// @Failure      401     {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      422     {object}  ErrorResponse  "INVALID_FORM_CONFIG for an invalid currentForm (details: InvalidFormDetails)"
+// @Failure      429     {object}  ErrorResponse  "RATE_LIMITED or QUOTA_EXCEEDED (see Retry-After)"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /chat/stream [post]
+func (cc *ChatController) StreamChatResponse(c *gin.Context) {
+	var request ChatRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		_ = c.Error(invalidRequest("%v", err))
+		return
+	}
+	input, err := request.toInput(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	// From here on the response is an event stream; errors become `error` events.
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable proxy buffering (nginx)
+	c.Status(http.StatusOK)
+
+	send := func(event string, data interface{}) {
+		c.SSEvent(event, data)
+		c.Writer.Flush()
+	}
+
+	result, err := cc.chatUseCase.StreamChatResponse(c.Request.Context(), input, usecase.StreamEvents{
+		OnToken: func(text string) {
+			send("token", gin.H{"text": text})
+		},
+		OnRepair: func(attempt int, issues validation.Issues) {
+			send("repair", RepairEvent{Attempt: attempt, Issues: issues})
+		},
+	})
+
+	var usageErr *usecase.UsageError
+	if errors.As(err, &usageErr) {
+		recordUsage(c, usageErr.Usage)
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if err != nil {
+		status, response := newErrorResponse(c, err)
+		if status >= http.StatusInternalServerError {
+			log.Printf("Request %s failed: %v", response.RequestID, err)
+		}
+		send("error", response)
+		return
+	}
+
+	recordUsage(c, result.Metadata.Usage)
+	send("form", result)
+}
+
+// recordUsage hands the tokens consumed by the request to the rate limiter.
+func recordUsage(c *gin.Context, usage domain.TokenUsage) {
+	c.Set("tokenUsage", usage)
+}
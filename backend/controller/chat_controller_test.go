@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/usecase"
+	"better-form-doc-backend/validation"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type contextKey string
+
+// fakeChat runs the given generation instead of calling a model, and keeps
+// the context of the last call.
+type fakeChat struct {
+	generate func(ctx context.Context, events usecase.StreamEvents) (*usecase.GenerationResult, error)
+	ctx      context.Context
+}
+
+func (f *fakeChat) GenerateChatResponse(ctx context.Context, _ usecase.ChatInput) (*usecase.GenerationResult, error) {
+	f.ctx = ctx
+	return f.generate(ctx, usecase.StreamEvents{})
+}
+
+func (f *fakeChat) StreamChatResponse(ctx context.Context, _ usecase.ChatInput, events usecase.StreamEvents) (*usecase.GenerationResult, error) {
+	f.ctx = ctx
+	return f.generate(ctx, events)
+}
+
+// serveChat posts a prompt to path of a chat router backed by chat, in a
+// request context holding the "request" value "req-1".
+func serveChat(chat *fakeChat, path string) *httptest.ResponseRecorder {
+	controller := NewChatController(chat)
+	router := gin.New()
+	router.Use(ErrorMiddleware())
+	router.POST("/chat", controller.GenerateChatResponse)
+	router.POST("/chat/stream", controller.StreamChatResponse)
+
+	ctx := context.WithValue(context.Background(), contextKey("request"), "req-1")
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"prompt":"a login form"}`)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// sseEvent is one event of a text/event-stream response.
+type sseEvent struct {
+	name, data string
+}
+
+func sseEvents(t *testing.T, rec *httptest.ResponseRecorder) []sseEvent {
+	t.Helper()
+	if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/event-stream") {
+		t.Fatalf("Content-Type = %q, want text/event-stream", contentType)
+	}
+	var events []sseEvent
+	for _, block := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n") {
+		if block == "" {
+			continue
+		}
+		var event sseEvent
+		for _, line := range strings.Split(block, "\n") {
+			field, value, _ := strings.Cut(line, ":")
+			switch field {
+			case "event":
+				event.name = value
+			case "data":
+				event.data = value
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func failingChat(err error) *fakeChat {
+	return &fakeChat{generate: func(context.Context, usecase.StreamEvents) (*usecase.GenerationResult, error) {
+		return nil, err
+	}}
+}
+
+func TestChatContextErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   ErrorCode
+	}{
+		{"client gone", fmt.Errorf("error from LLM client: %w", context.Canceled), statusClientClosedRequest, ""},
+		{"deadline exceeded", fmt.Errorf("error from LLM client: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, CodeTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat := failingChat(tt.err)
+			rec := serveChat(chat, "/chat")
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if chat.ctx == nil || chat.ctx.Value(contextKey("request")) != "req-1" {
+				t.Error("the use case did not get the request context")
+			}
+			if tt.wantCode == "" {
+				// Nobody reads the answer.
+				if rec.Body.Len() != 0 {
+					t.Errorf("body = %s, want none", rec.Body)
+				}
+				return
+			}
+			var response ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || response.Code != tt.wantCode {
+				t.Errorf("response = %s, want %s", rec.Body, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestChatStreamContextErrors(t *testing.T) {
+	chat := failingChat(fmt.Errorf("error from LLM client: %w", context.Canceled))
+	rec := serveChat(chat, "/chat/stream")
+	if events := sseEvents(t, rec); len(events) != 0 {
+		t.Errorf("events = %+v, want none for a client that went away", events)
+	}
+	if chat.ctx == nil || chat.ctx.Value(contextKey("request")) != "req-1" {
+		t.Error("the use case did not get the request context")
+	}
+
+	rec = serveChat(failingChat(context.DeadlineExceeded), "/chat/stream")
+	events := sseEvents(t, rec)
+	if len(events) != 1 || events[0].name != "error" {
+		t.Fatalf("events = %+v, want one error event", events)
+	}
+	var response ErrorResponse
+	if err := json.Unmarshal([]byte(events[0].data), &response); err != nil || response.Code != CodeTimeout {
+		t.Errorf("error event = %s, want %s", events[0].data, CodeTimeout)
+	}
+}
+
+func TestChatStreamEvents(t *testing.T) {
+	form, err := domain.ParseFormConfig([]byte(testFormConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chat := &fakeChat{generate: func(_ context.Context, events usecase.StreamEvents) (*usecase.GenerationResult, error) {
+		events.OnToken(`{"title":`)
+		events.OnRepair(1, validation.Issues{{Code: validation.CodeRequired, Path: validation.Path{"endpoint"}, Message: "Required"}})
+		events.OnToken(`"Contact"}`)
+		return &usecase.GenerationResult{ConversationID: "c1", Form: form, Metadata: usecase.GenerationMetadata{RepairAttempts: 1}}, nil
+	}}
+	rec := serveChat(chat, "/chat/stream")
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("response = %d with Cache-Control %q, want 200 no-cache", rec.Code, rec.Header().Get("Cache-Control"))
+	}
+
+	events := sseEvents(t, rec)
+	var names []string
+	for _, event := range events {
+		names = append(names, event.name)
+	}
+	if strings.Join(names, ",") != "token,repair,token,form" {
+		t.Fatalf("events = %v, want token,repair,token,form", names)
+	}
+
+	var token struct{ Text string }
+	if err := json.Unmarshal([]byte(events[0].data), &token); err != nil || token.Text != `{"title":` {
+		t.Errorf("token event = %s", events[0].data)
+	}
+	var repair RepairEvent
+	if err := json.Unmarshal([]byte(events[1].data), &repair); err != nil || repair.Attempt != 1 ||
+		len(repair.Issues) != 1 || repair.Issues[0].Code != validation.CodeRequired {
+		t.Errorf("repair event = %s", events[1].data)
+	}
+	var result usecase.GenerationResult
+	if err := json.Unmarshal([]byte(events[3].data), &result); err != nil || result.ConversationID != "c1" ||
+		result.Form == nil || result.Form.Title != "Contact" || result.Metadata.RepairAttempts != 1 {
+		t.Errorf("form event = %s", events[3].data)
+	}
+}
+
+func TestChatStreamErrorEvent(t *testing.T) {
+	chat := &fakeChat{generate: func(_ context.Context, events usecase.StreamEvents) (*usecase.GenerationResult, error) {
+		events.OnToken(`{"ti`)
+		return nil, fmt.Errorf("%w: %w", usecase.ErrModelOutputInvalid, &usecase.InvalidFormError{
+			Issues:         validation.Issues{{Code: validation.CodeRequired, Path: validation.Path{"endpoint"}, Message: "Required"}},
+			RepairAttempts: 2,
+		})
+	}}
+	events := sseEvents(t, serveChat(chat, "/chat/stream"))
+	if len(events) != 2 || events[0].name != "token" || events[1].name != "error" {
+		t.Fatalf("events = %+v, want a token and an error", events)
+	}
+	var response struct {
+		Code    ErrorCode          `json:"code"`
+		Details InvalidFormDetails `json:"details"`
+	}
+	if err := json.Unmarshal([]byte(events[1].data), &response); err != nil || response.Code != CodeModelOutputInvalid ||
+		len(response.Details.Issues) != 1 || response.Details.RepairAttempts != 2 {
+		t.Errorf("error event = %s, want %s with the issues", events[1].data, CodeModelOutputInvalid)
+	}
+}
+
+func TestChatStreamRejectsInvalidRequests(t *testing.T) {
+	// Requests are checked before the stream starts, so they fail with a
+	// plain JSON error.
+	rec := serveJSON(newFormRouter(), http.MethodPost, "/chat/stream", `{"history":[]}`)
+	if rec.Code != http.StatusBadRequest || strings.HasPrefix(rec.Header().Get("Content-Type"), "text/event-stream") {
+		t.Errorf("response = %d %s, want a 400 JSON error", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
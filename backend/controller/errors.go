@@ -0,0 +1,194 @@
+package controller
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/usecase"
+	"better-form-doc-backend/validation"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorCode is a stable, machine-readable error identifier. Clients should
+// branch on the code, never on the message.
+type ErrorCode string
+
+const (
+	CodeInvalidRequest      ErrorCode = "INVALID_REQUEST"
+	CodeInvalidFormConfig   ErrorCode = "INVALID_FORM_CONFIG"
+	CodeInvalidSubmission   ErrorCode = "INVALID_SUBMISSION"
+	CodePayloadTooLarge     ErrorCode = "PAYLOAD_TOO_LARGE"
+	CodeUnauthorized        ErrorCode = "UNAUTHORIZED"
+	CodeForbidden           ErrorCode = "FORBIDDEN"
+	CodeNotFound            ErrorCode = "NOT_FOUND"
+	CodeConflict            ErrorCode = "CONFLICT"
+	CodeIrrelevantPrompt    ErrorCode = "IRRELEVANT_PROMPT"
+	CodeModelOutputInvalid  ErrorCode = "MODEL_OUTPUT_INVALID"
+	CodeSafetyBlocked       ErrorCode = "SAFETY_BLOCKED"
+	CodeRateLimited         ErrorCode = "RATE_LIMITED"
+	CodeQuotaExceeded       ErrorCode = "QUOTA_EXCEEDED"
+	CodeUpstreamRateLimited ErrorCode = "UPSTREAM_RATE_LIMITED"
+	CodeUpstreamUnavailable ErrorCode = "UPSTREAM_UNAVAILABLE"
+	CodeUpstreamError       ErrorCode = "UPSTREAM_ERROR"
+	CodeTimeout             ErrorCode = "TIMEOUT"
+	CodeInternal            ErrorCode = "INTERNAL_ERROR"
+)
+
+// ErrorResponse is the body of every error response, and of the `error`
+// event of streaming endpoints.
+type ErrorResponse struct {
+	Code    ErrorCode `json:"code" example:"INVALID_REQUEST"`
+	Message string    `json:"message" example:"Invalid request"`
+	// Details is a string, InvalidFormDetails for form validation errors or
+	// InvalidSubmissionDetails for rejected submissions. It is only set for
+	// client errors: the text of internal and upstream errors stays in the
+	// server log.
+	Details   interface{} `json:"details,omitempty" swaggertype:"object"`
+	RequestID string      `json:"requestId,omitempty"`
+}
+
+// InvalidFormDetails are the details of INVALID_FORM_CONFIG and
+// MODEL_OUTPUT_INVALID errors. Issues has the same shape as the frontend's
+// FormConfigError issues.
+type InvalidFormDetails struct {
+	Issues         []validation.Issue `json:"issues"`
+	RepairAttempts int                `json:"repairAttempts,omitempty"`
+}
+
+// InvalidSubmissionDetails are the details of INVALID_SUBMISSION errors.
+// Issues are addressed by field name.
+type InvalidSubmissionDetails struct {
+	Issues []validation.Issue `json:"issues"`
+}
+
+// errInvalidRequest marks malformed requests rejected by the controllers.
+var errInvalidRequest = errors.New("invalid request")
+
+// errPayloadTooLarge is returned when a request body exceeds its limit.
+var errPayloadTooLarge = errors.New("request body too large")
+
+// invalidRequest wraps a binding or parsing problem as an INVALID_REQUEST error.
+func invalidRequest(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", errInvalidRequest, fmt.Sprintf(format, args...))
+}
+
+// errorMapping reports errors matching target with status and code.
+type errorMapping struct {
+	target  error
+	status  int
+	code    ErrorCode
+	message string
+}
+
+// errorMappings are tried in order; the first match wins.
+var errorMappings = []errorMapping{
+	{errInvalidRequest, http.StatusBadRequest, CodeInvalidRequest, "Invalid request"},
+	{errPayloadTooLarge, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, "Request body too large"},
+	{usecase.ErrInvalidHistory, http.StatusBadRequest, CodeInvalidRequest, "Invalid request"},
+	{usecase.ErrInvalidPatch, http.StatusBadRequest, CodeInvalidRequest, "Invalid request"},
+	{usecase.ErrFormNameRequired, http.StatusBadRequest, CodeInvalidRequest, "Invalid request"},
+	{usecase.ErrInvalidAPIKeyInput, http.StatusBadRequest, CodeInvalidRequest, "Invalid request"},
+	{usecase.ErrInvalidDateRange, http.StatusBadRequest, CodeInvalidRequest, "Invalid request"},
+	{usecase.ErrUnknownPromptVersion, http.StatusBadRequest, CodeInvalidRequest, "Invalid request"},
+	{usecase.ErrEmptyPrompt, http.StatusBadRequest, CodeInvalidRequest, "Invalid request"},
+	{usecase.ErrInvalidSubmissionQuery, http.StatusBadRequest, CodeInvalidRequest, "Invalid request"},
+	{usecase.ErrInvalidWebhook, http.StatusBadRequest, CodeInvalidRequest, "Invalid request"},
+	{usecase.ErrInvalidOptionsQuery, http.StatusBadRequest, CodeInvalidRequest, "Invalid request"},
+	{usecase.ErrPromptTooLong, http.StatusBadRequest, CodeInvalidRequest, "Prompt too long"},
+	{domain.ErrUnauthorized, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized"},
+	{domain.ErrInvalidAPIKey, http.StatusUnauthorized, CodeUnauthorized, "Invalid API key"},
+	{domain.ErrInsufficientScope, http.StatusForbidden, CodeForbidden, "Insufficient scope"},
+	{usecase.ErrInvalidDownloadLink, http.StatusForbidden, CodeForbidden, "Invalid or expired download link"},
+	{domain.ErrFormNotFound, http.StatusNotFound, CodeNotFound, "Form not found"},
+	{domain.ErrSubmissionNotFound, http.StatusNotFound, CodeNotFound, "Submission not found"},
+	{domain.ErrWebhookNotFound, http.StatusNotFound, CodeNotFound, "Webhook not found"},
+	{domain.ErrDeliveryNotFound, http.StatusNotFound, CodeNotFound, "Webhook delivery not found"},
+	{domain.ErrFileNotFound, http.StatusNotFound, CodeNotFound, "File not found"},
+	{domain.ErrFieldNotFound, http.StatusNotFound, CodeNotFound, "Field not found"},
+	{usecase.ErrNoDataSource, http.StatusNotFound, CodeNotFound, "Field has no data source"},
+	{domain.ErrVersionNotFound, http.StatusNotFound, CodeNotFound, "Version not found"},
+	{domain.ErrAPIKeyNotFound, http.StatusNotFound, CodeNotFound, "API key not found"},
+	{domain.ErrVersionConflict, http.StatusConflict, CodeConflict, "Form was modified concurrently"},
+	{usecase.ErrIrrelevantPrompt, http.StatusUnprocessableEntity, CodeIrrelevantPrompt, "The prompt does not describe a form"},
+	{usecase.ErrModelOutputInvalid, http.StatusUnprocessableEntity, CodeModelOutputInvalid, "Invalid form configuration"},
+	{domain.ErrSafetyBlocked, http.StatusUnprocessableEntity, CodeSafetyBlocked, "Request blocked by safety filters"},
+	{domain.ErrRateLimitExceeded, http.StatusTooManyRequests, CodeRateLimited, "Rate limit exceeded"},
+	{domain.ErrQuotaExceeded, http.StatusTooManyRequests, CodeQuotaExceeded, "Token quota exceeded"},
+	{domain.ErrRateLimited, http.StatusTooManyRequests, CodeUpstreamRateLimited, "Model provider rate limit exceeded"},
+	{domain.ErrUpstreamUnavailable, http.StatusServiceUnavailable, CodeUpstreamUnavailable, "Model provider unavailable"},
+	{usecase.ErrDataSourceFailed, http.StatusBadGateway, CodeUpstreamError, "Data source request failed"},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout, "Generation timed out"},
+}
+
+// ErrorMiddleware renders the last error a handler or middleware attached
+// with c.Error as an ErrorResponse, unless a response was already written.
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		status, response := newErrorResponse(c, err)
+		if status >= http.StatusInternalServerError {
+			log.Printf("Request %s failed: %v", response.RequestID, err)
+		}
+		setRetryAfter(c, err)
+		c.JSON(status, response)
+	}
+}
+
+// setRetryAfter passes on how long the model provider asked clients to wait.
+func setRetryAfter(c *gin.Context, err error) {
+	var upstreamErr *domain.UpstreamError
+	if errors.As(err, &upstreamErr) && upstreamErr.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(upstreamErr.RetryAfter.Seconds()))))
+	}
+}
+
+// newErrorResponse maps err to a status and an ErrorResponse.
+func newErrorResponse(c *gin.Context, err error) (int, ErrorResponse) {
+	response := ErrorResponse{
+		Code:      CodeInternal,
+		Message:   "Internal server error",
+		Details:   err.Error(),
+		RequestID: c.GetString("requestID"),
+	}
+	status := http.StatusInternalServerError
+
+	var upstreamErr *domain.UpstreamError
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.target) {
+			status, response.Code, response.Message = mapping.status, mapping.code, mapping.message
+			break
+		}
+	}
+	if response.Code == CodeInternal && errors.As(err, &upstreamErr) {
+		// The provider rejected the call for a reason that is not transient.
+		status, response.Code, response.Message = http.StatusBadGateway, CodeUpstreamError, "Model provider error"
+	}
+
+	var invalidForm *usecase.InvalidFormError
+	if errors.As(err, &invalidForm) {
+		if response.Code == CodeInternal {
+			// Submitted by the client rather than generated.
+			status, response.Code, response.Message = http.StatusUnprocessableEntity, CodeInvalidFormConfig, "Invalid form configuration"
+		}
+		response.Details = InvalidFormDetails{Issues: invalidForm.Issues, RepairAttempts: invalidForm.RepairAttempts}
+	}
+	var invalidSubmission *usecase.InvalidSubmissionError
+	if errors.As(err, &invalidSubmission) {
+		status, response.Code, response.Message = http.StatusUnprocessableEntity, CodeInvalidSubmission, "Invalid submission"
+		response.Details = InvalidSubmissionDetails{Issues: invalidSubmission.Issues}
+	}
+	if status >= http.StatusInternalServerError {
+		response.Details = nil
+	}
+	return status, response
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/usecase"
+	"better-form-doc-backend/validation"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveError renders err through ErrorMiddleware and decodes the response.
+func serveError(t *testing.T, err error) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("requestID", "req-1") })
+	router.Use(ErrorMiddleware())
+	router.GET("/", func(c *gin.Context) { _ = c.Error(err) })
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	var body map[string]interface{}
+	if decodeErr := json.Unmarshal(rec.Body.Bytes(), &body); decodeErr != nil {
+		t.Fatalf("response %s: %v", rec.Body, decodeErr)
+	}
+	return rec, body
+}
+
+func TestErrorMiddlewareMapsErrors(t *testing.T) {
+	invalidForm := &usecase.InvalidFormError{Issues: validation.Issues{{Code: validation.CodeRequired, Message: "Form endpoint is required", Path: validation.Path{"endpoint"}}}, RepairAttempts: 2}
+	tests := []struct {
+		err         error
+		wantStatus  int
+		wantCode    ErrorCode
+		wantDetails bool
+	}{
+		{invalidRequest("prompt is required"), http.StatusBadRequest, CodeInvalidRequest, true},
+		{fmt.Errorf("wrapped: %w", usecase.ErrInvalidPatch), http.StatusBadRequest, CodeInvalidRequest, true},
+		{usecase.ErrUnknownPromptVersion, http.StatusBadRequest, CodeInvalidRequest, true},
+		{usecase.ErrPromptTooLong, http.StatusBadRequest, CodeInvalidRequest, true},
+		{errPayloadTooLarge, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, true},
+		{domain.ErrUnauthorized, http.StatusUnauthorized, CodeUnauthorized, true},
+		{domain.ErrInvalidAPIKey, http.StatusUnauthorized, CodeUnauthorized, true},
+		{domain.ErrInsufficientScope, http.StatusForbidden, CodeForbidden, true},
+		{domain.ErrFormNotFound, http.StatusNotFound, CodeNotFound, true},
+		{domain.ErrVersionNotFound, http.StatusNotFound, CodeNotFound, true},
+		{domain.ErrVersionConflict, http.StatusConflict, CodeConflict, true},
+		{usecase.ErrIrrelevantPrompt, http.StatusUnprocessableEntity, CodeIrrelevantPrompt, true},
+		{invalidForm, http.StatusUnprocessableEntity, CodeInvalidFormConfig, true},
+		{fmt.Errorf("%w: %w", usecase.ErrModelOutputInvalid, invalidForm), http.StatusUnprocessableEntity, CodeModelOutputInvalid, true},
+		{&usecase.InvalidSubmissionError{Issues: validation.Issues{{Code: validation.CodeRequired, Path: validation.Path{"email"}}}}, http.StatusUnprocessableEntity, CodeInvalidSubmission, true},
+		{domain.ErrSafetyBlocked, http.StatusUnprocessableEntity, CodeSafetyBlocked, true},
+		{domain.ErrRateLimitExceeded, http.StatusTooManyRequests, CodeRateLimited, true},
+		{domain.ErrQuotaExceeded, http.StatusTooManyRequests, CodeQuotaExceeded, true},
+		{&domain.UpstreamError{Err: domain.ErrRateLimited, StatusCode: 429, Message: "quota"}, http.StatusTooManyRequests, CodeUpstreamRateLimited, true},
+		{&domain.UpstreamError{Err: domain.ErrUpstreamUnavailable, StatusCode: 503, Message: "api key abc in url"}, http.StatusServiceUnavailable, CodeUpstreamUnavailable, false},
+		{&domain.UpstreamError{StatusCode: 400, Message: "bad request"}, http.StatusBadGateway, CodeUpstreamError, false},
+		{&usecase.UsageError{Err: &domain.UpstreamError{Message: "stream failed"}}, http.StatusBadGateway, CodeUpstreamError, false},
+		{usecase.ErrDataSourceFailed, http.StatusBadGateway, CodeUpstreamError, false},
+		{fmt.Errorf("generation: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, CodeTimeout, false},
+		{errors.New("database is locked"), http.StatusInternalServerError, CodeInternal, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.wantCode)+" "+tt.err.Error(), func(t *testing.T) {
+			rec, body := serveError(t, tt.err)
+			if rec.Code != tt.wantStatus || body["code"] != string(tt.wantCode) {
+				t.Errorf("response = %d %v, want %d %s", rec.Code, body["code"], tt.wantStatus, tt.wantCode)
+			}
+			if body["message"] == "" || body["requestId"] != "req-1" {
+				t.Errorf("body = %v, want a message and the request ID", body)
+			}
+			if _, hasDetails := body["details"]; hasDetails != tt.wantDetails {
+				t.Errorf("details = %v, want present = %v", body["details"], tt.wantDetails)
+			}
+		})
+	}
+}
+
+func TestErrorMiddlewareInvalidFormDetails(t *testing.T) {
+	issues := validation.Issues{{Code: validation.CodeRequired, Message: "Form endpoint is required", Path: validation.Path{"endpoint"}}}
+	rec, _ := serveError(t, fmt.Errorf("%w: %w", usecase.ErrModelOutputInvalid, &usecase.InvalidFormError{Issues: issues, RepairAttempts: 2}))
+	var response struct {
+		Details InvalidFormDetails `json:"details"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &response)
+	if len(response.Details.Issues) != 1 || response.Details.Issues[0].Code != validation.CodeRequired || response.Details.RepairAttempts != 2 {
+		t.Errorf("details = %+v", response.Details)
+	}
+}
+
+func TestErrorMiddlewareRetryAfter(t *testing.T) {
+	rec, _ := serveError(t, &domain.UpstreamError{Err: domain.ErrRateLimited, RetryAfter: 1500 * time.Millisecond, Message: "slow down"})
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+	rec, _ = serveError(t, domain.ErrFormNotFound)
+	if got := rec.Header().Get("Retry-After"); got != "" {
+		t.Errorf("Retry-After = %q, want none", got)
+	}
+}
+
+func TestErrorMiddlewareKeepsWrittenResponses(t *testing.T) {
+	router := gin.New()
+	router.Use(ErrorMiddleware())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusAccepted, "done")
+		_ = c.Error(errors.New("late failure"))
+	})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusAccepted || rec.Body.String() != "done" {
+		t.Errorf("response = %d %s, want the handler's", rec.Code, rec.Body)
+	}
+}
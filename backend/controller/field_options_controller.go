@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"better-form-doc-backend/usecase"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FieldOptionsController will hold the dependencies for the data source proxy
+type FieldOptionsController struct {
+	fieldOptionsUseCase usecase.FieldOptionsUseCaseInterface
+}
+
+// NewFieldOptionsController creates a new instance of FieldOptionsController
+func NewFieldOptionsController(fieldOptionsUseCase usecase.FieldOptionsUseCaseInterface) *FieldOptionsController {
+	return &FieldOptionsController{
+		fieldOptionsUseCase: fieldOptionsUseCase,
+	}
+}
+
+// GetFieldOptions godoc
+// @Summary      Load the options of a field from its data source
+// @Description  Calls the remote endpoint of a field's dataSource on the server, so the secret named by authTokenRef never reaches the browser; it is sent upstream as a bearer token, and only to the hosts the secret is bound to. Endpoints outside the allowed hosts and the server's own origin are refused, as are private addresses. q is passed in queryParam and cursor in cursorParam, or as the page number in pageParam. The items of the response (an array, or its data, items or results member) are mapped to options with labelKey and valueKey. Pass nextCursor back as cursor for the following page. Responses are cached for cacheTtlMs; X-Cache tells whether the page came from the cache. No authentication is required: anyone who knows the form ID may load its options.
+// @Tags         forms
+// @Produce      json
+// @Param        id      path      string  true   "Form ID"
+// @Param        name    path      string  true   "Field name"
+// @Param        q       query     string  false  "Search term"
+// @Param        cursor  query     string  false  "nextCursor of the previous page"
+// @Success      200  {object}  usecase.OptionsPage
+// @Header       200  {string}  X-Cache  "HIT or MISS; absent when the data source sets no cacheTtlMs"
+// @Failure      400  {object}  ErrorResponse  "INVALID_REQUEST"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND: form or field not found, or the field has no data source"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Failure      502  {object}  ErrorResponse  "UPSTREAM_ERROR: the data source failed"
+// @Router       /forms/{id}/fields/{name}/options [get]
+func (fc *FieldOptionsController) GetFieldOptions(c *gin.Context) {
+	page, err := fc.fieldOptionsUseCase.FieldOptions(c.Request.Context(), c.Param("id"), c.Param("name"), c.Query("q"), c.Query("cursor"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if page.Cache != "" {
+		c.Header("X-Cache", page.Cache)
+	}
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,304 @@
+package controller
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/usecase"
+	"better-form-doc-backend/validation"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// anonymousUserID owns the forms saved while authentication is disabled.
+const anonymousUserID = "anonymous"
+
+// FormController will hold the dependencies for the form storage handlers
+type FormController struct {
+	formUseCase usecase.FormUseCaseInterface
+}
+
+// NewFormController creates a new instance of FormController
+func NewFormController(formUseCase usecase.FormUseCaseInterface) *FormController {
+	return &FormController{
+		formUseCase: formUseCase,
+	}
+}
+
+// CreateFormRequest is the body of POST /forms.
+type CreateFormRequest struct {
+	// Name defaults to the form title.
+	Name string `json:"name,omitempty"`
+	// Config is a FormConfig, decoded as strictly as generated ones.
+	Config json.RawMessage `json:"config" binding:"required" swaggertype:"object"`
+	// PromptVersion is the metadata.promptVersion of the generation that
+	// produced the config, recorded on the first version.
+	PromptVersion string `json:"promptVersion,omitempty"`
+}
+
+// UpdateFormRequest is the body of PUT /forms/{id}. Omitted fields are left unchanged.
+type UpdateFormRequest struct {
+	Name *string `json:"name,omitempty"`
+	// Config is a FormConfig, decoded as strictly as generated ones.
+	Config json.RawMessage `json:"config,omitempty" swaggertype:"object"`
+}
+
+// parseFormConfig decodes a FormConfig sent by the client with
+// domain.ParseFormConfig, which rejects unknown properties and unsupported
+// enum values. A missing or null config gives nil.
+func parseFormConfig(raw json.RawMessage) (*domain.FormConfig, error) {
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) == 0 || string(trimmed) == "null" {
+		return nil, nil
+	}
+	config, err := domain.ParseFormConfig(raw)
+	if err != nil {
+		return nil, &usecase.InvalidFormError{Issues: validation.Issues{validation.DecodeIssue(err)}}
+	}
+	return config, nil
+}
+
+// RollbackRequest is the body of POST /forms/{id}/rollback.
+type RollbackRequest struct {
+	Version int `json:"version" binding:"required,min=1"`
+}
+
+// CreateForm godoc
+// @Summary      Save a form
+// @Description  Validates the form configuration and stores it for the current user.
+// @Tags         forms
+// @Accept       json
+// @Produce      json
+// @Param        form  body      CreateFormRequest  true  "Form to save"
+// @Success      201   {object}  domain.StoredForm
+// @Failure      400  {object}  ErrorResponse  "INVALID_REQUEST"
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      422  {object}  ErrorResponse  "INVALID_FORM_CONFIG (details: InvalidFormDetails)"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /forms [post]
+func (fc *FormController) CreateForm(c *gin.Context) {
+	var request CreateFormRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		_ = c.Error(invalidRequest("%v", err))
+		return
+	}
+
+	config, err := parseFormConfig(request.Config)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if config == nil {
+		_ = c.Error(invalidRequest("config is required"))
+		return
+	}
+
+	form, err := fc.formUseCase.CreateForm(currentUserID(c), request.Name, config, request.PromptVersion)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, form)
+}
+
+// ListForms godoc
+// @Summary      List saved forms
+// @Description  Returns the forms of the current user, most recently updated first.
+// @Tags         forms
+// @Produce      json
+// @Success      200  {array}   domain.StoredForm
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /forms [get]
+func (fc *FormController) ListForms(c *gin.Context) {
+	forms, err := fc.formUseCase.ListForms(currentUserID(c))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, forms)
+}
+
+// GetForm godoc
+// @Summary      Get a saved form
+// @Tags         forms
+// @Produce      json
+// @Param        id   path      string  true  "Form ID"
+// @Success      200  {object}  domain.StoredForm
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND: form not found"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /forms/{id} [get]
+func (fc *FormController) GetForm(c *gin.Context) {
+	form, err := fc.formUseCase.GetForm(currentUserID(c), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, form)
+}
+
+// UpdateForm godoc
+// @Summary      Update a saved form
+// @Description  Renames the form and/or replaces its configuration. A new configuration is saved as a new version.
+// @Tags         forms
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string             true  "Form ID"
+// @Param        form  body      UpdateFormRequest  true  "Changes to apply"
+// @Success      200   {object}  domain.StoredForm
+// @Failure      400  {object}  ErrorResponse  "INVALID_REQUEST"
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND: form not found"
+// @Failure      409  {object}  ErrorResponse  "CONFLICT: form was modified concurrently"
+// @Failure      422  {object}  ErrorResponse  "INVALID_FORM_CONFIG (details: InvalidFormDetails)"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /forms/{id} [put]
+func (fc *FormController) UpdateForm(c *gin.Context) {
+	var request UpdateFormRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		_ = c.Error(invalidRequest("%v", err))
+		return
+	}
+
+	config, err := parseFormConfig(request.Config)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	form, err := fc.formUseCase.UpdateForm(currentUserID(c), c.Param("id"), request.Name, config)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, form)
+}
+
+// DeleteForm godoc
+// @Summary      Delete a saved form
+// @Tags         forms
+// @Param        id  path  string  true  "Form ID"
+// @Success      204
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND: form not found"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /forms/{id} [delete]
+func (fc *FormController) DeleteForm(c *gin.Context) {
+	if err := fc.formUseCase.DeleteForm(currentUserID(c), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListVersions godoc
+// @Summary      List the versions of a saved form
+// @Description  Every save, AI refinement and rollback creates an immutable version. Versions are returned oldest first.
+// @Tags         forms
+// @Produce      json
+// @Param        id   path      string  true  "Form ID"
+// @Success      200  {array}   domain.FormVersion
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND: form not found"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /forms/{id}/versions [get]
+func (fc *FormController) ListVersions(c *gin.Context) {
+	versions, err := fc.formUseCase.ListVersions(currentUserID(c), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, versions)
+}
+
+// DiffVersions godoc
+// @Summary      Compare two versions of a saved form
+// @Description  Returns the JSON Patch and change summary turning version `against` into version `v`.
+// @Tags         forms
+// @Produce      json
+// @Param        id       path      string   true   "Form ID"
+// @Param        v        path      integer  true   "Version to inspect"
+// @Param        against  query     integer  false  "Version to compare with (defaults to the preceding version)"
+// @Success      200      {object}  usecase.VersionDiff
+// @Failure      400  {object}  ErrorResponse  "INVALID_REQUEST"
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND: form or version not found"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /forms/{id}/versions/{v}/diff [get]
+func (fc *FormController) DiffVersions(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("v"))
+	if err != nil || version < 1 {
+		_ = c.Error(invalidRequest("version must be a positive integer"))
+		return
+	}
+	against := 0
+	if raw := c.Query("against"); raw != "" {
+		if against, err = strconv.Atoi(raw); err != nil || against < 1 {
+			_ = c.Error(invalidRequest("against must be a positive integer"))
+			return
+		}
+	}
+
+	diff, err := fc.formUseCase.DiffVersions(currentUserID(c), c.Param("id"), version, against)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// Rollback godoc
+// @Summary      Restore an earlier version of a saved form
+// @Description  Saves the config of the given version as a new version; history is never rewritten.
+// @Tags         forms
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string           true  "Form ID"
+// @Param        request  body      RollbackRequest  true  "Version to restore"
+// @Success      200      {object}  domain.StoredForm
+// @Failure      400  {object}  ErrorResponse  "INVALID_REQUEST"
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND: form or version not found"
+// @Failure      409  {object}  ErrorResponse  "CONFLICT: form was modified concurrently"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /forms/{id}/rollback [post]
+func (fc *FormController) Rollback(c *gin.Context) {
+	var request RollbackRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		_ = c.Error(invalidRequest("%v", err))
+		return
+	}
+
+	form, err := fc.formUseCase.Rollback(currentUserID(c), c.Param("id"), request.Version)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, form)
+}
+
+// currentUserID returns the user set by the auth middleware.
+func currentUserID(c *gin.Context) string {
+	if userID := c.GetString("userID"); userID != "" {
+		return userID
+	}
+	return anonymousUserID
+}
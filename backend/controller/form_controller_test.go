@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"better-form-doc-backend/infrastructure"
+	"better-form-doc-backend/usecase"
+	"better-form-doc-backend/validation"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testFormConfig = `{"title":"Contact","endpoint":"/api/contact","submit":{"label":"Send"},"fields":[{"name":"email","type":"email","label":"Email"}]}`
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newFormRouter serves the form and chat endpoints over in-memory storage,
+// with errors rendered by ErrorMiddleware. The chat use case is never
+// reached by the requests of these tests.
+func newFormRouter() *gin.Engine {
+	forms := NewFormController(usecase.NewFormUseCase(infrastructure.NewInMemoryFormRepository(), validation.DataSourcePolicy{}))
+	chat := NewChatController(nil)
+	router := gin.New()
+	router.Use(ErrorMiddleware())
+	router.POST("/forms", forms.CreateForm)
+	router.PUT("/forms/:id", forms.UpdateForm)
+	router.POST("/chat", chat.GenerateChatResponse)
+	router.POST("/chat/stream", chat.StreamChatResponse)
+	return router
+}
+
+func serveJSON(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// invalidFormIssues decodes an INVALID_FORM_CONFIG response.
+func invalidFormIssues(t *testing.T, rec *httptest.ResponseRecorder) []validation.Issue {
+	t.Helper()
+	var response struct {
+		Code    ErrorCode          `json:"code"`
+		Details InvalidFormDetails `json:"details"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("response %s: %v", rec.Body, err)
+	}
+	if rec.Code != http.StatusUnprocessableEntity || response.Code != CodeInvalidFormConfig {
+		t.Fatalf("response = %d %s, want 422 %s", rec.Code, rec.Body, CodeInvalidFormConfig)
+	}
+	return response.Details.Issues
+}
+
+func TestFormConfigIsDecodedStrictly(t *testing.T) {
+	invalid := map[string]string{
+		"unknown property":   `{"endpoint":"/api/contact","submit":{"label":"Send"},"fields":[],"theme":"dark"}`,
+		"unknown field key":  `{"endpoint":"/api/contact","submit":{"label":"Send"},"fields":[{"name":"a","type":"text","colour":"red"}]}`,
+		"method":             `{"endpoint":"/api/contact","method":"TRACE","submit":{"label":"Send"},"fields":[]}`,
+		"submit variant":     `{"endpoint":"/api/contact","submit":{"label":"Send","variant":"sparkly"},"fields":[]}`,
+		"data source type":   `{"endpoint":"/api/contact","submit":{"label":"Send"},"fields":[{"name":"c","type":"select","dataSource":{"type":"graphql","endpoint":"/api/c"}}]}`,
+		"pagination mode":    `{"endpoint":"/api/contact","submit":{"label":"Send"},"fields":[{"name":"c","type":"select","dataSource":{"type":"remote","endpoint":"/api/c","pagination":{"mode":"pages","labelKey":"l","valueKey":"v"}}}]}`,
+		"not an object":      `["fields"]`,
+		"wrongly typed name": `{"endpoint":"/api/contact","submit":{"label":"Send"},"fields":[{"name":7,"type":"text"}]}`,
+	}
+	router := newFormRouter()
+	created := serveJSON(router, http.MethodPost, "/forms", `{"config":`+testFormConfig+`}`)
+	if created.Code != http.StatusCreated {
+		t.Fatalf("POST /forms = %d %s, want 201", created.Code, created.Body)
+	}
+	var form struct{ ID string }
+	_ = json.Unmarshal(created.Body.Bytes(), &form)
+
+	for name, config := range invalid {
+		t.Run(name, func(t *testing.T) {
+			requests := map[string]*httptest.ResponseRecorder{
+				"POST /forms":       serveJSON(router, http.MethodPost, "/forms", `{"config":`+config+`}`),
+				"PUT /forms/{id}":   serveJSON(router, http.MethodPut, "/forms/"+form.ID, `{"config":`+config+`}`),
+				"POST /chat":        serveJSON(router, http.MethodPost, "/chat", `{"prompt":"add a phone field","currentForm":`+config+`}`),
+				"POST /chat/stream": serveJSON(router, http.MethodPost, "/chat/stream", `{"prompt":"add a phone field","currentForm":`+config+`}`),
+			}
+			for request, rec := range requests {
+				issues := invalidFormIssues(t, rec)
+				if len(issues) != 1 || issues[0].Code != validation.CodeInvalidJSON {
+					t.Errorf("%s: issues = %+v, want one %s issue", request, issues, validation.CodeInvalidJSON)
+				}
+			}
+		})
+	}
+}
+
+func TestFormConfigIsOptionalOnUpdate(t *testing.T) {
+	router := newFormRouter()
+	created := serveJSON(router, http.MethodPost, "/forms", `{"name":"Contact","config":`+testFormConfig+`}`)
+	var form struct{ ID string }
+	_ = json.Unmarshal(created.Body.Bytes(), &form)
+
+	for _, body := range []string{`{"name":"Renamed"}`, `{"name":"Renamed","config":null}`} {
+		if rec := serveJSON(router, http.MethodPut, "/forms/"+form.ID, body); rec.Code != http.StatusOK {
+			t.Errorf("PUT %s = %d %s, want 200", body, rec.Code, rec.Body)
+		}
+	}
+	for _, body := range []string{`{}`, `{"config":null}`} {
+		if rec := serveJSON(router, http.MethodPost, "/forms", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("POST %s = %d %s, want 400", body, rec.Code, rec.Body)
+		}
+	}
+}
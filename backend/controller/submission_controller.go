@@ -0,0 +1,317 @@
+package controller
+
+import (
+	"better-form-doc-backend/usecase"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSubmissionBytes bounds the body of a form submission without files.
+const maxSubmissionBytes = 1 << 20
+
+// maxUploadBytes bounds the body of a multipart submission, all files
+// included. The maxSize attribute of a file field cannot raise it.
+const maxUploadBytes = 50 << 20
+
+// multipartMemory is how much of a multipart body is kept in memory; larger
+// files are spooled to temporary files.
+const multipartMemory = 8 << 20
+
+// SubmissionController will hold the dependencies for the submission handlers
+type SubmissionController struct {
+	submissionUseCase usecase.SubmissionUseCaseInterface
+}
+
+// NewSubmissionController creates a new instance of SubmissionController
+func NewSubmissionController(submissionUseCase usecase.SubmissionUseCaseInterface) *SubmissionController {
+	return &SubmissionController{
+		submissionUseCase: submissionUseCase,
+	}
+}
+
+// Submit godoc
+// @Summary      Submit a stored form
+// @Description  Validates the values against the stored form with the rules the frontend applies (required, min/max, pattern, email, url, sameAs, maxSelections; fields hidden by visibleWhen are skipped), coerces them by dataType and stores the submission. Accepts a JSON object keyed by field name, a URL-encoded form or, to upload files to file fields, a multipart form. Uploads are checked against the accept, maxSize (default 10MB), multiple and maxFiles attributes of their field, with the type sniffed from the content; the submission lists them under files with signed download URLs. No authentication is required: anyone who knows the form ID may submit.
+// @Tags         submissions
+// @Accept       json
+// @Accept       x-www-form-urlencoded
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        formId  path      string                  true  "Form ID"
+// @Param        values  body      map[string]interface{}  true  "Field values keyed by field name"
+// @Success      201  {object}  domain.Submission
+// @Failure      400  {object}  ErrorResponse  "INVALID_REQUEST"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND"
+// @Failure      413  {object}  ErrorResponse  "PAYLOAD_TOO_LARGE"
+// @Failure      422  {object}  ErrorResponse  "INVALID_SUBMISSION (details: InvalidSubmissionDetails)"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Router       /submit/{formId} [post]
+func (sc *SubmissionController) Submit(c *gin.Context) {
+	limit := int64(maxSubmissionBytes)
+	if c.ContentType() == gin.MIMEMultipartPOSTForm {
+		limit = maxUploadBytes
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+
+	values, err := bindSubmission(c)
+	if form := c.Request.MultipartForm; form != nil {
+		defer form.RemoveAll()
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		_ = c.Error(errPayloadTooLarge)
+		return
+	}
+	if err != nil {
+		_ = c.Error(invalidRequest("%v", err))
+		return
+	}
+	uploads, closeUploads, err := openUploads(c.Request.MultipartForm)
+	defer closeUploads()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	submission, err := sc.submissionUseCase.Submit(c.Param("formId"), values, uploads)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, submission)
+}
+
+// bindSubmission reads the submitted values from a JSON object, a
+// URL-encoded form or the non-file parts of a multipart form. Repeated form
+// keys become arrays.
+func bindSubmission(c *gin.Context) (map[string]interface{}, error) {
+	switch c.ContentType() {
+	case gin.MIMEPOSTForm:
+		if err := c.Request.ParseForm(); err != nil {
+			return nil, err
+		}
+		values := make(map[string]interface{}, len(c.Request.PostForm))
+		for key, list := range c.Request.PostForm {
+			values[key] = formValue(list)
+		}
+		return values, nil
+	case gin.MIMEMultipartPOSTForm:
+		if err := c.Request.ParseMultipartForm(multipartMemory); err != nil {
+			return nil, err
+		}
+		values := make(map[string]interface{}, len(c.Request.MultipartForm.Value))
+		for key, list := range c.Request.MultipartForm.Value {
+			values[key] = formValue(list)
+		}
+		return values, nil
+	}
+
+	var values map[string]interface{}
+	if err := c.ShouldBindJSON(&values); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		return nil, errors.New("body must be a JSON object")
+	}
+	return values, nil
+}
+
+// openUploads opens the file parts of a multipart form, keyed by field
+// name. The returned function closes them and must be called even on error.
+func openUploads(form *multipart.Form) (map[string][]usecase.FileUpload, func(), error) {
+	var opened []multipart.File
+	closeAll := func() {
+		for _, file := range opened {
+			_ = file.Close()
+		}
+	}
+	if form == nil {
+		return nil, closeAll, nil
+	}
+	uploads := make(map[string][]usecase.FileUpload, len(form.File))
+	for key, headers := range form.File {
+		for _, header := range headers {
+			file, err := header.Open()
+			if err != nil {
+				return nil, closeAll, fmt.Errorf("failed to read upload: %w", err)
+			}
+			opened = append(opened, file)
+			uploads[key] = append(uploads[key], usecase.FileUpload{Name: header.Filename, Size: header.Size, Content: file})
+		}
+	}
+	return uploads, closeAll, nil
+}
+
+// formValue returns a single form value as a string and repeated ones as an array.
+func formValue(list []string) interface{} {
+	if len(list) == 1 {
+		return list[0]
+	}
+	items := make([]interface{}, len(list))
+	for i, item := range list {
+		items[i] = item
+	}
+	return items
+}
+
+// ListSubmissions godoc
+// @Summary      List the submissions of a form
+// @Description  Returns the submissions of a form of the current user, newest first. Pass nextCursor back as cursor to fetch the next page. Filter by field value with filter[name]=value; an array value such as a multiselect matches when it contains the value.
+// @Tags         submissions
+// @Produce      json
+// @Param        id            path      string  true   "Form ID"
+// @Param        from          query     string  false  "Earliest submission time, YYYY-MM-DD or RFC 3339"
+// @Param        to            query     string  false  "Latest submission time, YYYY-MM-DD (inclusive) or RFC 3339 (exclusive)"
+// @Param        filter[name]  query     string  false  "Value the field called name must have"
+// @Param        cursor        query     string  false  "nextCursor of the previous page"
+// @Param        limit         query     int     false  "Page size (default 50, max 200)"
+// @Success      200  {object}  usecase.SubmissionPage
+// @Failure      400  {object}  ErrorResponse  "INVALID_REQUEST"
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND: form not found"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /forms/{id}/submissions [get]
+func (sc *SubmissionController) ListSubmissions(c *gin.Context) {
+	filter, err := bindSubmissionFilter(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			_ = c.Error(invalidRequest("limit must be a positive integer"))
+			return
+		}
+	}
+
+	page, err := sc.submissionUseCase.ListSubmissions(currentUserID(c), c.Param("id"), filter, c.Query("cursor"), limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// ExportSubmissions godoc
+// @Summary      Export the submissions of a form
+// @Description  Downloads every matching submission of a form of the current user, newest first. CSV and XLSX files have a column per field, titled with the field label; multiselect and other array values are joined with "; ". JSONL files hold one submission object per line. Takes the same filters as the listing.
+// @Tags         submissions
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param        id            path      string  true   "Form ID"
+// @Param        format        query     string  false  "File format" Enums(csv, jsonl, xlsx) default(csv)
+// @Param        from          query     string  false  "Earliest submission time, YYYY-MM-DD or RFC 3339"
+// @Param        to            query     string  false  "Latest submission time, YYYY-MM-DD (inclusive) or RFC 3339 (exclusive)"
+// @Param        filter[name]  query     string  false  "Value the field called name must have"
+// @Success      200  {file}    file
+// @Failure      400  {object}  ErrorResponse  "INVALID_REQUEST"
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND: form not found"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /forms/{id}/submissions/export [get]
+func (sc *SubmissionController) ExportSubmissions(c *gin.Context) {
+	format, ok := exportFormats[c.DefaultQuery("format", "csv")]
+	if !ok {
+		_ = c.Error(invalidRequest("format must be csv, jsonl or xlsx"))
+		return
+	}
+	filter, err := bindSubmissionFilter(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	formID := c.Param("id")
+	c.Header("Content-Type", format.contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="submissions-%s.%s"`, formID, format.extension))
+	err = sc.submissionUseCase.ExportSubmissions(currentUserID(c), formID, filter, format.newWriter(c.Writer))
+	if err == nil {
+		return
+	}
+	if c.Writer.Written() {
+		// The status line is gone; all that is left is to cut the file short.
+		log.Printf("Export of the submissions of form %s failed: %v", formID, err)
+		return
+	}
+	c.Writer.Header().Del("Content-Type")
+	c.Writer.Header().Del("Content-Disposition")
+	_ = c.Error(err)
+}
+
+// bindSubmissionFilter reads the from, to and filter[name] query parameters.
+func bindSubmissionFilter(c *gin.Context) (usecase.SubmissionFilter, error) {
+	var filter usecase.SubmissionFilter
+	var err error
+	if raw := c.Query("from"); raw != "" {
+		if filter.From, _, err = parseSubmissionTime(raw); err != nil {
+			return filter, invalidRequest("from must be a date such as 2025-01-01 or an RFC 3339 time")
+		}
+	}
+	if raw := c.Query("to"); raw != "" {
+		to, dateOnly, err := parseSubmissionTime(raw)
+		if err != nil {
+			return filter, invalidRequest("to must be a date such as 2025-01-31 or an RFC 3339 time")
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1) // include the whole day
+		}
+		filter.To = to
+	}
+	if fields := c.QueryMap("filter"); len(fields) > 0 {
+		filter.Fields = fields
+	}
+	return filter, nil
+}
+
+// parseSubmissionTime accepts a UTC date or an RFC 3339 time and reports
+// which of the two it was given.
+func parseSubmissionTime(raw string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	return t, false, err
+}
+
+// DownloadFile godoc
+// @Summary      Download an uploaded file
+// @Description  Streams a file uploaded with a submission. The URL, including its expires and signature parameters, is taken from the files of the submission; it needs no other credentials and stops working when it expires.
+// @Tags         submissions
+// @Produce      octet-stream
+// @Param        submissionId  path      string  true  "Submission ID"
+// @Param        fileId        path      string  true  "File ID"
+// @Param        expires       query     string  true  "Expiry of the URL, Unix seconds"
+// @Param        signature     query     string  true  "Signature of the URL"
+// @Success      200  {file}    file
+// @Failure      403  {object}  ErrorResponse  "FORBIDDEN: invalid or expired download link"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND: file not found"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Router       /submissions/{submissionId}/files/{fileId} [get]
+func (sc *SubmissionController) DownloadFile(c *gin.Context) {
+	file, content, err := sc.submissionUseCase.OpenFile(c.Param("submissionId"), c.Param("fileId"), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, file.Size, file.ContentType, content, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "private, no-store",
+	})
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"archive/zip"
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/usecase"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// exportFormat is a file format submissions can be exported in.
+type exportFormat struct {
+	contentType string
+	extension   string
+	newWriter   func(w io.Writer) usecase.SubmissionExportWriter
+}
+
+var exportFormats = map[string]exportFormat{
+	"csv": {
+		contentType: "text/csv; charset=utf-8",
+		extension:   "csv",
+		newWriter:   func(w io.Writer) usecase.SubmissionExportWriter { return &csvExportWriter{w: csv.NewWriter(w)} },
+	},
+	"jsonl": {
+		contentType: "application/x-ndjson",
+		extension:   "jsonl",
+		newWriter:   func(w io.Writer) usecase.SubmissionExportWriter { return newJSONLExportWriter(w) },
+	},
+	"xlsx": {
+		contentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		extension:   "xlsx",
+		newWriter:   func(w io.Writer) usecase.SubmissionExportWriter { return &xlsxExportWriter{zip: zip.NewWriter(w)} },
+	},
+}
+
+// csvExportWriter writes one row per submission under a header row.
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func (e *csvExportWriter) WriteHeader(columns []string) error {
+	return e.w.Write(columns)
+}
+
+func (e *csvExportWriter) WriteRow(_ *domain.Submission, cells []string) error {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = escapeFormula(cell)
+	}
+	return e.w.Write(escaped)
+}
+
+func (e *csvExportWriter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// escapeFormula keeps spreadsheet applications from evaluating a submitted
+// value as a formula when they open the CSV file.
+func escapeFormula(cell string) string {
+	if cell == "" || !strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return cell
+	}
+	if _, err := strconv.ParseFloat(cell, 64); err == nil {
+		return cell // a negative number
+	}
+	return "'" + cell
+}
+
+// jsonlExportWriter writes every submission as a JSON object on its own line.
+// Values keep their types, so the column titles are not needed.
+type jsonlExportWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func newJSONLExportWriter(w io.Writer) *jsonlExportWriter {
+	buf := bufio.NewWriter(w)
+	return &jsonlExportWriter{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+func (e *jsonlExportWriter) WriteHeader([]string) error {
+	return nil
+}
+
+func (e *jsonlExportWriter) WriteRow(submission *domain.Submission, _ []string) error {
+	return e.enc.Encode(submission)
+}
+
+func (e *jsonlExportWriter) Close() error {
+	return e.buf.Flush()
+}
+
+// xlsxExportWriter writes a workbook with a single sheet of inline strings.
+// Rows are streamed into the sheet; the remaining parts of the package are
+// added on Close.
+type xlsxExportWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+}
+
+const xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+const xlsxSheetEnd = `</sheetData></worksheet>`
+
+// xlsxParts are the static parts of the package, by path.
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Submissions" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+func (e *xlsxExportWriter) WriteHeader(columns []string) error {
+	part, err := e.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	e.sheet = bufio.NewWriter(part)
+	if _, err := e.sheet.WriteString(xlsxSheetStart); err != nil {
+		return err
+	}
+	return e.writeRow(columns)
+}
+
+func (e *xlsxExportWriter) WriteRow(_ *domain.Submission, cells []string) error {
+	return e.writeRow(cells)
+}
+
+func (e *xlsxExportWriter) writeRow(cells []string) error {
+	e.sheet.WriteString("<row>")
+	for _, cell := range cells {
+		e.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		if err := xml.EscapeText(e.sheet, []byte(cell)); err != nil {
+			return err
+		}
+		e.sheet.WriteString("</t></is></c>")
+	}
+	_, err := e.sheet.WriteString("</row>")
+	return err
+}
+
+func (e *xlsxExportWriter) Close() error {
+	if _, err := e.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := e.sheet.Flush(); err != nil {
+		return err
+	}
+	for _, p := range xlsxParts {
+		part, err := e.zip.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(part, p.content); err != nil {
+			return fmt.Errorf("failed to write %s: %w", p.name, err)
+		}
+	}
+	return e.zip.Close()
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/usecase"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultUsageReportDays is the range reported when no dates are given.
+const defaultUsageReportDays = 30
+
+// UsageController will hold the dependencies for the usage report handlers
+type UsageController struct {
+	usageUseCase usecase.UsageUseCaseInterface
+}
+
+// NewUsageController creates a new instance of UsageController
+func NewUsageController(usageUseCase usecase.UsageUseCaseInterface) *UsageController {
+	return &UsageController{
+		usageUseCase: usageUseCase,
+	}
+}
+
+// GetUsage godoc
+// @Summary      Report token usage
+// @Description  Returns the model token consumption per UTC day and model. Defaults to the last 30 days. Reporting on another user requires the admin scope.
+// @Tags         usage
+// @Produce      json
+// @Param        from    query     string  false  "First day, YYYY-MM-DD"
+// @Param        to      query     string  false  "Last day, YYYY-MM-DD (default today)"
+// @Param        userId  query     string  false  "User to report on (admin only)"
+// @Success      200  {object}  usecase.UsageReport
+// @Failure      400  {object}  ErrorResponse  "INVALID_REQUEST"
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      403  {object}  ErrorResponse  "FORBIDDEN"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /usage [get]
+func (uc *UsageController) GetUsage(c *gin.Context) {
+	userID := currentUserID(c)
+	if requested := c.Query("userId"); requested != "" && requested != userID {
+		scopes, _ := c.Get("scopes")
+		granted, _ := scopes.([]string)
+		if !slices.Contains(granted, domain.ScopeAdmin) {
+			_ = c.Error(fmt.Errorf("%w: reporting on another user requires the admin scope", domain.ErrInsufficientScope))
+			return
+		}
+		userID = requested
+	}
+
+	to := time.Now().UTC()
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			_ = c.Error(invalidRequest("to must be a date such as 2025-01-31"))
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -(defaultUsageReportDays - 1))
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			_ = c.Error(invalidRequest("from must be a date such as 2025-01-01"))
+			return
+		}
+		from = parsed
+	}
+
+	report, err := uc.usageUseCase.DailyUsage(userID, from, to)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
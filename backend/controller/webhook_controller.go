@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"better-form-doc-backend/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookController will hold the dependencies for the webhook handlers
+type WebhookController struct {
+	webhookUseCase usecase.WebhookUseCaseInterface
+}
+
+// NewWebhookController creates a new instance of WebhookController
+func NewWebhookController(webhookUseCase usecase.WebhookUseCaseInterface) *WebhookController {
+	return &WebhookController{
+		webhookUseCase: webhookUseCase,
+	}
+}
+
+// CreateWebhookRequest is the body of POST /forms/{id}/webhooks.
+type CreateWebhookRequest struct {
+	URL string `json:"url" binding:"required"`
+	// Secret signs the requests, at least 16 characters; one is generated
+	// when omitted.
+	Secret string `json:"secret,omitempty"`
+	// Events the webhook receives; defaults to every event.
+	Events []string `json:"events,omitempty" enums:"submission.created"`
+}
+
+// CreateWebhook godoc
+// @Summary      Add a webhook to a form
+// @Description  Registers an endpoint that receives the events of a form as signed POST requests with a usecase.WebhookEventPayload body. Deliveries are sent asynchronously and retried with exponential backoff until a 2xx response; deliveries that fail every attempt are dead-lettered. The X-BetterForm-Signature header is "t=<unix time>,v1=<hex HMAC-SHA256 of '<t>.<body>' keyed with the secret>". The secret is only returned in this response.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                true  "Form ID"
+// @Param        webhook  body      CreateWebhookRequest  true  "Webhook to create"
+// @Success      201  {object}  usecase.CreatedWebhook
+// @Failure      400  {object}  ErrorResponse  "INVALID_REQUEST"
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND: form not found"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /forms/{id}/webhooks [post]
+func (wc *WebhookController) CreateWebhook(c *gin.Context) {
+	var request CreateWebhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		_ = c.Error(invalidRequest("%v", err))
+		return
+	}
+
+	webhook, err := wc.webhookUseCase.CreateWebhook(currentUserID(c), c.Param("id"), usecase.CreateWebhookInput{
+		URL:    request.URL,
+		Secret: request.Secret,
+		Events: request.Events,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// ListWebhooks godoc
+// @Summary      List the webhooks of a form
+// @Description  Returns the webhooks of a form (without their secrets), oldest first.
+// @Tags         webhooks
+// @Produce      json
+// @Param        id   path      string  true  "Form ID"
+// @Success      200  {array}   domain.Webhook
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND: form not found"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /forms/{id}/webhooks [get]
+func (wc *WebhookController) ListWebhooks(c *gin.Context) {
+	webhooks, err := wc.webhookUseCase.ListWebhooks(currentUserID(c), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// DeleteWebhook godoc
+// @Summary      Remove a webhook
+// @Description  Removes a webhook together with its deliveries; pending retries are dropped.
+// @Tags         webhooks
+// @Param        id         path  string  true  "Form ID"
+// @Param        webhookId  path  string  true  "Webhook ID"
+// @Success      204
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND: form or webhook not found"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /forms/{id}/webhooks/{webhookId} [delete]
+func (wc *WebhookController) DeleteWebhook(c *gin.Context) {
+	if err := wc.webhookUseCase.DeleteWebhook(currentUserID(c), c.Param("id"), c.Param("webhookId")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries godoc
+// @Summary      List webhook deliveries
+// @Description  Returns the latest deliveries of the webhooks of a form, newest first, with their status, attempt count and last error. status=dead lists the dead letters: deliveries that failed every attempt.
+// @Tags         webhooks
+// @Produce      json
+// @Param        id         path      string  true   "Form ID"
+// @Param        webhookId  query     string  false  "Only list the deliveries of this webhook"
+// @Param        status     query     string  false  "Only list deliveries with this status" Enums(pending, succeeded, dead)
+// @Param        limit      query     int     false  "Number of deliveries (default 50, max 200)"
+// @Success      200  {array}   domain.WebhookDelivery
+// @Failure      400  {object}  ErrorResponse  "INVALID_REQUEST"
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND: form not found"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /forms/{id}/webhooks/deliveries [get]
+func (wc *WebhookController) ListDeliveries(c *gin.Context) {
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			_ = c.Error(invalidRequest("limit must be a positive integer"))
+			return
+		}
+	}
+
+	deliveries, err := wc.webhookUseCase.ListDeliveries(currentUserID(c), c.Param("id"), c.Query("webhookId"), c.Query("status"), limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// Redeliver godoc
+// @Summary      Redeliver a webhook event
+// @Description  Queues a delivery, typically a dead letter, to be sent again right away with a fresh set of attempts. The payload and delivery ID stay the same.
+// @Tags         webhooks
+// @Produce      json
+// @Param        id          path      string  true  "Form ID"
+// @Param        deliveryId  path      string  true  "Delivery ID"
+// @Success      202  {object}  domain.WebhookDelivery
+// @Failure      401  {object}  ErrorResponse  "UNAUTHORIZED"
+// @Failure      404  {object}  ErrorResponse  "NOT_FOUND: form or delivery not found"
+// @Failure      500  {object}  ErrorResponse  "INTERNAL_ERROR"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /forms/{id}/webhooks/deliveries/{deliveryId}/redeliver [post]
+func (wc *WebhookController) Redeliver(c *gin.Context) {
+	delivery, err := wc.webhookUseCase.Redeliver(currentUserID(c), c.Param("id"), c.Param("deliveryId"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
@@ -24,14 +24,193 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/api-keys": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists API keys (without their secrets), newest first. Requires the admin scope.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List API keys",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only list the keys of this user",
+                        "name": "ownerId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.APIKey"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "FORBIDDEN",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a long-lived API key for machine clients. The key is only returned in this response; store it securely. Requires the admin scope.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Mint an API key",
+                "parameters": [
+                    {
+                        "description": "Key to create",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.MintAPIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.MintedAPIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "INVALID_REQUEST",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "FORBIDDEN",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/api-keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revokes a key immediately. Revoked keys stay listed. Requires the admin scope.",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "FORBIDDEN",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: API key not found",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/chat": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
                     }
                 ],
-                "description": "Accepts a user prompt and returns a JSON response from the Gemini AI model.",
+                "description": "Accepts a user prompt and returns a JSON response from the Gemini AI model. Pass the returned conversationId (or the history / currentForm) to refine the form in follow-up requests. With formId the stored form is refined and the result saved as a new version. New forms are cached by normalized prompt; X-Cache tells whether the answer came from the cache.",
                 "consumes": [
                     "application/json"
                 ],
@@ -51,52 +230,2698 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/controller.ChatRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "no-cache generates the form again instead of serving it from the cache",
+                        "name": "Cache-Control",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/usecase.GenerationResult"
+                        },
+                        "headers": {
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "HIT or MISS; absent for requests that are not cacheable"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Invalid request",
+                        "description": "INVALID_REQUEST, e.g. an empty or too long prompt or an unknown promptVersion",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/controller.ErrorResponse"
                         }
                     },
                     "401": {
-                        "description": "Unauthorized",
+                        "description": "UNAUTHORIZED",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: stored form not found",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "CONFLICT: stored form was modified concurrently",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "INVALID_FORM_CONFIG for an invalid currentForm, IRRELEVANT_PROMPT, MODEL_OUTPUT_INVALID (details: InvalidFormDetails) or SAFETY_BLOCKED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "RATE_LIMITED, QUOTA_EXCEEDED or UPSTREAM_RATE_LIMITED (see Retry-After)",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Server error",
+                        "description": "INTERNAL_ERROR",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "UPSTREAM_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "UPSTREAM_UNAVAILABLE (see Retry-After)",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "TIMEOUT",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "controller.ChatRequest": {
-            "type": "object",
-            "required": [
-                "prompt"
-            ],
-            "properties": {
-                "prompt": {
-                    "type": "string"
+        },
+        "/chat/stream": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Accepts a user prompt and streams the generation as SSE events: ` + "`" + `token` + "`" + ` ({\"text\"}) for every partial chunk, ` + "`" + `repair` + "`" + ` (RepairEvent) when an invalid answer is sent back for correction, then a final ` + "`" + `form` + "`" + ` (usecase.GenerationResult) or ` + "`" + `error` + "`" + ` (ErrorResponse, with the same codes as POST /chat).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Stream a form generation over Server-Sent Events",
+                "parameters": [
+                    {
+                        "description": "User's prompt for the AI",
+                        "name": "prompt",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.ChatRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event stream",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "INVALID_REQUEST",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "INVALID_FORM_CONFIG for an invalid currentForm (details: InvalidFormDetails)",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "RATE_LIMITED or QUOTA_EXCEEDED (see Retry-After)",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
                 }
             }
-        }
-    },
-    "securityDefinitions": {
+        },
+        "/forms": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the forms of the current user, most recently updated first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "forms"
+                ],
+                "summary": "List saved forms",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.StoredForm"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Validates the form configuration and stores it for the current user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "forms"
+                ],
+                "summary": "Save a form",
+                "parameters": [
+                    {
+                        "description": "Form to save",
+                        "name": "form",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.CreateFormRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/domain.StoredForm"
+                        }
+                    },
+                    "400": {
+                        "description": "INVALID_REQUEST",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "INVALID_FORM_CONFIG (details: InvalidFormDetails)",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/forms/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "forms"
+                ],
+                "summary": "Get a saved form",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.StoredForm"
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: form not found",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Renames the form and/or replaces its configuration. A new configuration is saved as a new version.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "forms"
+                ],
+                "summary": "Update a saved form",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Changes to apply",
+                        "name": "form",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.UpdateFormRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.StoredForm"
+                        }
+                    },
+                    "400": {
+                        "description": "INVALID_REQUEST",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: form not found",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "CONFLICT: form was modified concurrently",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "INVALID_FORM_CONFIG (details: InvalidFormDetails)",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "forms"
+                ],
+                "summary": "Delete a saved form",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: form not found",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/forms/{id}/fields/{name}/options": {
+            "get": {
+                "description": "Calls the remote endpoint of a field's dataSource on the server, so the secret named by authTokenRef never reaches the browser; it is sent upstream as a bearer token, and only to the hosts the secret is bound to. Endpoints outside the allowed hosts and the server's own origin are refused, as are private addresses. q is passed in queryParam and cursor in cursorParam, or as the page number in pageParam. The items of the response (an array, or its data, items or results member) are mapped to options with labelKey and valueKey. Pass nextCursor back as cursor for the following page. Responses are cached for cacheTtlMs; X-Cache tells whether the page came from the cache. No authentication is required: anyone who knows the form ID may load its options.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "forms"
+                ],
+                "summary": "Load the options of a field from its data source",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Field name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search term",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "nextCursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.OptionsPage"
+                        },
+                        "headers": {
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "HIT or MISS; absent when the data source sets no cacheTtlMs"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "INVALID_REQUEST",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: form or field not found, or the field has no data source",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "UPSTREAM_ERROR: the data source failed",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/forms/{id}/rollback": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Saves the config of the given version as a new version; history is never rewritten.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "forms"
+                ],
+                "summary": "Restore an earlier version of a saved form",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Version to restore",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RollbackRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.StoredForm"
+                        }
+                    },
+                    "400": {
+                        "description": "INVALID_REQUEST",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: form or version not found",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "CONFLICT: form was modified concurrently",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/forms/{id}/submissions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the submissions of a form of the current user, newest first. Pass nextCursor back as cursor to fetch the next page. Filter by field value with filter[name]=value; an array value such as a multiselect matches when it contains the value.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "submissions"
+                ],
+                "summary": "List the submissions of a form",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest submission time, YYYY-MM-DD or RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest submission time, YYYY-MM-DD (inclusive) or RFC 3339 (exclusive)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Value the field called name must have",
+                        "name": "filter[name]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "nextCursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.SubmissionPage"
+                        }
+                    },
+                    "400": {
+                        "description": "INVALID_REQUEST",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: form not found",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/forms/{id}/submissions/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Downloads every matching submission of a form of the current user, newest first. CSV and XLSX files have a column per field, titled with the field label; multiselect and other array values are joined with \"; \". JSONL files hold one submission object per line. Takes the same filters as the listing.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "submissions"
+                ],
+                "summary": "Export the submissions of a form",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "jsonl",
+                            "xlsx"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "File format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest submission time, YYYY-MM-DD or RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest submission time, YYYY-MM-DD (inclusive) or RFC 3339 (exclusive)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Value the field called name must have",
+                        "name": "filter[name]",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "INVALID_REQUEST",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: form not found",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/forms/{id}/versions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Every save, AI refinement and rollback creates an immutable version. Versions are returned oldest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "forms"
+                ],
+                "summary": "List the versions of a saved form",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.FormVersion"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: form not found",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/forms/{id}/versions/{v}/diff": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the JSON Patch and change summary turning version ` + "`" + `against` + "`" + ` into version ` + "`" + `v` + "`" + `.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "forms"
+                ],
+                "summary": "Compare two versions of a saved form",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Version to inspect",
+                        "name": "v",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Version to compare with (defaults to the preceding version)",
+                        "name": "against",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.VersionDiff"
+                        }
+                    },
+                    "400": {
+                        "description": "INVALID_REQUEST",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: form or version not found",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/forms/{id}/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the webhooks of a form (without their secrets), oldest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List the webhooks of a form",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Webhook"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: form not found",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Registers an endpoint that receives the events of a form as signed POST requests with a usecase.WebhookEventPayload body. Deliveries are sent asynchronously and retried with exponential backoff until a 2xx response; deliveries that fail every attempt are dead-lettered. The X-BetterForm-Signature header is \"t=\u003cunix time\u003e,v1=\u003chex HMAC-SHA256 of '\u003ct\u003e.\u003cbody\u003e' keyed with the secret\u003e\". The secret is only returned in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Add a webhook to a form",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook to create",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.CreateWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.CreatedWebhook"
+                        }
+                    },
+                    "400": {
+                        "description": "INVALID_REQUEST",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: form not found",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/forms/{id}/webhooks/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the latest deliveries of the webhooks of a form, newest first, with their status, attempt count and last error. status=dead lists the dead letters: deliveries that failed every attempt.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list the deliveries of this webhook",
+                        "name": "webhookId",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "succeeded",
+                            "dead"
+                        ],
+                        "type": "string",
+                        "description": "Only list deliveries with this status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of deliveries (default 50, max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.WebhookDelivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "INVALID_REQUEST",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: form not found",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/forms/{id}/webhooks/deliveries/{deliveryId}/redeliver": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Queues a delivery, typically a dead letter, to be sent again right away with a fresh set of attempts. The payload and delivery ID stay the same.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Redeliver a webhook event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delivery ID",
+                        "name": "deliveryId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/domain.WebhookDelivery"
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: form or delivery not found",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/forms/{id}/webhooks/{webhookId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Removes a webhook together with its deliveries; pending retries are dropped.",
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Remove a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "webhookId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: form or webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/submissions/{submissionId}/files/{fileId}": {
+            "get": {
+                "description": "Streams a file uploaded with a submission. The URL, including its expires and signature parameters, is taken from the files of the submission; it needs no other credentials and stops working when it expires.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "submissions"
+                ],
+                "summary": "Download an uploaded file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Submission ID",
+                        "name": "submissionId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File ID",
+                        "name": "fileId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expiry of the URL, Unix seconds",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signature of the URL",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "FORBIDDEN: invalid or expired download link",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND: file not found",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/submit/{formId}": {
+            "post": {
+                "description": "Validates the values against the stored form with the rules the frontend applies (required, min/max, pattern, email, url, sameAs, maxSelections; fields hidden by visibleWhen are skipped), coerces them by dataType and stores the submission. Accepts a JSON object keyed by field name, a URL-encoded form or, to upload files to file fields, a multipart form. Uploads are checked against the accept, maxSize (default 10MB), multiple and maxFiles attributes of their field, with the type sniffed from the content; the submission lists them under files with signed download URLs. No authentication is required: anyone who knows the form ID may submit.",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "submissions"
+                ],
+                "summary": "Submit a stored form",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form ID",
+                        "name": "formId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Field values keyed by field name",
+                        "name": "values",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Submission"
+                        }
+                    },
+                    "400": {
+                        "description": "INVALID_REQUEST",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NOT_FOUND",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "PAYLOAD_TOO_LARGE",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "INVALID_SUBMISSION (details: InvalidSubmissionDetails)",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/usage": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the model token consumption per UTC day and model. Defaults to the last 30 days. Reporting on another user requires the admin scope.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "usage"
+                ],
+                "summary": "Report token usage",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day, YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day, YYYY-MM-DD (default today)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User to report on (admin only)",
+                        "name": "userId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.UsageReport"
+                        }
+                    },
+                    "400": {
+                        "description": "INVALID_REQUEST",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "FORBIDDEN",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "controller.ChatRequest": {
+            "type": "object",
+            "required": [
+                "prompt"
+            ],
+            "properties": {
+                "conversationId": {
+                    "description": "ConversationID continues a previous conversation returned by this API\nto the same user.",
+                    "type": "string"
+                },
+                "currentForm": {
+                    "description": "CurrentForm is the FormConfig being refined, including any manual\nedits. It is decoded as strictly as generated forms.",
+                    "type": "object"
+                },
+                "formId": {
+                    "description": "FormID refines a stored form and saves the result as a new version.",
+                    "type": "string"
+                },
+                "history": {
+                    "description": "History lets the client send prior turns itself (roles \"user\"/\"assistant\").",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Message"
+                    }
+                },
+                "patch": {
+                    "description": "Patch is an RFC 6902 JSON Patch of user edits, applied server-side to the\nform being refined before the model is called.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/jsonpatch.Operation"
+                    }
+                },
+                "prompt": {
+                    "type": "string"
+                },
+                "promptVersion": {
+                    "description": "PromptVersion selects the prompt templates among those the deployment\nmakes selectable; the deployment default is used when omitted.",
+                    "type": "string"
+                }
+            }
+        },
+        "controller.CreateFormRequest": {
+            "type": "object",
+            "required": [
+                "config"
+            ],
+            "properties": {
+                "config": {
+                    "description": "Config is a FormConfig, decoded as strictly as generated ones.",
+                    "type": "object"
+                },
+                "name": {
+                    "description": "Name defaults to the form title.",
+                    "type": "string"
+                },
+                "promptVersion": {
+                    "description": "PromptVersion is the metadata.promptVersion of the generation that\nproduced the config, recorded on the first version.",
+                    "type": "string"
+                }
+            }
+        },
+        "controller.CreateWebhookRequest": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "events": {
+                    "description": "Events the webhook receives; defaults to every event.",
+                    "type": "array",
+                    "items": {
+                        "type": "string",
+                        "enum": [
+                            "submission.created"
+                        ]
+                    }
+                },
+                "secret": {
+                    "description": "Secret signs the requests, at least 16 characters; one is generated\nwhen omitted.",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.ErrorCode": {
+            "type": "string",
+            "enum": [
+                "INVALID_REQUEST",
+                "INVALID_FORM_CONFIG",
+                "INVALID_SUBMISSION",
+                "PAYLOAD_TOO_LARGE",
+                "UNAUTHORIZED",
+                "FORBIDDEN",
+                "NOT_FOUND",
+                "CONFLICT",
+                "IRRELEVANT_PROMPT",
+                "MODEL_OUTPUT_INVALID",
+                "SAFETY_BLOCKED",
+                "RATE_LIMITED",
+                "QUOTA_EXCEEDED",
+                "UPSTREAM_RATE_LIMITED",
+                "UPSTREAM_UNAVAILABLE",
+                "UPSTREAM_ERROR",
+                "TIMEOUT",
+                "INTERNAL_ERROR"
+            ],
+            "x-enum-varnames": [
+                "CodeInvalidRequest",
+                "CodeInvalidFormConfig",
+                "CodeInvalidSubmission",
+                "CodePayloadTooLarge",
+                "CodeUnauthorized",
+                "CodeForbidden",
+                "CodeNotFound",
+                "CodeConflict",
+                "CodeIrrelevantPrompt",
+                "CodeModelOutputInvalid",
+                "CodeSafetyBlocked",
+                "CodeRateLimited",
+                "CodeQuotaExceeded",
+                "CodeUpstreamRateLimited",
+                "CodeUpstreamUnavailable",
+                "CodeUpstreamError",
+                "CodeTimeout",
+                "CodeInternal"
+            ]
+        },
+        "controller.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/controller.ErrorCode"
+                        }
+                    ],
+                    "example": "INVALID_REQUEST"
+                },
+                "details": {
+                    "description": "Details is a string, InvalidFormDetails for form validation errors or\nInvalidSubmissionDetails for rejected submissions. It is only set for\nclient errors: the text of internal and upstream errors stays in the\nserver log.",
+                    "type": "object"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Invalid request"
+                },
+                "requestId": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.MintAPIKeyRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "expiresAt": {
+                    "description": "ExpiresAt is optional; keys without it do not expire.",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ownerId": {
+                    "description": "OwnerID is the user the key acts as; defaults to the caller.",
+                    "type": "string"
+                },
+                "scopes": {
+                    "description": "Scopes granted to the key: chat, forms and/or admin.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "controller.RollbackRequest": {
+            "type": "object",
+            "required": [
+                "version"
+            ],
+            "properties": {
+                "version": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "controller.UpdateFormRequest": {
+            "type": "object",
+            "properties": {
+                "config": {
+                    "description": "Config is a FormConfig, decoded as strictly as generated ones.",
+                    "type": "object"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.APIKey": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ownerId": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "description": "Prefix is the start of the key, to help users recognize it.",
+                    "type": "string"
+                },
+                "revokedAt": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "domain.BackendDataType": {
+            "type": "string",
+            "enum": [
+                "string",
+                "number",
+                "boolean",
+                "date",
+                "datetime",
+                "enum",
+                "object",
+                "array",
+                "json"
+            ],
+            "x-enum-varnames": [
+                "DataString",
+                "DataNumber",
+                "DataBoolean",
+                "DataDate",
+                "DataDatetime",
+                "DataEnum",
+                "DataObject",
+                "DataArray",
+                "DataJSON"
+            ]
+        },
+        "domain.ConfirmDialog": {
+            "type": "object",
+            "properties": {
+                "cancelLabel": {
+                    "type": "string"
+                },
+                "confirmLabel": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.DailyUsage": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "description": "Date is the UTC day, formatted as 2006-01-02.",
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "requests": {
+                    "type": "integer"
+                },
+                "usage": {
+                    "$ref": "#/definitions/domain.TokenUsage"
+                }
+            }
+        },
+        "domain.DataSourcePagination": {
+            "type": "object",
+            "properties": {
+                "cursorParam": {
+                    "type": "string"
+                },
+                "hasMoreKey": {
+                    "type": "string"
+                },
+                "labelKey": {
+                    "type": "string"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "pageParam": {
+                    "type": "string"
+                },
+                "pageSize": {
+                    "type": "integer"
+                },
+                "valueKey": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.DraftConfig": {
+            "type": "object",
+            "properties": {
+                "autosave": {
+                    "type": "boolean"
+                },
+                "intervalMs": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.DynamicDataSource": {
+            "type": "object",
+            "properties": {
+                "authTokenRef": {
+                    "type": "string"
+                },
+                "cacheTtlMs": {
+                    "type": "integer"
+                },
+                "debounceMs": {
+                    "type": "integer"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "headers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "method": {
+                    "type": "string"
+                },
+                "pagination": {
+                    "$ref": "#/definitions/domain.DataSourcePagination"
+                },
+                "payloadTemplate": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "queryParam": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.FieldLayout": {
+            "type": "object",
+            "properties": {
+                "colSpan": {
+                    "type": "integer"
+                },
+                "order": {
+                    "type": "integer"
+                },
+                "rowSpan": {
+                    "type": "integer"
+                },
+                "width": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.FormConfig": {
+            "type": "object",
+            "properties": {
+                "authTokenRef": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "draft": {
+                    "$ref": "#/definitions/domain.DraftConfig"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.FormField"
+                    }
+                },
+                "headers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "method": {
+                    "type": "string"
+                },
+                "onErrorMessage": {
+                    "type": "string"
+                },
+                "onSuccessMessage": {
+                    "type": "string"
+                },
+                "onSuccessRedirect": {
+                    "type": "string"
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.FormStep"
+                    }
+                },
+                "submit": {
+                    "$ref": "#/definitions/domain.SubmitAction"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.FormField": {
+            "type": "object",
+            "properties": {
+                "attributes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/domain.ScalarValue"
+                    }
+                },
+                "autoComplete": {
+                    "type": "string"
+                },
+                "dataSource": {
+                    "$ref": "#/definitions/domain.DynamicDataSource"
+                },
+                "dataType": {
+                    "$ref": "#/definitions/domain.BackendDataType"
+                },
+                "defaultValue": {},
+                "description": {
+                    "type": "string"
+                },
+                "disabled": {
+                    "type": "boolean"
+                },
+                "helpText": {
+                    "type": "string"
+                },
+                "icon": {
+                    "type": "string"
+                },
+                "inputMode": {
+                    "type": "string"
+                },
+                "isPassword": {
+                    "type": "boolean"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "layout": {
+                    "$ref": "#/definitions/domain.FieldLayout"
+                },
+                "mask": {
+                    "type": "string"
+                },
+                "max": {
+                    "$ref": "#/definitions/domain.NumberOrString"
+                },
+                "maxSelections": {
+                    "type": "integer"
+                },
+                "min": {
+                    "$ref": "#/definitions/domain.NumberOrString"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.StaticOption"
+                    }
+                },
+                "placeholder": {
+                    "type": "string"
+                },
+                "readOnly": {
+                    "type": "boolean"
+                },
+                "rows": {
+                    "type": "integer"
+                },
+                "step": {
+                    "type": "number"
+                },
+                "type": {
+                    "$ref": "#/definitions/domain.FormFieldType"
+                },
+                "validation": {
+                    "$ref": "#/definitions/domain.FormFieldValidation"
+                },
+                "visibleWhen": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.VisibilityRule"
+                    }
+                }
+            }
+        },
+        "domain.FormFieldType": {
+            "type": "string",
+            "enum": [
+                "text",
+                "email",
+                "password",
+                "textarea",
+                "number",
+                "select",
+                "multiselect",
+                "checkbox",
+                "radio",
+                "date",
+                "datetime",
+                "file",
+                "toggle"
+            ],
+            "x-enum-varnames": [
+                "FieldText",
+                "FieldEmail",
+                "FieldPassword",
+                "FieldTextarea",
+                "FieldNumber",
+                "FieldSelect",
+                "FieldMultiselect",
+                "FieldCheckbox",
+                "FieldRadio",
+                "FieldDate",
+                "FieldDatetime",
+                "FieldFile",
+                "FieldToggle"
+            ]
+        },
+        "domain.FormFieldValidation": {
+            "type": "object",
+            "properties": {
+                "customValidatorKey": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "boolean"
+                },
+                "max": {
+                    "type": "number"
+                },
+                "maxLength": {
+                    "type": "integer"
+                },
+                "min": {
+                    "type": "number"
+                },
+                "minLength": {
+                    "type": "integer"
+                },
+                "pattern": {
+                    "type": "string"
+                },
+                "required": {
+                    "$ref": "#/definitions/domain.RequiredRule"
+                },
+                "sameAs": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "domain.FormStep": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "nextLabel": {
+                    "type": "string"
+                },
+                "previousLabel": {
+                    "type": "string"
+                },
+                "progressLabel": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.FormVersion": {
+            "type": "object",
+            "properties": {
+                "config": {
+                    "$ref": "#/definitions/domain.FormConfig"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "createdBy": {
+                    "type": "string"
+                },
+                "formId": {
+                    "type": "string"
+                },
+                "note": {
+                    "description": "Note explains the version, e.g. the refinement prompt or the version\nthat was restored.",
+                    "type": "string"
+                },
+                "promptVersion": {
+                    "description": "PromptVersion identifies the prompt templates that generated the config;\nempty for configs written by hand.",
+                    "type": "string"
+                },
+                "source": {
+                    "$ref": "#/definitions/domain.VersionSource"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Message": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "role": {
+                    "$ref": "#/definitions/domain.Role"
+                }
+            }
+        },
+        "domain.NumberOrString": {
+            "type": "object",
+            "properties": {
+                "number": {
+                    "type": "number",
+                    "format": "float64"
+                },
+                "string": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.RequiredRule": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "required": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "domain.Role": {
+            "type": "string",
+            "enum": [
+                "system",
+                "user",
+                "assistant"
+            ],
+            "x-enum-varnames": [
+                "RoleSystem",
+                "RoleUser",
+                "RoleAssistant"
+            ]
+        },
+        "domain.SafetyRating": {
+            "type": "object",
+            "properties": {
+                "blocked": {
+                    "type": "boolean"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "probability": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ScalarValue": {
+            "type": "object",
+            "properties": {
+                "value": {}
+            }
+        },
+        "domain.StaticOption": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "disabled": {
+                    "type": "boolean"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "value": {
+                    "$ref": "#/definitions/domain.ScalarValue"
+                }
+            }
+        },
+        "domain.StoredForm": {
+            "type": "object",
+            "properties": {
+                "config": {
+                    "$ref": "#/definitions/domain.FormConfig"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ownerId": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version is the number of the version Config belongs to.",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Submission": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "data": {
+                    "description": "Data holds the values of the visible fields, coerced by dataType and\nkeyed by field name.",
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "files": {
+                    "description": "Files describes the files uploaded to file fields, whose values in\nData are the file names.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.SubmissionFile"
+                    }
+                },
+                "formId": {
+                    "type": "string"
+                },
+                "formVersion": {
+                    "description": "FormVersion is the version of the form the data was validated against.",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.SubmissionFile": {
+            "type": "object",
+            "properties": {
+                "contentType": {
+                    "description": "ContentType is sniffed from the content; the type claimed by the\nclient is ignored.",
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "url": {
+                    "description": "URL is a signed, expiring download link. It is not stored; it is\nadded whenever the submission is returned.",
+                    "type": "string"
+                }
+            }
+        },
+        "domain.SubmitAction": {
+            "type": "object",
+            "properties": {
+                "confirmDialog": {
+                    "$ref": "#/definitions/domain.ConfirmDialog"
+                },
+                "errorMessage": {
+                    "type": "string"
+                },
+                "icon": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "loadingText": {
+                    "type": "string"
+                },
+                "successMessage": {
+                    "type": "string"
+                },
+                "variant": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.TokenUsage": {
+            "type": "object",
+            "properties": {
+                "completionTokens": {
+                    "type": "integer"
+                },
+                "promptTokens": {
+                    "type": "integer"
+                },
+                "thoughtsTokens": {
+                    "description": "ThoughtsTokens are spent on reasoning by thinking models; they are\nincluded in TotalTokens but not in CompletionTokens.",
+                    "type": "integer"
+                },
+                "totalTokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.VersionSource": {
+            "type": "string",
+            "enum": [
+                "created",
+                "edited",
+                "refined",
+                "rollback"
+            ],
+            "x-enum-varnames": [
+                "VersionCreated",
+                "VersionEdited",
+                "VersionRefined",
+                "VersionRolledBack"
+            ]
+        },
+        "domain.VisibilityOperator": {
+            "type": "string",
+            "enum": [
+                "equals",
+                "notEquals",
+                "in",
+                "notIn",
+                "exists",
+                "greaterThan",
+                "lessThan"
+            ],
+            "x-enum-varnames": [
+                "OpEquals",
+                "OpNotEquals",
+                "OpIn",
+                "OpNotIn",
+                "OpExists",
+                "OpGreaterThan",
+                "OpLessThan"
+            ]
+        },
+        "domain.VisibilityRule": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "operator": {
+                    "$ref": "#/definitions/domain.VisibilityOperator"
+                },
+                "value": {}
+            }
+        },
+        "domain.Webhook": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "formId": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "formId": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "lastStatusCode": {
+                    "description": "LastStatusCode is the response status of the last attempt; 0 when the\nrequest failed before a response arrived.",
+                    "type": "integer"
+                },
+                "nextAttemptAt": {
+                    "description": "NextAttemptAt is set while the delivery is pending.",
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "webhookId": {
+                    "type": "string"
+                }
+            }
+        },
+        "jsonpatch.Operation": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "op": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "value": {}
+            }
+        },
+        "usecase.CreatedWebhook": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "formId": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "usecase.FormChanges": {
+            "type": "object",
+            "properties": {
+                "patch": {
+                    "description": "Patch is the RFC 6902 JSON Patch turning the previous form into the new one.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/jsonpatch.Operation"
+                    }
+                },
+                "summary": {
+                    "description": "Summary lists the changes in plain language, e.g. \"Added field 'phone' (text)\".",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "usecase.GenerationMetadata": {
+            "type": "object",
+            "properties": {
+                "cache": {
+                    "description": "Cache is HIT when the form was served from the cache and MISS when it\nwas generated and cached; empty when the request is not cacheable.",
+                    "type": "string"
+                },
+                "finishReason": {
+                    "description": "FinishReason is why the model stopped, e.g. STOP or MAX_TOKENS.",
+                    "type": "string"
+                },
+                "model": {
+                    "description": "Model is the model version that produced the form.",
+                    "type": "string"
+                },
+                "promptVersion": {
+                    "description": "PromptVersion identifies the prompt templates that produced the form.",
+                    "type": "string"
+                },
+                "repairAttempts": {
+                    "description": "RepairAttempts is the number of extra model calls needed to turn an\ninvalid answer into a valid form. Zero means the first answer was valid.",
+                    "type": "integer"
+                },
+                "safetyRatings": {
+                    "description": "SafetyRatings are the provider's safety assessments of the answer.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.SafetyRating"
+                    }
+                },
+                "usage": {
+                    "description": "Usage is the token consumption of every model call of the request.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.TokenUsage"
+                        }
+                    ]
+                }
+            }
+        },
+        "usecase.GenerationResult": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "description": "Changes is set for refinements and describes the edit to the previous form.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/usecase.FormChanges"
+                        }
+                    ]
+                },
+                "conversationId": {
+                    "type": "string"
+                },
+                "form": {
+                    "$ref": "#/definitions/domain.FormConfig"
+                },
+                "formVersion": {
+                    "description": "FormVersion is the version created when a stored form was refined.",
+                    "type": "integer"
+                },
+                "metadata": {
+                    "$ref": "#/definitions/usecase.GenerationMetadata"
+                }
+            }
+        },
+        "usecase.MintedAPIKey": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ownerId": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "description": "Prefix is the start of the key, to help users recognize it.",
+                    "type": "string"
+                },
+                "revokedAt": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "usecase.OptionsPage": {
+            "type": "object",
+            "properties": {
+                "nextCursor": {
+                    "description": "NextCursor fetches the following page; empty on the last page.",
+                    "type": "string"
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.StaticOption"
+                    }
+                }
+            }
+        },
+        "usecase.SubmissionPage": {
+            "type": "object",
+            "properties": {
+                "nextCursor": {
+                    "description": "NextCursor fetches the next page; empty on the last page.",
+                    "type": "string"
+                },
+                "submissions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Submission"
+                    }
+                }
+            }
+        },
+        "usecase.UsageReport": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.DailyUsage"
+                    }
+                },
+                "from": {
+                    "description": "From and To are the first and last UTC day of the report, inclusive.",
+                    "type": "string"
+                },
+                "requests": {
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total": {
+                    "$ref": "#/definitions/domain.TokenUsage"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "usecase.VersionDiff": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "$ref": "#/definitions/usecase.FormChanges"
+                },
+                "formId": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "integer"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "\"An API key minted through /admin/api-keys.\"",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
         "BearerAuth": {
             "description": "\"Type 'Bearer' followed by a space and a JWT.\"",
             "type": "apiKey",
@@ -0,0 +1,64 @@
+// domain/api_key.go
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrAPIKeyNotFound is returned when an API key does not exist.
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// ErrInvalidAPIKey is returned when a presented key is unknown, expired or revoked.
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// ErrUnauthorized is returned when a request carries no valid credentials.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// ErrInsufficientScope is returned when the credentials do not grant a required scope.
+var ErrInsufficientScope = errors.New("insufficient scope")
+
+// APIKeyPrefix starts every API key, so keys are recognizable in configs and
+// can be told apart from JWTs in the Authorization header.
+const APIKeyPrefix = "bf_"
+
+// Scopes grant access to groups of endpoints.
+const (
+	// ScopeChat allows generating and refining forms with the model.
+	ScopeChat = "chat"
+	// ScopeForms allows managing stored forms.
+	ScopeForms = "forms"
+	// ScopeAdmin allows managing API keys.
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists every valid scope.
+var Scopes = []string{ScopeChat, ScopeForms, ScopeAdmin}
+
+// APIKey is a long-lived credential for machine clients such as CI pipelines.
+// Only a hash of the key is stored; the key itself is shown once when minted.
+type APIKey struct {
+	ID      string `json:"id"`
+	OwnerID string `json:"ownerId"`
+	Name    string `json:"name"`
+	// Prefix is the start of the key, to help users recognize it.
+	Prefix    string     `json:"prefix"`
+	Hash      string     `json:"-"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// HasScope reports whether the key grants scope.
+func (k *APIKey) HasScope(scope string) bool {
+	return containsValue(k.Scopes, scope)
+}
+
+// Active reports whether the key can be used at the given time.
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
@@ -0,0 +1,59 @@
+// domain/form.go
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrFormNotFound is returned when a stored form does not exist or belongs to
+// another user.
+var ErrFormNotFound = errors.New("form not found")
+
+// ErrFieldNotFound is returned when a form has no field with the requested name.
+var ErrFieldNotFound = errors.New("field not found")
+
+// ErrVersionNotFound is returned when a form has no version with the requested number.
+var ErrVersionNotFound = errors.New("form version not found")
+
+// ErrVersionConflict is returned when another save created the same version first.
+var ErrVersionConflict = errors.New("form was modified concurrently, please retry")
+
+// StoredForm is a FormConfig saved by a user.
+type StoredForm struct {
+	ID      string `json:"id"`
+	OwnerID string `json:"ownerId"`
+	Name    string `json:"name"`
+	// Version is the number of the version Config belongs to.
+	Version   int        `json:"version"`
+	Config    FormConfig `json:"config"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// VersionSource records what produced a form version.
+type VersionSource string
+
+const (
+	VersionCreated    VersionSource = "created"
+	VersionEdited     VersionSource = "edited"
+	VersionRefined    VersionSource = "refined"
+	VersionRolledBack VersionSource = "rollback"
+)
+
+// FormVersion is an immutable snapshot of a stored form's config. Versions
+// are numbered from 1 in the order they were saved.
+type FormVersion struct {
+	FormID  string        `json:"formId"`
+	Version int           `json:"version"`
+	Source  VersionSource `json:"source"`
+	// Note explains the version, e.g. the refinement prompt or the version
+	// that was restored.
+	Note   string     `json:"note,omitempty"`
+	Config FormConfig `json:"config"`
+	// PromptVersion identifies the prompt templates that generated the config;
+	// empty for configs written by hand.
+	PromptVersion string    `json:"promptVersion,omitempty"`
+	CreatedBy     string    `json:"createdBy"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
@@ -0,0 +1,453 @@
+// domain/form_config.go
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// These types mirror webapp_betterhack/types/form.types.ts. Optional scalars are
+// pointers so that an explicit `false` or `0` survives a round trip.
+
+// FormFieldType is the input widget the frontend renders for a field.
+type FormFieldType string
+
+const (
+	FieldText        FormFieldType = "text"
+	FieldEmail       FormFieldType = "email"
+	FieldPassword    FormFieldType = "password"
+	FieldTextarea    FormFieldType = "textarea"
+	FieldNumber      FormFieldType = "number"
+	FieldSelect      FormFieldType = "select"
+	FieldMultiselect FormFieldType = "multiselect"
+	FieldCheckbox    FormFieldType = "checkbox"
+	FieldRadio       FormFieldType = "radio"
+	FieldDate        FormFieldType = "date"
+	FieldDatetime    FormFieldType = "datetime"
+	FieldFile        FormFieldType = "file"
+	FieldToggle      FormFieldType = "toggle"
+)
+
+// FormFieldTypes lists every accepted FormFieldType.
+var FormFieldTypes = []FormFieldType{
+	FieldText, FieldEmail, FieldPassword, FieldTextarea, FieldNumber, FieldSelect, FieldMultiselect,
+	FieldCheckbox, FieldRadio, FieldDate, FieldDatetime, FieldFile, FieldToggle,
+}
+
+// BackendDataType is the type a field's value should be coerced to on the server.
+type BackendDataType string
+
+const (
+	DataString   BackendDataType = "string"
+	DataNumber   BackendDataType = "number"
+	DataBoolean  BackendDataType = "boolean"
+	DataDate     BackendDataType = "date"
+	DataDatetime BackendDataType = "datetime"
+	DataEnum     BackendDataType = "enum"
+	DataObject   BackendDataType = "object"
+	DataArray    BackendDataType = "array"
+	DataJSON     BackendDataType = "json"
+)
+
+// BackendDataTypes lists every accepted BackendDataType.
+var BackendDataTypes = []BackendDataType{
+	DataString, DataNumber, DataBoolean, DataDate, DataDatetime, DataEnum, DataObject, DataArray, DataJSON,
+}
+
+// VisibilityOperator compares a field value inside a VisibilityRule.
+type VisibilityOperator string
+
+const (
+	OpEquals      VisibilityOperator = "equals"
+	OpNotEquals   VisibilityOperator = "notEquals"
+	OpIn          VisibilityOperator = "in"
+	OpNotIn       VisibilityOperator = "notIn"
+	OpExists      VisibilityOperator = "exists"
+	OpGreaterThan VisibilityOperator = "greaterThan"
+	OpLessThan    VisibilityOperator = "lessThan"
+)
+
+// VisibilityOperators lists every accepted VisibilityOperator.
+var VisibilityOperators = []VisibilityOperator{
+	OpEquals, OpNotEquals, OpIn, OpNotIn, OpExists, OpGreaterThan, OpLessThan,
+}
+
+// Enum sets for the remaining string unions of the TypeScript schema.
+var (
+	FormMethods       = []string{"POST", "PUT", "PATCH"}
+	DataSourceMethods = []string{"GET", "POST"}
+	InputModes        = []string{"text", "email", "numeric", "tel", "url"}
+	LayoutWidths      = []string{"full", "half", "third"}
+	SubmitVariants    = []string{"primary", "secondary", "danger"}
+	PaginationModes   = []string{"infinite", "paged"}
+)
+
+// StaticOption is a fixed choice for select, multiselect and radio fields.
+type StaticOption struct {
+	Value       ScalarValue `json:"value"`
+	Label       string      `json:"label"`
+	Description string      `json:"description,omitempty"`
+	Disabled    *bool       `json:"disabled,omitempty"`
+}
+
+// DataSourcePagination describes how a remote option source pages its results.
+type DataSourcePagination struct {
+	Mode        string `json:"mode"`
+	PageSize    *int   `json:"pageSize,omitempty"`
+	PageParam   string `json:"pageParam,omitempty"`
+	CursorParam string `json:"cursorParam,omitempty"`
+	LabelKey    string `json:"labelKey"`
+	ValueKey    string `json:"valueKey"`
+	HasMoreKey  string `json:"hasMoreKey,omitempty"`
+}
+
+// DynamicDataSource loads field options from a remote endpoint.
+type DynamicDataSource struct {
+	Type            string                 `json:"type"`
+	Endpoint        string                 `json:"endpoint"`
+	Method          string                 `json:"method,omitempty"`
+	QueryParam      string                 `json:"queryParam,omitempty"`
+	PayloadTemplate map[string]interface{} `json:"payloadTemplate,omitempty"`
+	Headers         map[string]string      `json:"headers,omitempty"`
+	AuthTokenRef    string                 `json:"authTokenRef,omitempty"`
+	DebounceMs      *int                   `json:"debounceMs,omitempty"`
+	Pagination      *DataSourcePagination  `json:"pagination,omitempty"`
+	CacheTTLMs      *int                   `json:"cacheTtlMs,omitempty"`
+}
+
+// FormFieldValidation holds the client-side validation rules of a field.
+type FormFieldValidation struct {
+	Required           *RequiredRule `json:"required,omitempty"`
+	MinLength          *int          `json:"minLength,omitempty"`
+	MaxLength          *int          `json:"maxLength,omitempty"`
+	Min                *float64      `json:"min,omitempty"`
+	Max                *float64      `json:"max,omitempty"`
+	Pattern            string        `json:"pattern,omitempty"`
+	Email              *bool         `json:"email,omitempty"`
+	URL                *bool         `json:"url,omitempty"`
+	SameAs             string        `json:"sameAs,omitempty"`
+	CustomValidatorKey string        `json:"customValidatorKey,omitempty"`
+}
+
+// VisibilityRule shows a field only when another field's value matches.
+type VisibilityRule struct {
+	Field    string             `json:"field"`
+	Operator VisibilityOperator `json:"operator"`
+	Value    interface{}        `json:"value,omitempty"`
+}
+
+// FieldLayout positions a field inside the form grid.
+type FieldLayout struct {
+	ColSpan *int   `json:"colSpan,omitempty"`
+	RowSpan *int   `json:"rowSpan,omitempty"`
+	Order   *int   `json:"order,omitempty"`
+	Width   string `json:"width,omitempty"`
+}
+
+// FormField is a single input of a form.
+type FormField struct {
+	Name          string                 `json:"name"`
+	Type          FormFieldType          `json:"type"`
+	Label         string                 `json:"label,omitempty"`
+	Placeholder   string                 `json:"placeholder,omitempty"`
+	Description   string                 `json:"description,omitempty"`
+	HelpText      string                 `json:"helpText,omitempty"`
+	Icon          string                 `json:"icon,omitempty"`
+	DefaultValue  interface{}            `json:"defaultValue,omitempty"`
+	Disabled      *bool                  `json:"disabled,omitempty"`
+	ReadOnly      *bool                  `json:"readOnly,omitempty"`
+	IsPassword    *bool                  `json:"isPassword,omitempty"`
+	InputMode     string                 `json:"inputMode,omitempty"`
+	AutoComplete  string                 `json:"autoComplete,omitempty"`
+	Mask          string                 `json:"mask,omitempty"`
+	Rows          *int                   `json:"rows,omitempty"`
+	Step          *float64               `json:"step,omitempty"`
+	Min           *NumberOrString        `json:"min,omitempty"`
+	Max           *NumberOrString        `json:"max,omitempty"`
+	MaxSelections *int                   `json:"maxSelections,omitempty"`
+	DataType      BackendDataType        `json:"dataType,omitempty"`
+	Options       []StaticOption         `json:"options,omitempty"`
+	DataSource    *DynamicDataSource     `json:"dataSource,omitempty"`
+	Validation    *FormFieldValidation   `json:"validation,omitempty"`
+	VisibleWhen   []VisibilityRule       `json:"visibleWhen,omitempty"`
+	Layout        *FieldLayout           `json:"layout,omitempty"`
+	Attributes    map[string]ScalarValue `json:"attributes,omitempty"`
+}
+
+// FormStep groups fields into one page of a multi-step form.
+type FormStep struct {
+	ID            string   `json:"id"`
+	Title         string   `json:"title,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	Fields        []string `json:"fields"`
+	NextLabel     string   `json:"nextLabel,omitempty"`
+	PreviousLabel string   `json:"previousLabel,omitempty"`
+	ProgressLabel string   `json:"progressLabel,omitempty"`
+}
+
+// ConfirmDialog is shown before the form is submitted.
+type ConfirmDialog struct {
+	Title        string `json:"title"`
+	Message      string `json:"message"`
+	ConfirmLabel string `json:"confirmLabel,omitempty"`
+	CancelLabel  string `json:"cancelLabel,omitempty"`
+}
+
+// SubmitAction configures the submit button.
+type SubmitAction struct {
+	Label          string         `json:"label"`
+	Icon           string         `json:"icon,omitempty"`
+	Variant        string         `json:"variant,omitempty"`
+	LoadingText    string         `json:"loadingText,omitempty"`
+	SuccessMessage string         `json:"successMessage,omitempty"`
+	ErrorMessage   string         `json:"errorMessage,omitempty"`
+	ConfirmDialog  *ConfirmDialog `json:"confirmDialog,omitempty"`
+}
+
+// DraftConfig controls client-side draft autosaving.
+type DraftConfig struct {
+	Autosave   *bool `json:"autosave,omitempty"`
+	IntervalMs *int  `json:"intervalMs,omitempty"`
+}
+
+// FormConfig is the complete description of a generated form.
+type FormConfig struct {
+	Title             string            `json:"title,omitempty"`
+	Description       string            `json:"description,omitempty"`
+	Endpoint          string            `json:"endpoint"`
+	Method            string            `json:"method,omitempty"`
+	Headers           map[string]string `json:"headers,omitempty"`
+	AuthTokenRef      string            `json:"authTokenRef,omitempty"`
+	Fields            []FormField       `json:"fields"`
+	Steps             []FormStep        `json:"steps,omitempty"`
+	Submit            SubmitAction      `json:"submit"`
+	OnSuccessRedirect string            `json:"onSuccessRedirect,omitempty"`
+	OnSuccessMessage  string            `json:"onSuccessMessage,omitempty"`
+	OnErrorMessage    string            `json:"onErrorMessage,omitempty"`
+	Draft             *DraftConfig      `json:"draft,omitempty"`
+}
+
+// --- Union types ---
+
+// RequiredRule is the `boolean | string` union of FormFieldValidation.required.
+// A string means the field is required and carries a custom error message.
+type RequiredRule struct {
+	Required bool
+	Message  string
+}
+
+// UnmarshalJSON accepts either a boolean or a message string.
+func (r *RequiredRule) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*r = RequiredRule{Required: b}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*r = RequiredRule{Required: true, Message: s}
+		return nil
+	}
+	return fmt.Errorf("required must be a boolean or a string, got %s", data)
+}
+
+// MarshalJSON writes the message when one is set, otherwise the boolean.
+func (r RequiredRule) MarshalJSON() ([]byte, error) {
+	if r.Message != "" {
+		return json.Marshal(r.Message)
+	}
+	return json.Marshal(r.Required)
+}
+
+// NumberOrString is the `number | string` union used by FormField.min/max,
+// where strings are typically ISO dates.
+type NumberOrString struct {
+	Number *float64
+	String string
+}
+
+// UnmarshalJSON accepts either a number or a string.
+func (n *NumberOrString) UnmarshalJSON(data []byte) error {
+	var f float64
+	if err := json.Unmarshal(data, &f); err == nil {
+		*n = NumberOrString{Number: &f}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*n = NumberOrString{String: s}
+		return nil
+	}
+	return fmt.Errorf("value must be a number or a string, got %s", data)
+}
+
+// MarshalJSON writes whichever side of the union is set.
+func (n NumberOrString) MarshalJSON() ([]byte, error) {
+	if n.Number != nil {
+		return json.Marshal(*n.Number)
+	}
+	return json.Marshal(n.String)
+}
+
+// ScalarValue is the `string | number | boolean` union used by option values
+// and field attributes.
+type ScalarValue struct {
+	Value interface{}
+}
+
+// UnmarshalJSON rejects anything that is not a string, number or boolean.
+func (v *ScalarValue) UnmarshalJSON(data []byte) error {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	switch raw.(type) {
+	case string, float64, bool:
+		v.Value = raw
+		return nil
+	}
+	return fmt.Errorf("value must be a string, number or boolean, got %s", data)
+}
+
+// MarshalJSON writes the wrapped scalar.
+func (v ScalarValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.Value)
+}
+
+// String renders the scalar the way it would appear in a form submission.
+func (v ScalarValue) String() string {
+	if v.Value == nil {
+		return ""
+	}
+	return fmt.Sprint(v.Value)
+}
+
+// --- Enum checks applied during decoding ---
+
+// UnmarshalJSON rejects unknown field types.
+func (t *FormFieldType) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if !containsValue(FormFieldTypes, FormFieldType(s)) {
+		return fmt.Errorf("unknown field type %q", s)
+	}
+	*t = FormFieldType(s)
+	return nil
+}
+
+// UnmarshalJSON rejects unknown backend data types.
+func (t *BackendDataType) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if !containsValue(BackendDataTypes, BackendDataType(s)) {
+		return fmt.Errorf("unknown dataType %q", s)
+	}
+	*t = BackendDataType(s)
+	return nil
+}
+
+// UnmarshalJSON rejects unknown visibility operators.
+func (o *VisibilityOperator) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if !containsValue(VisibilityOperators, VisibilityOperator(s)) {
+		return fmt.Errorf("unknown visibility operator %q", s)
+	}
+	*o = VisibilityOperator(s)
+	return nil
+}
+
+// --- Decoding ---
+
+// ErrNotAFormConfig is returned when the payload is not a JSON object.
+var ErrNotAFormConfig = errors.New("form config must be a JSON object")
+
+// ParseFormConfig strictly decodes a FormConfig: unknown properties, wrongly
+// typed values and unknown enum members are all rejected. The remaining
+// string unions (method, variant, ...) are checked before returning.
+func ParseFormConfig(data []byte) (*FormConfig, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, ErrNotAFormConfig
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.DisallowUnknownFields()
+
+	var config FormConfig
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("invalid form config: %w", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("invalid form config: unexpected data after the JSON object")
+	}
+
+	if err := config.checkEnums(); err != nil {
+		return nil, fmt.Errorf("invalid form config: %w", err)
+	}
+	return &config, nil
+}
+
+// checkEnums verifies the string unions that are stored as plain strings.
+func (fc *FormConfig) checkEnums() error {
+	if err := checkEnum("method", fc.Method, FormMethods); err != nil {
+		return err
+	}
+	if err := checkEnum("submit.variant", fc.Submit.Variant, SubmitVariants); err != nil {
+		return err
+	}
+	for i, field := range fc.Fields {
+		prefix := fmt.Sprintf("fields[%d].", i)
+		if err := checkEnum(prefix+"inputMode", field.InputMode, InputModes); err != nil {
+			return err
+		}
+		if field.Layout != nil {
+			if err := checkEnum(prefix+"layout.width", field.Layout.Width, LayoutWidths); err != nil {
+				return err
+			}
+		}
+		if ds := field.DataSource; ds != nil {
+			if ds.Type != "remote" {
+				return fmt.Errorf("%sdataSource.type must be \"remote\", got %q", prefix, ds.Type)
+			}
+			if err := checkEnum(prefix+"dataSource.method", ds.Method, DataSourceMethods); err != nil {
+				return err
+			}
+			if ds.Pagination != nil {
+				if ds.Pagination.Mode == "" {
+					return fmt.Errorf("%sdataSource.pagination.mode is required", prefix)
+				}
+				if err := checkEnum(prefix+"dataSource.pagination.mode", ds.Pagination.Mode, PaginationModes); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkEnum allows an empty (omitted) value or one of the allowed values.
+func checkEnum(path, value string, allowed []string) error {
+	if value == "" || containsValue(allowed, value) {
+		return nil
+	}
+	return fmt.Errorf("%s has unsupported value %q", path, value)
+}
+
+func containsValue[T comparable](values []T, v T) bool {
+	for _, candidate := range values {
+		if candidate == v {
+			return true
+		}
+	}
+	return false
+}
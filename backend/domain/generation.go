@@ -0,0 +1,105 @@
+// domain/generation.go
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Role identifies the author of a conversation message.
+type Role string
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+// Message is one turn of a conversation sent to a language model.
+type Message struct {
+	Role    Role   `json:"role"`
+	Content string `json:"content"`
+}
+
+// GenerationRequest is everything a model call needs besides the model itself.
+type GenerationRequest struct {
+	// Messages is the conversation, starting with the system instruction.
+	Messages []Message
+
+	// ResponseMimeType forces the output format, e.g. "application/json".
+	ResponseMimeType string
+	// ResponseSchema constrains the JSON output when set.
+	ResponseSchema *Schema
+
+	// Sampling parameters; nil or zero values use the model defaults.
+	Temperature     *float64
+	TopP            *float64
+	MaxOutputTokens int
+}
+
+// TokenUsage counts the tokens consumed by a model call.
+type TokenUsage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+	// ThoughtsTokens are spent on reasoning by thinking models; they are
+	// included in TotalTokens but not in CompletionTokens.
+	ThoughtsTokens int `json:"thoughtsTokens,omitempty"`
+	TotalTokens    int `json:"totalTokens"`
+}
+
+// Add accumulates the usage of another call.
+func (u *TokenUsage) Add(other TokenUsage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.ThoughtsTokens += other.ThoughtsTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// SafetyRating is the model's assessment of a harm category, e.g.
+// HARM_CATEGORY_HARASSMENT rated NEGLIGIBLE.
+type SafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+// GenerationResponse is the provider-neutral result of a model call.
+type GenerationResponse struct {
+	Text         string
+	Usage        TokenUsage
+	FinishReason string
+	Model        string
+	// SafetyRatings are reported by providers with safety filters (Gemini).
+	SafetyRatings []SafetyRating
+	// BlockReason is set when the prompt itself was blocked.
+	BlockReason string
+}
+
+// Errors reported by model clients, whatever the provider.
+var (
+	// ErrRateLimited means the provider rejected the call because of its quotas.
+	ErrRateLimited = errors.New("model provider rate limit exceeded")
+	// ErrUpstreamUnavailable means the provider failed or could not be reached.
+	ErrUpstreamUnavailable = errors.New("model provider unavailable")
+	// ErrSafetyBlocked means the provider's safety filters blocked the prompt or the answer.
+	ErrSafetyBlocked = errors.New("blocked by the model provider's safety filters")
+)
+
+// UpstreamError is a failed call to a model provider. It wraps
+// ErrRateLimited or ErrUpstreamUnavailable when the failure is transient.
+type UpstreamError struct {
+	Err error
+	// StatusCode is the HTTP status, or 0 when no response was received.
+	StatusCode int
+	// RetryAfter is how long the provider asked to wait; 0 if unknown.
+	RetryAfter time.Duration
+	Message    string
+}
+
+func (e *UpstreamError) Error() string {
+	return e.Message
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,328 @@
+// domain/schema.go
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Schema is the OpenAPI subset understood by Gemini's `responseSchema`.
+type Schema struct {
+	Type             string             `json:"type,omitempty"`
+	Format           string             `json:"format,omitempty"`
+	Description      string             `json:"description,omitempty"`
+	Nullable         bool               `json:"nullable,omitempty"`
+	Enum             []string           `json:"enum,omitempty"`
+	Properties       map[string]*Schema `json:"properties,omitempty"`
+	Required         []string           `json:"required,omitempty"`
+	PropertyOrdering []string           `json:"propertyOrdering,omitempty"`
+	Items            *Schema            `json:"items,omitempty"`
+	AnyOf            []*Schema          `json:"anyOf,omitempty"`
+}
+
+// Schema type names.
+const (
+	SchemaString  = "STRING"
+	SchemaNumber  = "NUMBER"
+	SchemaInteger = "INTEGER"
+	SchemaBoolean = "BOOLEAN"
+	SchemaArray   = "ARRAY"
+	SchemaObject  = "OBJECT"
+)
+
+// stringEnums lists the allowed values of string properties that are not
+// backed by a dedicated Go type, keyed by "<GoType>.<jsonName>".
+var stringEnums = map[string][]string{
+	"FormConfig.method":         FormMethods,
+	"DynamicDataSource.type":    {"remote"},
+	"DynamicDataSource.method":  DataSourceMethods,
+	"DataSourcePagination.mode": PaginationModes,
+	"FormField.inputMode":       InputModes,
+	"FieldLayout.width":         LayoutWidths,
+	"SubmitAction.variant":      SubmitVariants,
+	"FormFieldType":             enumStrings(FormFieldTypes),
+	"BackendDataType":           enumStrings(BackendDataTypes),
+	"VisibilityOperator":        enumStrings(VisibilityOperators),
+}
+
+var (
+	formConfigSchemaOnce sync.Once
+	formConfigSchema     *Schema
+)
+
+// FormConfigSchema derives a response schema from the FormConfig Go types.
+//
+// The dialect has no free-form objects, so maps such as headers or
+// attributes are described as lists of {key, value} entries, which
+// ParseGeneratedFormConfig turns back into objects. `unknown` values such as
+// defaultValue are a scalar or a list of scalars.
+func FormConfigSchema() *Schema {
+	formConfigSchemaOnce.Do(func() {
+		formConfigSchema = schemaForType(reflect.TypeOf(FormConfig{}))
+	})
+	return formConfigSchema
+}
+
+// IrrelevantPromptSchema describes the error object the model returns when a
+// request is not about building a form.
+func IrrelevantPromptSchema() *Schema {
+	return &Schema{
+		Type: SchemaObject,
+		Properties: map[string]*Schema{
+			"error":   stringSchema([]string{"IrrelevantPrompt"}),
+			"message": {Type: SchemaString},
+		},
+		Required:         []string{"error", "message"},
+		PropertyOrdering: []string{"error", "message"},
+	}
+}
+
+var (
+	requiredRuleType   = reflect.TypeOf(RequiredRule{})
+	numberOrStringType = reflect.TypeOf(NumberOrString{})
+	scalarValueType    = reflect.TypeOf(ScalarValue{})
+)
+
+func schemaForType(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case requiredRuleType:
+		return &Schema{AnyOf: []*Schema{{Type: SchemaBoolean}, {Type: SchemaString}}}
+	case numberOrStringType:
+		return &Schema{AnyOf: []*Schema{{Type: SchemaNumber}, {Type: SchemaString}}}
+	case scalarValueType:
+		return scalarSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return stringSchema(stringEnums[t.Name()])
+	case reflect.Bool:
+		return &Schema{Type: SchemaBoolean}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return &Schema{Type: SchemaInteger}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: SchemaNumber}
+	case reflect.Slice:
+		items := schemaForType(t.Elem())
+		if items == nil {
+			return nil
+		}
+		return &Schema{Type: SchemaArray, Items: items}
+	case reflect.Struct:
+		return schemaForStruct(t)
+	case reflect.Map:
+		value := schemaForType(t.Elem())
+		if value == nil {
+			return nil
+		}
+		return entriesSchema(value)
+	case reflect.Interface:
+		return freeValueSchema()
+	}
+	return nil
+}
+
+// scalarSchema describes a string, number or boolean.
+func scalarSchema() *Schema {
+	return &Schema{AnyOf: []*Schema{{Type: SchemaString}, {Type: SchemaNumber}, {Type: SchemaBoolean}}}
+}
+
+// freeValueSchema describes `unknown` values: a scalar, or a list of scalars
+// for the values of multi-select fields.
+func freeValueSchema() *Schema {
+	return &Schema{AnyOf: []*Schema{{Type: SchemaString}, {Type: SchemaNumber}, {Type: SchemaBoolean}, {Type: SchemaArray, Items: scalarSchema()}}}
+}
+
+// entriesSchema describes a map as a list of {key, value} entries.
+func entriesSchema(value *Schema) *Schema {
+	return &Schema{
+		Type:        SchemaArray,
+		Description: "Entries of a map, each key at most once.",
+		Items: &Schema{
+			Type: SchemaObject,
+			Properties: map[string]*Schema{
+				"key":   {Type: SchemaString},
+				"value": value,
+			},
+			Required:         []string{"key", "value"},
+			PropertyOrdering: []string{"key", "value"},
+		},
+	}
+}
+
+func schemaForStruct(t reflect.Type) *Schema {
+	schema := &Schema{Type: SchemaObject, Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitempty := jsonName(field)
+		if name == "" {
+			continue
+		}
+
+		property := schemaForType(field.Type)
+		if property == nil {
+			continue
+		}
+		if enum, ok := stringEnums[t.Name()+"."+name]; ok {
+			property = stringSchema(enum)
+		}
+
+		schema.Properties[name] = property
+		schema.PropertyOrdering = append(schema.PropertyOrdering, name)
+		if !omitempty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// ParseGeneratedFormConfig decodes a form produced under FormConfigSchema:
+// maps given as lists of {key, value} entries are turned back into objects
+// before the config is decoded by ParseFormConfig. Maps given as objects are
+// accepted as well.
+func ParseGeneratedFormConfig(data []byte) (*FormConfig, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return ParseFormConfig(data) // reports the syntax error
+	}
+	converted, err := json.Marshal(entriesToObjects(raw, reflect.TypeOf(FormConfig{})))
+	if err != nil {
+		return ParseFormConfig(data)
+	}
+	return ParseFormConfig(converted)
+}
+
+// entriesToObjects walks value alongside the Go type it decodes into and
+// converts the entry lists found where t has a map. Anything that does not
+// have the expected shape is left for the decoder to reject.
+func entriesToObjects(value interface{}, t reflect.Type) interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		entries, ok := value.([]interface{})
+		if !ok {
+			return value
+		}
+		object := make(map[string]interface{}, len(entries))
+		for _, item := range entries {
+			entry, ok := item.(map[string]interface{})
+			if !ok {
+				return value
+			}
+			key, ok := entry["key"].(string)
+			if !ok || len(entry) != 2 {
+				return value
+			}
+			object[key] = entriesToObjects(entry["value"], t.Elem())
+		}
+		return object
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			return value
+		}
+		for i := range items {
+			items[i] = entriesToObjects(items[i], t.Elem())
+		}
+		return items
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		for i := 0; i < t.NumField(); i++ {
+			name, _ := jsonName(t.Field(i))
+			if property, ok := object[name]; ok && name != "" {
+				object[name] = entriesToObjects(property, t.Field(i).Type)
+			}
+		}
+		return object
+	}
+	return value
+}
+
+// stringSchema describes a string, restricted to enum when it is not empty.
+func stringSchema(enum []string) *Schema {
+	if len(enum) == 0 {
+		return &Schema{Type: SchemaString}
+	}
+	return &Schema{Type: SchemaString, Format: "enum", Enum: enum}
+}
+
+// jsonName returns the JSON property name of a struct field and whether it is
+// optional, or "" if the field is not serialized.
+func jsonName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(options, "omitempty")
+}
+
+func enumStrings[T ~string](values []T) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
+// JSONSchema converts the schema to standard JSON Schema, the dialect used by
+// OpenAI-compatible `response_format` and Ollama's `format`.
+func (s *Schema) JSONSchema() map[string]interface{} {
+	out := map[string]interface{}{}
+	if s.Type != "" {
+		jsonType := strings.ToLower(s.Type)
+		if s.Nullable {
+			out["type"] = []string{jsonType, "null"}
+		} else {
+			out["type"] = jsonType
+		}
+	}
+	if s.Description != "" {
+		out["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		out["enum"] = s.Enum
+	}
+	if len(s.Properties) > 0 {
+		properties := make(map[string]interface{}, len(s.Properties))
+		for name, property := range s.Properties {
+			properties[name] = property.JSONSchema()
+		}
+		out["properties"] = properties
+		out["additionalProperties"] = false
+	}
+	if len(s.Required) > 0 {
+		out["required"] = s.Required
+	}
+	if s.Items != nil {
+		out["items"] = s.Items.JSONSchema()
+	}
+	if len(s.AnyOf) > 0 {
+		anyOf := make([]interface{}, len(s.AnyOf))
+		for i, option := range s.AnyOf {
+			anyOf[i] = option.JSONSchema()
+		}
+		out["anyOf"] = anyOf
+	}
+	return out
+}
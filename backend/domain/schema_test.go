@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// walkSchema calls visit with every schema nested in s and its path.
+func walkSchema(path string, s *Schema, visit func(path string, s *Schema)) {
+	visit(path, s)
+	for name, property := range s.Properties {
+		walkSchema(path+"."+name, property, visit)
+	}
+	if s.Items != nil {
+		walkSchema(path+"[]", s.Items, visit)
+	}
+	for i, option := range s.AnyOf {
+		walkSchema(path+"|"+strconv.Itoa(i), option, visit)
+	}
+}
+
+func TestFormConfigSchema(t *testing.T) {
+	schema := FormConfigSchema()
+	if schema.Type != SchemaObject || !slices.Contains(schema.Required, "endpoint") || !slices.Contains(schema.Required, "fields") || slices.Contains(schema.Required, "title") {
+		t.Errorf("root schema = %+v", schema)
+	}
+
+	// The Gemini dialect has no free-form objects and needs a type or
+	// alternatives everywhere.
+	walkSchema("", schema, func(path string, s *Schema) {
+		switch {
+		case s.Type == "" && len(s.AnyOf) == 0:
+			t.Errorf("%s has neither a type nor alternatives", path)
+		case s.Type == SchemaObject && len(s.Properties) == 0:
+			t.Errorf("%s is a free-form object", path)
+		case s.Type == SchemaArray && s.Items == nil:
+			t.Errorf("%s is an array without items", path)
+		}
+		if len(s.PropertyOrdering) != len(s.Properties) {
+			t.Errorf("%s orders %v but has %d properties", path, s.PropertyOrdering, len(s.Properties))
+		}
+		for _, name := range s.Required {
+			if s.Properties[name] == nil {
+				t.Errorf("%s requires unknown property %s", path, name)
+			}
+		}
+	})
+
+	field := schema.Properties["fields"].Items
+	tests := map[string]struct {
+		schema *Schema
+		enum   []string
+	}{
+		"method":                          {schema.Properties["method"], FormMethods},
+		"submit.variant":                  {schema.Properties["submit"].Properties["variant"], SubmitVariants},
+		"fields[].type":                   {field.Properties["type"], enumStrings(FormFieldTypes)},
+		"fields[].inputMode":              {field.Properties["inputMode"], InputModes},
+		"fields[].dataSource.type":        {field.Properties["dataSource"].Properties["type"], []string{"remote"}},
+		"fields[].visibleWhen[].operator": {field.Properties["visibleWhen"].Items.Properties["operator"], enumStrings(VisibilityOperators)},
+	}
+	for path, tt := range tests {
+		if tt.schema == nil || tt.schema.Type != SchemaString || !reflect.DeepEqual(tt.schema.Enum, tt.enum) {
+			t.Errorf("%s = %+v, want the enum %v", path, tt.schema, tt.enum)
+		}
+	}
+
+	// Maps are lists of {key, value} entries.
+	headers := schema.Properties["headers"]
+	if headers.Type != SchemaArray || headers.Items.Type != SchemaObject || headers.Items.Properties["value"].Type != SchemaString ||
+		!reflect.DeepEqual(headers.Items.Required, []string{"key", "value"}) {
+		t.Errorf("headers = %+v, want a list of entries", headers)
+	}
+	if attributes := field.Properties["attributes"]; attributes.Type != SchemaArray || len(attributes.Items.Properties["value"].AnyOf) != 3 {
+		t.Errorf("attributes = %+v, want a list of scalar entries", attributes)
+	}
+}
+
+func TestSchemaJSONSchema(t *testing.T) {
+	schema := &Schema{
+		Type: SchemaObject,
+		Properties: map[string]*Schema{
+			"name":  {Type: SchemaString, Nullable: true},
+			"kind":  stringSchema([]string{"a", "b"}),
+			"items": {Type: SchemaArray, Items: &Schema{Type: SchemaInteger}},
+			"value": {AnyOf: []*Schema{{Type: SchemaNumber}, {Type: SchemaBoolean}}},
+		},
+		Required: []string{"kind"},
+	}
+	got, _ := json.Marshal(schema.JSONSchema())
+	want := `{"additionalProperties":false,"properties":{"items":{"items":{"type":"integer"},"type":"array"},"kind":{"enum":["a","b"],"type":"string"},` +
+		`"name":{"type":["string","null"]},"value":{"anyOf":[{"type":"number"},{"type":"boolean"}]}},"required":["kind"],"type":"object"}`
+	if string(got) != want {
+		t.Errorf("JSONSchema = %s\nwant %s", got, want)
+	}
+}
+
+func TestParseGeneratedFormConfig(t *testing.T) {
+	const objects = `{"endpoint":"/api/x","headers":{"Content-Type":"application/json","X-Tenant":"a"},"submit":{"label":"Send"},"fields":[
+		{"name":"cv","type":"file","attributes":{"accept":".pdf","maxSize":2048,"multiple":true}},
+		{"name":"country","type":"select","dataSource":{"type":"remote","endpoint":"/api/c","headers":{"X-Key":"k"},"payloadTemplate":{"q":"{{query}}","page":1}}}
+	]}`
+	const entries = `{"endpoint":"/api/x","headers":[{"key":"Content-Type","value":"application/json"},{"key":"X-Tenant","value":"a"}],"submit":{"label":"Send"},"fields":[
+		{"name":"cv","type":"file","attributes":[{"key":"accept","value":".pdf"},{"key":"maxSize","value":2048},{"key":"multiple","value":true}]},
+		{"name":"country","type":"select","dataSource":{"type":"remote","endpoint":"/api/c","headers":[{"key":"X-Key","value":"k"}],"payloadTemplate":[{"key":"q","value":"{{query}}"},{"key":"page","value":1}]}}
+	]}`
+	want, err := ParseFormConfig([]byte(objects))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, raw := range map[string]string{"entries": entries, "objects": objects} {
+		got, err := ParseGeneratedFormConfig([]byte(raw))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(want)
+			t.Errorf("%s: decoded %s\nwant %s", name, gotJSON, wantJSON)
+		}
+	}
+
+	// Entry lists that are not exactly {key, value} are left for the decoder
+	// to reject.
+	for name, raw := range map[string]string{
+		"extra member":   `[{"key":"a","value":"1","note":"x"}]`,
+		"key not string": `[{"key":1,"value":"1"}]`,
+		"not an entry":   `["a"]`,
+		"wrong value":    `[{"key":"a","value":{"nested":true}}]`,
+	} {
+		config := `{"endpoint":"/api/x","headers":` + raw + `,"submit":{"label":"Send"},"fields":[]}`
+		if _, err := ParseGeneratedFormConfig([]byte(config)); err == nil {
+			t.Errorf("%s: %s was accepted", name, raw)
+		}
+	}
+	if _, err := ParseGeneratedFormConfig([]byte(`{"endpoint":`)); err == nil {
+		t.Errorf("syntax error: err = %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+// domain/submission.go
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrSubmissionNotFound is returned when a submission does not exist.
+var ErrSubmissionNotFound = errors.New("submission not found")
+
+// ErrFileNotFound is returned when an uploaded file does not exist.
+var ErrFileNotFound = errors.New("file not found")
+
+// Submission is a filled-in form accepted by the submission runtime.
+type Submission struct {
+	ID     string `json:"id"`
+	FormID string `json:"formId"`
+	// FormVersion is the version of the form the data was validated against.
+	FormVersion int `json:"formVersion"`
+	// Data holds the values of the visible fields, coerced by dataType and
+	// keyed by field name.
+	Data map[string]interface{} `json:"data"`
+	// Files describes the files uploaded to file fields, whose values in
+	// Data are the file names.
+	Files     []SubmissionFile `json:"files,omitempty"`
+	CreatedAt time.Time        `json:"createdAt"`
+}
+
+// SubmissionFile is a file uploaded with a submission.
+type SubmissionFile struct {
+	ID    string `json:"id"`
+	Field string `json:"field"`
+	Name  string `json:"name"`
+	// ContentType is sniffed from the content; the type claimed by the
+	// client is ignored.
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	// URL is a signed, expiring download link. It is not stored; it is
+	// added whenever the submission is returned.
+	URL string `json:"url,omitempty"`
+}
+
+// SubmissionCursor is the position after the last submission of a page.
+// Submissions are listed newest first, ties broken by descending ID.
+type SubmissionCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// SubmissionQuery selects the submissions of a form.
+type SubmissionQuery struct {
+	FormID string
+	// From and To bound the submission time to [From, To); zero values leave
+	// the range open.
+	From time.Time
+	To   time.Time
+	// Fields keeps submissions whose value for each field equals the given
+	// value, or contains it when the value is an array.
+	Fields map[string]interface{}
+	// After continues a listing after the given position.
+	After *SubmissionCursor
+	// Limit bounds the number of submissions returned; 0 returns all.
+	Limit int
+}
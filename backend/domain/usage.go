@@ -0,0 +1,38 @@
+// domain/usage.go
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrRateLimitExceeded is returned when a caller sends too many requests per minute.
+var ErrRateLimitExceeded = errors.New("rate limit exceeded")
+
+// ErrQuotaExceeded is returned when a caller has used up their daily token budget.
+var ErrQuotaExceeded = errors.New("token quota exceeded")
+
+// UsageRecord is the model consumption of one generation request, including
+// every repair attempt.
+type UsageRecord struct {
+	ID     string `json:"id"`
+	UserID string `json:"userId"`
+	Model  string `json:"model"`
+	// Calls is the number of model calls the request made.
+	Calls        int        `json:"calls"`
+	Usage        TokenUsage `json:"usage"`
+	FinishReason string     `json:"finishReason,omitempty"`
+	// Outcome tells whether the request produced a form: "success",
+	// "invalid_form", "irrelevant_prompt" or "error".
+	Outcome   string    `json:"outcome"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// DailyUsage aggregates the usage records of one user, model and UTC day.
+type DailyUsage struct {
+	// Date is the UTC day, formatted as 2006-01-02.
+	Date     string     `json:"date"`
+	Model    string     `json:"model"`
+	Requests int        `json:"requests"`
+	Usage    TokenUsage `json:"usage"`
+}
@@ -0,0 +1,83 @@
+// domain/webhook.go
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrWebhookNotFound is returned when a webhook does not exist.
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// ErrDeliveryNotFound is returned when a webhook delivery does not exist.
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+// Webhook event types.
+const (
+	// EventSubmissionCreated is sent when a submission of the form is accepted.
+	EventSubmissionCreated = "submission.created"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to.
+var WebhookEvents = []string{EventSubmissionCreated}
+
+// Webhook forwards events of a stored form to an HTTP endpoint. Every request
+// is signed with Secret, which is shown once when the webhook is created.
+type Webhook struct {
+	ID        string    `json:"id"`
+	FormID    string    `json:"formId"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Subscribes reports whether the webhook wants event.
+func (w *Webhook) Subscribes(event string) bool {
+	return containsValue(w.Events, event)
+}
+
+// Delivery statuses.
+const (
+	// DeliveryPending deliveries wait for their next attempt.
+	DeliveryPending = "pending"
+	// DeliverySucceeded deliveries were acknowledged with a 2xx response.
+	DeliverySucceeded = "succeeded"
+	// DeliveryDead deliveries failed every attempt. They form the dead-letter
+	// list and are only sent again when redelivered by hand.
+	DeliveryDead = "dead"
+)
+
+// DeliveryStatuses lists every delivery status.
+var DeliveryStatuses = []string{DeliveryPending, DeliverySucceeded, DeliveryDead}
+
+// WebhookDelivery is one event sent, or to be sent, to a webhook. The payload
+// is fixed when the event occurs, so every attempt sends the same body.
+type WebhookDelivery struct {
+	ID        string          `json:"id"`
+	WebhookID string          `json:"webhookId"`
+	FormID    string          `json:"formId"`
+	Event     string          `json:"event"`
+	Payload   json.RawMessage `json:"payload" swaggertype:"object"`
+	Status    string          `json:"status"`
+	Attempts  int             `json:"attempts"`
+	// LastStatusCode is the response status of the last attempt; 0 when the
+	// request failed before a response arrived.
+	LastStatusCode int    `json:"lastStatusCode,omitempty"`
+	LastError      string `json:"lastError,omitempty"`
+	// NextAttemptAt is set while the delivery is pending.
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// DeliveryQuery selects the deliveries of a form, newest first.
+type DeliveryQuery struct {
+	FormID string
+	// WebhookID and Status narrow the list when set.
+	WebhookID string
+	Status    string
+	// Limit bounds the number of deliveries returned; 0 returns all.
+	Limit int
+}
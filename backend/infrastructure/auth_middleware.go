@@ -1,64 +1,191 @@
 package infrastructure
 
 import (
+	"better-form-doc-backend/domain"
+	"errors"
 	"fmt"
-	"net/http"
-	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// AuthMiddleware creates a Gin middleware for JWT authentication.
-func AuthMiddleware() gin.HandlerFunc {
+// JWTConfig lists the claims every token must carry.
+type JWTConfig struct {
+	// Issuer is the expected "iss" claim; empty skips the check.
+	Issuer string
+	// Audience is the expected "aud" claim; empty skips the check.
+	Audience string
+	// Leeway tolerates clock skew when checking exp, nbf and iat.
+	Leeway time.Duration
+}
+
+// JWTVerifier validates bearer tokens and extracts the user ID.
+type JWTVerifier struct {
+	keyfunc jwt.Keyfunc
+	parser  *jwt.Parser
+}
+
+// NewJWKSVerifier creates a verifier for asymmetric tokens (EdDSA, RS256,
+// ES256) signed with a key from the given key set, as issued by the Better
+// Auth jwt() plugin.
+func NewJWKSVerifier(keys *JWKS, config JWTConfig) *JWTVerifier {
+	return newJWTVerifier(keys.Keyfunc, JWKSAlgorithms, config)
+}
+
+// NewHMACVerifier creates a verifier for HS256 tokens signed with a shared secret.
+func NewHMACVerifier(secret []byte, config JWTConfig) *JWTVerifier {
+	return newJWTVerifier(func(*jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, []string{"HS256"}, config)
+}
+
+func newJWTVerifier(keyfunc jwt.Keyfunc, algorithms []string, config JWTConfig) *JWTVerifier {
+	options := []jwt.ParserOption{
+		// Pinning the algorithms rules out "none" and key confusion attacks.
+		jwt.WithValidMethods(algorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(config.Leeway),
+	}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+	return &JWTVerifier{keyfunc: keyfunc, parser: jwt.NewParser(options...)}
+}
+
+// Verify checks the token's signature and claims and returns its subject.
+func (v *JWTVerifier) Verify(tokenString string) (string, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(tokenString, claims, v.keyfunc); err != nil {
+		return "", err
+	}
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return "", errors.New("user ID not found in token")
+	}
+	return subject, nil
+}
+
+// APIKeyAuthenticator checks API keys. It is implemented by the API key use case.
+type APIKeyAuthenticator interface {
+	Authenticate(rawKey string) (*domain.APIKey, error)
+}
+
+// AuthConfig selects which credentials are accepted; a nil method is disabled.
+type AuthConfig struct {
+	JWT     *JWTVerifier
+	APIKeys APIKeyAuthenticator
+	// AdminUsers are JWT subjects that are also granted the admin scope.
+	AdminUsers []string
+}
+
+// userScopes are granted to everyone signed in with a JWT.
+var userScopes = []string{domain.ScopeChat, domain.ScopeForms}
+
+// AuthMiddleware creates a Gin middleware that authenticates requests with a
+// Bearer JWT, an API key (X-API-Key header, or "Bearer bf_..."), or either.
+// It sets "userID", "scopes" and "authMethod" (and "apiKeyID" for API keys)
+// on the context.
+func AuthMiddleware(config AuthConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 1. Get the token from the Authorization header
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
-			return
-		}
+		// 1. Get the credential from the X-API-Key or Authorization header
+		apiKey := c.GetHeader("X-API-Key")
+		tokenString := ""
+		if apiKey == "" {
+			authHeader := c.GetHeader("Authorization")
+			if authHeader == "" {
+				abortWithError(c, fmt.Errorf("%w: authorization header is required", domain.ErrUnauthorized))
+				return
+			}
 
-		// 2. The token is expected to be in "Bearer <token>" format
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == authHeader {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Bearer token format is required"})
-			return
+			// 2. The token is expected to be in "Bearer <token>" format
+			tokenString = strings.TrimPrefix(authHeader, "Bearer ")
+			if tokenString == authHeader {
+				abortWithError(c, fmt.Errorf("%w: bearer token format is required", domain.ErrUnauthorized))
+				return
+			}
+			if strings.HasPrefix(tokenString, domain.APIKeyPrefix) {
+				apiKey, tokenString = tokenString, ""
+			}
 		}
 
-		// 3. Parse and validate the token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Ensure the signing algorithm is what we expect (HMAC)
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		if apiKey != "" {
+			// 3a. Look up the API key
+			if config.APIKeys == nil {
+				abortWithError(c, fmt.Errorf("%w: API keys are not accepted", domain.ErrUnauthorized))
+				return
 			}
-			// Return the secret key used to sign the token
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
+			key, err := config.APIKeys.Authenticate(apiKey)
+			if err != nil {
+				abortWithError(c, err)
+				return
+			}
+			c.Set("userID", key.OwnerID)
+			c.Set("scopes", key.Scopes)
+			c.Set("authMethod", "apiKey")
+			c.Set("apiKeyID", key.ID)
+			c.Next()
+			return
+		}
 
+		// 3b. Verify the signature, expiry, issuer and audience, and extract the
+		// user ID ('sub' claim is standard for subject/ID)
+		if config.JWT == nil {
+			abortWithError(c, fmt.Errorf("%w: an API key is required", domain.ErrUnauthorized))
+			return
+		}
+		userID, err := config.JWT.Verify(tokenString)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token", "details": err.Error()})
+			abortWithError(c, fmt.Errorf("%w: invalid token: %v", domain.ErrUnauthorized, err))
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// 4. The token is valid. Extract the user ID ('sub' claim is standard for subject/ID)
-			userID, exists := claims["sub"]
-			if !exists {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
-				return
-			}
+		// 4. IMPORTANT: Add the user ID to the request context
+		// This makes it available to the downstream controllers.
+		scopes := userScopes
+		if slices.Contains(config.AdminUsers, userID) {
+			scopes = append(slices.Clone(userScopes), domain.ScopeAdmin)
+		}
+		c.Set("userID", userID)
+		c.Set("scopes", scopes)
+		c.Set("authMethod", "jwt")
 
-			// 5. IMPORTANT: Add the user ID to the request context
-			// This makes it available to the downstream controllers.
-			c.Set("userID", userID)
-		} else {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		// 5. Call the next handler in the chain
+		c.Next()
+	}
+}
+
+// NoAuthMiddleware is used when authentication is disabled for local
+// development: every request is anonymous and has every scope.
+func NoAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("scopes", domain.Scopes)
+		c.Set("authMethod", "none")
+		c.Next()
+	}
+}
+
+// RequireScope rejects requests whose credentials do not grant scope.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, _ := c.Get("scopes")
+		granted, _ := scopes.([]string)
+		if !slices.Contains(granted, scope) {
+			abortWithError(c, fmt.Errorf("%w: this endpoint requires the %s scope", domain.ErrInsufficientScope, scope))
 			return
 		}
-
-		// 6. Call the next handler in the chain
 		c.Next()
 	}
 }
+
+// abortWithError stops the chain and leaves err for the error middleware to render.
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
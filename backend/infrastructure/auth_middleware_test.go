@@ -0,0 +1,112 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeAPIKeys accepts the keys it holds.
+type fakeAPIKeys map[string]*domain.APIKey
+
+func (f fakeAPIKeys) Authenticate(rawKey string) (*domain.APIKey, error) {
+	if key, ok := f[rawKey]; ok {
+		return key, nil
+	}
+	return nil, domain.ErrInvalidAPIKey
+}
+
+// newAuthRouter serves GET /me behind AuthMiddleware and answers with the
+// identity it set. Errors are rendered as 401, standing in for
+// controller.ErrorMiddleware.
+func newAuthRouter(config AuthConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Next()
+		if err := c.Errors.Last(); err != nil {
+			c.String(http.StatusUnauthorized, err.Error())
+		}
+	})
+	router.Use(AuthMiddleware(config))
+	router.GET("/me", func(c *gin.Context) {
+		scopes, _ := c.Get("scopes")
+		c.String(http.StatusOK, "%s %s %s %s", c.GetString("authMethod"), c.GetString("userID"), c.GetString("apiKeyID"), strings.Join(scopes.([]string), ","))
+	})
+	return router
+}
+
+func serveAuth(router *gin.Engine, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthMiddlewareAPIKeys(t *testing.T) {
+	const rawKey = domain.APIKeyPrefix + "secret"
+	secret := []byte("jwt-secret")
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "bob",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := fakeAPIKeys{rawKey: {ID: "k1", OwnerID: "alice", Scopes: []string{domain.ScopeChat}}}
+
+	tests := []struct {
+		name    string
+		config  AuthConfig
+		headers map[string]string
+		want    string
+	}{
+		{"X-API-Key", AuthConfig{APIKeys: keys}, map[string]string{"X-API-Key": rawKey}, "apiKey alice k1 chat"},
+		{"bearer API key", AuthConfig{APIKeys: keys}, map[string]string{"Authorization": "Bearer " + rawKey}, "apiKey alice k1 chat"},
+		{"X-API-Key wins over a JWT", AuthConfig{APIKeys: keys, JWT: NewHMACVerifier(secret, JWTConfig{})}, map[string]string{"X-API-Key": rawKey, "Authorization": "Bearer " + token}, "apiKey alice k1 chat"},
+		{"JWT next to API keys", AuthConfig{APIKeys: keys, JWT: NewHMACVerifier(secret, JWTConfig{})}, map[string]string{"Authorization": "Bearer " + token}, "jwt bob  chat,forms"},
+		{"unknown key", AuthConfig{APIKeys: keys}, map[string]string{"X-API-Key": domain.APIKeyPrefix + "other"}, ""},
+		{"unknown bearer key", AuthConfig{APIKeys: keys, JWT: NewHMACVerifier(secret, JWTConfig{})}, map[string]string{"Authorization": "Bearer " + domain.APIKeyPrefix + "other"}, ""},
+		{"API keys disabled", AuthConfig{JWT: NewHMACVerifier(secret, JWTConfig{})}, map[string]string{"X-API-Key": rawKey}, ""},
+		{"JWT disabled", AuthConfig{APIKeys: keys}, map[string]string{"Authorization": "Bearer " + token}, ""},
+		{"no credentials", AuthConfig{APIKeys: keys}, nil, ""},
+		{"not a bearer token", AuthConfig{APIKeys: keys}, map[string]string{"Authorization": rawKey}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveAuth(newAuthRouter(tt.config), tt.headers)
+			if tt.want == "" {
+				if w.Code != http.StatusUnauthorized {
+					t.Errorf("status = %d %s, want 401", w.Code, w.Body)
+				}
+				return
+			}
+			if w.Code != http.StatusOK || w.Body.String() != tt.want {
+				t.Errorf("response = %d %q, want %q", w.Code, w.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewarePassesRepositoryErrors(t *testing.T) {
+	failing := errors.New("database is locked")
+	router := newAuthRouter(AuthConfig{APIKeys: failingAPIKeys{failing}})
+	if w := serveAuth(router, map[string]string{"X-API-Key": domain.APIKeyPrefix + "x"}); !strings.Contains(w.Body.String(), failing.Error()) {
+		t.Errorf("response = %d %s, want the repository error", w.Code, w.Body)
+	}
+}
+
+type failingAPIKeys struct{ err error }
+
+func (f failingAPIKeys) Authenticate(string) (*domain.APIKey, error) { return nil, f.err }
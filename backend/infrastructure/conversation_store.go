@@ -0,0 +1,149 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// InMemoryConversationStore keeps conversation turns in process memory, keyed
+// by user and conversation ID. Conversations expire after ttl without
+// activity and are removed by Run. At most maxEntries conversations are kept,
+// and at most maxPerUser of each user, so that one user cannot push out the
+// conversations of others; beyond either limit the least recently updated
+// conversation goes. Zero disables a limit.
+type InMemoryConversationStore struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	maxPerUser int
+	// order and the lists of users hold *storedConversation, front is the
+	// most recently updated.
+	order         *list.List
+	users         map[string]*list.List
+	conversations map[conversationKey]*list.Element
+}
+
+type conversationKey struct {
+	userID, id string
+}
+
+type storedConversation struct {
+	key       conversationKey
+	messages  []domain.Message
+	updatedAt time.Time
+	// userElement is the conversation's element in the list of its user.
+	userElement *list.Element
+}
+
+// NewInMemoryConversationStore creates a new instance of the InMemoryConversationStore.
+func NewInMemoryConversationStore(ttl time.Duration, maxEntries, maxPerUser int) *InMemoryConversationStore {
+	return &InMemoryConversationStore{
+		ttl:           ttl,
+		maxEntries:    maxEntries,
+		maxPerUser:    maxPerUser,
+		order:         list.New(),
+		users:         make(map[string]*list.List),
+		conversations: make(map[conversationKey]*list.Element),
+	}
+}
+
+// Get returns a copy of the stored turns of a conversation of userID.
+func (s *InMemoryConversationStore) Get(userID, id string) ([]domain.Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.conversations[conversationKey{userID: userID, id: id}]
+	if !ok {
+		return nil, false
+	}
+	conversation := element.Value.(*storedConversation)
+	if s.expired(conversation, time.Now()) {
+		return nil, false
+	}
+	return append([]domain.Message(nil), conversation.messages...), true
+}
+
+// Save replaces the turns of a conversation of userID and evicts the least
+// recently updated conversations beyond the limits.
+func (s *InMemoryConversationStore) Save(userID, id string, messages []domain.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := conversationKey{userID: userID, id: id}
+	messages = append([]domain.Message(nil), messages...)
+	if element, ok := s.conversations[key]; ok {
+		conversation := element.Value.(*storedConversation)
+		conversation.messages = messages
+		conversation.updatedAt = time.Now()
+		s.order.MoveToFront(element)
+		s.users[userID].MoveToFront(conversation.userElement)
+		return
+	}
+
+	conversation := &storedConversation{key: key, messages: messages, updatedAt: time.Now()}
+	userConversations, ok := s.users[userID]
+	if !ok {
+		userConversations = list.New()
+		s.users[userID] = userConversations
+	}
+	conversation.userElement = userConversations.PushFront(conversation)
+	s.conversations[key] = s.order.PushFront(conversation)
+
+	for s.maxPerUser > 0 && userConversations.Len() > s.maxPerUser {
+		s.remove(userConversations.Back().Value.(*storedConversation))
+	}
+	for s.maxEntries > 0 && s.order.Len() > s.maxEntries {
+		s.remove(s.order.Back().Value.(*storedConversation))
+	}
+}
+
+// Run removes expired conversations every ttl/4, until ctx is done. It
+// returns right away when conversations do not expire.
+func (s *InMemoryConversationStore) Run(ctx context.Context) {
+	if s.ttl <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.ttl / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep(time.Now())
+		}
+	}
+}
+
+// sweep removes the conversations that expired at now. They are the oldest,
+// at the back of the order.
+func (s *InMemoryConversationStore) sweep(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for element := s.order.Back(); element != nil; element = s.order.Back() {
+		conversation := element.Value.(*storedConversation)
+		if !s.expired(conversation, now) {
+			return
+		}
+		s.remove(conversation)
+	}
+}
+
+// remove deletes a conversation. Must be called with mu held.
+func (s *InMemoryConversationStore) remove(conversation *storedConversation) {
+	s.order.Remove(s.conversations[conversation.key])
+	delete(s.conversations, conversation.key)
+	userConversations := s.users[conversation.key.userID]
+	userConversations.Remove(conversation.userElement)
+	if userConversations.Len() == 0 {
+		delete(s.users, conversation.key.userID)
+	}
+}
+
+func (s *InMemoryConversationStore) expired(conversation *storedConversation, now time.Time) bool {
+	return s.ttl > 0 && now.Sub(conversation.updatedAt) > s.ttl
+}
@@ -0,0 +1,107 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"context"
+	"testing"
+	"time"
+)
+
+func turns(content string) []domain.Message {
+	return []domain.Message{{Role: domain.RoleUser, Content: content}}
+}
+
+func assertConversation(t *testing.T, store *InMemoryConversationStore, userID, id, want string) {
+	t.Helper()
+	messages, ok := store.Get(userID, id)
+	switch {
+	case want == "" && ok:
+		t.Errorf("Get(%s, %s) = %v, want nothing", userID, id, messages)
+	case want != "" && (!ok || len(messages) != 1 || messages[0].Content != want):
+		t.Errorf("Get(%s, %s) = %v, %v; want %s", userID, id, messages, ok, want)
+	}
+}
+
+func TestConversationStoreIsolatesUsers(t *testing.T) {
+	store := NewInMemoryConversationStore(time.Hour, 0, 0)
+	store.Save("alice", "c1", turns("alice's"))
+	store.Save("bob", "c1", turns("bob's"))
+
+	assertConversation(t, store, "alice", "c1", "alice's")
+	assertConversation(t, store, "bob", "c1", "bob's")
+	assertConversation(t, store, "mallory", "c1", "")
+	assertConversation(t, store, "alice", "c2", "")
+
+	// Callers cannot change what is stored.
+	messages, _ := store.Get("alice", "c1")
+	messages[0].Content = "changed"
+	assertConversation(t, store, "alice", "c1", "alice's")
+}
+
+func TestConversationStoreExpiry(t *testing.T) {
+	store := NewInMemoryConversationStore(time.Hour, 0, 0)
+	store.Save("alice", "old", turns("old"))
+	store.Save("alice", "new", turns("new"))
+	store.conversations[conversationKey{"alice", "old"}].Value.(*storedConversation).updatedAt = time.Now().Add(-2 * time.Hour)
+	// The order is kept by update time.
+	store.order.MoveToBack(store.conversations[conversationKey{"alice", "old"}])
+
+	assertConversation(t, store, "alice", "old", "")
+	assertConversation(t, store, "alice", "new", "new")
+
+	store.sweep(time.Now())
+	if len(store.conversations) != 1 || store.users["alice"].Len() != 1 {
+		t.Errorf("sweep left %d conversations", len(store.conversations))
+	}
+	store.sweep(time.Now().Add(2 * time.Hour))
+	if len(store.conversations) != 0 || len(store.users) != 0 || store.order.Len() != 0 {
+		t.Errorf("sweep left %d conversations", len(store.conversations))
+	}
+}
+
+func TestConversationStoreRun(t *testing.T) {
+	store := NewInMemoryConversationStore(20*time.Millisecond, 0, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		store.Run(ctx)
+		close(done)
+	}()
+	store.Save("alice", "c1", turns("hi"))
+	time.Sleep(100 * time.Millisecond)
+	store.mu.Lock()
+	left := len(store.conversations)
+	store.mu.Unlock()
+	if left != 0 {
+		t.Errorf("Run left %d expired conversations", left)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not stop")
+	}
+}
+
+func TestConversationStoreLimits(t *testing.T) {
+	store := NewInMemoryConversationStore(time.Hour, 3, 2)
+	store.Save("alice", "a1", turns("a1"))
+	store.Save("alice", "a2", turns("a2"))
+	store.Save("alice", "a1", turns("a1 again")) // a2 is now alice's oldest
+	store.Save("alice", "a3", turns("a3"))
+	assertConversation(t, store, "alice", "a2", "")
+	assertConversation(t, store, "alice", "a1", "a1 again")
+	assertConversation(t, store, "alice", "a3", "a3")
+
+	// Alice's conversations do not count against Bob's limit, but the
+	// oldest conversation of anyone goes beyond the total.
+	store.Save("bob", "b1", turns("b1"))
+	store.Save("bob", "b2", turns("b2"))
+	assertConversation(t, store, "alice", "a1", "")
+	assertConversation(t, store, "alice", "a3", "a3")
+	assertConversation(t, store, "bob", "b1", "b1")
+	assertConversation(t, store, "bob", "b2", "b2")
+	if store.order.Len() != 3 || len(store.conversations) != 3 || store.users["alice"].Len() != 1 {
+		t.Errorf("store holds %d conversations, alice %d", store.order.Len(), store.users["alice"].Len())
+	}
+}
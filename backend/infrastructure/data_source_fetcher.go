@@ -0,0 +1,123 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxDataSourceResponseBytes bounds the response of a data source.
+const maxDataSourceResponseBytes = 1024 * 1024
+
+// HTTPDataSourceFetcher calls the upstream endpoints of data sources.
+type HTTPDataSourceFetcher struct {
+	client *http.Client
+}
+
+// errPrivateAddress is returned when a data source resolves to an address
+// of the server's own network.
+var errPrivateAddress = errors.New("data sources may not connect to private, loopback or link-local addresses")
+
+// reservedPrefixes are blocked besides the ranges netip.Addr classifies.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which maps to IPv4
+}
+
+// NewHTTPDataSourceFetcher creates a fetcher. Redirects are reported as
+// errors rather than followed, so a request carrying a secret never reaches
+// a host other than the configured endpoint. Connections to private,
+// loopback and link-local addresses are refused when they are dialled, after
+// name resolution, except for trustedHosts: the application's own origin,
+// which relative endpoints point to. Every call is bounded by the deadline
+// of its context.
+func NewHTTPDataSourceFetcher(trustedHosts ...string) *HTTPDataSourceFetcher {
+	trusted := make(map[string]bool, len(trustedHosts))
+	for _, host := range trustedHosts {
+		trusted[strings.ToLower(host)] = true
+	}
+	direct := &net.Dialer{Timeout: 10 * time.Second}
+	guarded := &net.Dialer{Timeout: 10 * time.Second, Control: rejectPrivateAddress}
+	return &HTTPDataSourceFetcher{client: &http.Client{
+		Transport: &http.Transport{
+			// No proxy: the guard has to see the address of the endpoint itself.
+			Proxy: nil,
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				if host, _, err := net.SplitHostPort(address); err == nil && trusted[strings.ToLower(host)] {
+					return direct.DialContext(ctx, network, address)
+				}
+				return guarded.DialContext(ctx, network, address)
+			},
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// rejectPrivateAddress is a net.Dialer Control function: it runs once the
+// host name is resolved, so a public name pointing at an internal address is
+// caught too.
+func rejectPrivateAddress(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errPrivateAddress, address)
+	}
+	addr := addrPort.Addr().Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return fmt.Errorf("%w: %s", errPrivateAddress, addr)
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("%w: %s", errPrivateAddress, addr)
+		}
+	}
+	return nil
+}
+
+// Fetch sends the request and returns the body of a 2xx response.
+func (f *HTTPDataSourceFetcher) Fetch(ctx context.Context, method, url string, headers map[string]string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "BetterForm-DataSources/1.0")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDataSourceResponseBytes))
+		return nil, fmt.Errorf("upstream responded with status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDataSourceResponseBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDataSourceResponseBytes {
+		return nil, fmt.Errorf("upstream response is larger than %d bytes", maxDataSourceResponseBytes)
+	}
+	return data, nil
+}
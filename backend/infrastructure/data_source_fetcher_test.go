@@ -0,0 +1,86 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRejectPrivateAddress(t *testing.T) {
+	refused := []string{
+		"127.0.0.1:80",
+		"10.1.2.3:443",
+		"172.16.0.1:443",
+		"192.168.1.1:80",
+		"169.254.169.254:80",
+		"0.0.0.0:80",
+		"100.64.0.1:80",
+		"198.18.0.1:80",
+		"224.0.0.1:80",
+		"[::1]:80",
+		"[fe80::1]:80",
+		"[fc00::1]:80",
+		"[::ffff:127.0.0.1]:80",
+		"[::ffff:169.254.169.254]:80",
+		"[64:ff9b::a00:1]:80",
+		"not an address",
+	}
+	for _, address := range refused {
+		if err := rejectPrivateAddress("tcp", address, nil); !errors.Is(err, errPrivateAddress) {
+			t.Errorf("rejectPrivateAddress(%s) = %v, want errPrivateAddress", address, err)
+		}
+	}
+	for _, address := range []string{"93.184.216.34:443", "8.8.8.8:53", "[2606:4700::1111]:443"} {
+		if err := rejectPrivateAddress("tcp", address, nil); err != nil {
+			t.Errorf("rejectPrivateAddress(%s) = %v, want nil", address, err)
+		}
+	}
+}
+
+func TestHTTPDataSourceFetcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/options":
+			if r.Header.Get("Authorization") != "Bearer s3cret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`[{"value":"nl","label":"Netherlands"}]`))
+		case "/redirect":
+			http.Redirect(w, r, "/options", http.StatusFound)
+		case "/large":
+			_, _ = w.Write([]byte(strings.Repeat(" ", maxDataSourceResponseBytes+1)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host, _, _ := net.SplitHostPort(server.Listener.Addr().String())
+	headers := map[string]string{"Authorization": "Bearer s3cret"}
+
+	trusted := NewHTTPDataSourceFetcher(host)
+	body, err := trusted.Fetch(context.Background(), http.MethodGet, server.URL+"/options", headers, nil)
+	if err != nil || !strings.Contains(string(body), "Netherlands") {
+		t.Errorf("Fetch = %s, %v; want the options", body, err)
+	}
+	// The redirect is reported, so the secret is not sent on to its target.
+	if _, err := trusted.Fetch(context.Background(), http.MethodGet, server.URL+"/redirect", headers, nil); err == nil || !strings.Contains(err.Error(), "302") {
+		t.Errorf("Fetch of a redirect = %v, want a status error", err)
+	}
+	if _, err := trusted.Fetch(context.Background(), http.MethodGet, server.URL+"/large", nil, nil); err == nil {
+		t.Error("Fetch of an oversized response succeeded")
+	}
+	if _, err := trusted.Fetch(context.Background(), http.MethodGet, server.URL+"/missing", nil, nil); err == nil {
+		t.Error("Fetch of a 404 succeeded")
+	}
+
+	// Loopback is refused unless trusted.
+	_, err = NewHTTPDataSourceFetcher().Fetch(context.Background(), http.MethodGet, server.URL+"/options", headers, nil)
+	if !errors.Is(err, errPrivateAddress) {
+		t.Errorf("Fetch of a loopback endpoint = %v, want errPrivateAddress", err)
+	}
+}
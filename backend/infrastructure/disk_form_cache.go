@@ -0,0 +1,142 @@
+package infrastructure
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DiskFormCache keeps cached forms as files in a directory, so that they
+// survive restarts and can be shared by instances on the same volume. An
+// entry expires ttl after it was written. The size limit is enforced every
+// few writes by removing the oldest files beyond maxEntries.
+type DiskFormCache struct {
+	dir        string
+	maxEntries int
+	ttl        time.Duration
+
+	mu     sync.Mutex
+	writes int
+}
+
+// diskCacheSweepInterval is how many writes pass between size checks.
+const diskCacheSweepInterval = 50
+
+// NewDiskFormCache creates the cache directory if needed and returns the cache.
+func NewDiskFormCache(dir string, maxEntries int, ttl time.Duration) (*DiskFormCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create form cache directory: %w", err)
+	}
+	cache := &DiskFormCache{dir: dir, maxEntries: maxEntries, ttl: ttl}
+	if err := cache.sweep(); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+// Get returns the value of key unless it has expired.
+func (dc *DiskFormCache) Get(key string) ([]byte, bool, error) {
+	path, err := dc.path(key)
+	if err != nil {
+		return nil, false, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if time.Since(info.ModTime()) > dc.ttl {
+		_ = os.Remove(path)
+		return nil, false, nil
+	}
+	value, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	return value, err == nil, err
+}
+
+// Set writes value under key. The file is renamed into place so that
+// readers never see a partial entry.
+func (dc *DiskFormCache) Set(key string, value []byte) error {
+	path, err := dc.path(key)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dc.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write form cache entry: %w", err)
+	}
+	_, err = tmp.Write(value)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write form cache entry: %w", err)
+	}
+
+	dc.mu.Lock()
+	dc.writes++
+	sweep := dc.writes%diskCacheSweepInterval == 0
+	dc.mu.Unlock()
+	if sweep {
+		return dc.sweep()
+	}
+	return nil
+}
+
+// path maps a key to its file. Keys are hex digests, which keeps them safe
+// to use as file names.
+func (dc *DiskFormCache) path(key string) (string, error) {
+	if _, err := hex.DecodeString(key); err != nil || key == "" {
+		return "", fmt.Errorf("invalid form cache key %q", key)
+	}
+	return filepath.Join(dc.dir, key+".json"), nil
+}
+
+// sweep removes expired entries and the oldest ones beyond maxEntries.
+func (dc *DiskFormCache) sweep() error {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(dc.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	type cacheFile struct {
+		path    string
+		modTime time.Time
+	}
+	live := make([]cacheFile, 0, len(files))
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if time.Since(info.ModTime()) > dc.ttl {
+			_ = os.Remove(path)
+			continue
+		}
+		live = append(live, cacheFile{path: path, modTime: info.ModTime()})
+	}
+	if len(live) <= dc.maxEntries {
+		return nil
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].modTime.Before(live[j].modTime) })
+	for _, file := range live[:len(live)-dc.maxEntries] {
+		_ = os.Remove(file.path)
+	}
+	return nil
+}
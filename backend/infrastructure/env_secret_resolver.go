@@ -0,0 +1,61 @@
+package infrastructure
+
+import (
+	"os"
+	"strings"
+	"unicode"
+)
+
+// EnvSecretResolver reads the secrets referenced by authTokenRef from
+// environment variables: the reference is upper-cased, every character but
+// letters and digits becomes an underscore, and prefix is prepended, so
+// "crm-token" is read from <prefix>CRM_TOKEN. The prefix keeps references
+// from reaching the server's own credentials.
+//
+// A secret is bound to the comma-separated hosts in <prefix>CRM_TOKEN_HOST;
+// without them it is never sent anywhere.
+type EnvSecretResolver struct {
+	prefix string
+}
+
+// NewEnvSecretResolver creates a new instance of EnvSecretResolver.
+func NewEnvSecretResolver(prefix string) *EnvSecretResolver {
+	return &EnvSecretResolver{prefix: prefix}
+}
+
+// Secret returns the value of the variable named after ref.
+func (r *EnvSecretResolver) Secret(ref string) (string, bool) {
+	name, ok := r.variable(ref)
+	if !ok {
+		return "", false
+	}
+	value := os.Getenv(name)
+	return value, value != ""
+}
+
+// Hosts returns the hosts the secret named ref is bound to, none when
+// <prefix>NAME_HOST is unset. It returns false when the secret is not
+// configured.
+func (r *EnvSecretResolver) Hosts(ref string) ([]string, bool) {
+	if _, ok := r.Secret(ref); !ok {
+		return nil, false
+	}
+	name, _ := r.variable(ref)
+	var hosts []string
+	for _, host := range strings.Split(os.Getenv(name+"_HOST"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts, true
+}
+
+func (r *EnvSecretResolver) variable(ref string) (string, bool) {
+	name := strings.Map(func(c rune) rune {
+		if c <= unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c)) {
+			return unicode.ToUpper(c)
+		}
+		return '_'
+	}, strings.TrimSpace(ref))
+	return r.prefix + name, name != ""
+}
@@ -0,0 +1,123 @@
+package infrastructure
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// formCache is the behaviour shared by the form cache implementations.
+type formCache interface {
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte) error
+}
+
+func assertCached(t *testing.T, cache formCache, key, want string) {
+	t.Helper()
+	value, ok, err := cache.Get(key)
+	switch {
+	case err != nil:
+		t.Errorf("Get(%s): %v", key, err)
+	case want == "" && ok:
+		t.Errorf("Get(%s) = %s, want a miss", key, value)
+	case want != "" && (!ok || string(value) != want):
+		t.Errorf("Get(%s) = %s, %v; want %s", key, value, ok, want)
+	}
+}
+
+func TestInMemoryFormCache(t *testing.T) {
+	cache := NewInMemoryFormCache(2, time.Hour)
+	assertCached(t, cache, "aa", "")
+
+	_ = cache.Set("aa", []byte("1"))
+	_ = cache.Set("bb", []byte("2"))
+	assertCached(t, cache, "aa", "1")
+	_ = cache.Set("bb", []byte("2b"))
+	assertCached(t, cache, "bb", "2b")
+
+	// aa is now the least recently used entry.
+	_ = cache.Set("cc", []byte("3"))
+	assertCached(t, cache, "aa", "")
+	assertCached(t, cache, "bb", "2b")
+	assertCached(t, cache, "cc", "3")
+
+	// Reading bb makes cc the one to go.
+	assertCached(t, cache, "bb", "2b")
+	_ = cache.Set("dd", []byte("4"))
+	assertCached(t, cache, "cc", "")
+	assertCached(t, cache, "bb", "2b")
+	assertCached(t, cache, "dd", "4")
+}
+
+func TestInMemoryFormCacheExpiry(t *testing.T) {
+	cache := NewInMemoryFormCache(10, time.Millisecond)
+	_ = cache.Set("aa", []byte("1"))
+	time.Sleep(5 * time.Millisecond)
+	assertCached(t, cache, "aa", "")
+	if cache.order.Len() != 0 || len(cache.entries) != 0 {
+		t.Errorf("expired entry was not removed")
+	}
+}
+
+func TestDiskFormCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewDiskFormCache(dir, 2, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCached(t, cache, "aa", "")
+	if err := cache.Set("aa", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	assertCached(t, cache, "aa", "1")
+
+	// Entries survive a restart.
+	reopened, err := NewDiskFormCache(dir, 2, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCached(t, reopened, "aa", "1")
+
+	for _, key := range []string{"", "../etc/passwd", "not-hex"} {
+		if err := cache.Set(key, []byte("x")); err == nil {
+			t.Errorf("Set(%q) was accepted", key)
+		}
+		if _, _, err := cache.Get(key); err == nil {
+			t.Errorf("Get(%q) was accepted", key)
+		}
+	}
+}
+
+func TestDiskFormCacheExpiryAndSweep(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewDiskFormCache(dir, 2, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	age := func(key string, d time.Duration) {
+		at := time.Now().Add(-d)
+		if err := os.Chtimes(filepath.Join(dir, key+".json"), at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range []string{"aa", "bb", "cc", "dd"} {
+		_ = cache.Set(key, []byte(key))
+	}
+	age("aa", 2*time.Hour)
+	assertCached(t, cache, "aa", "")
+	if _, err := os.Stat(filepath.Join(dir, "aa.json")); !os.IsNotExist(err) {
+		t.Errorf("expired entry was not removed: %v", err)
+	}
+
+	// Beyond maxEntries the oldest files go.
+	age("bb", 3*time.Minute)
+	age("cc", 2*time.Minute)
+	age("dd", time.Minute)
+	if err := cache.sweep(); err != nil {
+		t.Fatal(err)
+	}
+	assertCached(t, cache, "bb", "")
+	assertCached(t, cache, "cc", "cc")
+	assertCached(t, cache, "dd", "dd")
+}
@@ -0,0 +1,148 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// formRepository is implemented by both form repositories.
+type formRepository interface {
+	Create(form *domain.StoredForm, version *domain.FormVersion) error
+	Get(id string) (*domain.StoredForm, error)
+	AddVersion(form *domain.StoredForm, version *domain.FormVersion) error
+	GetVersion(formID string, version int) (*domain.FormVersion, error)
+	ListVersions(formID string) ([]*domain.FormVersion, error)
+	Delete(id string) error
+}
+
+// formRepositories returns a fresh repository of every kind, each holding
+// version 1 of the form "form-1".
+func formRepositories(t *testing.T) map[string]formRepository {
+	t.Helper()
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	sqlite, err := NewSQLiteFormRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repositories := map[string]formRepository{
+		"memory": NewInMemoryFormRepository(),
+		"sqlite": sqlite,
+	}
+	for _, repository := range repositories {
+		form := testStoredForm(1, "Version 1")
+		if err := repository.Create(form, testFormVersion(form)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repositories
+}
+
+func testStoredForm(version int, title string) *domain.StoredForm {
+	now := time.Now().UTC()
+	return &domain.StoredForm{
+		ID:        "form-1",
+		OwnerID:   "owner",
+		Name:      "Form",
+		Version:   version,
+		Config:    domain.FormConfig{Title: title},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func testFormVersion(form *domain.StoredForm) *domain.FormVersion {
+	return &domain.FormVersion{
+		FormID:    form.ID,
+		Version:   form.Version,
+		Source:    domain.VersionEdited,
+		Config:    form.Config,
+		CreatedBy: form.OwnerID,
+		CreatedAt: form.UpdatedAt,
+	}
+}
+
+func TestFormRepositoryVersions(t *testing.T) {
+	for name, repository := range formRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			form := testStoredForm(2, "Version 2")
+			if err := repository.AddVersion(form, testFormVersion(form)); err != nil {
+				t.Fatal(err)
+			}
+
+			current, err := repository.Get("form-1")
+			if err != nil || current.Version != 2 || current.Config.Title != "Version 2" {
+				t.Fatalf("Get = %+v, %v, want version 2", current, err)
+			}
+			first, err := repository.GetVersion("form-1", 1)
+			if err != nil || first.Config.Title != "Version 1" {
+				t.Errorf("GetVersion(1) = %+v, %v, want the first config", first, err)
+			}
+			versions, err := repository.ListVersions("form-1")
+			if err != nil || len(versions) != 2 || versions[0].Version != 1 || versions[1].Version != 2 {
+				t.Errorf("ListVersions = %+v, %v, want versions 1 and 2", versions, err)
+			}
+			for _, version := range []int{0, 3} {
+				if _, err := repository.GetVersion("form-1", version); !errors.Is(err, domain.ErrVersionNotFound) {
+					t.Errorf("GetVersion(%d) error = %v, want ErrVersionNotFound", version, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFormRepositoryVersionConflict(t *testing.T) {
+	for name, repository := range formRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			winner := testStoredForm(2, "Winner")
+			if err := repository.AddVersion(winner, testFormVersion(winner)); err != nil {
+				t.Fatal(err)
+			}
+
+			// A save based on version 1 lost the race, and a save may not skip
+			// a version.
+			for _, form := range []*domain.StoredForm{testStoredForm(2, "Loser"), testStoredForm(4, "Skipped")} {
+				if err := repository.AddVersion(form, testFormVersion(form)); !errors.Is(err, domain.ErrVersionConflict) {
+					t.Errorf("AddVersion(%d) error = %v, want ErrVersionConflict", form.Version, err)
+				}
+			}
+
+			current, err := repository.Get("form-1")
+			if err != nil || current.Version != 2 || current.Config.Title != "Winner" {
+				t.Errorf("Get = %+v, %v, want the winning version 2", current, err)
+			}
+			if versions, _ := repository.ListVersions("form-1"); len(versions) != 2 || versions[1].Config.Title != "Winner" {
+				t.Errorf("ListVersions = %+v, want versions 1 and the winning 2", versions)
+			}
+		})
+	}
+}
+
+func TestFormRepositoryDelete(t *testing.T) {
+	for name, repository := range formRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := repository.Delete("form-1"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := repository.Get("form-1"); !errors.Is(err, domain.ErrFormNotFound) {
+				t.Errorf("Get after delete error = %v, want ErrFormNotFound", err)
+			}
+			if _, err := repository.GetVersion("form-1", 1); !errors.Is(err, domain.ErrVersionNotFound) {
+				t.Errorf("GetVersion after delete error = %v, want ErrVersionNotFound", err)
+			}
+			form := testStoredForm(2, "Gone")
+			if err := repository.AddVersion(form, testFormVersion(form)); err == nil {
+				t.Error("AddVersion to a deleted form succeeded")
+			}
+			if err := repository.Delete("form-1"); !errors.Is(err, domain.ErrFormNotFound) {
+				t.Errorf("second Delete error = %v, want ErrFormNotFound", err)
+			}
+		})
+	}
+}
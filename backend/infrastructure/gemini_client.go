@@ -1,40 +1,65 @@
 package infrastructure
 
 import (
-	"bytes"
+	"better-form-doc-backend/domain"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
+	"strings"
 )
 
 // GeminiClient is a client for interacting with the Google Gemini API.
 type GeminiClient struct {
-	httpClient *http.Client
-	apiKey     string
-	modelName  string // e.g., "gemini-pro"
+	httpClient   *http.Client
+	streamClient *http.Client
+	baseURL      string
+	apiKey       string
+	modelName    string // e.g., "gemini-pro"
+	retry        RetryPolicy
+	breaker      *CircuitBreaker
 }
 
-// NewGeminiClient creates a new instance of the GeminiClient.
-func NewGeminiClient(apiKey, modelName string) *GeminiClient {
+// NewGeminiClient creates a new instance of the GeminiClient. Rate limited,
+// 5xx and network failures are retried according to retry; breaker may be nil
+// to never fast-fail.
+func NewGeminiClient(apiKey, modelName string, retry RetryPolicy, breaker *CircuitBreaker) *GeminiClient {
 	return &GeminiClient{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second, // Set a reasonable timeout
-		},
-		apiKey:    apiKey,
-		modelName: modelName,
+		httpClient:   newHTTPClient(),
+		streamClient: newStreamingHTTPClient(),
+		baseURL:      geminiBaseURL,
+		apiKey:       apiKey,
+		modelName:    modelName,
+		retry:        retry,
+		breaker:      breaker,
 	}
 }
 
+// geminiBaseURL is the Gemini API endpoint the model methods are called on.
+const geminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// geminiSafetyFinishReasons are the finish reasons of answers stopped by a
+// content filter.
+var geminiSafetyFinishReasons = map[string]bool{
+	"SAFETY":             true,
+	"PROHIBITED_CONTENT": true,
+	"BLOCKLIST":          true,
+	"SPII":               true,
+	"IMAGE_SAFETY":       true,
+}
+
 // --- Gemini API Request/Response Structures ---
 
 // geminiRequest represents the JSON payload sent to the Gemini API.
 type geminiRequest struct {
-	Contents []*geminiContent `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Contents          []*geminiContent        `json:"contents"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
 }
 
 type geminiContent struct {
+	Role  string        `json:"role,omitempty"`
 	Parts []*geminiPart `json:"parts"`
 }
 
@@ -42,67 +67,212 @@ type geminiPart struct {
 	Text string `json:"text"`
 }
 
+// geminiGenerationConfig controls sampling and the shape of the output.
+type geminiGenerationConfig struct {
+	ResponseMimeType string         `json:"responseMimeType,omitempty"`
+	ResponseSchema   *domain.Schema `json:"responseSchema,omitempty"`
+	Temperature      *float64       `json:"temperature,omitempty"`
+	TopP             *float64       `json:"topP,omitempty"`
+	MaxOutputTokens  int            `json:"maxOutputTokens,omitempty"`
+}
+
 // geminiResponse is the structure of the JSON response from the Gemini API.
-// We only care about the text content for now, so we'll simplify it.
+// Streaming calls return a sequence of these, each with a partial candidate.
 type geminiResponse struct {
-	Candidates []*geminiCandidate `json:"candidates"`
+	Candidates     []*geminiCandidate    `json:"candidates"`
+	PromptFeedback *geminiPromptFeedback `json:"promptFeedback"`
+	UsageMetadata  *geminiUsageMetadata  `json:"usageMetadata"`
+	ModelVersion   string                `json:"modelVersion"`
+	// Error is sent instead of a chunk when generation fails mid-stream.
+	Error *geminiError `json:"error"`
+}
+
+type geminiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
 }
 
 type geminiCandidate struct {
-	Content *geminiContent `json:"content"`
+	Content       *geminiContent        `json:"content"`
+	FinishReason  string                `json:"finishReason"`
+	SafetyRatings []domain.SafetyRating `json:"safetyRatings"`
 }
 
-// GenerateContent sends a prompt to the Gemini API and returns the response.
-func (gc *GeminiClient) GenerateContent(prompt string) (map[string]interface{}, error) {
-	// Construct the API URL
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", gc.modelName, gc.apiKey)
+// geminiPromptFeedback is sent instead of candidates when the prompt is blocked.
+type geminiPromptFeedback struct {
+	BlockReason   string                `json:"blockReason"`
+	SafetyRatings []domain.SafetyRating `json:"safetyRatings"`
+}
 
-	// Prepare the request body according to the Gemini API spec
-	reqBody := geminiRequest{
-		Contents: []*geminiContent{
-			{
-				Parts: []*geminiPart{
-					{Text: prompt},
-				},
-			},
-		},
-	}
+type geminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
 
-	payload, err := json.Marshal(reqBody)
+// Generate sends a request to the Gemini API and returns the response.
+func (gc *GeminiClient) Generate(ctx context.Context, request domain.GenerationRequest) (*domain.GenerationResponse, error) {
+	var result geminiResponse
+	err := withRetry(ctx, gc.retry, gc.breaker, "Gemini API", func() error {
+		req, err := gc.newHTTPRequest(ctx, "generateContent", "", request)
+		if err != nil {
+			return err
+		}
+		return doJSON(gc.httpClient, req, "Gemini API", &result)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		return nil, err
 	}
 
-	// Create the HTTP request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(payload))
+	response := &domain.GenerationResponse{Model: gc.modelName}
+	gc.accumulate(response, &result, nil)
+	return response, checkSafety(response)
+}
+
+// Stream calls streamGenerateContent with alt=sse and passes every text chunk
+// to onText as it arrives.
+func (gc *GeminiClient) Stream(ctx context.Context, request domain.GenerationRequest, onText func(text string)) (*domain.GenerationResponse, error) {
+	// Only opening the stream is retried; once text was passed on, a
+	// failure cannot be undone.
+	var body io.ReadCloser
+	err := withRetry(ctx, gc.retry, gc.breaker, "Gemini API", func() error {
+		req, err := gc.newHTTPRequest(ctx, "streamGenerateContent", "alt=sse", request)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		body, err = openStream(gc.streamClient, req, "Gemini API")
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create http request: %w", err)
+		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	defer body.Close()
 
-	// Send the request
-	resp, err := gc.httpClient.Do(req)
+	// Each SSE event carries one partial GenerateContentResponse.
+	response := &domain.GenerationResponse{Model: gc.modelName}
+	err = readSSE(body, func(data string) error {
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal Gemini stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return chunkError("Gemini API", chunk.Error.Code, chunk.Error.Status+": "+chunk.Error.Message)
+		}
+		gc.accumulate(response, &chunk, onText)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request to Gemini API: %w", err)
+		// The usage reported so far is still accounted.
+		return response, streamError(ctx, "Gemini API", err)
 	}
-	defer resp.Body.Close()
+	return response, checkSafety(response)
+}
 
-	// Read the response body
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+// checkSafety returns an error wrapping domain.ErrSafetyBlocked when the
+// prompt was blocked or the answer was stopped by a safety filter.
+func checkSafety(response *domain.GenerationResponse) error {
+	switch {
+	case response.BlockReason != "":
+		return fmt.Errorf("%w: prompt blocked (%s)", domain.ErrSafetyBlocked, response.BlockReason)
+	case geminiSafetyFinishReasons[response.FinishReason]:
+		return fmt.Errorf("%w: answer stopped (%s)", domain.ErrSafetyBlocked, response.FinishReason)
 	}
+	return nil
+}
 
-	// Check for non-200 status codes
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Gemini API returned an error: %s - %s", resp.Status, string(respBody))
+// accumulate appends the text of the first candidate to response and keeps
+// the latest finish reason, safety ratings and usage, which Gemini reports
+// cumulatively.
+func (gc *GeminiClient) accumulate(response *domain.GenerationResponse, chunk *geminiResponse, onText func(string)) {
+	if chunk.ModelVersion != "" {
+		response.Model = chunk.ModelVersion
+	}
+	if chunk.UsageMetadata != nil {
+		response.Usage = domain.TokenUsage{
+			PromptTokens:     chunk.UsageMetadata.PromptTokenCount,
+			CompletionTokens: chunk.UsageMetadata.CandidatesTokenCount,
+			ThoughtsTokens:   chunk.UsageMetadata.ThoughtsTokenCount,
+			TotalTokens:      chunk.UsageMetadata.TotalTokenCount,
+		}
+	}
+	if feedback := chunk.PromptFeedback; feedback != nil {
+		if feedback.BlockReason != "" {
+			response.BlockReason = feedback.BlockReason
+		}
+		if len(feedback.SafetyRatings) > 0 {
+			response.SafetyRatings = feedback.SafetyRatings
+		}
+	}
+	if len(chunk.Candidates) == 0 {
+		return
+	}
+	candidate := chunk.Candidates[0]
+	if candidate.FinishReason != "" {
+		response.FinishReason = candidate.FinishReason
 	}
+	if len(candidate.SafetyRatings) > 0 {
+		response.SafetyRatings = candidate.SafetyRatings
+	}
+	if candidate.Content == nil {
+		return
+	}
+	for _, part := range candidate.Content.Parts {
+		if part.Text == "" {
+			continue
+		}
+		response.Text += part.Text
+		if onText != nil {
+			onText(part.Text)
+		}
+	}
+}
 
-	// Unmarshal the successful response into a generic map
-	var result map[string]interface{}
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal Gemini response: %w", err)
+// newHTTPRequest builds a POST to the given model method with the request body
+// expected by the Gemini API.
+func (gc *GeminiClient) newHTTPRequest(ctx context.Context, method, query string, request domain.GenerationRequest) (*http.Request, error) {
+	// Construct the API URL. The key goes in a header so that it never
+	// appears in the URL quoted by transport errors.
+	url := fmt.Sprintf("%s/models/%s:%s", gc.baseURL, gc.modelName, method)
+	if query != "" {
+		url += "?" + query
 	}
 
-	return result, nil
+	// Prepare the request body according to the Gemini API spec. System
+	// messages become the system instruction and assistant turns use the
+	// "model" role.
+	reqBody := geminiRequest{
+		GenerationConfig: &geminiGenerationConfig{
+			ResponseMimeType: request.ResponseMimeType,
+			ResponseSchema:   request.ResponseSchema,
+			Temperature:      request.Temperature,
+			TopP:             request.TopP,
+			MaxOutputTokens:  request.MaxOutputTokens,
+		},
+	}
+	var system []string
+	for _, message := range request.Messages {
+		switch message.Role {
+		case domain.RoleSystem:
+			system = append(system, message.Content)
+		case domain.RoleAssistant:
+			reqBody.Contents = append(reqBody.Contents, &geminiContent{Role: "model", Parts: []*geminiPart{{Text: message.Content}}})
+		default:
+			reqBody.Contents = append(reqBody.Contents, &geminiContent{Role: "user", Parts: []*geminiPart{{Text: message.Content}}})
+		}
+	}
+	if len(system) > 0 {
+		reqBody.SystemInstruction = &geminiContent{
+			Parts: []*geminiPart{{Text: strings.Join(system, "\n\n")}},
+		}
+	}
+
+	req, err := newJSONRequest(ctx, url, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-goog-api-key", gc.apiKey)
+	return req, nil
 }
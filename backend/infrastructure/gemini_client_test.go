@@ -0,0 +1,71 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGeminiClientStructuredOutput(t *testing.T) {
+	var path, apiKey string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, apiKey = r.URL.String(), r.Header.Get("x-goog-api-key")
+		raw, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(raw, &body)
+		fmt.Fprint(w, `{"candidates":[{"content":{"parts":[{"text":"{\"title\":"},{"text":"\"Login\"}"}]},"finishReason":"STOP"}],`+
+			`"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"thoughtsTokenCount":2,"totalTokenCount":17},"modelVersion":"gemini-test-001"}`)
+	}))
+	defer server.Close()
+
+	client := NewGeminiClient("secret", "gemini-test", RetryPolicy{}, nil)
+	client.baseURL = server.URL
+	temperature := 0.2
+	response, err := client.Generate(context.Background(), domain.GenerationRequest{
+		Messages: []domain.Message{
+			{Role: domain.RoleSystem, Content: "rules"},
+			{Role: domain.RoleUser, Content: "a login form"},
+			{Role: domain.RoleAssistant, Content: "{}"},
+			{Role: domain.RoleUser, Content: "add a password"},
+		},
+		ResponseMimeType: "application/json",
+		ResponseSchema:   &domain.Schema{AnyOf: []*domain.Schema{domain.FormConfigSchema(), domain.IrrelevantPromptSchema()}},
+		Temperature:      &temperature,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if path != "/models/gemini-test:generateContent" || apiKey != "secret" {
+		t.Errorf("request to %s with key %q; the key belongs in the header only", path, apiKey)
+	}
+	config, _ := body["generationConfig"].(map[string]interface{})
+	schema, _ := config["responseSchema"].(map[string]interface{})
+	if config["responseMimeType"] != "application/json" || config["temperature"] != 0.2 || len(schema["anyOf"].([]interface{})) != 2 {
+		t.Errorf("generationConfig = %v", config)
+	}
+	raw, _ := json.Marshal(body["contents"])
+	if want := `[{"parts":[{"text":"a login form"}],"role":"user"},{"parts":[{"text":"{}"}],"role":"model"},{"parts":[{"text":"add a password"}],"role":"user"}]`; string(raw) != want {
+		t.Errorf("contents = %s, want %s", raw, want)
+	}
+	raw, _ = json.Marshal(body["systemInstruction"])
+	if !strings.Contains(string(raw), `"text":"rules"`) {
+		t.Errorf("systemInstruction = %s", raw)
+	}
+
+	want := domain.GenerationResponse{
+		Text:         `{"title":"Login"}`,
+		Usage:        domain.TokenUsage{PromptTokens: 10, CompletionTokens: 5, ThoughtsTokens: 2, TotalTokens: 17},
+		FinishReason: "STOP",
+		Model:        "gemini-test-001",
+	}
+	if fmt.Sprint(*response) != fmt.Sprint(want) {
+		t.Errorf("response = %+v, want %+v", *response, want)
+	}
+}
@@ -0,0 +1,217 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// streamHeaderTimeout bounds the wait for the headers of a streaming call.
+// Complete calls are bounded by the deadline of their context instead.
+const streamHeaderTimeout = 30 * time.Second
+
+// maxStreamEventSize bounds a single line read from a streaming endpoint.
+const maxStreamEventSize = 1024 * 1024
+
+// newHTTPClient returns the client used for regular model calls. It has no
+// timeout of its own: every call carries the deadline of its request context.
+func newHTTPClient() *http.Client {
+	return &http.Client{}
+}
+
+// newStreamingHTTPClient returns a client without an overall timeout so long
+// generations can keep streaming; it only bounds the wait for the headers.
+func newStreamingHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			ResponseHeaderTimeout: streamHeaderTimeout,
+		},
+	}
+}
+
+// newJSONRequest builds a POST request with a JSON body, bound to ctx.
+func newJSONRequest(ctx context.Context, url string, body interface{}) (*http.Request, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// doJSON sends req, checks for a 200 status and decodes the body into out.
+// provider names the upstream in error messages.
+func doJSON(client *http.Client, req *http.Request, provider string, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return networkError(req, provider, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return networkError(req, provider, fmt.Errorf("failed to read response body: %w", err))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(provider, resp, respBody)
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to unmarshal %s response: %w", provider, err)
+	}
+	return nil
+}
+
+// openStream sends req and returns the response body of a successful streaming call.
+func openStream(client *http.Client, req *http.Request, provider string) (io.ReadCloser, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, networkError(req, provider, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, statusError(provider, resp, respBody)
+	}
+	return resp.Body, nil
+}
+
+// networkError reports a call that got no (complete) response as unavailable,
+// unless its context was canceled or timed out.
+func networkError(req *http.Request, provider string, err error) error {
+	if ctxErr := req.Context().Err(); ctxErr != nil {
+		return fmt.Errorf("request to %s aborted: %w", provider, ctxErr)
+	}
+	return &domain.UpstreamError{
+		Err:     domain.ErrUpstreamUnavailable,
+		Message: fmt.Sprintf("failed to send request to %s: %v", provider, err),
+	}
+}
+
+// streamError reports a stream that broke off, or carried a chunk that could
+// not be read, after the call had succeeded, unless ctx was canceled or timed
+// out. Text may already have been passed on, so the error wraps no transient
+// sentinel and is not retried.
+func streamError(ctx context.Context, provider string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("request to %s aborted: %w", provider, ctxErr)
+	}
+	var upstreamErr *domain.UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return err
+	}
+	return &domain.UpstreamError{Message: fmt.Sprintf("%s stream failed: %v", provider, err)}
+}
+
+// chunkError reports an error the provider sent in place of a stream chunk.
+// code is the status it gives, if any. Like streamError, it is not retried.
+func chunkError(provider string, code int, message string) error {
+	return &domain.UpstreamError{
+		StatusCode: code,
+		Message:    fmt.Sprintf("%s reported an error mid-stream: %s", provider, message),
+	}
+}
+
+// statusError turns a non-200 response into an UpstreamError. 429 means rate
+// limited; 408 and 5xx mean the provider is unavailable. Other statuses are
+// not transient and wrap no sentinel.
+func statusError(provider string, resp *http.Response, body []byte) error {
+	upstreamErr := &domain.UpstreamError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		Message:    fmt.Sprintf("%s returned an error: %s - %s", provider, resp.Status, string(body)),
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		upstreamErr.Err = domain.ErrRateLimited
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= 500:
+		upstreamErr.Err = domain.ErrUpstreamUnavailable
+	}
+	return upstreamErr
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// readSSE reads a Server-Sent Events stream and calls onData with the joined
+// data lines of every event.
+func readSSE(r io.Reader, onData func(data string) error) error {
+	var data strings.Builder
+	dispatch := func() error {
+		if data.Len() == 0 {
+			return nil
+		}
+		payload := data.String()
+		data.Reset()
+		return onData(payload)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamEventSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return dispatch()
+}
+
+// readJSONLines calls onLine for every non-empty line of a newline-delimited JSON stream.
+func readJSONLines(r io.Reader, onLine func(line []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamEventSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := onLine(line); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil
+}
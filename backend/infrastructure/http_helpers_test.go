@@ -0,0 +1,182 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// brokenStream answers with a 200 and the given body, then drops the
+// connection before the announced length was sent.
+func brokenStream(t *testing.T, contentType, body string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n%s", contentType, len(body)+1000, body)
+		buf.Flush()
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// streamer is implemented by every model client.
+type streamer interface {
+	Stream(ctx context.Context, request domain.GenerationRequest, onText func(text string)) (*domain.GenerationResponse, error)
+}
+
+// streamServer answers with a 200 and the given body.
+func streamServer(t *testing.T, contentType, body string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestStreamErrorsAreUpstreamErrors(t *testing.T) {
+	const openAIChunk = `data: {"model":"m","choices":[{"delta":{"content":"{\"ti"}}]}` + "\n\n"
+	const ollamaChunk = `{"model":"m","message":{"content":"{\"ti"}}` + "\n"
+	tests := []struct {
+		name   string
+		server func(t *testing.T) *httptest.Server
+		client func(url string) streamer
+	}{
+		{
+			"OpenAI connection dropped",
+			func(t *testing.T) *httptest.Server { return brokenStream(t, "text/event-stream", openAIChunk) },
+			func(url string) streamer {
+				return NewOpenAIClient(url, "", "m")
+			},
+		},
+		{
+			"OpenAI unreadable chunk",
+			func(t *testing.T) *httptest.Server {
+				return streamServer(t, "text/event-stream", openAIChunk+"data: {not json\n\n")
+			},
+			func(url string) streamer {
+				return NewOpenAIClient(url, "", "m")
+			},
+		},
+		{
+			"OpenAI event too large",
+			func(t *testing.T) *httptest.Server {
+				return streamServer(t, "text/event-stream", openAIChunk+"data: "+strings.Repeat("x", maxStreamEventSize+1)+"\n\n")
+			},
+			func(url string) streamer {
+				return NewOpenAIClient(url, "", "m")
+			},
+		},
+		{
+			"Ollama connection dropped",
+			func(t *testing.T) *httptest.Server { return brokenStream(t, "application/x-ndjson", ollamaChunk) },
+			func(url string) streamer {
+				return NewOllamaClient(url, "m")
+			},
+		},
+		{
+			"Ollama unreadable chunk",
+			func(t *testing.T) *httptest.Server {
+				return streamServer(t, "application/x-ndjson", ollamaChunk+"{not json\n")
+			},
+			func(url string) streamer {
+				return NewOllamaClient(url, "m")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := tt.server(t)
+			var streamed strings.Builder
+			response, err := tt.client(server.URL).Stream(context.Background(), domain.GenerationRequest{}, func(text string) {
+				streamed.WriteString(text)
+			})
+			var upstreamErr *domain.UpstreamError
+			if !errors.As(err, &upstreamErr) {
+				t.Fatalf("err = %v (%T), want an UpstreamError", err, err)
+			}
+			// Not transient: the text already passed on cannot be taken back.
+			if errors.Is(err, domain.ErrUpstreamUnavailable) || errors.Is(err, domain.ErrRateLimited) {
+				t.Errorf("err = %v wraps a transient sentinel", err)
+			}
+			if streamed.String() != `{"ti` || response == nil || response.Text != `{"ti` {
+				t.Errorf("streamed %q, response %+v; want the text before the failure", streamed.String(), response)
+			}
+		})
+	}
+}
+
+func TestStreamErrorKeepsCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := streamError(ctx, "test", errors.New("read: connection reset"))
+	var upstreamErr *domain.UpstreamError
+	if !errors.Is(err, context.Canceled) || errors.As(err, &upstreamErr) {
+		t.Errorf("err = %v, want context.Canceled and no UpstreamError", err)
+	}
+
+	original := &domain.UpstreamError{StatusCode: 500, Message: "model error"}
+	if err := streamError(context.Background(), "test", fmt.Errorf("chunk: %w", original)); !errors.As(err, &upstreamErr) || upstreamErr != original {
+		t.Errorf("err = %v, want the original UpstreamError", err)
+	}
+}
+
+func TestStreamErrorChunks(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		client      func(url string) streamer
+		wantStatus  int
+	}{
+		{
+			"Gemini",
+			"text/event-stream",
+			`data: {"candidates":[{"content":{"parts":[{"text":"{\"ti"}]}}]}` + "\n\n" +
+				`data: {"error":{"code":500,"message":"Internal error encountered.","status":"INTERNAL"}}` + "\n\n",
+			func(url string) streamer {
+				client := NewGeminiClient("key", "m", RetryPolicy{}, nil)
+				client.baseURL = url
+				return client
+			},
+			500,
+		},
+		{
+			"OpenAI",
+			"text/event-stream",
+			`data: {"choices":[{"delta":{"content":"{\"ti"}}]}` + "\n\n" +
+				`data: {"error":{"message":"The server had an error","type":"server_error"}}` + "\n\n",
+			func(url string) streamer { return NewOpenAIClient(url, "", "m") },
+			0,
+		},
+		{
+			"Ollama",
+			"application/x-ndjson",
+			`{"message":{"content":"{\"ti"}}` + "\n" + `{"error":"model runner has unexpectedly stopped"}` + "\n",
+			func(url string) streamer { return NewOllamaClient(url, "m") },
+			0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := streamServer(t, tt.contentType, tt.body)
+			response, err := tt.client(server.URL).Stream(context.Background(), domain.GenerationRequest{}, func(string) {})
+			var upstreamErr *domain.UpstreamError
+			if !errors.As(err, &upstreamErr) || upstreamErr.StatusCode != tt.wantStatus {
+				t.Fatalf("err = %v (%T), want an UpstreamError with status %d", err, err, tt.wantStatus)
+			}
+			if response == nil || response.Text != `{"ti` {
+				t.Errorf("response = %+v, want the text before the error", response)
+			}
+		})
+	}
+}
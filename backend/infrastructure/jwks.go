@@ -0,0 +1,334 @@
+package infrastructure
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+)
+
+// JWKSAlgorithms are the asymmetric signing algorithms accepted from a JWKS.
+var JWKSAlgorithms = []string{"EdDSA", "RS256", "ES256"}
+
+const (
+	// defaultJWKSRefreshInterval is how long fetched keys are trusted before
+	// the key set is fetched again.
+	defaultJWKSRefreshInterval = time.Hour
+	// minJWKSRefreshInterval limits refreshes triggered by unknown key ids, so
+	// tokens with made-up kids cannot be used to hammer the JWKS endpoint.
+	minJWKSRefreshInterval = time.Minute
+)
+
+// JWKS is a cached JSON Web Key Set, e.g. the one Better Auth publishes at
+// /api/auth/jwks. Keys are refreshed periodically and whenever a token names
+// a key id that is not cached yet, so signing key rotation needs no restart.
+// The key set is fetched without holding the lock, so verifications keep
+// using the cached keys meanwhile, and concurrent refreshes share one fetch.
+type JWKS struct {
+	source          string
+	load            func() ([]byte, error)
+	refreshInterval time.Duration
+	refreshes       singleflight.Group
+
+	mu          sync.RWMutex
+	keys        map[string]jwk
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+// jwk is a parsed public key together with the algorithm it may be used with.
+type jwk struct {
+	alg string // empty when the JWK does not pin an algorithm
+	key crypto.PublicKey
+}
+
+// NewRemoteJWKS creates a key set fetched from url. refreshInterval of zero
+// uses a default of one hour.
+func NewRemoteJWKS(url string, refreshInterval time.Duration) *JWKS {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return newJWKS(url, refreshInterval, func() ([]byte, error) {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		var raw json.RawMessage
+		if err := doJSON(client, req, "JWKS endpoint", &raw); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	})
+}
+
+// NewFileJWKS creates a key set read from a local file, which is handy for
+// offline testing. The file is re-read on refresh, so keys can be rotated by
+// replacing it.
+func NewFileJWKS(path string, refreshInterval time.Duration) *JWKS {
+	return newJWKS(path, refreshInterval, func() ([]byte, error) {
+		return os.ReadFile(path)
+	})
+}
+
+func newJWKS(source string, refreshInterval time.Duration, load func() ([]byte, error)) *JWKS {
+	if refreshInterval <= 0 {
+		refreshInterval = defaultJWKSRefreshInterval
+	}
+	return &JWKS{source: source, load: load, refreshInterval: refreshInterval}
+}
+
+// Refresh loads the key set now. It is called lazily by Keyfunc, but calling
+// it at startup surfaces configuration errors early.
+func (k *JWKS) Refresh() error {
+	return k.refresh(true)
+}
+
+// refresh fetches the key set, sharing the fetch with concurrent callers.
+// Unless forced, nothing is fetched within minJWKSRefreshInterval of the
+// previous attempt.
+func (k *JWKS) refresh(force bool) error {
+	_, err, _ := k.refreshes.Do("", func() (interface{}, error) {
+		now := time.Now()
+		k.mu.Lock()
+		if !force && now.Sub(k.attemptedAt) < minJWKSRefreshInterval {
+			k.mu.Unlock()
+			return nil, nil
+		}
+		k.attemptedAt = now
+		k.mu.Unlock()
+
+		raw, err := k.load()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWKS from %s: %w", k.source, err)
+		}
+		keys, err := parseJWKS(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS from %s: %w", k.source, err)
+		}
+
+		k.mu.Lock()
+		k.keys = keys
+		k.fetchedAt = now
+		k.mu.Unlock()
+		return nil, nil
+	})
+	return err
+}
+
+// Keyfunc resolves the verification key of a token from its "kid" header.
+// It can be passed to jwt.Parse.
+func (k *JWKS) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	alg := token.Method.Alg()
+
+	k.mu.RLock()
+	stale := time.Since(k.fetchedAt) > k.refreshInterval
+	_, known := k.keys[kid]
+	k.mu.RUnlock()
+
+	if stale || (kid != "" && !known) {
+		if err := k.refresh(false); err != nil {
+			k.mu.RLock()
+			cached := k.keys != nil
+			k.mu.RUnlock()
+			if !cached {
+				return nil, err
+			}
+			// Keep serving the cached keys while the source is unavailable.
+			log.Printf("Using cached JWKS keys: %v", err)
+		}
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.lookup(kid, alg)
+}
+
+// lookup finds the key for kid; tokens without a kid are accepted when
+// exactly one key fits the algorithm. The caller holds k.mu.
+func (k *JWKS) lookup(kid, alg string) (crypto.PublicKey, error) {
+	if kid != "" {
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if !key.accepts(alg) {
+			return nil, fmt.Errorf("signing key %q cannot be used with %s", kid, alg)
+		}
+		return key.key, nil
+	}
+
+	var match crypto.PublicKey
+	for _, key := range k.keys {
+		if !key.accepts(alg) {
+			continue
+		}
+		if match != nil {
+			return nil, errors.New("token has no key id and the JWKS holds several keys")
+		}
+		match = key.key
+	}
+	if match == nil {
+		return nil, fmt.Errorf("no signing key for %s", alg)
+	}
+	return match, nil
+}
+
+// accepts reports whether the key may verify a signature made with alg.
+func (j jwk) accepts(alg string) bool {
+	if j.alg != "" && j.alg != alg {
+		return false
+	}
+	switch key := j.key.(type) {
+	case ed25519.PublicKey:
+		return alg == "EdDSA"
+	case *rsa.PublicKey:
+		return alg == "RS256" || alg == "RS384" || alg == "RS512"
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return alg == "ES256"
+		case elliptic.P384():
+			return alg == "ES384"
+		case elliptic.P521():
+			return alg == "ES512"
+		}
+	}
+	return false
+}
+
+// --- JWK parsing (RFC 7517 / RFC 8037) ---
+
+type jwkJSON struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// parseJWKS decodes a key set. Keys meant for encryption and key types we
+// cannot verify with are skipped, not rejected.
+func parseJWKS(raw []byte) (map[string]jwk, error) {
+	var set struct {
+		Keys []jwkJSON `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]jwk, len(set.Keys))
+	for i, entry := range set.Keys {
+		if entry.Use != "" && entry.Use != "sig" {
+			continue
+		}
+		key, err := parseJWK(entry)
+		if err != nil {
+			return nil, fmt.Errorf("key %d (%q): %w", i, entry.Kid, err)
+		}
+		if key == nil {
+			continue
+		}
+		keys[entry.Kid] = jwk{alg: entry.Alg, key: key}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no usable signing keys")
+	}
+	return keys, nil
+}
+
+func parseJWK(entry jwkJSON) (crypto.PublicKey, error) {
+	switch entry.Kty {
+	case "OKP":
+		if entry.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := decodeSegment(entry.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key size")
+		}
+		return ed25519.PublicKey(x), nil
+
+	case "RSA":
+		n, err := decodeSegment(entry.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeSegment(entry.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		modulus := new(big.Int).SetBytes(n)
+		if modulus.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		return &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}, nil
+
+	case "EC":
+		var (
+			curve     elliptic.Curve
+			ecdhCurve ecdh.Curve
+		)
+		switch entry.Crv {
+		case "P-256":
+			curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, nil
+		}
+		x, err := decodeSegment(entry.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeSegment(entry.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("invalid %s coordinate size", entry.Crv)
+		}
+		// Reject points that are not on the curve.
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+			return nil, fmt.Errorf("invalid %s public key: %w", entry.Crv, err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, nil
+}
+
+func decodeSegment(value string) ([]byte, error) {
+	if value == "" {
+		return nil, errors.New("missing key parameter")
+	}
+	return base64.RawURLEncoding.DecodeString(value)
+}
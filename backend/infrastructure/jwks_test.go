@@ -0,0 +1,371 @@
+package infrastructure
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testSigner is a private key together with its public JWK.
+type testSigner struct {
+	kid    string
+	method jwt.SigningMethod
+	key    crypto.Signer
+	jwk    map[string]string
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func newEdDSASigner(t *testing.T, kid string) testSigner {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testSigner{kid, jwt.SigningMethodEdDSA, private, map[string]string{
+		"kty": "OKP", "crv": "Ed25519", "kid": kid, "x": b64(public),
+	}}
+}
+
+func newRS256Signer(t *testing.T, kid string, bits int) testSigner {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testSigner{kid, jwt.SigningMethodRS256, private, map[string]string{
+		"kty": "RSA", "kid": kid, "alg": "RS256",
+		"n": b64(private.N.Bytes()), "e": b64(big.NewInt(int64(private.E)).Bytes()),
+	}}
+}
+
+func newES256Signer(t *testing.T, kid string) testSigner {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, y := make([]byte, 32), make([]byte, 32)
+	private.X.FillBytes(x)
+	private.Y.FillBytes(y)
+	return testSigner{kid, jwt.SigningMethodES256, private, map[string]string{
+		"kty": "EC", "crv": "P-256", "kid": kid, "use": "sig", "x": b64(x), "y": b64(y),
+	}}
+}
+
+func (s testSigner) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(s.method, claims)
+	if s.kid != "" {
+		token.Header["kid"] = s.kid
+	}
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"sub": "user-1",
+		"iss": "https://auth.example.com",
+		"aud": "better-form",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+}
+
+func keySet(t *testing.T, signers ...testSigner) []byte {
+	t.Helper()
+	keys := make([]map[string]string, len(signers))
+	for i, signer := range signers {
+		keys[i] = signer.jwk
+	}
+	raw, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// countingJWKS serves whatever set holds and counts the loads.
+type countingJWKS struct {
+	mu    sync.Mutex
+	set   []byte
+	err   error
+	loads atomic.Int32
+}
+
+func (c *countingJWKS) load() ([]byte, error) {
+	c.loads.Add(1)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.set, c.err
+}
+
+func (c *countingJWKS) serve(set []byte, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set, c.err = set, err
+}
+
+var testJWTConfig = JWTConfig{Issuer: "https://auth.example.com", Audience: "better-form"}
+
+func TestJWKSVerifierAlgorithms(t *testing.T) {
+	eddsa := newEdDSASigner(t, "ed")
+	rs256 := newRS256Signer(t, "rsa", 2048)
+	es256 := newES256Signer(t, "ec")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(keySet(t, eddsa, rs256, es256))
+	}))
+	defer server.Close()
+	verifier := NewJWKSVerifier(NewRemoteJWKS(server.URL, 0), testJWTConfig)
+
+	for _, signer := range []testSigner{eddsa, rs256, es256} {
+		t.Run(signer.method.Alg(), func(t *testing.T) {
+			subject, err := verifier.Verify(signer.sign(t, validClaims()))
+			if err != nil {
+				t.Fatalf("Verify failed: %v", err)
+			}
+			if subject != "user-1" {
+				t.Errorf("subject = %q, want user-1", subject)
+			}
+		})
+	}
+}
+
+func TestJWKSVerifierRejects(t *testing.T) {
+	eddsa := newEdDSASigner(t, "ed")
+	es256 := newES256Signer(t, "ec")
+	source := &countingJWKS{set: keySet(t, eddsa, es256)}
+	verifier := NewJWKSVerifier(newJWKS("test", 0, source.load), testJWTConfig)
+
+	// A key the set does not hold, published under a kid it does hold.
+	impostor := newES256Signer(t, "ec")
+	// An ES256 token naming the EdDSA key.
+	confused := es256
+	confused.kid = "ed"
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	otherAudience := validClaims()
+	otherAudience["aud"] = "someone-else"
+	noSubject := validClaims()
+	delete(noSubject, "sub")
+	noExpiry := validClaims()
+	delete(noExpiry, "exp")
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmac, err := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte(eddsa.jwk["x"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"wrong key", impostor.sign(t, validClaims())},
+		{"algorithm of another key", confused.sign(t, validClaims())},
+		{"expired", eddsa.sign(t, expired)},
+		{"other audience", eddsa.sign(t, otherAudience)},
+		{"no subject", eddsa.sign(t, noSubject)},
+		{"no expiry", eddsa.sign(t, noExpiry)},
+		{"alg none", unsigned},
+		{"HS256", hmac},
+		{"tampered", eddsa.sign(t, validClaims()) + "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if subject, err := verifier.Verify(tt.token); err == nil {
+				t.Errorf("Verify = %q, want an error", subject)
+			}
+		})
+	}
+}
+
+func TestJWKSWithoutKid(t *testing.T) {
+	single := newEdDSASigner(t, "")
+	single.jwk["kid"] = "only"
+	source := &countingJWKS{set: keySet(t, single)}
+	verifier := NewJWKSVerifier(newJWKS("test", 0, source.load), testJWTConfig)
+	if _, err := verifier.Verify(single.sign(t, validClaims())); err != nil {
+		t.Errorf("token without kid against a single key: %v", err)
+	}
+
+	second := newEdDSASigner(t, "second")
+	source.serve(keySet(t, single, second), nil)
+	keys := newJWKS("test", 0, source.load)
+	if _, err := NewJWKSVerifier(keys, testJWTConfig).Verify(single.sign(t, validClaims())); err == nil {
+		t.Error("token without kid was accepted against several candidate keys")
+	}
+}
+
+func TestJWKSUnknownKidRefresh(t *testing.T) {
+	first := newEdDSASigner(t, "first")
+	rotated := newES256Signer(t, "rotated")
+	source := &countingJWKS{set: keySet(t, first)}
+	keys := newJWKS("test", 0, source.load)
+	verifier := NewJWKSVerifier(keys, testJWTConfig)
+
+	if _, err := verifier.Verify(first.sign(t, validClaims())); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if loads := source.loads.Load(); loads != 1 {
+		t.Fatalf("loads = %d, want 1", loads)
+	}
+
+	// Unknown kids do not refresh more than once a minute...
+	forged := newEdDSASigner(t, "forged")
+	for i := 0; i < 5; i++ {
+		if _, err := verifier.Verify(forged.sign(t, validClaims())); err == nil {
+			t.Fatal("token signed with an unknown key was accepted")
+		}
+	}
+	if loads := source.loads.Load(); loads != 1 {
+		t.Errorf("loads after unknown kids = %d, want 1", loads)
+	}
+
+	// ...but a rotated key is picked up once the minute is over.
+	source.serve(keySet(t, first, rotated), nil)
+	keys.mu.Lock()
+	keys.attemptedAt = time.Now().Add(-minJWKSRefreshInterval)
+	keys.mu.Unlock()
+	if _, err := verifier.Verify(rotated.sign(t, validClaims())); err != nil {
+		t.Fatalf("rotated key was not picked up: %v", err)
+	}
+	if loads := source.loads.Load(); loads != 2 {
+		t.Errorf("loads after rotation = %d, want 2", loads)
+	}
+}
+
+func TestJWKSConcurrentRefreshesShareOneLoad(t *testing.T) {
+	signer := newEdDSASigner(t, "key")
+	release := make(chan struct{})
+	var loads atomic.Int32
+	keys := newJWKS("test", 0, func() ([]byte, error) {
+		loads.Add(1)
+		<-release
+		return keySet(t, signer), nil
+	})
+	verifier := NewJWKSVerifier(keys, testJWTConfig)
+	token := signer.sign(t, validClaims())
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := verifier.Verify(token)
+			errs <- err
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("loads = %d, want 1", n)
+	}
+	for err := range errs {
+		// Callers that arrive after the shared load started are rate
+		// limited; they may fail, but only because no key is cached yet.
+		if err != nil && !errors.Is(err, jwt.ErrTokenUnverifiable) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if _, err := verifier.Verify(token); err != nil {
+		t.Errorf("Verify after the load: %v", err)
+	}
+}
+
+func TestJWKSKeepsCachedKeysWhenSourceFails(t *testing.T) {
+	signer := newEdDSASigner(t, "key")
+	source := &countingJWKS{set: keySet(t, signer)}
+	keys := newJWKS("test", time.Millisecond, source.load)
+	if err := keys.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	source.serve(nil, errors.New("unavailable"))
+	keys.mu.Lock()
+	keys.attemptedAt = time.Time{}
+	keys.mu.Unlock()
+	time.Sleep(2 * time.Millisecond)
+	if _, err := NewJWKSVerifier(keys, testJWTConfig).Verify(signer.sign(t, validClaims())); err != nil {
+		t.Errorf("cached key was not used while the source failed: %v", err)
+	}
+	if loads := source.loads.Load(); loads != 2 {
+		t.Errorf("loads = %d, want 2", loads)
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	weak := newRS256Signer(t, "weak", 1024)
+	offCurve := newES256Signer(t, "off")
+	offCurve.jwk["y"] = offCurve.jwk["x"]
+	encryption := newEdDSASigner(t, "enc")
+	encryption.jwk["use"] = "enc"
+	unsupported := map[string]string{"kty": "OKP", "crv": "X25519", "kid": "x", "x": b64(make([]byte, 32))}
+
+	tests := []struct {
+		name    string
+		keys    []map[string]string
+		wantErr bool
+		want    int
+	}{
+		{"usable keys", []map[string]string{newEdDSASigner(t, "a").jwk, newES256Signer(t, "b").jwk}, false, 2},
+		{"encryption keys skipped", []map[string]string{encryption.jwk, newEdDSASigner(t, "a").jwk}, false, 1},
+		{"unsupported curve skipped", []map[string]string{unsupported, newEdDSASigner(t, "a").jwk}, false, 1},
+		{"no usable key", []map[string]string{encryption.jwk}, true, 0},
+		{"short RSA key", []map[string]string{weak.jwk}, true, 0},
+		{"point off the curve", []map[string]string{offCurve.jwk}, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := json.Marshal(map[string]interface{}{"keys": tt.keys})
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys, err := parseJWKS(raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseJWKS = %d keys, want an error", len(keys))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseJWKS failed: %v", err)
+			}
+			if len(keys) != tt.want {
+				t.Errorf("parseJWKS = %d keys, want %d", len(keys), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalBlobStore keeps uploaded files in a directory of the local file
+// system, one file per blob under a directory per key prefix.
+type LocalBlobStore struct {
+	dir string
+}
+
+// NewLocalBlobStore creates the directory if needed and returns the store.
+func NewLocalBlobStore(dir string) (*LocalBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	return &LocalBlobStore{dir: dir}, nil
+}
+
+// Put writes content under key. The file is renamed into place so that
+// readers never see a partial blob.
+func (s *LocalBlobStore) Put(key string, content io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+	_, err = io.Copy(tmp, content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+	return nil
+}
+
+// Open returns the content stored under key, or domain.ErrFileNotFound.
+func (s *LocalBlobStore) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, domain.ErrFileNotFound
+	}
+	return file, err
+}
+
+// Delete removes the blob stored under key; missing blobs are ignored.
+func (s *LocalBlobStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path maps a key to its file. Keys are slash-separated hex IDs, which keeps
+// every segment safe to use as a file name.
+func (s *LocalBlobStore) path(key string) (string, error) {
+	segments := strings.Split(key, "/")
+	for _, segment := range segments {
+		if _, err := hex.DecodeString(segment); err != nil || segment == "" {
+			return "", fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return filepath.Join(append([]string{s.dir}, segments...)...), nil
+}
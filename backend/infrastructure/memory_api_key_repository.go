@@ -0,0 +1,71 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"sort"
+	"sync"
+	"time"
+)
+
+// InMemoryAPIKeyRepository stores API keys in process memory. Data is lost on restart.
+type InMemoryAPIKeyRepository struct {
+	mu   sync.RWMutex
+	keys map[string]domain.APIKey
+}
+
+// NewInMemoryAPIKeyRepository creates a new instance of the InMemoryAPIKeyRepository.
+func NewInMemoryAPIKeyRepository() *InMemoryAPIKeyRepository {
+	return &InMemoryAPIKeyRepository{keys: make(map[string]domain.APIKey)}
+}
+
+// Create stores a new key.
+func (r *InMemoryAPIKeyRepository) Create(key *domain.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[key.ID] = *key
+	return nil
+}
+
+// GetByHash returns the key with the given hash.
+func (r *InMemoryAPIKeyRepository) GetByHash(hash string) (*domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, key := range r.keys {
+		if key.Hash == hash {
+			return &key, nil
+		}
+	}
+	return nil, domain.ErrAPIKeyNotFound
+}
+
+// List returns the keys of ownerID, or every key if ownerID is empty, newest first.
+func (r *InMemoryAPIKeyRepository) List(ownerID string) ([]*domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := []*domain.APIKey{}
+	for _, key := range r.keys {
+		if ownerID == "" || key.OwnerID == ownerID {
+			key := key
+			keys = append(keys, &key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// Revoke marks a key as revoked.
+func (r *InMemoryAPIKeyRepository) Revoke(id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.keys[id]
+	if !ok {
+		return domain.ErrAPIKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		key.RevokedAt = &at
+		r.keys[id] = key
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package infrastructure
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// InMemoryFormCache is a least-recently-used cache in process memory. It
+// holds at most maxEntries values, each for ttl.
+type InMemoryFormCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	order      *list.List // front is the most recently used
+	entries    map[string]*list.Element
+}
+
+type formCacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewInMemoryFormCache creates a new instance of the InMemoryFormCache.
+func NewInMemoryFormCache(maxEntries int, ttl time.Duration) *InMemoryFormCache {
+	return &InMemoryFormCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value of key and marks it as recently used.
+func (fc *InMemoryFormCache) Get(key string) ([]byte, bool, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	element, ok := fc.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*formCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		fc.order.Remove(element)
+		delete(fc.entries, key)
+		return nil, false, nil
+	}
+	fc.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+// Set stores value under key and evicts the least recently used entries
+// beyond the size limit.
+func (fc *InMemoryFormCache) Set(key string, value []byte) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	entry := &formCacheEntry{key: key, value: value, expiresAt: time.Now().Add(fc.ttl)}
+	if element, ok := fc.entries[key]; ok {
+		element.Value = entry
+		fc.order.MoveToFront(element)
+	} else {
+		fc.entries[key] = fc.order.PushFront(entry)
+	}
+	for fc.order.Len() > fc.maxEntries {
+		oldest := fc.order.Back()
+		fc.order.Remove(oldest)
+		delete(fc.entries, oldest.Value.(*formCacheEntry).key)
+	}
+	return nil
+}
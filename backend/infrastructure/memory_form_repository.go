@@ -0,0 +1,125 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"sort"
+	"sync"
+)
+
+// InMemoryFormRepository stores forms in process memory. Data is lost on restart.
+type InMemoryFormRepository struct {
+	mu       sync.RWMutex
+	forms    map[string]domain.StoredForm
+	versions map[string][]domain.FormVersion
+}
+
+// NewInMemoryFormRepository creates a new instance of the InMemoryFormRepository.
+func NewInMemoryFormRepository() *InMemoryFormRepository {
+	return &InMemoryFormRepository{
+		forms:    make(map[string]domain.StoredForm),
+		versions: make(map[string][]domain.FormVersion),
+	}
+}
+
+// Create stores a new form with its first version.
+func (r *InMemoryFormRepository) Create(form *domain.StoredForm, version *domain.FormVersion) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.forms[form.ID] = *form
+	r.versions[form.ID] = []domain.FormVersion{*version}
+	return nil
+}
+
+// Get returns a copy of the form with the given id.
+func (r *InMemoryFormRepository) Get(id string) (*domain.StoredForm, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	form, ok := r.forms[id]
+	if !ok {
+		return nil, domain.ErrFormNotFound
+	}
+	return &form, nil
+}
+
+// ListByOwner returns the forms of a user, most recently updated first.
+func (r *InMemoryFormRepository) ListByOwner(ownerID string) ([]*domain.StoredForm, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	forms := []*domain.StoredForm{}
+	for _, form := range r.forms {
+		if form.OwnerID == ownerID {
+			form := form
+			forms = append(forms, &form)
+		}
+	}
+	sort.Slice(forms, func(i, j int) bool {
+		return forms[i].UpdatedAt.After(forms[j].UpdatedAt)
+	})
+	return forms, nil
+}
+
+// Update saves the form's metadata.
+func (r *InMemoryFormRepository) Update(form *domain.StoredForm) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.forms[form.ID]
+	if !ok {
+		return domain.ErrFormNotFound
+	}
+	stored.Name = form.Name
+	stored.UpdatedAt = form.UpdatedAt
+	r.forms[form.ID] = stored
+	return nil
+}
+
+// AddVersion appends a version and makes it the form's current config.
+func (r *InMemoryFormRepository) AddVersion(form *domain.StoredForm, version *domain.FormVersion) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.forms[form.ID]; !ok {
+		return domain.ErrFormNotFound
+	}
+	versions := r.versions[form.ID]
+	if version.Version != len(versions)+1 {
+		return domain.ErrVersionConflict
+	}
+	r.versions[form.ID] = append(versions, *version)
+	r.forms[form.ID] = *form
+	return nil
+}
+
+// GetVersion returns a single version of a form.
+func (r *InMemoryFormRepository) GetVersion(formID string, version int) (*domain.FormVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions := r.versions[formID]
+	if version < 1 || version > len(versions) {
+		return nil, domain.ErrVersionNotFound
+	}
+	found := versions[version-1]
+	return &found, nil
+}
+
+// ListVersions returns every version of a form, oldest first.
+func (r *InMemoryFormRepository) ListVersions(formID string) ([]*domain.FormVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions := make([]*domain.FormVersion, 0, len(r.versions[formID]))
+	for _, version := range r.versions[formID] {
+		version := version
+		versions = append(versions, &version)
+	}
+	return versions, nil
+}
+
+// Delete removes a form and its versions.
+func (r *InMemoryFormRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.forms[id]; !ok {
+		return domain.ErrFormNotFound
+	}
+	delete(r.forms, id)
+	delete(r.versions, id)
+	return nil
+}
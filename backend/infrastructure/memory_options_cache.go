@@ -0,0 +1,71 @@
+package infrastructure
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// InMemoryOptionsCache is a least-recently-used cache of data source
+// responses in process memory. It holds at most maxEntries values, each for
+// the TTL it was stored with.
+type InMemoryOptionsCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // front is the most recently used
+	entries    map[string]*list.Element
+}
+
+type optionsCacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewInMemoryOptionsCache creates a new instance of the InMemoryOptionsCache.
+func NewInMemoryOptionsCache(maxEntries int) *InMemoryOptionsCache {
+	return &InMemoryOptionsCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value of key and marks it as recently used.
+func (oc *InMemoryOptionsCache) Get(key string) ([]byte, bool) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+
+	element, ok := oc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*optionsCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		oc.order.Remove(element)
+		delete(oc.entries, key)
+		return nil, false
+	}
+	oc.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// Set stores value under key for ttl and evicts the least recently used
+// entries beyond the size limit.
+func (oc *InMemoryOptionsCache) Set(key string, value []byte, ttl time.Duration) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+
+	entry := &optionsCacheEntry{key: key, value: value, expiresAt: time.Now().Add(ttl)}
+	if element, ok := oc.entries[key]; ok {
+		element.Value = entry
+		oc.order.MoveToFront(element)
+	} else {
+		oc.entries[key] = oc.order.PushFront(entry)
+	}
+	for oc.order.Len() > oc.maxEntries {
+		oldest := oc.order.Back()
+		oc.order.Remove(oldest)
+		delete(oc.entries, oldest.Value.(*optionsCacheEntry).key)
+	}
+}
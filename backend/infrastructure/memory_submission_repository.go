@@ -0,0 +1,108 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// InMemorySubmissionRepository stores submissions in process memory. Data is lost on restart.
+type InMemorySubmissionRepository struct {
+	mu          sync.RWMutex
+	submissions []domain.Submission
+}
+
+// NewInMemorySubmissionRepository creates a new instance of the InMemorySubmissionRepository.
+func NewInMemorySubmissionRepository() *InMemorySubmissionRepository {
+	return &InMemorySubmissionRepository{}
+}
+
+// Create stores a submission.
+func (r *InMemorySubmissionRepository) Create(submission *domain.Submission) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *submission
+	stored.Files = slices.Clone(submission.Files)
+	r.submissions = append(r.submissions, stored)
+	return nil
+}
+
+// Get returns a submission by ID.
+func (r *InMemorySubmissionRepository) Get(id string) (*domain.Submission, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, submission := range r.submissions {
+		if submission.ID == id {
+			submission.Files = slices.Clone(submission.Files)
+			return &submission, nil
+		}
+	}
+	return nil, domain.ErrSubmissionNotFound
+}
+
+// List returns the submissions matching query, newest first.
+func (r *InMemorySubmissionRepository) List(query domain.SubmissionQuery) ([]*domain.Submission, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	submissions := []*domain.Submission{}
+	for _, submission := range r.submissions {
+		if submission.FormID != query.FormID || !matchesSubmissionQuery(&submission, query) {
+			continue
+		}
+		submission := submission
+		submission.Files = slices.Clone(submission.Files)
+		submissions = append(submissions, &submission)
+	}
+	sort.Slice(submissions, func(i, j int) bool {
+		return submissionBefore(submissions[i], submissions[j])
+	})
+	if query.Limit > 0 && len(submissions) > query.Limit {
+		submissions = submissions[:query.Limit]
+	}
+	return submissions, nil
+}
+
+// submissionBefore reports whether a is listed before b: newest first, then
+// by descending ID.
+func submissionBefore(a, b *domain.Submission) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID > b.ID
+}
+
+func matchesSubmissionQuery(submission *domain.Submission, query domain.SubmissionQuery) bool {
+	if !query.From.IsZero() && submission.CreatedAt.Before(query.From) {
+		return false
+	}
+	if !query.To.IsZero() && !submission.CreatedAt.Before(query.To) {
+		return false
+	}
+	if after := query.After; after != nil &&
+		!submissionBefore(&domain.Submission{CreatedAt: after.CreatedAt, ID: after.ID}, submission) {
+		return false
+	}
+	for name, want := range query.Fields {
+		if !fieldValueMatches(submission.Data[name], want) {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldValueMatches reports whether value equals want or, for arrays,
+// contains it.
+func fieldValueMatches(value, want interface{}) bool {
+	if items, ok := value.([]interface{}); ok {
+		for _, item := range items {
+			if reflect.DeepEqual(item, want) {
+				return true
+			}
+		}
+		return false
+	}
+	return reflect.DeepEqual(value, want)
+}
@@ -0,0 +1,61 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"sort"
+	"sync"
+	"time"
+)
+
+// InMemoryUsageRepository stores usage records in process memory. Data is lost on restart.
+type InMemoryUsageRepository struct {
+	mu      sync.RWMutex
+	records []domain.UsageRecord
+}
+
+// NewInMemoryUsageRepository creates a new instance of the InMemoryUsageRepository.
+func NewInMemoryUsageRepository() *InMemoryUsageRepository {
+	return &InMemoryUsageRepository{}
+}
+
+// Record stores a usage record.
+func (r *InMemoryUsageRepository) Record(record *domain.UsageRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, *record)
+	return nil
+}
+
+// DailyTotals aggregates the records of userID created in [from, to) by UTC day and model.
+func (r *InMemoryUsageRepository) DailyTotals(userID string, from, to time.Time) ([]domain.DailyUsage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type dayModel struct{ date, model string }
+	totals := map[dayModel]*domain.DailyUsage{}
+	for _, record := range r.records {
+		if record.UserID != userID || record.CreatedAt.Before(from) || !record.CreatedAt.Before(to) {
+			continue
+		}
+		key := dayModel{record.CreatedAt.UTC().Format("2006-01-02"), record.Model}
+		total, ok := totals[key]
+		if !ok {
+			total = &domain.DailyUsage{Date: key.date, Model: key.model}
+			totals[key] = total
+		}
+		total.Requests++
+		total.Usage.Add(record.Usage)
+	}
+
+	days := make([]domain.DailyUsage, 0, len(totals))
+	for _, total := range totals {
+		days = append(days, *total)
+	}
+	sort.Slice(days, func(i, j int) bool {
+		if days[i].Date != days[j].Date {
+			return days[i].Date < days[j].Date
+		}
+		return days[i].Model < days[j].Model
+	})
+	return days, nil
+}
@@ -0,0 +1,155 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// InMemoryWebhookRepository stores webhooks and their deliveries in process
+// memory. Data is lost on restart, pending retries included.
+type InMemoryWebhookRepository struct {
+	mu         sync.RWMutex
+	webhooks   map[string]domain.Webhook
+	deliveries map[string]domain.WebhookDelivery
+}
+
+// NewInMemoryWebhookRepository creates a new instance of the InMemoryWebhookRepository.
+func NewInMemoryWebhookRepository() *InMemoryWebhookRepository {
+	return &InMemoryWebhookRepository{
+		webhooks:   make(map[string]domain.Webhook),
+		deliveries: make(map[string]domain.WebhookDelivery),
+	}
+}
+
+// Create stores a new webhook.
+func (r *InMemoryWebhookRepository) Create(webhook *domain.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *webhook
+	stored.Events = slices.Clone(webhook.Events)
+	r.webhooks[webhook.ID] = stored
+	return nil
+}
+
+// Get returns a webhook by ID.
+func (r *InMemoryWebhookRepository) Get(id string) (*domain.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	webhook, ok := r.webhooks[id]
+	if !ok {
+		return nil, domain.ErrWebhookNotFound
+	}
+	return &webhook, nil
+}
+
+// ListByForm returns the webhooks of a form, oldest first.
+func (r *InMemoryWebhookRepository) ListByForm(formID string) ([]*domain.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	webhooks := []*domain.Webhook{}
+	for _, webhook := range r.webhooks {
+		if webhook.FormID == formID {
+			webhook := webhook
+			webhooks = append(webhooks, &webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+	})
+	return webhooks, nil
+}
+
+// Delete removes a webhook and its deliveries.
+func (r *InMemoryWebhookRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.webhooks[id]; !ok {
+		return domain.ErrWebhookNotFound
+	}
+	delete(r.webhooks, id)
+	for deliveryID, delivery := range r.deliveries {
+		if delivery.WebhookID == id {
+			delete(r.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+// CreateDelivery stores a new delivery.
+func (r *InMemoryWebhookRepository) CreateDelivery(delivery *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+// GetDelivery returns a delivery by ID.
+func (r *InMemoryWebhookRepository) GetDelivery(id string) (*domain.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	delivery, ok := r.deliveries[id]
+	if !ok {
+		return nil, domain.ErrDeliveryNotFound
+	}
+	return &delivery, nil
+}
+
+// UpdateDelivery replaces a stored delivery.
+func (r *InMemoryWebhookRepository) UpdateDelivery(delivery *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.deliveries[delivery.ID]; !ok {
+		return domain.ErrDeliveryNotFound
+	}
+	r.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+// ListDeliveries returns the deliveries matching query, newest first.
+func (r *InMemoryWebhookRepository) ListDeliveries(query domain.DeliveryQuery) ([]*domain.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	deliveries := []*domain.WebhookDelivery{}
+	for _, delivery := range r.deliveries {
+		if delivery.FormID != query.FormID ||
+			(query.WebhookID != "" && delivery.WebhookID != query.WebhookID) ||
+			(query.Status != "" && delivery.Status != query.Status) {
+			continue
+		}
+		delivery := delivery
+		deliveries = append(deliveries, &delivery)
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID > deliveries[j].ID
+	})
+	if query.Limit > 0 && len(deliveries) > query.Limit {
+		deliveries = deliveries[:query.Limit]
+	}
+	return deliveries, nil
+}
+
+// DueDeliveries returns up to limit pending deliveries due at now, earliest first.
+func (r *InMemoryWebhookRepository) DueDeliveries(now time.Time, limit int) ([]*domain.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	deliveries := []*domain.WebhookDelivery{}
+	for _, delivery := range r.deliveries {
+		if delivery.Status == domain.DeliveryPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) {
+			delivery := delivery
+			deliveries = append(deliveries, &delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].NextAttemptAt.Before(*deliveries[j].NextAttemptAt)
+	})
+	if limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
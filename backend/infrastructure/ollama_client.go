@@ -0,0 +1,153 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// OllamaClient talks to a local Ollama server through its /api/chat endpoint.
+type OllamaClient struct {
+	httpClient   *http.Client
+	streamClient *http.Client
+	baseURL      string // e.g., "http://localhost:11434"
+	modelName    string // e.g., "llama3.1"
+}
+
+// NewOllamaClient creates a new instance of the OllamaClient.
+func NewOllamaClient(baseURL, modelName string) *OllamaClient {
+	return &OllamaClient{
+		httpClient:   newHTTPClient(),
+		streamClient: newStreamingHTTPClient(),
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		modelName:    modelName,
+	}
+}
+
+// --- Ollama API Request/Response Structures ---
+
+type ollamaRequest struct {
+	Model    string           `json:"model"`
+	Messages []domain.Message `json:"messages"`
+	Stream   bool             `json:"stream"`
+	// Format is either "json" or a JSON Schema object.
+	Format  interface{}    `json:"format,omitempty"`
+	Options *ollamaOptions `json:"options,omitempty"`
+}
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+}
+
+// ollamaResponse is a complete response, or one line of a streamed response.
+type ollamaResponse struct {
+	Model   string `json:"model"`
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	// Error is sent instead of a chunk when generation fails mid-stream.
+	Error string `json:"error"`
+}
+
+// Generate sends a non-streaming chat request and returns the response.
+func (olc *OllamaClient) Generate(ctx context.Context, request domain.GenerationRequest) (*domain.GenerationResponse, error) {
+	req, err := olc.newHTTPRequest(ctx, request, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var result ollamaResponse
+	if err := doJSON(olc.httpClient, req, "Ollama", &result); err != nil {
+		return nil, err
+	}
+
+	response := &domain.GenerationResponse{Model: olc.modelName}
+	olc.accumulate(response, &result, nil)
+	return response, nil
+}
+
+// Stream sends a streaming chat request; Ollama answers with one JSON object
+// per line, each carrying a piece of the message.
+func (olc *OllamaClient) Stream(ctx context.Context, request domain.GenerationRequest, onText func(text string)) (*domain.GenerationResponse, error) {
+	req, err := olc.newHTTPRequest(ctx, request, true)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := openStream(olc.streamClient, req, "Ollama")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	response := &domain.GenerationResponse{Model: olc.modelName}
+	err = readJSONLines(body, func(line []byte) error {
+		var chunk ollamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal Ollama stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return chunkError("Ollama", 0, chunk.Error)
+		}
+		olc.accumulate(response, &chunk, onText)
+		return nil
+	})
+	if err != nil {
+		// The usage reported so far is still accounted.
+		return response, streamError(ctx, "Ollama", err)
+	}
+	return response, nil
+}
+
+// accumulate appends the chunk text; token counts arrive on the final chunk.
+func (olc *OllamaClient) accumulate(response *domain.GenerationResponse, chunk *ollamaResponse, onText func(string)) {
+	if chunk.Model != "" {
+		response.Model = chunk.Model
+	}
+	if text := chunk.Message.Content; text != "" {
+		response.Text += text
+		if onText != nil {
+			onText(text)
+		}
+	}
+	if chunk.Done {
+		response.FinishReason = chunk.DoneReason
+		response.Usage = domain.TokenUsage{
+			PromptTokens:     chunk.PromptEvalCount,
+			CompletionTokens: chunk.EvalCount,
+			TotalTokens:      chunk.PromptEvalCount + chunk.EvalCount,
+		}
+	}
+}
+
+func (olc *OllamaClient) newHTTPRequest(ctx context.Context, request domain.GenerationRequest, stream bool) (*http.Request, error) {
+	reqBody := ollamaRequest{
+		Model:    olc.modelName,
+		Messages: request.Messages,
+		Stream:   stream,
+	}
+	switch {
+	case request.ResponseSchema != nil:
+		reqBody.Format = request.ResponseSchema.JSONSchema()
+	case request.ResponseMimeType == "application/json":
+		reqBody.Format = "json"
+	}
+	if request.Temperature != nil || request.TopP != nil || request.MaxOutputTokens > 0 {
+		reqBody.Options = &ollamaOptions{
+			Temperature: request.Temperature,
+			TopP:        request.TopP,
+			NumPredict:  request.MaxOutputTokens,
+		}
+	}
+
+	return newJSONRequest(ctx, olc.baseURL+"/api/chat", reqBody)
+}
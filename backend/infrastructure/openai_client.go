@@ -0,0 +1,201 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// OpenAIClient talks to any OpenAI-compatible chat-completions endpoint
+// (OpenAI, Azure-style gateways, vLLM, LM Studio, ...).
+type OpenAIClient struct {
+	httpClient   *http.Client
+	streamClient *http.Client
+	baseURL      string // e.g., "https://api.openai.com/v1"
+	apiKey       string
+	modelName    string
+}
+
+// NewOpenAIClient creates a new instance of the OpenAIClient. apiKey may be
+// empty for local servers that do not require authentication.
+func NewOpenAIClient(baseURL, apiKey, modelName string) *OpenAIClient {
+	return &OpenAIClient{
+		httpClient:   newHTTPClient(),
+		streamClient: newStreamingHTTPClient(),
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		apiKey:       apiKey,
+		modelName:    modelName,
+	}
+}
+
+// --- Chat Completions Request/Response Structures ---
+
+type openAIRequest struct {
+	Model          string                `json:"model"`
+	Messages       []domain.Message      `json:"messages"`
+	Temperature    *float64              `json:"temperature,omitempty"`
+	TopP           *float64              `json:"top_p,omitempty"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+}
+
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIJSONSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIResponse covers both the complete response and streamed chunks,
+// which carry a `delta` instead of a `message`.
+type openAIResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      *openAIMessage `json:"message"`
+		Delta        *openAIMessage `json:"delta"`
+		FinishReason string         `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+	// Error is sent instead of a chunk when generation fails mid-stream.
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+type openAIMessage struct {
+	Content string `json:"content"`
+}
+
+// Generate sends a chat-completions request and returns the response.
+func (oc *OpenAIClient) Generate(ctx context.Context, request domain.GenerationRequest) (*domain.GenerationResponse, error) {
+	req, err := oc.newHTTPRequest(ctx, request, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var result openAIResponse
+	if err := doJSON(oc.httpClient, req, "OpenAI-compatible API", &result); err != nil {
+		return nil, err
+	}
+
+	response := &domain.GenerationResponse{Model: oc.modelName}
+	oc.accumulate(response, &result, nil)
+	return response, nil
+}
+
+// Stream sends a chat-completions request with stream=true and passes every
+// content delta to onText as it arrives.
+func (oc *OpenAIClient) Stream(ctx context.Context, request domain.GenerationRequest, onText func(text string)) (*domain.GenerationResponse, error) {
+	req, err := oc.newHTTPRequest(ctx, request, true)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	body, err := openStream(oc.streamClient, req, "OpenAI-compatible API")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	response := &domain.GenerationResponse{Model: oc.modelName}
+	err = readSSE(body, func(data string) error {
+		if data == "[DONE]" {
+			return nil
+		}
+		var chunk openAIResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal OpenAI stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return chunkError("OpenAI-compatible API", 0, chunk.Error.Type+": "+chunk.Error.Message)
+		}
+		oc.accumulate(response, &chunk, onText)
+		return nil
+	})
+	if err != nil {
+		// The usage reported so far is still accounted.
+		return response, streamError(ctx, "OpenAI-compatible API", err)
+	}
+	return response, nil
+}
+
+func (oc *OpenAIClient) accumulate(response *domain.GenerationResponse, chunk *openAIResponse, onText func(string)) {
+	if chunk.Model != "" {
+		response.Model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		response.Usage = domain.TokenUsage{
+			PromptTokens:     chunk.Usage.PromptTokens,
+			CompletionTokens: chunk.Usage.CompletionTokens,
+			TotalTokens:      chunk.Usage.TotalTokens,
+		}
+	}
+	if len(chunk.Choices) == 0 {
+		return
+	}
+	choice := chunk.Choices[0]
+	if choice.FinishReason != "" {
+		response.FinishReason = choice.FinishReason
+	}
+	message := choice.Message
+	if message == nil {
+		message = choice.Delta
+	}
+	if message == nil || message.Content == "" {
+		return
+	}
+	response.Text += message.Content
+	if onText != nil {
+		onText(message.Content)
+	}
+}
+
+func (oc *OpenAIClient) newHTTPRequest(ctx context.Context, request domain.GenerationRequest, stream bool) (*http.Request, error) {
+	reqBody := openAIRequest{
+		Model:       oc.modelName,
+		Messages:    request.Messages,
+		Temperature: request.Temperature,
+		TopP:        request.TopP,
+		MaxTokens:   request.MaxOutputTokens,
+		Stream:      stream,
+	}
+	if stream {
+		reqBody.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+	switch {
+	case request.ResponseSchema != nil:
+		reqBody.ResponseFormat = &openAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &openAIJSONSchema{Name: "response", Schema: request.ResponseSchema.JSONSchema()},
+		}
+	case request.ResponseMimeType == "application/json":
+		reqBody.ResponseFormat = &openAIResponseFormat{Type: "json_object"}
+	}
+
+	req, err := newJSONRequest(ctx, oc.baseURL+"/chat/completions", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if oc.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+oc.apiKey)
+	}
+	return req, nil
+}
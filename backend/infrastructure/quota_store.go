@@ -0,0 +1,137 @@
+package infrastructure
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitDecision is the outcome of taking a request from a token bucket.
+type RateLimitDecision struct {
+	Allowed bool
+	// Remaining is the number of requests left in the bucket.
+	Remaining int
+	// RetryAfter is how long to wait for the next request when not allowed.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+// QuotaStore keeps the counters behind rate limits and token quotas.
+// InMemoryQuotaStore serves a single instance; RedisQuotaStore lets several
+// instances share their limits.
+type QuotaStore interface {
+	// Take removes one request from the token bucket of key. The bucket holds
+	// up to limit requests and refills completely over period.
+	Take(key string, limit int, period time.Duration) (RateLimitDecision, error)
+	// AddUsage adds n to the counter of key and returns the new total. The
+	// counter is dropped ttl after it was first written.
+	AddUsage(key string, n int64, ttl time.Duration) (int64, error)
+	// Usage returns the counter of key, or 0 when it does not exist.
+	Usage(key string) (int64, error)
+}
+
+// InMemoryQuotaStore keeps quota counters in process memory.
+type InMemoryQuotaStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	counters  map[string]*usageCounter
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	// expires is when the bucket is full again and can be forgotten.
+	expires time.Time
+}
+
+type usageCounter struct {
+	value   int64
+	expires time.Time
+}
+
+// quotaSweepInterval is how often expired buckets and counters are dropped.
+const quotaSweepInterval = time.Minute
+
+// NewInMemoryQuotaStore creates a new instance of the InMemoryQuotaStore.
+func NewInMemoryQuotaStore() *InMemoryQuotaStore {
+	return &InMemoryQuotaStore{
+		buckets:  make(map[string]*tokenBucket),
+		counters: make(map[string]*usageCounter),
+	}
+}
+
+// Take removes one request from the token bucket of key.
+func (s *InMemoryQuotaStore) Take(key string, limit int, period time.Duration) (RateLimitDecision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.sweep(now)
+
+	rate := float64(limit) / float64(period) // tokens per nanosecond
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit), updated: now}
+		s.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(float64(limit), bucket.tokens+float64(now.Sub(bucket.updated))*rate)
+	bucket.updated = now
+
+	decision := RateLimitDecision{}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = time.Duration((1 - bucket.tokens) / rate)
+	}
+	decision.Remaining = int(bucket.tokens)
+	decision.ResetAfter = time.Duration((float64(limit) - bucket.tokens) / rate)
+	bucket.expires = now.Add(decision.ResetAfter)
+	return decision, nil
+}
+
+// AddUsage adds n to the counter of key and returns the new total.
+func (s *InMemoryQuotaStore) AddUsage(key string, n int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.sweep(now)
+
+	counter, ok := s.counters[key]
+	if !ok || now.After(counter.expires) {
+		counter = &usageCounter{expires: now.Add(ttl)}
+		s.counters[key] = counter
+	}
+	counter.value += n
+	return counter.value, nil
+}
+
+// Usage returns the counter of key.
+func (s *InMemoryQuotaStore) Usage(key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counter, ok := s.counters[key]
+	if !ok || time.Now().After(counter.expires) {
+		return 0, nil
+	}
+	return counter.value, nil
+}
+
+// sweep drops full buckets and expired counters. Must be called with mu held.
+func (s *InMemoryQuotaStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < quotaSweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, bucket := range s.buckets {
+		if now.After(bucket.expires) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, counter := range s.counters {
+		if now.After(counter.expires) {
+			delete(s.counters, key)
+		}
+	}
+}
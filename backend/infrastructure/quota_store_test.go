@@ -0,0 +1,162 @@
+package infrastructure
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInMemoryQuotaStoreTake(t *testing.T) {
+	store := NewInMemoryQuotaStore()
+
+	for i, wantRemaining := range []int{2, 1, 0} {
+		decision, err := store.Take("rate:a", 3, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !decision.Allowed {
+			t.Fatalf("request %d was refused", i+1)
+		}
+		if decision.Remaining != wantRemaining {
+			t.Errorf("request %d: Remaining = %d, want %d", i+1, decision.Remaining, wantRemaining)
+		}
+		if decision.RetryAfter != 0 {
+			t.Errorf("request %d: RetryAfter = %v, want 0", i+1, decision.RetryAfter)
+		}
+	}
+
+	decision, err := store.Take("rate:a", 3, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Allowed {
+		t.Fatal("request beyond the limit was allowed")
+	}
+	// One request is refilled every 20 seconds.
+	if decision.RetryAfter <= 19*time.Second || decision.RetryAfter > 20*time.Second {
+		t.Errorf("RetryAfter = %v, want just under 20s", decision.RetryAfter)
+	}
+	if decision.ResetAfter <= 59*time.Second || decision.ResetAfter > time.Minute {
+		t.Errorf("ResetAfter = %v, want just under 1m", decision.ResetAfter)
+	}
+
+	// Buckets are per key.
+	if decision, _ := store.Take("rate:b", 3, time.Minute); !decision.Allowed {
+		t.Error("another key was limited too")
+	}
+}
+
+func TestInMemoryQuotaStoreRefill(t *testing.T) {
+	store := NewInMemoryQuotaStore()
+	const period = 200 * time.Millisecond
+
+	for i := 0; i < 2; i++ {
+		if decision, _ := store.Take("rate:a", 2, period); !decision.Allowed {
+			t.Fatalf("request %d was refused", i+1)
+		}
+	}
+	if decision, _ := store.Take("rate:a", 2, period); decision.Allowed {
+		t.Fatal("request beyond the limit was allowed")
+	}
+
+	// Half the period refills one request, but not two.
+	time.Sleep(period/2 + 20*time.Millisecond)
+	if decision, _ := store.Take("rate:a", 2, period); !decision.Allowed {
+		t.Fatal("request was refused after the bucket refilled")
+	}
+	if decision, _ := store.Take("rate:a", 2, period); decision.Allowed {
+		t.Fatal("bucket refilled more than one request")
+	}
+
+	// An idle bucket never holds more than limit.
+	time.Sleep(2 * period)
+	allowed := 0
+	for i := 0; i < 4; i++ {
+		if decision, _ := store.Take("rate:a", 2, period); decision.Allowed {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("allowed %d requests after a long pause, want 2", allowed)
+	}
+}
+
+func TestInMemoryQuotaStoreConcurrentTake(t *testing.T) {
+	store := NewInMemoryQuotaStore()
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			decision, err := store.Take("rate:a", 10, time.Hour)
+			if err == nil && decision.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 10 {
+		t.Errorf("allowed %d concurrent requests, want 10", allowed)
+	}
+}
+
+func TestInMemoryQuotaStoreUsage(t *testing.T) {
+	store := NewInMemoryQuotaStore()
+
+	if used, err := store.Usage("tokens:a"); err != nil || used != 0 {
+		t.Fatalf("Usage of a new key = %d, %v; want 0", used, err)
+	}
+	for _, n := range []int64{100, 250} {
+		if _, err := store.AddUsage("tokens:a", n, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	total, err := store.AddUsage("tokens:a", 50, time.Hour)
+	if err != nil || total != 400 {
+		t.Errorf("AddUsage total = %d, %v; want 400", total, err)
+	}
+	if used, _ := store.Usage("tokens:a"); used != 400 {
+		t.Errorf("Usage = %d, want 400", used)
+	}
+	if used, _ := store.Usage("tokens:b"); used != 0 {
+		t.Errorf("Usage of another key = %d, want 0", used)
+	}
+}
+
+func TestInMemoryQuotaStoreUsageExpires(t *testing.T) {
+	store := NewInMemoryQuotaStore()
+	if _, err := store.AddUsage("tokens:a", 100, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if used, _ := store.Usage("tokens:a"); used != 0 {
+		t.Errorf("Usage after expiry = %d, want 0", used)
+	}
+	// An expired counter starts over instead of adding to the old value.
+	if total, _ := store.AddUsage("tokens:a", 5, time.Hour); total != 5 {
+		t.Errorf("AddUsage after expiry = %d, want 5", total)
+	}
+}
+
+func TestInMemoryQuotaStoreSweep(t *testing.T) {
+	store := NewInMemoryQuotaStore()
+	_, _ = store.Take("rate:a", 1, time.Millisecond)
+	_, _ = store.AddUsage("tokens:a", 1, time.Millisecond)
+	_, _ = store.AddUsage("tokens:b", 1, time.Hour)
+	time.Sleep(5 * time.Millisecond)
+
+	store.mu.Lock()
+	store.lastSweep = time.Time{}
+	store.sweep(time.Now())
+	buckets, counters := len(store.buckets), len(store.counters)
+	store.mu.Unlock()
+	if buckets != 0 || counters != 1 {
+		t.Errorf("after sweep: %d buckets and %d counters, want 0 and 1", buckets, counters)
+	}
+}
@@ -0,0 +1,106 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitConfig sets the limits applied to every user or API key. Zero
+// disables a limit.
+type RateLimitConfig struct {
+	RequestsPerMinute int
+	// DailyTokenBudget caps the model tokens consumed per UTC day.
+	DailyTokenBudget int64
+}
+
+// RateLimitMiddleware enforces requests per minute and daily token budgets
+// per API key, or per user for JWT requests. Token consumption is read after
+// the handler ran from the "tokenUsage" context value (a domain.TokenUsage),
+// which the chat controller fills from the model's usage metadata.
+//
+// Limits fail open: if the store is unavailable, requests are let through.
+func RateLimitMiddleware(store QuotaStore, config RateLimitConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject := rateLimitSubject(c)
+
+		// 1. Requests per minute, as a token bucket refilled over one minute.
+		if config.RequestsPerMinute > 0 {
+			decision, err := store.Take("rate:"+subject, config.RequestsPerMinute, time.Minute)
+			if err != nil {
+				log.Printf("Rate limit check failed: %v", err)
+			} else {
+				c.Header("X-RateLimit-Limit", strconv.Itoa(config.RequestsPerMinute))
+				c.Header("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+				c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.ResetAfter)))
+				if !decision.Allowed {
+					c.Header("Retry-After", strconv.Itoa(ceilSeconds(decision.RetryAfter)))
+					abortWithError(c, fmt.Errorf("%w: too many requests, retry after %ds", domain.ErrRateLimitExceeded, ceilSeconds(decision.RetryAfter)))
+					return
+				}
+			}
+		}
+
+		// 2. Daily token budget. A request is admitted while budget remains;
+		// its actual consumption is only known once the model has answered.
+		now := time.Now().UTC()
+		quotaKey := "tokens:" + subject + ":" + now.Format("2006-01-02")
+		resetAfter := now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+		if config.DailyTokenBudget > 0 {
+			used, err := store.Usage(quotaKey)
+			if err != nil {
+				log.Printf("Token quota check failed: %v", err)
+			} else {
+				remaining := config.DailyTokenBudget - used
+				if remaining < 0 {
+					remaining = 0
+				}
+				c.Header("X-Token-Quota-Limit", strconv.FormatInt(config.DailyTokenBudget, 10))
+				c.Header("X-Token-Quota-Remaining", strconv.FormatInt(remaining, 10))
+				c.Header("X-Token-Quota-Reset", strconv.Itoa(ceilSeconds(resetAfter)))
+				if remaining == 0 {
+					c.Header("Retry-After", strconv.Itoa(ceilSeconds(resetAfter)))
+					abortWithError(c, fmt.Errorf("%w: the daily token budget is used up; it resets at midnight UTC", domain.ErrQuotaExceeded))
+					return
+				}
+			}
+		}
+
+		c.Next()
+
+		// 3. Record what the model actually consumed.
+		value, ok := c.Get("tokenUsage")
+		if !ok {
+			return
+		}
+		usage, ok := value.(domain.TokenUsage)
+		if !ok || usage.TotalTokens <= 0 {
+			return
+		}
+		// Keep the counter a little past midnight so late requests of the day still count.
+		if _, err := store.AddUsage(quotaKey, int64(usage.TotalTokens), resetAfter+time.Hour); err != nil {
+			log.Printf("Failed to record token usage: %v", err)
+		}
+	}
+}
+
+// rateLimitSubject identifies whose limits apply: the API key, the user, or
+// the client address when authentication is disabled.
+func rateLimitSubject(c *gin.Context) string {
+	if keyID := c.GetString("apiKeyID"); keyID != "" {
+		return "key:" + keyID
+	}
+	if userID := c.GetString("userID"); userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
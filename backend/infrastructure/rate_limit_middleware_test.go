@@ -0,0 +1,177 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRateLimitedRouter serves POST /chat behind RateLimitMiddleware. The
+// handler reports usage tokens of consumption and, when fail is set, an
+// error, as the chat controller does for failed generations. Rate limit
+// errors are rendered as 429, standing in for controller.ErrorMiddleware.
+func newRateLimitedRouter(store QuotaStore, config RateLimitConfig, usage int, fail bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-Test-User"); user != "" {
+			c.Set("userID", user)
+		}
+		c.Next()
+		if err := c.Errors.Last(); err != nil {
+			if errors.Is(err, domain.ErrRateLimitExceeded) || errors.Is(err, domain.ErrQuotaExceeded) {
+				c.String(http.StatusTooManyRequests, err.Error())
+				return
+			}
+			c.String(http.StatusBadGateway, err.Error())
+		}
+	})
+	router.Use(RateLimitMiddleware(store, config))
+	router.POST("/chat", func(c *gin.Context) {
+		if usage > 0 {
+			c.Set("tokenUsage", domain.TokenUsage{TotalTokens: usage})
+		}
+		if fail {
+			_ = c.Error(errors.New("model output invalid"))
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func serveChat(router *gin.Engine, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/chat", nil)
+	if user != "" {
+		req.Header.Set("X-Test-User", user)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitMiddlewareRequestsPerMinute(t *testing.T) {
+	router := newRateLimitedRouter(NewInMemoryQuotaStore(), RateLimitConfig{RequestsPerMinute: 2}, 0, false)
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		w := serveChat(router, "alice")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i+1, w.Code)
+		}
+		if got := w.Header().Get("X-RateLimit-Limit"); got != "2" {
+			t.Errorf("X-RateLimit-Limit = %q, want 2", got)
+		}
+		if got := w.Header().Get("X-RateLimit-Remaining"); got != wantRemaining {
+			t.Errorf("request %d: X-RateLimit-Remaining = %q, want %s", i+1, got, wantRemaining)
+		}
+	}
+
+	w := serveChat(router, "alice")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+	if reset, _ := strconv.Atoi(w.Header().Get("X-RateLimit-Reset")); reset != 60 {
+		t.Errorf("X-RateLimit-Reset = %d, want 60", reset)
+	}
+
+	// Limits are per user.
+	if w := serveChat(router, "bob"); w.Code != http.StatusOK {
+		t.Errorf("another user got status %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimitMiddlewareDailyTokenBudget(t *testing.T) {
+	store := NewInMemoryQuotaStore()
+	router := newRateLimitedRouter(store, RateLimitConfig{DailyTokenBudget: 1000}, 600, false)
+
+	w := serveChat(router, "alice")
+	if w.Code != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", w.Code)
+	}
+	if got := w.Header().Get("X-Token-Quota-Remaining"); got != "1000" {
+		t.Errorf("X-Token-Quota-Remaining = %q, want 1000 before any usage", got)
+	}
+	reset, _ := strconv.Atoi(w.Header().Get("X-Token-Quota-Reset"))
+	if reset <= 0 || reset > 24*60*60 {
+		t.Errorf("X-Token-Quota-Reset = %d, want until midnight UTC", reset)
+	}
+
+	// The request that crosses the budget is still served: its consumption
+	// is only known afterwards.
+	w = serveChat(router, "alice")
+	if w.Code != http.StatusOK {
+		t.Fatalf("second request: status %d, want 200", w.Code)
+	}
+	if got := w.Header().Get("X-Token-Quota-Remaining"); got != "400" {
+		t.Errorf("X-Token-Quota-Remaining = %q, want 400", got)
+	}
+
+	w = serveChat(router, "alice")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over budget: status %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("X-Token-Quota-Remaining"); got != "0" {
+		t.Errorf("X-Token-Quota-Remaining = %q, want 0", got)
+	}
+	if got := w.Header().Get("Retry-After"); got == "" {
+		t.Error("Retry-After is missing")
+	}
+
+	quotaKey := "tokens:user:alice:" + time.Now().UTC().Format("2006-01-02")
+	if used, _ := store.Usage(quotaKey); used != 1200 {
+		t.Errorf("usage = %d, want 1200", used)
+	}
+	if w := serveChat(router, "bob"); w.Code != http.StatusOK {
+		t.Errorf("another user got status %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimitMiddlewareCountsFailedRequests(t *testing.T) {
+	store := NewInMemoryQuotaStore()
+	router := newRateLimitedRouter(store, RateLimitConfig{DailyTokenBudget: 1000}, 300, true)
+
+	if w := serveChat(router, "alice"); w.Code != http.StatusBadGateway {
+		t.Fatalf("status %d, want 502", w.Code)
+	}
+	quotaKey := "tokens:user:alice:" + time.Now().UTC().Format("2006-01-02")
+	if used, _ := store.Usage(quotaKey); used != 300 {
+		t.Errorf("usage of a failed request = %d, want 300", used)
+	}
+}
+
+// failingQuotaStore is a QuotaStore whose backend is down.
+type failingQuotaStore struct{}
+
+func (failingQuotaStore) Take(string, int, time.Duration) (RateLimitDecision, error) {
+	return RateLimitDecision{}, errors.New("unavailable")
+}
+
+func (failingQuotaStore) AddUsage(string, int64, time.Duration) (int64, error) {
+	return 0, errors.New("unavailable")
+}
+
+func (failingQuotaStore) Usage(string) (int64, error) {
+	return 0, errors.New("unavailable")
+}
+
+func TestRateLimitMiddlewareFailsOpen(t *testing.T) {
+	router := newRateLimitedRouter(failingQuotaStore{}, RateLimitConfig{RequestsPerMinute: 1, DailyTokenBudget: 1}, 10, false)
+	for i := 0; i < 3; i++ {
+		w := serveChat(router, "alice")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i+1, w.Code)
+		}
+		if got := w.Header().Get("X-RateLimit-Limit"); got != "" {
+			t.Errorf("X-RateLimit-Limit = %q without a decision", got)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisCommander runs a single Redis command. RedisClient implements it; any
+// other client (or a Redis-compatible server such as Valkey or KeyDB) can be
+// plugged into RedisQuotaStore through a small adapter.
+type RedisCommander interface {
+	// Do sends a command and returns the reply: string, int64, nil or
+	// []interface{}. Redis error replies are returned as errors.
+	Do(args ...string) (interface{}, error)
+}
+
+// redisCommandTimeout bounds a single command, including the wait for a
+// pooled connection.
+const redisCommandTimeout = 5 * time.Second
+
+// RedisClient adapts a pooled go-redis client to RedisCommander.
+type RedisClient struct {
+	client *redis.Client
+}
+
+// NewRedisClient creates a client from a URL such as
+// "redis://:password@localhost:6379/0" or "rediss://..." for TLS. Pool
+// options such as pool_size can be given as query parameters. Connections
+// are opened lazily.
+func NewRedisClient(rawURL string) (*RedisClient, error) {
+	options, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	return &RedisClient{client: redis.NewClient(options)}, nil
+}
+
+// Do sends a command and returns its reply. A missing value is returned as
+// nil without an error.
+func (rc *RedisClient) Do(args ...string) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+	defer cancel()
+
+	commandArgs := make([]interface{}, len(args))
+	for i, arg := range args {
+		commandArgs[i] = arg
+	}
+	reply, err := rc.client.Do(ctx, commandArgs...).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return reply, err
+}
+
+// Close releases the connections of the pool.
+func (rc *RedisClient) Close() error {
+	return rc.client.Close()
+}
@@ -0,0 +1,110 @@
+package infrastructure
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// takeTokenScript refills and takes from a token bucket atomically. It
+// returns whether the request is allowed and the tokens left, as a string
+// because Lua numbers are truncated to integers in replies.
+const takeTokenScript = `
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}
+`
+
+// addUsageScript increments a counter and sets its expiry on first write.
+const addUsageScript = `
+local total = redis.call('INCRBY', KEYS[1], ARGV[1])
+if redis.call('PTTL', KEYS[1]) < 0 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return total
+`
+
+// RedisQuotaStore keeps quota counters in Redis so that several instances
+// share the same limits.
+type RedisQuotaStore struct {
+	redis  RedisCommander
+	prefix string
+}
+
+// NewRedisQuotaStore creates a quota store; prefix namespaces its keys.
+func NewRedisQuotaStore(redis RedisCommander, prefix string) *RedisQuotaStore {
+	return &RedisQuotaStore{redis: redis, prefix: prefix}
+}
+
+// Take removes one request from the token bucket of key.
+func (s *RedisQuotaStore) Take(key string, limit int, period time.Duration) (RateLimitDecision, error) {
+	rate := float64(limit) / float64(period.Milliseconds()) // tokens per millisecond
+	reply, err := s.redis.Do("EVAL", takeTokenScript, "1", s.prefix+key,
+		strconv.Itoa(limit),
+		strconv.FormatFloat(rate, 'g', -1, 64),
+		strconv.FormatInt(time.Now().UnixMilli(), 10),
+	)
+	if err != nil {
+		return RateLimitDecision{}, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return RateLimitDecision{}, fmt.Errorf("unexpected reply from rate limit script: %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	tokensText, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(tokensText, 64)
+	if err != nil {
+		return RateLimitDecision{}, fmt.Errorf("unexpected reply from rate limit script: %v", reply)
+	}
+
+	perToken := float64(time.Millisecond) / rate
+	decision := RateLimitDecision{
+		Allowed:    allowed == 1,
+		Remaining:  int(tokens),
+		ResetAfter: time.Duration((float64(limit) - tokens) * perToken),
+	}
+	if !decision.Allowed {
+		decision.RetryAfter = time.Duration((1 - tokens) * perToken)
+	}
+	return decision, nil
+}
+
+// AddUsage adds n to the counter of key and returns the new total.
+func (s *RedisQuotaStore) AddUsage(key string, n int64, ttl time.Duration) (int64, error) {
+	reply, err := s.redis.Do("EVAL", addUsageScript, "1", s.prefix+key,
+		strconv.FormatInt(n, 10), strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return 0, err
+	}
+	total, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply from usage script: %v", reply)
+	}
+	return total, nil
+}
+
+// Usage returns the counter of key.
+func (s *RedisQuotaStore) Usage(key string) (int64, error) {
+	reply, err := s.redis.Do("GET", s.prefix+key)
+	if err != nil || reply == nil {
+		return 0, err
+	}
+	text, ok := reply.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply for usage counter: %v", reply)
+	}
+	return strconv.ParseInt(text, 10, 64)
+}
@@ -0,0 +1,166 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how transient model provider failures are retried.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt; 0 disables retries.
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles with every retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff. A Retry-After longer than MaxDelay is not
+	// waited for; the error is returned so the caller can retry later.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy retries three times, waiting up to 0.5s, 1s and 2s.
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 10 * time.Second}
+
+// backoff returns the wait before retry number attempt (starting at 0), with
+// full jitter so that clients failing together do not retry together.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.MaxDelay
+	if attempt < 30 {
+		if exp := p.BaseDelay << attempt; exp > 0 && exp < ceiling {
+			ceiling = exp
+		}
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling + 1)
+}
+
+// isRetryable reports whether err is a transient provider failure.
+func isRetryable(err error) bool {
+	return errors.Is(err, domain.ErrRateLimited) || errors.Is(err, domain.ErrUpstreamUnavailable)
+}
+
+// retryAfter returns the wait requested by the provider, if any.
+func retryAfter(err error) time.Duration {
+	var upstreamErr *domain.UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr.RetryAfter
+	}
+	return 0
+}
+
+// withRetry runs call until it succeeds, fails permanently, the retries are
+// used up or ctx is done. breaker may be nil. provider names the upstream in
+// log messages.
+func withRetry(ctx context.Context, policy RetryPolicy, breaker *CircuitBreaker, provider string, call func() error) error {
+	for attempt := 0; ; attempt++ {
+		if breaker != nil {
+			if err := breaker.Allow(); err != nil {
+				return err
+			}
+		}
+		err := call()
+		if breaker != nil {
+			breaker.Record(err)
+		}
+		if err == nil || !isRetryable(err) || attempt >= policy.MaxRetries {
+			return err
+		}
+
+		wait := policy.backoff(attempt)
+		if requested := retryAfter(err); requested > 0 {
+			if requested > policy.MaxDelay {
+				return err
+			}
+			wait = requested
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			// The retry could not finish in time anyway.
+			return err
+		}
+		log.Printf("%s call failed (attempt %d of %d), retrying in %s: %v", provider, attempt+1, policy.MaxRetries+1, wait.Round(time.Millisecond), err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// CircuitBreaker stops calling a provider that keeps failing. After threshold
+// consecutive failures it opens and rejects calls for cooldown; then a single
+// trial call is let through, which closes it on success or reopens it.
+// Rate limiting does not count as a failure: the provider is up.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	open     bool
+	probing  bool
+}
+
+// NewCircuitBreaker creates a closed circuit breaker.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow returns an error wrapping domain.ErrUpstreamUnavailable while the
+// breaker is open.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return nil
+	}
+	remaining := b.cooldown - time.Since(b.openedAt)
+	if remaining <= 0 && !b.probing {
+		b.probing = true
+		return nil
+	}
+	if remaining < time.Second {
+		remaining = time.Second
+	}
+	return &domain.UpstreamError{
+		Err:        domain.ErrUpstreamUnavailable,
+		RetryAfter: remaining,
+		Message:    fmt.Sprintf("model provider circuit breaker is open after %d consecutive failures", b.failures),
+	}
+}
+
+// Record updates the breaker with the outcome of a call. Calls aborted by
+// their context say nothing about the provider and are ignored.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasProbing := b.probing
+	b.probing = false
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	if !errors.Is(err, domain.ErrUpstreamUnavailable) {
+		if b.open {
+			log.Println("Model provider recovered; circuit breaker closed")
+		}
+		b.failures = 0
+		b.open = false
+		return
+	}
+	b.failures++
+	if wasProbing || (!b.open && b.failures >= b.threshold) {
+		if !b.open {
+			log.Printf("Model provider failed %d times in a row; circuit breaker open for %s", b.failures, b.cooldown)
+		}
+		b.open = true
+		b.openedAt = time.Now()
+	}
+}
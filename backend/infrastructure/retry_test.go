@@ -0,0 +1,273 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	errUnavailable = &domain.UpstreamError{Err: domain.ErrUpstreamUnavailable, StatusCode: 503, Message: "unavailable"}
+	errRateLimited = &domain.UpstreamError{Err: domain.ErrRateLimited, StatusCode: 429, Message: "rate limited"}
+	errBadRequest  = &domain.UpstreamError{StatusCode: 400, Message: "bad request"}
+)
+
+var fastRetries = RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+// failingCall fails with the given errors in turn, then succeeds.
+func failingCall(calls *int, errs ...error) func() error {
+	return func() error {
+		*calls++
+		if *calls <= len(errs) {
+			return errs[*calls-1]
+		}
+		return nil
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{"success", nil, 1, nil},
+		{"transient failures", []error{errUnavailable, errRateLimited}, 3, nil},
+		{"permanent failure", []error{errBadRequest, errUnavailable}, 1, errBadRequest},
+		{"retries used up", []error{errUnavailable, errUnavailable, errUnavailable, errUnavailable, errUnavailable}, 4, errUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := withRetry(context.Background(), fastRetries, nil, "test", failingCall(&calls, tt.errs...))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("withRetry = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestWithRetryDisabled(t *testing.T) {
+	calls := 0
+	err := withRetry(context.Background(), RetryPolicy{}, nil, "test", failingCall(&calls, errUnavailable))
+	if err != errUnavailable || calls != 1 {
+		t.Errorf("withRetry = %v after %d calls, want the first error after 1 call", err, calls)
+	}
+}
+
+func TestWithRetryHonoursRetryAfter(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+
+	calls := 0
+	wait := &domain.UpstreamError{Err: domain.ErrRateLimited, RetryAfter: 50 * time.Millisecond}
+	start := time.Now()
+	if err := withRetry(context.Background(), policy, nil, "test", failingCall(&calls, wait)); err != nil {
+		t.Fatalf("withRetry = %v, want success", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("retried after %v, want at least the requested 50ms", elapsed)
+	}
+
+	// A wait longer than MaxDelay is left to the caller.
+	calls = 0
+	tooLong := &domain.UpstreamError{Err: domain.ErrRateLimited, RetryAfter: time.Minute}
+	if err := withRetry(context.Background(), policy, nil, "test", failingCall(&calls, tooLong)); err != tooLong || calls != 1 {
+		t.Errorf("withRetry = %v after %d calls, want the Retry-After error after 1 call", err, calls)
+	}
+}
+
+func TestWithRetryStopsAtContextDeadline(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: time.Second}
+	wait := &domain.UpstreamError{Err: domain.ErrUpstreamUnavailable, RetryAfter: time.Second}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	calls := 0
+	start := time.Now()
+	err := withRetry(ctx, policy, nil, "test", failingCall(&calls, wait, wait))
+	if err != wait || calls != 1 {
+		t.Errorf("withRetry = %v after %d calls, want the first error after 1 call", err, calls)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("withRetry waited %v for a retry that could not finish in time", elapsed)
+	}
+
+	// A canceled context interrupts the wait.
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	calls = 0
+	start = time.Now()
+	if err := withRetry(ctx, policy, nil, "test", failingCall(&calls, wait, wait)); err != wait {
+		t.Errorf("withRetry = %v, want the last error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("withRetry kept waiting %v after the context was canceled", elapsed)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, ceiling := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		for i := 0; i < 100; i++ {
+			if wait := policy.backoff(attempt); wait < 0 || wait > ceiling {
+				t.Fatalf("backoff(%d) = %v, want within [0, %v]", attempt, wait, ceiling)
+			}
+		}
+	}
+	if wait := policy.backoff(100); wait < 0 || wait > time.Second {
+		t.Errorf("backoff(100) = %v, want capped at MaxDelay", wait)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	breaker := NewCircuitBreaker(3, 50*time.Millisecond)
+
+	// Rate limiting and client errors do not count: the provider is up.
+	for _, err := range []error{errUnavailable, errUnavailable, errRateLimited, errUnavailable, errUnavailable, errBadRequest} {
+		breaker.Record(err)
+	}
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("breaker opened without %d consecutive failures: %v", 3, err)
+	}
+
+	// Canceled calls say nothing about the provider either.
+	breaker.Record(errUnavailable)
+	breaker.Record(errUnavailable)
+	breaker.Record(context.Canceled)
+	breaker.Record(errUnavailable)
+	err := breaker.Allow()
+	if !errors.Is(err, domain.ErrUpstreamUnavailable) {
+		t.Fatalf("Allow = %v after 3 failures, want ErrUpstreamUnavailable", err)
+	}
+	var upstreamErr *domain.UpstreamError
+	if !errors.As(err, &upstreamErr) || upstreamErr.RetryAfter < time.Second {
+		t.Errorf("Allow = %#v, want a Retry-After of at least a second", err)
+	}
+
+	// After the cooldown a single trial call is let through.
+	time.Sleep(60 * time.Millisecond)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("trial call refused after the cooldown: %v", err)
+	}
+	if err := breaker.Allow(); err == nil {
+		t.Fatal("a second call was let through during the trial")
+	}
+
+	// A failed trial reopens the breaker for another cooldown.
+	breaker.Record(errUnavailable)
+	if err := breaker.Allow(); err == nil {
+		t.Fatal("breaker closed after a failed trial")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("trial call refused after the second cooldown: %v", err)
+	}
+
+	// A successful trial closes it.
+	breaker.Record(nil)
+	for i := 0; i < 3; i++ {
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("breaker still open after a successful trial: %v", err)
+		}
+	}
+}
+
+func TestWithRetryStopsAtOpenBreaker(t *testing.T) {
+	breaker := NewCircuitBreaker(2, time.Minute)
+	calls := 0
+	err := withRetry(context.Background(), fastRetries, breaker, "test", failingCall(&calls, errUnavailable, errUnavailable, errUnavailable))
+	if !errors.Is(err, domain.ErrUpstreamUnavailable) {
+		t.Errorf("withRetry = %v, want ErrUpstreamUnavailable", err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2: the breaker opens after the second failure", calls)
+	}
+
+	calls = 0
+	if err := withRetry(context.Background(), fastRetries, breaker, "test", failingCall(&calls)); err == nil || calls != 0 {
+		t.Errorf("withRetry = %v after %d calls, want the breaker to refuse without calling", err, calls)
+	}
+}
+
+func TestWithRetryAgainstProvider(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer server.Close()
+
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Second}
+	var result struct{ OK bool }
+	start := time.Now()
+	err := withRetry(context.Background(), policy, nil, "test", func() error {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			return err
+		}
+		return doJSON(server.Client(), req, "test", &result)
+	})
+	if err != nil || !result.OK {
+		t.Fatalf("withRetry = %v, %+v; want success", err, result)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("requests = %d, want 3", n)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want the 1s Retry-After to be honoured", elapsed)
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		status     int
+		retryAfter string
+		want       error
+		wantWait   time.Duration
+	}{
+		{http.StatusTooManyRequests, "7", domain.ErrRateLimited, 7 * time.Second},
+		{http.StatusServiceUnavailable, "", domain.ErrUpstreamUnavailable, 0},
+		{http.StatusBadGateway, "soon", domain.ErrUpstreamUnavailable, 0},
+		{http.StatusRequestTimeout, "", domain.ErrUpstreamUnavailable, 0},
+		{http.StatusBadRequest, "", nil, 0},
+		{http.StatusUnauthorized, "", nil, 0},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Status: http.StatusText(tt.status), Header: http.Header{}}
+		if tt.retryAfter != "" {
+			resp.Header.Set("Retry-After", tt.retryAfter)
+		}
+		err := statusError("test", resp, nil)
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("status %d: %v, want %v", tt.status, err, tt.want)
+		}
+		if tt.want == nil && isRetryable(err) {
+			t.Errorf("status %d is retried", tt.status)
+		}
+		if got := retryAfter(err); got != tt.wantWait {
+			t.Errorf("status %d: RetryAfter = %v, want %v", tt.status, got, tt.wantWait)
+		}
+	}
+
+	// Retry-After may also be an HTTP date.
+	at := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	if wait := parseRetryAfter(at); wait < 28*time.Second || wait > 30*time.Second {
+		t.Errorf("parseRetryAfter(%q) = %v, want about 30s", at, wait)
+	}
+}
@@ -0,0 +1,122 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SQLiteAPIKeyRepository stores API keys in a SQLite database.
+type SQLiteAPIKeyRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteAPIKeyRepository creates the api_keys table if needed and returns the repository.
+func NewSQLiteAPIKeyRepository(db *sql.DB) (*SQLiteAPIKeyRepository, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id         TEXT PRIMARY KEY,
+			owner_id   TEXT NOT NULL,
+			name       TEXT NOT NULL,
+			prefix     TEXT NOT NULL,
+			hash       TEXT NOT NULL UNIQUE,
+			scopes     TEXT NOT NULL,
+			expires_at TEXT,
+			created_at TEXT NOT NULL,
+			revoked_at TEXT
+		);
+		CREATE INDEX IF NOT EXISTS api_keys_owner ON api_keys (owner_id);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api_keys table: %w", err)
+	}
+	return &SQLiteAPIKeyRepository{db: db}, nil
+}
+
+// Create stores a new key.
+func (r *SQLiteAPIKeyRepository) Create(key *domain.APIKey) error {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(
+		`INSERT INTO api_keys (id, owner_id, name, prefix, hash, scopes, expires_at, created_at, revoked_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		key.ID, key.OwnerID, key.Name, key.Prefix, key.Hash, string(scopes),
+		nullableTime(key.ExpiresAt), formatTime(key.CreatedAt), nullableTime(key.RevokedAt),
+	)
+	return err
+}
+
+// GetByHash returns the key with the given hash.
+func (r *SQLiteAPIKeyRepository) GetByHash(hash string) (*domain.APIKey, error) {
+	row := r.db.QueryRow(
+		`SELECT id, owner_id, name, prefix, hash, scopes, expires_at, created_at, revoked_at FROM api_keys WHERE hash = ?`, hash,
+	)
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+// List returns the keys of ownerID, or every key if ownerID is empty, newest first.
+func (r *SQLiteAPIKeyRepository) List(ownerID string) ([]*domain.APIKey, error) {
+	rows, err := r.db.Query(
+		`SELECT id, owner_id, name, prefix, hash, scopes, expires_at, created_at, revoked_at FROM api_keys
+		 WHERE ? = '' OR owner_id = ? ORDER BY created_at DESC`,
+		ownerID, ownerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*domain.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke marks a key as revoked; revoking twice keeps the first timestamp.
+func (r *SQLiteAPIKeyRepository) Revoke(id string, at time.Time) error {
+	result, err := r.db.Exec(
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?`, formatTime(at), id,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, domain.ErrAPIKeyNotFound)
+}
+
+func scanAPIKey(row rowScanner) (*domain.APIKey, error) {
+	var (
+		key                  domain.APIKey
+		scopes, createdAt    string
+		expiresAt, revokedAt sql.NullString
+	)
+	if err := row.Scan(&key.ID, &key.OwnerID, &key.Name, &key.Prefix, &key.Hash, &scopes, &expiresAt, &createdAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, fmt.Errorf("failed to decode API key scopes: %w", err)
+	}
+	var err error
+	if key.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if key.ExpiresAt, err = parseNullableTime(expiresAt); err != nil {
+		return nil, err
+	}
+	if key.RevokedAt, err = parseNullableTime(revokedAt); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
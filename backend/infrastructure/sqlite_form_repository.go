@@ -0,0 +1,247 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SQLiteFormRepository stores forms in a SQLite database.
+type SQLiteFormRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteFormRepository creates the forms tables if needed and returns the repository.
+func NewSQLiteFormRepository(db *sql.DB) (*SQLiteFormRepository, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS forms (
+			id         TEXT PRIMARY KEY,
+			owner_id   TEXT NOT NULL,
+			name       TEXT NOT NULL,
+			version    INTEGER NOT NULL,
+			config     TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS forms_owner_updated ON forms (owner_id, updated_at);
+		CREATE TABLE IF NOT EXISTS form_versions (
+			form_id        TEXT NOT NULL REFERENCES forms (id) ON DELETE CASCADE,
+			version        INTEGER NOT NULL,
+			source         TEXT NOT NULL,
+			note           TEXT NOT NULL,
+			config         TEXT NOT NULL,
+			prompt_version TEXT NOT NULL DEFAULT '',
+			created_by     TEXT NOT NULL,
+			created_at     TEXT NOT NULL,
+			PRIMARY KEY (form_id, version)
+		);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create forms table: %w", err)
+	}
+	return &SQLiteFormRepository{db: db}, nil
+}
+
+// Create stores a new form with its first version.
+func (r *SQLiteFormRepository) Create(form *domain.StoredForm, version *domain.FormVersion) error {
+	config, err := json.Marshal(form.Config)
+	if err != nil {
+		return fmt.Errorf("failed to encode form config: %w", err)
+	}
+	return withTx(r.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			`INSERT INTO forms (id, owner_id, name, version, config, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			form.ID, form.OwnerID, form.Name, form.Version, string(config), formatTime(form.CreatedAt), formatTime(form.UpdatedAt),
+		)
+		if err != nil {
+			return err
+		}
+		return insertVersion(tx, version)
+	})
+}
+
+// Get returns the form with the given id.
+func (r *SQLiteFormRepository) Get(id string) (*domain.StoredForm, error) {
+	row := r.db.QueryRow(
+		`SELECT id, owner_id, name, version, config, created_at, updated_at FROM forms WHERE id = ?`, id,
+	)
+	form, err := scanForm(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrFormNotFound
+	}
+	return form, err
+}
+
+// ListByOwner returns the forms of a user, most recently updated first.
+func (r *SQLiteFormRepository) ListByOwner(ownerID string) ([]*domain.StoredForm, error) {
+	rows, err := r.db.Query(
+		`SELECT id, owner_id, name, version, config, created_at, updated_at FROM forms WHERE owner_id = ? ORDER BY updated_at DESC`,
+		ownerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	forms := []*domain.StoredForm{}
+	for rows.Next() {
+		form, err := scanForm(rows)
+		if err != nil {
+			return nil, err
+		}
+		forms = append(forms, form)
+	}
+	return forms, rows.Err()
+}
+
+// Update saves the form's metadata.
+func (r *SQLiteFormRepository) Update(form *domain.StoredForm) error {
+	result, err := r.db.Exec(
+		`UPDATE forms SET name = ?, updated_at = ? WHERE id = ?`,
+		form.Name, formatTime(form.UpdatedAt), form.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, domain.ErrFormNotFound)
+}
+
+// AddVersion stores a version and makes it the form's current config. The
+// form row is only updated while it still holds the preceding version, so two
+// concurrent saves cannot both create the same version.
+func (r *SQLiteFormRepository) AddVersion(form *domain.StoredForm, version *domain.FormVersion) error {
+	config, err := json.Marshal(form.Config)
+	if err != nil {
+		return fmt.Errorf("failed to encode form config: %w", err)
+	}
+	return withTx(r.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec(
+			`UPDATE forms SET name = ?, version = ?, config = ?, updated_at = ? WHERE id = ? AND version = ?`,
+			form.Name, version.Version, string(config), formatTime(form.UpdatedAt), form.ID, version.Version-1,
+		)
+		if err != nil {
+			return err
+		}
+		if err := requireAffected(result, domain.ErrVersionConflict); err != nil {
+			return err
+		}
+		return insertVersion(tx, version)
+	})
+}
+
+// GetVersion returns a single version of a form.
+func (r *SQLiteFormRepository) GetVersion(formID string, version int) (*domain.FormVersion, error) {
+	row := r.db.QueryRow(
+		`SELECT form_id, version, source, note, config, prompt_version, created_by, created_at FROM form_versions WHERE form_id = ? AND version = ?`,
+		formID, version,
+	)
+	found, err := scanVersion(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrVersionNotFound
+	}
+	return found, err
+}
+
+// ListVersions returns every version of a form, oldest first.
+func (r *SQLiteFormRepository) ListVersions(formID string) ([]*domain.FormVersion, error) {
+	rows, err := r.db.Query(
+		`SELECT form_id, version, source, note, config, prompt_version, created_by, created_at FROM form_versions WHERE form_id = ? ORDER BY version`,
+		formID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []*domain.FormVersion{}
+	for rows.Next() {
+		version, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// Delete removes a form; its versions are removed by the foreign key cascade.
+func (r *SQLiteFormRepository) Delete(id string) error {
+	result, err := r.db.Exec(`DELETE FROM forms WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, domain.ErrFormNotFound)
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanForm(row rowScanner) (*domain.StoredForm, error) {
+	var (
+		form                 domain.StoredForm
+		config               string
+		createdAt, updatedAt string
+	)
+	if err := row.Scan(&form.ID, &form.OwnerID, &form.Name, &form.Version, &config, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(config), &form.Config); err != nil {
+		return nil, fmt.Errorf("failed to decode form config: %w", err)
+	}
+	var err error
+	if form.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if form.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return nil, err
+	}
+	return &form, nil
+}
+
+func insertVersion(tx *sql.Tx, version *domain.FormVersion) error {
+	config, err := json.Marshal(version.Config)
+	if err != nil {
+		return fmt.Errorf("failed to encode form config: %w", err)
+	}
+	_, err = tx.Exec(
+		`INSERT INTO form_versions (form_id, version, source, note, config, prompt_version, created_by, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		version.FormID, version.Version, string(version.Source), version.Note, string(config), version.PromptVersion, version.CreatedBy, formatTime(version.CreatedAt),
+	)
+	return err
+}
+
+func scanVersion(row rowScanner) (*domain.FormVersion, error) {
+	var (
+		version        domain.FormVersion
+		source, config string
+		createdAt      string
+	)
+	if err := row.Scan(&version.FormID, &version.Version, &source, &version.Note, &config, &version.PromptVersion, &version.CreatedBy, &createdAt); err != nil {
+		return nil, err
+	}
+	version.Source = domain.VersionSource(source)
+	if err := json.Unmarshal([]byte(config), &version.Config); err != nil {
+		return nil, fmt.Errorf("failed to decode form config: %w", err)
+	}
+	var err error
+	if version.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// requireAffected returns notFound when a write touched no rows.
+func requireAffected(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SQLiteSubmissionRepository stores form submissions in a SQLite database.
+type SQLiteSubmissionRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteSubmissionRepository creates the submissions table if needed and
+// returns the repository. It must be created after the forms table.
+func NewSQLiteSubmissionRepository(db *sql.DB) (*SQLiteSubmissionRepository, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS submissions (
+			id           TEXT PRIMARY KEY,
+			form_id      TEXT NOT NULL REFERENCES forms (id) ON DELETE CASCADE,
+			form_version INTEGER NOT NULL,
+			data         TEXT NOT NULL,
+			files        TEXT NOT NULL DEFAULT '[]',
+			created_at   TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS submissions_form_created ON submissions (form_id, created_at);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create submissions table: %w", err)
+	}
+	return &SQLiteSubmissionRepository{db: db}, nil
+}
+
+// Create stores a submission.
+func (r *SQLiteSubmissionRepository) Create(submission *domain.Submission) error {
+	data, err := json.Marshal(submission.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode submission data: %w", err)
+	}
+	files, err := json.Marshal(submission.Files)
+	if err != nil {
+		return fmt.Errorf("failed to encode submission files: %w", err)
+	}
+	_, err = r.db.Exec(
+		`INSERT INTO submissions (id, form_id, form_version, data, files, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		submission.ID, submission.FormID, submission.FormVersion, string(data), string(files), formatTime(submission.CreatedAt),
+	)
+	return err
+}
+
+// Get returns a submission by ID.
+func (r *SQLiteSubmissionRepository) Get(id string) (*domain.Submission, error) {
+	row := r.db.QueryRow(`SELECT id, form_id, form_version, data, files, created_at FROM submissions WHERE id = ?`, id)
+	submission, err := scanSubmission(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrSubmissionNotFound
+	}
+	return submission, err
+}
+
+// List returns the submissions matching query, newest first.
+func (r *SQLiteSubmissionRepository) List(query domain.SubmissionQuery) ([]*domain.Submission, error) {
+	conditions := []string{"form_id = ?"}
+	args := []interface{}{query.FormID}
+	if !query.From.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, formatTime(query.From))
+	}
+	if !query.To.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, formatTime(query.To))
+	}
+	if after := query.After; after != nil {
+		createdAt := formatTime(after.CreatedAt)
+		conditions = append(conditions, "(created_at < ? OR (created_at = ? AND id < ?))")
+		args = append(args, createdAt, createdAt, after.ID)
+	}
+	names := make([]string, 0, len(query.Fields))
+	for name := range query.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// json_each yields a scalar itself and the items of an array, so the
+		// value is matched either way.
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM json_each(submissions.data, ?) WHERE json_each.value = ?)")
+		args = append(args, "$."+name, query.Fields[name])
+	}
+
+	statement := `SELECT id, form_id, form_version, data, files, created_at FROM submissions WHERE ` +
+		strings.Join(conditions, " AND ") + ` ORDER BY created_at DESC, id DESC`
+	if query.Limit > 0 {
+		statement += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+	rows, err := r.db.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	submissions := []*domain.Submission{}
+	for rows.Next() {
+		submission, err := scanSubmission(rows)
+		if err != nil {
+			return nil, err
+		}
+		submissions = append(submissions, submission)
+	}
+	return submissions, rows.Err()
+}
+
+func scanSubmission(row rowScanner) (*domain.Submission, error) {
+	var (
+		submission             domain.Submission
+		data, files, createdAt string
+	)
+	if err := row.Scan(&submission.ID, &submission.FormID, &submission.FormVersion, &data, &files, &createdAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(data), &submission.Data); err != nil {
+		return nil, fmt.Errorf("failed to decode submission data: %w", err)
+	}
+	if err := json.Unmarshal([]byte(files), &submission.Files); err != nil {
+		return nil, fmt.Errorf("failed to decode submission files: %w", err)
+	}
+	var err error
+	if submission.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
@@ -0,0 +1,78 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLiteUsageRepository stores usage records in a SQLite database.
+type SQLiteUsageRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteUsageRepository creates the usage_records table if needed and returns the repository.
+func NewSQLiteUsageRepository(db *sql.DB) (*SQLiteUsageRepository, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS usage_records (
+			id                TEXT PRIMARY KEY,
+			user_id           TEXT NOT NULL,
+			model             TEXT NOT NULL,
+			calls             INTEGER NOT NULL,
+			prompt_tokens     INTEGER NOT NULL,
+			completion_tokens INTEGER NOT NULL,
+			thoughts_tokens   INTEGER NOT NULL,
+			total_tokens      INTEGER NOT NULL,
+			finish_reason     TEXT NOT NULL,
+			outcome           TEXT NOT NULL,
+			created_at        TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS usage_records_user_created ON usage_records (user_id, created_at);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create usage_records table: %w", err)
+	}
+	return &SQLiteUsageRepository{db: db}, nil
+}
+
+// Record stores a usage record.
+func (r *SQLiteUsageRepository) Record(record *domain.UsageRecord) error {
+	_, err := r.db.Exec(
+		`INSERT INTO usage_records (id, user_id, model, calls, prompt_tokens, completion_tokens, thoughts_tokens, total_tokens, finish_reason, outcome, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.ID, record.UserID, record.Model, record.Calls,
+		record.Usage.PromptTokens, record.Usage.CompletionTokens, record.Usage.ThoughtsTokens, record.Usage.TotalTokens,
+		record.FinishReason, record.Outcome, formatTime(record.CreatedAt),
+	)
+	return err
+}
+
+// DailyTotals aggregates the records of userID created in [from, to) by UTC day and model.
+func (r *SQLiteUsageRepository) DailyTotals(userID string, from, to time.Time) ([]domain.DailyUsage, error) {
+	// Timestamps are stored in UTC, so the first ten characters are the day.
+	rows, err := r.db.Query(
+		`SELECT substr(created_at, 1, 10) AS day, model, COUNT(*),
+		        SUM(prompt_tokens), SUM(completion_tokens), SUM(thoughts_tokens), SUM(total_tokens)
+		 FROM usage_records
+		 WHERE user_id = ? AND created_at >= ? AND created_at < ?
+		 GROUP BY day, model
+		 ORDER BY day, model`,
+		userID, formatTime(from), formatTime(to),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []domain.DailyUsage{}
+	for rows.Next() {
+		var day domain.DailyUsage
+		if err := rows.Scan(&day.Date, &day.Model, &day.Requests,
+			&day.Usage.PromptTokens, &day.Usage.CompletionTokens, &day.Usage.ThoughtsTokens, &day.Usage.TotalTokens); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
@@ -0,0 +1,238 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLiteWebhookRepository stores webhooks and their deliveries in a SQLite
+// database, so pending retries survive a restart.
+type SQLiteWebhookRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteWebhookRepository creates the webhooks and webhook_deliveries
+// tables if needed and returns the repository. It must be created after the
+// forms table.
+func NewSQLiteWebhookRepository(db *sql.DB) (*SQLiteWebhookRepository, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id         TEXT PRIMARY KEY,
+			form_id    TEXT NOT NULL REFERENCES forms (id) ON DELETE CASCADE,
+			url        TEXT NOT NULL,
+			secret     TEXT NOT NULL,
+			events     TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS webhooks_form ON webhooks (form_id);
+
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id               TEXT PRIMARY KEY,
+			webhook_id       TEXT NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+			form_id          TEXT NOT NULL,
+			event            TEXT NOT NULL,
+			payload          TEXT NOT NULL,
+			status           TEXT NOT NULL,
+			attempts         INTEGER NOT NULL,
+			last_status_code INTEGER NOT NULL,
+			last_error       TEXT NOT NULL,
+			next_attempt_at  TEXT,
+			created_at       TEXT NOT NULL,
+			updated_at       TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS webhook_deliveries_form_created ON webhook_deliveries (form_id, created_at);
+		CREATE INDEX IF NOT EXISTS webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook tables: %w", err)
+	}
+	return &SQLiteWebhookRepository{db: db}, nil
+}
+
+// Create stores a new webhook.
+func (r *SQLiteWebhookRepository) Create(webhook *domain.Webhook) error {
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(
+		`INSERT INTO webhooks (id, form_id, url, secret, events, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		webhook.ID, webhook.FormID, webhook.URL, webhook.Secret, string(events), formatTime(webhook.CreatedAt),
+	)
+	return err
+}
+
+// Get returns a webhook by ID.
+func (r *SQLiteWebhookRepository) Get(id string) (*domain.Webhook, error) {
+	row := r.db.QueryRow(`SELECT id, form_id, url, secret, events, created_at FROM webhooks WHERE id = ?`, id)
+	webhook, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+// ListByForm returns the webhooks of a form, oldest first.
+func (r *SQLiteWebhookRepository) ListByForm(formID string) ([]*domain.Webhook, error) {
+	rows, err := r.db.Query(
+		`SELECT id, form_id, url, secret, events, created_at FROM webhooks WHERE form_id = ? ORDER BY created_at`, formID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*domain.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// Delete removes a webhook; its deliveries are removed by the foreign key.
+func (r *SQLiteWebhookRepository) Delete(id string) error {
+	result, err := r.db.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, domain.ErrWebhookNotFound)
+}
+
+// CreateDelivery stores a new delivery.
+func (r *SQLiteWebhookRepository) CreateDelivery(delivery *domain.WebhookDelivery) error {
+	_, err := r.db.Exec(
+		`INSERT INTO webhook_deliveries (id, webhook_id, form_id, event, payload, status, attempts, last_status_code, last_error, next_attempt_at, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		delivery.ID, delivery.WebhookID, delivery.FormID, delivery.Event, string(delivery.Payload), delivery.Status,
+		delivery.Attempts, delivery.LastStatusCode, delivery.LastError, nullableTime(delivery.NextAttemptAt),
+		formatTime(delivery.CreatedAt), formatTime(delivery.UpdatedAt),
+	)
+	return err
+}
+
+// GetDelivery returns a delivery by ID.
+func (r *SQLiteWebhookRepository) GetDelivery(id string) (*domain.WebhookDelivery, error) {
+	row := r.db.QueryRow(`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id)
+	delivery, err := scanDelivery(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrDeliveryNotFound
+	}
+	return delivery, err
+}
+
+// UpdateDelivery stores the status, attempt counters and schedule of a delivery.
+func (r *SQLiteWebhookRepository) UpdateDelivery(delivery *domain.WebhookDelivery) error {
+	result, err := r.db.Exec(
+		`UPDATE webhook_deliveries SET status = ?, attempts = ?, last_status_code = ?, last_error = ?, next_attempt_at = ?, updated_at = ?
+		 WHERE id = ?`,
+		delivery.Status, delivery.Attempts, delivery.LastStatusCode, delivery.LastError,
+		nullableTime(delivery.NextAttemptAt), formatTime(delivery.UpdatedAt), delivery.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, domain.ErrDeliveryNotFound)
+}
+
+// ListDeliveries returns the deliveries matching query, newest first.
+func (r *SQLiteWebhookRepository) ListDeliveries(query domain.DeliveryQuery) ([]*domain.WebhookDelivery, error) {
+	conditions := []string{"form_id = ?"}
+	args := []interface{}{query.FormID}
+	if query.WebhookID != "" {
+		conditions = append(conditions, "webhook_id = ?")
+		args = append(args, query.WebhookID)
+	}
+	if query.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, query.Status)
+	}
+	statement := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE ` +
+		strings.Join(conditions, " AND ") + ` ORDER BY created_at DESC, id DESC`
+	if query.Limit > 0 {
+		statement += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+	return r.queryDeliveries(statement, args...)
+}
+
+// DueDeliveries returns up to limit pending deliveries due at now, earliest first.
+func (r *SQLiteWebhookRepository) DueDeliveries(now time.Time, limit int) ([]*domain.WebhookDelivery, error) {
+	return r.queryDeliveries(
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		 WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ?`,
+		domain.DeliveryPending, formatTime(now), limit,
+	)
+}
+
+const deliveryColumns = `id, webhook_id, form_id, event, payload, status, attempts, last_status_code, last_error, next_attempt_at, created_at, updated_at`
+
+func (r *SQLiteWebhookRepository) queryDeliveries(statement string, args ...interface{}) ([]*domain.WebhookDelivery, error) {
+	rows, err := r.db.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*domain.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanWebhook(row rowScanner) (*domain.Webhook, error) {
+	var (
+		webhook           domain.Webhook
+		events, createdAt string
+	)
+	if err := row.Scan(&webhook.ID, &webhook.FormID, &webhook.URL, &webhook.Secret, &events, &createdAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(events), &webhook.Events); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook events: %w", err)
+	}
+	var err error
+	if webhook.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func scanDelivery(row rowScanner) (*domain.WebhookDelivery, error) {
+	var (
+		delivery                      domain.WebhookDelivery
+		payload, createdAt, updatedAt string
+		nextAttemptAt                 sql.NullString
+	)
+	err := row.Scan(
+		&delivery.ID, &delivery.WebhookID, &delivery.FormID, &delivery.Event, &payload, &delivery.Status,
+		&delivery.Attempts, &delivery.LastStatusCode, &delivery.LastError, &nextAttemptAt, &createdAt, &updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = json.RawMessage(payload)
+	if delivery.NextAttemptAt, err = parseNullableTime(nextAttemptAt); err != nil {
+		return nil, err
+	}
+	if delivery.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if delivery.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
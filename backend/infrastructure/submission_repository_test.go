@@ -0,0 +1,153 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// submissionRepository is implemented by both submission repositories.
+type submissionRepository interface {
+	Create(submission *domain.Submission) error
+	List(query domain.SubmissionQuery) ([]*domain.Submission, error)
+}
+
+// submissionRepositories returns a fresh repository of every kind, each
+// holding the form "form-1".
+func submissionRepositories(t *testing.T) map[string]submissionRepository {
+	t.Helper()
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	forms, err := NewSQLiteFormRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	form := &domain.StoredForm{ID: "form-1", OwnerID: "owner", Name: "Form", Version: 1, CreatedAt: now, UpdatedAt: now}
+	if err := forms.Create(form, &domain.FormVersion{FormID: form.ID, Version: 1, Source: domain.VersionCreated, CreatedBy: "owner", CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := NewSQLiteSubmissionRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]submissionRepository{
+		"memory": NewInMemorySubmissionRepository(),
+		"sqlite": sqlite,
+	}
+}
+
+func submissionIDs(submissions []*domain.Submission) []string {
+	ids := make([]string, len(submissions))
+	for i, submission := range submissions {
+		ids[i] = submission.ID
+	}
+	return ids
+}
+
+func TestSubmissionRepositoryPagesAcrossEqualTimestamps(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	created := map[string]time.Time{
+		"a": base,
+		"b": base.Add(time.Second),
+		"c": base.Add(time.Second),
+		"d": base.Add(time.Second),
+		"e": base.Add(time.Second),
+		"f": base.Add(time.Second + time.Nanosecond),
+		"g": base.Add(2 * time.Second),
+	}
+	// Newest first, ties by descending ID.
+	want := []string{"g", "f", "e", "d", "c", "b", "a"}
+
+	for name, repo := range submissionRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			for id, createdAt := range created {
+				submission := &domain.Submission{ID: id, FormID: "form-1", FormVersion: 1, Data: map[string]interface{}{}, CreatedAt: createdAt}
+				if err := repo.Create(submission); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			all, err := repo.List(domain.SubmissionQuery{FormID: "form-1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := submissionIDs(all); !reflect.DeepEqual(got, want) {
+				t.Fatalf("List = %v, want %v", got, want)
+			}
+
+			for _, limit := range []int{1, 2, 3} {
+				var got []string
+				query := domain.SubmissionQuery{FormID: "form-1", Limit: limit}
+				for pages := 0; ; pages++ {
+					if pages > len(want) {
+						t.Fatalf("limit %d: paging does not end", limit)
+					}
+					page, err := repo.List(query)
+					if err != nil {
+						t.Fatal(err)
+					}
+					got = append(got, submissionIDs(page)...)
+					if len(page) < limit {
+						break
+					}
+					last := page[len(page)-1]
+					query.After = &domain.SubmissionCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("limit %d: pages = %v, want %v", limit, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSubmissionRepositoryFilters(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	submissions := []*domain.Submission{
+		{ID: "a", Data: map[string]interface{}{"plan": "free", "tags": []interface{}{"x"}}, CreatedAt: base},
+		{ID: "b", Data: map[string]interface{}{"plan": "pro", "tags": []interface{}{"x", "y"}, "seats": 3.0}, CreatedAt: base.Add(time.Hour)},
+		{ID: "c", Data: map[string]interface{}{"plan": "pro", "seats": 5.0}, CreatedAt: base.Add(2 * time.Hour)},
+	}
+	tests := []struct {
+		name  string
+		query domain.SubmissionQuery
+		want  []string
+	}{
+		{"from", domain.SubmissionQuery{From: base.Add(time.Hour)}, []string{"c", "b"}},
+		{"to is exclusive", domain.SubmissionQuery{To: base.Add(time.Hour)}, []string{"a"}},
+		{"scalar field", domain.SubmissionQuery{Fields: map[string]interface{}{"plan": "pro"}}, []string{"c", "b"}},
+		{"number field", domain.SubmissionQuery{Fields: map[string]interface{}{"seats": 3.0}}, []string{"b"}},
+		{"array contains", domain.SubmissionQuery{Fields: map[string]interface{}{"tags": "x"}}, []string{"b", "a"}},
+		{"several fields", domain.SubmissionQuery{Fields: map[string]interface{}{"plan": "pro", "tags": "y"}}, []string{"b"}},
+		{"no match", domain.SubmissionQuery{Fields: map[string]interface{}{"plan": "team"}}, []string{}},
+	}
+
+	for name, repo := range submissionRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, submission := range submissions {
+				submission := *submission
+				submission.FormID = "form-1"
+				if err := repo.Create(&submission); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, tt := range tests {
+				query := tt.query
+				query.FormID = "form-1"
+				got, err := repo.List(query)
+				if err != nil {
+					t.Fatalf("%s: %v", tt.name, err)
+				}
+				if ids := submissionIDs(got); !reflect.DeepEqual(ids, tt.want) {
+					t.Errorf("%s: List = %v, want %v", tt.name, ids, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package infrastructure
+
+import (
+	"better-form-doc-backend/domain"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// usageRepository is implemented by both usage repositories.
+type usageRepository interface {
+	Record(record *domain.UsageRecord) error
+	DailyTotals(userID string, from, to time.Time) ([]domain.DailyUsage, error)
+}
+
+func usageRepositories(t *testing.T) map[string]usageRepository {
+	t.Helper()
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	sqlite, err := NewSQLiteUsageRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]usageRepository{
+		"memory": NewInMemoryUsageRepository(),
+		"sqlite": sqlite,
+	}
+}
+
+func TestUsageRepositoryDailyTotals(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	usage := func(prompt, completion, thoughts int) domain.TokenUsage {
+		return domain.TokenUsage{PromptTokens: prompt, CompletionTokens: completion, ThoughtsTokens: thoughts, TotalTokens: prompt + completion + thoughts}
+	}
+	records := []domain.UsageRecord{
+		{UserID: "alice", Model: "gemini", Usage: usage(10, 5, 0), CreatedAt: day},
+		{UserID: "alice", Model: "gemini", Usage: usage(20, 10, 4), CreatedAt: day.Add(23*time.Hour + 59*time.Minute)},
+		{UserID: "alice", Model: "gpt", Usage: usage(1, 1, 0), CreatedAt: day.Add(12 * time.Hour)},
+		// Recorded in another time zone, the day is still the UTC day.
+		{UserID: "alice", Model: "gemini", Usage: usage(7, 3, 0), CreatedAt: day.Add(25 * time.Hour).In(time.FixedZone("UTC-5", -5*60*60))},
+		{UserID: "bob", Model: "gemini", Usage: usage(100, 100, 0), CreatedAt: day},
+		// Outside [from, to).
+		{UserID: "alice", Model: "gemini", Usage: usage(1000, 0, 0), CreatedAt: day.Add(-time.Second)},
+		{UserID: "alice", Model: "gemini", Usage: usage(1000, 0, 0), CreatedAt: day.Add(48 * time.Hour)},
+	}
+	want := []domain.DailyUsage{
+		{Date: "2024-05-01", Model: "gemini", Requests: 2, Usage: usage(30, 15, 4)},
+		{Date: "2024-05-01", Model: "gpt", Requests: 1, Usage: usage(1, 1, 0)},
+		{Date: "2024-05-02", Model: "gemini", Requests: 1, Usage: usage(7, 3, 0)},
+	}
+
+	for name, repository := range usageRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, record := range records {
+				record.ID = string(rune('a' + i))
+				record.Calls = 1
+				record.Outcome = "success"
+				if err := repository.Record(&record); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := repository.DailyTotals("alice", day, day.Add(48*time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("DailyTotals = %+v, want %+v", got, want)
+			}
+
+			none, err := repository.DailyTotals("carol", day, day.Add(48*time.Hour))
+			if err != nil || none == nil || len(none) != 0 {
+				t.Errorf("DailyTotals of a user without usage = %#v, %v, want an empty list", none, err)
+			}
+		})
+	}
+}
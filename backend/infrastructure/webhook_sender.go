@@ -0,0 +1,48 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+)
+
+// maxWebhookResponseBytes bounds how much of a webhook response is read
+// before the connection is reused. The body itself is ignored.
+const maxWebhookResponseBytes = 64 * 1024
+
+// HTTPWebhookSender posts webhook deliveries over HTTP.
+type HTTPWebhookSender struct {
+	client *http.Client
+}
+
+// NewHTTPWebhookSender creates a sender. Redirects are reported as failed
+// attempts rather than followed, so a signed payload never reaches a host
+// the webhook was not registered for. Every attempt is bounded by the
+// deadline of its context.
+func NewHTTPWebhookSender() *HTTPWebhookSender {
+	return &HTTPWebhookSender{client: &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Send posts body to url and returns the response status code.
+func (s *HTTPWebhookSender) Send(ctx context.Context, url string, headers map[string]string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "BetterForm-Webhooks/1.0")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxWebhookResponseBytes))
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,459 @@
+// jsonpatch/jsonpatch.go
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operation is a single RFC 6902 JSON Patch operation.
+type Operation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+
+	// valueMissing and fromMissing record members absent from the decoded
+	// JSON, which Value and From alone cannot tell apart from null and "".
+	valueMissing bool
+	fromMissing  bool
+}
+
+// MarshalJSON always emits the value of "add", "replace" and "test", even
+// when it is null; omitempty alone would turn setting a null into an
+// operation without a value.
+func (op Operation) MarshalJSON() ([]byte, error) {
+	type operation Operation // without this method
+	switch op.Op {
+	case "add", "replace", "test":
+		return json.Marshal(struct {
+			operation
+			Value interface{} `json:"value"`
+		}{operation(op), op.Value})
+	}
+	return json.Marshal(operation(op))
+}
+
+// UnmarshalJSON decodes an operation and records whether "value" and "from"
+// were present, so that Apply can reject operations missing the member they
+// require (RFC 6902, section 4).
+func (op *Operation) UnmarshalJSON(data []byte) error {
+	type operation Operation // without this method
+	var decoded struct {
+		operation
+		From  *string         `json:"from"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*op = Operation(decoded.operation)
+	op.fromMissing = decoded.From == nil
+	if !op.fromMissing {
+		op.From = *decoded.From
+	}
+	op.valueMissing = decoded.Value == nil
+	if !op.valueMissing {
+		if err := json.Unmarshal(decoded.Value, &op.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Patch is an ordered list of operations.
+type Patch []Operation
+
+// ErrTestFailed is returned by Apply when a "test" operation does not match.
+var ErrTestFailed = errors.New("test operation failed")
+
+// ErrMissingMember is returned by Apply for a decoded operation that lacks
+// the "value" or "from" member its op requires.
+var ErrMissingMember = errors.New("operation is missing a required member")
+
+// ToDocument converts any JSON-serializable value into its generic form
+// (maps, slices, float64, string, bool, nil) so it can be diffed or patched.
+func ToDocument(v interface{}) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// --- Diff ---
+
+// Diff returns a patch that turns the document a into b. Both must be in
+// generic form (see ToDocument). Arrays are aligned on a longest common
+// subsequence so that inserting one field yields a single "add"; objects
+// carrying a "name" or "id" are matched by that key and diffed recursively.
+func Diff(a, b interface{}) Patch {
+	var patch Patch
+	diffValue(&patch, "", a, b)
+	return patch
+}
+
+func diffValue(patch *Patch, path string, a, b interface{}) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			diffObject(patch, path, av, bv)
+			return
+		}
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok {
+			diffArray(patch, path, av, bv)
+			return
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		*patch = append(*patch, Operation{Op: "replace", Path: path, Value: b})
+	}
+}
+
+func diffObject(patch *Patch, path string, a, b map[string]interface{}) {
+	for _, key := range sortedKeys(a) {
+		if _, ok := b[key]; !ok {
+			*patch = append(*patch, Operation{Op: "remove", Path: path + "/" + EscapeToken(key)})
+		}
+	}
+	for _, key := range sortedKeys(b) {
+		childPath := path + "/" + EscapeToken(key)
+		if av, ok := a[key]; ok {
+			diffValue(patch, childPath, av, b[key])
+		} else {
+			*patch = append(*patch, Operation{Op: "add", Path: childPath, Value: b[key]})
+		}
+	}
+}
+
+func diffArray(patch *Patch, path string, a, b []interface{}) {
+	pairs := lcs(a, b)
+	pairs = append(pairs, [2]int{len(a), len(b)}) // sentinel
+
+	// pos is the index in the array as it looks after the operations so far.
+	pos, i, j := 0, 0, 0
+	for _, pair := range pairs {
+		for ; i < pair[0]; i++ {
+			*patch = append(*patch, Operation{Op: "remove", Path: path + "/" + strconv.Itoa(pos)})
+		}
+		for ; j < pair[1]; j++ {
+			*patch = append(*patch, Operation{Op: "add", Path: path + "/" + strconv.Itoa(pos), Value: b[j]})
+			pos++
+		}
+		if i < len(a) && j < len(b) {
+			diffValue(patch, path+"/"+strconv.Itoa(pos), a[i], b[j])
+			pos++
+			i++
+			j++
+		}
+	}
+}
+
+// lcs returns the index pairs of the longest common subsequence of a and b,
+// comparing elements by identityKey.
+func lcs(a, b []interface{}) [][2]int {
+	ka := make([]string, len(a))
+	kb := make([]string, len(b))
+	for i, v := range a {
+		ka[i] = identityKey(v)
+	}
+	for j, v := range b {
+		kb[j] = identityKey(v)
+	}
+
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if ka[i] == kb[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else if table[i+1][j] >= table[i][j+1] {
+				table[i][j] = table[i+1][j]
+			} else {
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+
+	var pairs [][2]int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case ka[i] == kb[j]:
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
+
+// identityKey identifies array elements across versions: objects by their
+// "name" or "id" property, everything else by value.
+func identityKey(v interface{}) string {
+	if obj, ok := v.(map[string]interface{}); ok {
+		for _, key := range []string{"name", "id"} {
+			if s, ok := obj[key].(string); ok && s != "" {
+				return key + ":" + s
+			}
+		}
+	}
+	raw, _ := json.Marshal(v)
+	return "value:" + string(raw)
+}
+
+// --- Apply ---
+
+// Apply applies the patch to a document in generic form and returns the
+// result. The input document is not modified.
+func Apply(doc interface{}, patch Patch) (interface{}, error) {
+	result, err := deepCopy(doc)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range patch {
+		result, err = applyOperation(result, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return result, nil
+}
+
+func applyOperation(doc interface{}, op Operation) (interface{}, error) {
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.valueMissing {
+			return nil, fmt.Errorf("%w: value", ErrMissingMember)
+		}
+	case "move", "copy":
+		if op.fromMissing {
+			return nil, fmt.Errorf("%w: from", ErrMissingMember)
+		}
+	}
+	switch op.Op {
+	case "add":
+		return setValue(doc, op.Path, op.Value, true)
+	case "remove":
+		result, _, err := removeValue(doc, op.Path)
+		return result, err
+	case "replace":
+		if _, err := getValue(doc, op.Path); err != nil {
+			return nil, err
+		}
+		return setValue(doc, op.Path, op.Value, false)
+	case "move":
+		if strings.HasPrefix(op.Path+"/", op.From+"/") && op.Path != op.From {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		result, value, err := removeValue(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return setValue(result, op.Path, value, true)
+	case "copy":
+		value, err := getValue(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err = deepCopy(value)
+		if err != nil {
+			return nil, err
+		}
+		return setValue(doc, op.Path, value, true)
+	case "test":
+		value, err := getValue(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		expected, err := ToDocument(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, expected) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unsupported operation %q", op.Op)
+}
+
+func getValue(doc interface{}, path string) (interface{}, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	current := doc
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %q does not exist", path)
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("path %q does not exist", path)
+		}
+	}
+	return current, nil
+}
+
+// setValue adds (insert=true) or replaces the value at path.
+func setValue(doc interface{}, path string, value interface{}, insert bool) (interface{}, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	parentPath := pointerOf(tokens[:len(tokens)-1])
+	parent, err := getValue(doc, parentPath)
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return doc, nil
+	case []interface{}:
+		if !insert {
+			index, err := arrayIndex(last, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			node[index] = value
+			return doc, nil
+		}
+		index, err := arrayIndex(last, len(node), true)
+		if err != nil {
+			return nil, err
+		}
+		grown := append(node[:index:index], append([]interface{}{value}, node[index:]...)...)
+		return setValue(doc, parentPath, grown, false)
+	}
+	return nil, fmt.Errorf("path %q does not exist", path)
+}
+
+func removeValue(doc interface{}, path string) (interface{}, interface{}, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil, errors.New("cannot remove the document root")
+	}
+	parentPath := pointerOf(tokens[:len(tokens)-1])
+	parent, err := getValue(doc, parentPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	last := tokens[len(tokens)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		value, ok := node[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("path %q does not exist", path)
+		}
+		delete(node, last)
+		return doc, value, nil
+	case []interface{}:
+		index, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := node[index]
+		shrunk := append(node[:index:index], node[index+1:]...)
+		result, err := setValue(doc, parentPath, shrunk, false)
+		return result, value, err
+	}
+	return nil, nil, fmt.Errorf("path %q does not exist", path)
+}
+
+// arrayIndex parses an array token. "-" addresses the end of the array when
+// inserting.
+func arrayIndex(token string, length int, insert bool) (int, error) {
+	if insert && token == "-" {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	limit := length - 1
+	if insert {
+		limit = length
+	}
+	if index > limit {
+		return 0, fmt.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
+
+// --- JSON Pointer (RFC 6901) ---
+
+// EscapeToken escapes a single reference token of a JSON Pointer.
+func EscapeToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// parsePointer splits a JSON Pointer into unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func pointerOf(tokens []string) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString("/" + EscapeToken(token))
+	}
+	return sb.String()
+}
+
+func deepCopy(v interface{}) (interface{}, error) {
+	return ToDocument(v)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,238 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mustDocument(t *testing.T, raw string) interface{} {
+	t.Helper()
+	var doc interface{}
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		t.Fatalf("invalid test document %s: %v", raw, err)
+	}
+	return doc
+}
+
+func TestDiffApplyRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"identical", `{"a":1}`, `{"a":1}`},
+		{"add member", `{"a":1}`, `{"a":1,"b":"x"}`},
+		{"remove member", `{"a":1,"b":2}`, `{"a":1}`},
+		{"replace scalar", `{"a":1}`, `{"a":"one"}`},
+		{"replace type", `{"a":{"b":1}}`, `{"a":[1,2]}`},
+		{"set null", `{"a":1}`, `{"a":null}`},
+		{"add null", `{}`, `{"a":null}`},
+		{"replace root", `[1]`, `{"a":1}`},
+		{"escaped keys", `{"a/b":1,"c~d":2}`, `{"a/b":3,"e~f/g":4}`},
+		{"append to array", `{"a":[1,2]}`, `{"a":[1,2,3]}`},
+		{"insert into array", `{"a":[1,3]}`, `{"a":[1,2,3]}`},
+		{"remove from array", `{"a":[1,2,3]}`, `{"a":[1,3]}`},
+		{"reorder array", `{"a":[1,2,3]}`, `{"a":[3,1,2]}`},
+		{"empty array", `{"a":[1,2]}`, `{"a":[]}`},
+		{
+			"fields matched by name",
+			`{"fields":[{"name":"email","label":"Email"},{"name":"age","label":"Age"}]}`,
+			`{"fields":[{"name":"first","label":"First"},{"name":"email","label":"E-mail"},{"name":"age","label":"Age","required":true}]}`,
+		},
+		{
+			"objects matched by id",
+			`[{"id":"x","v":1},{"id":"y","v":2}]`,
+			`[{"id":"y","v":3},{"id":"z","v":4}]`,
+		},
+		{"nested arrays", `[[1,2],[3]]`, `[[1],[3,4],[]]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := mustDocument(t, tt.a), mustDocument(t, tt.b)
+			before := mustDocument(t, tt.a)
+
+			patch := Diff(a, b)
+			got, err := Apply(a, patch)
+			if err != nil {
+				t.Fatalf("Apply(Diff) failed: %v\npatch: %+v", err, patch)
+			}
+			if !reflect.DeepEqual(got, b) {
+				t.Errorf("Apply(Diff) = %v, want %v\npatch: %+v", got, b, patch)
+			}
+			if !reflect.DeepEqual(a, before) {
+				t.Errorf("Apply modified its input: %v, want %v", a, before)
+			}
+
+			// The patch survives a trip through JSON, null values included.
+			encoded, err := json.Marshal(patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded Patch
+			if err := json.Unmarshal(encoded, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			got, err = Apply(a, decoded)
+			if err != nil {
+				t.Fatalf("Apply(decoded Diff) failed: %v\npatch: %s", err, encoded)
+			}
+			if !reflect.DeepEqual(got, b) {
+				t.Errorf("Apply(decoded Diff) = %v, want %v\npatch: %s", got, b, encoded)
+			}
+		})
+	}
+}
+
+func TestDiffIsMinimal(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"identical", `{"a":[1,2]}`, `{"a":[1,2]}`, `null`},
+		{
+			"inserted field",
+			`{"fields":[{"name":"a"},{"name":"c"}]}`,
+			`{"fields":[{"name":"a"},{"name":"b"},{"name":"c"}]}`,
+			`[{"op":"add","path":"/fields/1","value":{"name":"b"}}]`,
+		},
+		{
+			"changed field property",
+			`{"fields":[{"name":"a","label":"A"},{"name":"b"}]}`,
+			`{"fields":[{"name":"a","label":"Alpha"},{"name":"b"}]}`,
+			`[{"op":"replace","path":"/fields/0/label","value":"Alpha"}]`,
+		},
+		{
+			"removed field",
+			`{"fields":[{"name":"a"},{"name":"b"}]}`,
+			`{"fields":[{"name":"b"}]}`,
+			`[{"op":"remove","path":"/fields/0"}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch := Diff(mustDocument(t, tt.a), mustDocument(t, tt.b))
+			got, err := json.Marshal(patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Diff = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr bool
+	}{
+		{"add to end", `{"a":[1]}`, `[{"op":"add","path":"/a/-","value":2}]`, `{"a":[1,2]}`, false},
+		{"move", `{"a":1,"b":{}}`, `[{"op":"move","from":"/a","path":"/b/c"}]`, `{"b":{"c":1}}`, false},
+		{"copy", `{"a":[1]}`, `[{"op":"copy","from":"/a","path":"/b"}]`, `{"a":[1],"b":[1]}`, false},
+		{"test passes", `{"a":{"b":[1,"x"]}}`, `[{"op":"test","path":"/a","value":{"b":[1,"x"]}}]`, `{"a":{"b":[1,"x"]}}`, false},
+		{"test null", `{"a":null}`, `[{"op":"test","path":"/a","value":null}]`, `{"a":null}`, false},
+		{"test fails", `{"a":1}`, `[{"op":"test","path":"/a","value":2}]`, ``, true},
+		{"replace missing", `{}`, `[{"op":"replace","path":"/a","value":1}]`, ``, true},
+		{"remove missing", `{}`, `[{"op":"remove","path":"/a"}]`, ``, true},
+		{"remove root", `{}`, `[{"op":"remove","path":""}]`, ``, true},
+		{"index out of range", `[1]`, `[{"op":"add","path":"/2","value":2}]`, ``, true},
+		{"leading zero index", `[1,2]`, `[{"op":"remove","path":"/01"}]`, ``, true},
+		{"move into child", `{"a":{}}`, `[{"op":"move","from":"/a","path":"/a/b"}]`, ``, true},
+		{"invalid pointer", `{}`, `[{"op":"add","path":"a","value":1}]`, ``, true},
+		{"unknown operation", `{}`, `[{"op":"merge","path":"/a"}]`, ``, true},
+		{"add null", `{}`, `[{"op":"add","path":"/a","value":null}]`, `{"a":null}`, false},
+		{"replace with null", `{"a":1}`, `[{"op":"replace","path":"/a","value":null}]`, `{"a":null}`, false},
+		{"move from the root member", `{"":1}`, `[{"op":"move","from":"/","path":"/b"}]`, `{"b":1}`, false},
+		{"add without value", `{}`, `[{"op":"add","path":"/a"}]`, ``, true},
+		{"replace without value", `{"a":1}`, `[{"op":"replace","path":"/a"}]`, ``, true},
+		{"test without value", `{"a":null}`, `[{"op":"test","path":"/a"}]`, ``, true},
+		{"move without from", `{"a":1}`, `[{"op":"move","path":"/b"}]`, ``, true},
+		{"copy without from", `{"a":1}`, `[{"op":"copy","path":"/b"}]`, ``, true},
+		{"invalid value", `{}`, `[{"op":"add","path":"/a","value":}]`, ``, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patch Patch
+			var got interface{}
+			err := json.Unmarshal([]byte(tt.patch), &patch)
+			if err == nil {
+				got, err = Apply(mustDocument(t, tt.doc), patch)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Apply = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if want := mustDocument(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("Apply = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyTestFailure(t *testing.T) {
+	patch := Patch{{Op: "test", Path: "/a", Value: 2}}
+	if _, err := Apply(mustDocument(t, `{"a":1}`), patch); !errors.Is(err, ErrTestFailed) {
+		t.Errorf("Apply error = %v, want ErrTestFailed", err)
+	}
+}
+
+func TestApplyMissingMember(t *testing.T) {
+	var patch Patch
+	if err := json.Unmarshal([]byte(`[{"op":"test","path":"/a","value":1},{"op":"add","path":"/b"}]`), &patch); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Apply(mustDocument(t, `{"a":1}`), patch)
+	if !errors.Is(err, ErrMissingMember) || !strings.Contains(err.Error(), "operation 1") {
+		t.Errorf("Apply error = %v, want ErrMissingMember for operation 1", err)
+	}
+	// Operations built in code carry their value even when it is nil.
+	if _, err := Apply(mustDocument(t, `{}`), Patch{{Op: "add", Path: "/a"}}); err != nil {
+		t.Errorf("Apply of a constructed operation: %v", err)
+	}
+}
+
+func TestOperationMarshalJSON(t *testing.T) {
+	tests := []struct {
+		op   Operation
+		want string
+	}{
+		{Operation{Op: "add", Path: "/a"}, `{"op":"add","path":"/a","value":null}`},
+		{Operation{Op: "replace", Path: "/a"}, `{"op":"replace","path":"/a","value":null}`},
+		{Operation{Op: "test", Path: "/a", Value: false}, `{"op":"test","path":"/a","value":false}`},
+		{Operation{Op: "remove", Path: "/a"}, `{"op":"remove","path":"/a"}`},
+		{Operation{Op: "move", From: "/a", Path: "/b"}, `{"op":"move","path":"/b","from":"/a"}`},
+	}
+	for _, tt := range tests {
+		got, err := json.Marshal(tt.op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Marshal(%+v) = %s, want %s", tt.op, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeToken(t *testing.T) {
+	for token, want := range map[string]string{"a": "a", "a/b": "a~1b", "m~n": "m~0n", "~/": "~0~1"} {
+		if got := EscapeToken(token); got != want {
+			t.Errorf("EscapeToken(%q) = %q, want %q", token, got, want)
+		}
+		tokens, err := parsePointer("/" + EscapeToken(token))
+		if err != nil || len(tokens) != 1 || tokens[0] != token {
+			t.Errorf("parsePointer(EscapeToken(%q)) = %q, %v", token, tokens, err)
+		}
+	}
+}
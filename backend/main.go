@@ -6,9 +6,18 @@ import (
 	"better-form-doc-backend/infrastructure"
 	"better-form-doc-backend/router"
 	"better-form-doc-backend/usecase"
+	"better-form-doc-backend/validation"
+	"context"
+	"crypto/rand"
+	"io/fs"
 	"log"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
@@ -31,6 +40,11 @@ import (
 // @in header
 // @name Authorization
 // @description "Type 'Bearer' followed by a space and a JWT."
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description "An API key minted through /admin/api-keys."
 func main() {
 	// Load environment variables from .env file
 	if err := godotenv.Load(); err != nil {
@@ -38,20 +52,58 @@ func main() {
 	}
 
 	// Load config from environment
-	geminiAPIKey := os.Getenv("GEMINI_API_KEY")
-	if geminiAPIKey == "" {
-		log.Fatal("GEMINI_API_KEY is not set")
-	}
-	geminiModelName := os.Getenv("GEMINI_MODEL_NAME")
-	if geminiModelName == "" {
-		geminiModelName = "gemini-2.5-flash"
+	generatorConfig := usecase.FormGeneratorConfig{
+		MaxRepairAttempts: envInt("FORM_REPAIR_MAX_ATTEMPTS", usecase.DefaultMaxRepairAttempts),
+		Temperature:       envFloat("LLM_TEMPERATURE"),
+		TopP:              envFloat("LLM_TOP_P"),
+		MaxOutputTokens:   envInt("LLM_MAX_OUTPUT_TOKENS", 0),
+		RequestTimeout:    envDuration("CHAT_REQUEST_TIMEOUT", 2*time.Minute),
+		CallTimeout:       envDuration("LLM_CALL_TIMEOUT", 30*time.Second),
+		MaxPromptLength:   envInt("MAX_PROMPT_LENGTH", usecase.DefaultMaxPromptLength),
+		// Generated forms may only point to these hosts besides relative URLs.
+		OutputPolicy: validation.OutputPolicy{AllowedHosts: envList("FORM_ALLOWED_HOSTS")},
 	}
 
 	// Instantiate our infrastructure components
-	geminiClient := infrastructure.NewGeminiClient(geminiAPIKey, geminiModelName)
-	chatUsecase := usecase.NewChatUseCase(geminiClient)
+	llmClient, modelName := newLLMClient()
+	generatorConfig.ModelName = modelName
+	generatorConfig.Prompts = newPromptRegistry()
+	conversationStore := infrastructure.NewInMemoryConversationStore(
+		envDuration("CONVERSATION_TTL", 2*time.Hour),
+		envInt("CONVERSATION_MAX_ENTRIES", 10000),
+		envInt("CONVERSATION_MAX_PER_USER", 50),
+	)
+	go conversationStore.Run(context.Background())
+	repositories := newRepositories()
+	dataSourceSecrets := infrastructure.NewEnvSecretResolver(dataSourceSecretPrefix)
+	dataSourcePolicy := newDataSourcePolicy(dataSourceSecrets)
+	formUsecase := usecase.NewFormUseCase(repositories.forms, dataSourcePolicy)
+	chatUsecase := usecase.NewChatUseCase(llmClient, conversationStore, formUsecase, repositories.usage, newFormCache(), generatorConfig)
+	apiKeyUsecase := usecase.NewAPIKeyUseCase(repositories.apiKeys, os.Getenv("ADMIN_API_KEY"))
+	usageUsecase := usecase.NewUsageUseCase(repositories.usage)
+	webhookUsecase := usecase.NewWebhookUseCase(repositories.forms, repositories.webhooks, infrastructure.NewHTTPWebhookSender(), usecase.WebhookConfig{
+		MaxAttempts: envInt("WEBHOOK_MAX_ATTEMPTS", usecase.DefaultWebhookConfig.MaxAttempts),
+		BaseDelay:   envDuration("WEBHOOK_RETRY_BASE_DELAY", usecase.DefaultWebhookConfig.BaseDelay),
+		MaxDelay:    envDuration("WEBHOOK_RETRY_MAX_DELAY", usecase.DefaultWebhookConfig.MaxDelay),
+		Timeout:     envDuration("WEBHOOK_TIMEOUT", usecase.DefaultWebhookConfig.Timeout),
+	})
+	go webhookUsecase.Run(context.Background())
+	submissionUsecase := usecase.NewSubmissionUseCase(repositories.forms, repositories.submissions, webhookUsecase, newFileConfig())
+	fieldOptionsUsecase := usecase.NewFieldOptionsUseCase(repositories.forms, newDataSourceFetcher(dataSourcePolicy), dataSourceSecrets, newOptionsCache(), usecase.DataSourceConfig{
+		Policy:  dataSourcePolicy,
+		Timeout: envDuration("DATA_SOURCE_TIMEOUT", usecase.DefaultDataSourceTimeout),
+	})
 	chatController := controller.NewChatController(chatUsecase)
-	router := router.SetupRouter(*chatController)
+	formController := controller.NewFormController(formUsecase)
+	apiKeyController := controller.NewAPIKeyController(apiKeyUsecase)
+	usageController := controller.NewUsageController(usageUsecase)
+	submissionController := controller.NewSubmissionController(submissionUsecase)
+	webhookController := controller.NewWebhookController(webhookUsecase)
+	fieldOptionsController := controller.NewFieldOptionsController(fieldOptionsUsecase)
+	router := router.SetupRouter(*chatController, *formController, *apiKeyController, *usageController, *submissionController, *webhookController, *fieldOptionsController, router.Middleware{
+		Auth:      newAuthMiddleware(apiKeyUsecase),
+		RateLimit: newRateLimitMiddleware(),
+	})
 
 	// Start the server
 	port := "8080"
@@ -60,3 +112,371 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// newLLMClient selects the model provider from LLM_PROVIDER (gemini, openai or
+// ollama) and returns the client together with the model name.
+func newLLMClient() (usecase.LLMClient, string) {
+	provider := envString("LLM_PROVIDER", "gemini")
+	switch provider {
+	case "gemini":
+		geminiAPIKey := os.Getenv("GEMINI_API_KEY")
+		if geminiAPIKey == "" {
+			log.Fatal("GEMINI_API_KEY is not set")
+		}
+		geminiModelName := envString("GEMINI_MODEL_NAME", "gemini-2.5-flash")
+		log.Printf("Using Gemini model %s", geminiModelName)
+		retry := infrastructure.RetryPolicy{
+			MaxRetries: envInt("GEMINI_MAX_RETRIES", infrastructure.DefaultRetryPolicy.MaxRetries),
+			BaseDelay:  envDuration("GEMINI_RETRY_BASE_DELAY", infrastructure.DefaultRetryPolicy.BaseDelay),
+			MaxDelay:   envDuration("GEMINI_RETRY_MAX_DELAY", infrastructure.DefaultRetryPolicy.MaxDelay),
+		}
+		var breaker *infrastructure.CircuitBreaker
+		if threshold := envInt("GEMINI_BREAKER_THRESHOLD", 5); threshold > 0 {
+			breaker = infrastructure.NewCircuitBreaker(threshold, envDuration("GEMINI_BREAKER_COOLDOWN", 30*time.Second))
+		}
+		return infrastructure.NewGeminiClient(geminiAPIKey, geminiModelName, retry, breaker), "gemini/" + geminiModelName
+	case "openai":
+		baseURL := envString("OPENAI_BASE_URL", "https://api.openai.com/v1")
+		modelName := os.Getenv("OPENAI_MODEL_NAME")
+		if modelName == "" {
+			log.Fatal("OPENAI_MODEL_NAME is not set")
+		}
+		log.Printf("Using OpenAI-compatible model %s at %s", modelName, baseURL)
+		return infrastructure.NewOpenAIClient(baseURL, os.Getenv("OPENAI_API_KEY"), modelName), "openai/" + baseURL + "/" + modelName
+	case "ollama":
+		baseURL := envString("OLLAMA_BASE_URL", "http://localhost:11434")
+		modelName := os.Getenv("OLLAMA_MODEL_NAME")
+		if modelName == "" {
+			log.Fatal("OLLAMA_MODEL_NAME is not set")
+		}
+		log.Printf("Using Ollama model %s at %s", modelName, baseURL)
+		return infrastructure.NewOllamaClient(baseURL, modelName), "ollama/" + modelName
+	default:
+		log.Fatalf("Unknown LLM_PROVIDER %q (expected gemini, openai or ollama)", provider)
+		return nil, ""
+	}
+}
+
+// newFormCache selects where generated forms are cached from FORM_CACHE
+// (memory, disk or none). Entries live FORM_CACHE_TTL, at most
+// FORM_CACHE_MAX_ENTRIES of them.
+func newFormCache() usecase.FormCache {
+	ttl := envDuration("FORM_CACHE_TTL", 24*time.Hour)
+	maxEntries := envInt("FORM_CACHE_MAX_ENTRIES", 1000)
+	backend := envString("FORM_CACHE", "memory")
+	switch backend {
+	case "none":
+		return nil
+	case "memory":
+		log.Printf("Caching up to %d generated forms in memory for %s", maxEntries, ttl)
+		return infrastructure.NewInMemoryFormCache(maxEntries, ttl)
+	case "disk":
+		dir := envString("FORM_CACHE_DIR", "form-cache")
+		cache, err := infrastructure.NewDiskFormCache(dir, maxEntries, ttl)
+		if err != nil {
+			log.Fatalf("Failed to prepare form cache: %v", err)
+		}
+		log.Printf("Caching up to %d generated forms in %s for %s", maxEntries, dir, ttl)
+		return cache
+	default:
+		log.Fatalf("Unknown FORM_CACHE %q (expected memory, disk or none)", backend)
+		return nil
+	}
+}
+
+// dataSourceSecretPrefix starts the variables holding data source secrets:
+// authTokenRef "crm-token" is read from DATA_SOURCE_SECRET_CRM_TOKEN and only
+// sent to the hosts listed in DATA_SOURCE_SECRET_CRM_TOKEN_HOST.
+const dataSourceSecretPrefix = "DATA_SOURCE_SECRET_"
+
+// newDataSourcePolicy lets data sources call DATA_SOURCE_ALLOWED_HOSTS and
+// the origin in DATA_SOURCE_BASE_URL, which relative endpoints resolve
+// against, and send secrets to the hosts they are bound to. Nothing else is
+// allowed.
+func newDataSourcePolicy(secrets *infrastructure.EnvSecretResolver) validation.DataSourcePolicy {
+	policy := validation.DataSourcePolicy{
+		AllowedHosts: envList("DATA_SOURCE_ALLOWED_HOSTS"),
+		SecretHosts:  secrets.Hosts,
+	}
+	if raw := os.Getenv("DATA_SOURCE_BASE_URL"); raw != "" {
+		base, err := url.Parse(raw)
+		if err != nil || !base.IsAbs() {
+			log.Fatalf("DATA_SOURCE_BASE_URL must be an absolute URL, got %q", raw)
+		}
+		policy.BaseURL = base
+	}
+	return policy
+}
+
+// newDataSourceFetcher refuses connections to internal addresses, except to
+// the application's own origin.
+func newDataSourceFetcher(policy validation.DataSourcePolicy) *infrastructure.HTTPDataSourceFetcher {
+	if policy.BaseURL == nil {
+		return infrastructure.NewHTTPDataSourceFetcher()
+	}
+	return infrastructure.NewHTTPDataSourceFetcher(policy.BaseURL.Hostname())
+}
+
+// newOptionsCache keeps up to DATA_SOURCE_CACHE_MAX_ENTRIES data source
+// responses in memory, each for the cacheTtlMs of its data source; 0
+// disables caching.
+func newOptionsCache() usecase.OptionsCache {
+	maxEntries := envInt("DATA_SOURCE_CACHE_MAX_ENTRIES", 1000)
+	if maxEntries == 0 {
+		return nil
+	}
+	return infrastructure.NewInMemoryOptionsCache(maxEntries)
+}
+
+// newPromptRegistry loads the embedded prompt templates plus the version
+// directories of PROMPT_TEMPLATE_DIR, if set, which add versions or replace
+// embedded ones. PROMPT_TEMPLATE_VERSION selects the default version and
+// PROMPT_SELECTABLE_VERSIONS lists the other versions requests may choose;
+// by default they cannot choose any.
+func newPromptRegistry() *usecase.PromptRegistry {
+	var sources []fs.FS
+	if dir := os.Getenv("PROMPT_TEMPLATE_DIR"); dir != "" {
+		sources = append(sources, os.DirFS(dir))
+	}
+	prompts, err := usecase.NewPromptRegistry(envString("PROMPT_TEMPLATE_VERSION", usecase.DefaultPromptVersion), sources...)
+	if err != nil {
+		log.Fatalf("Failed to load prompt templates: %v", err)
+	}
+	if err := prompts.SetSelectable(envList("PROMPT_SELECTABLE_VERSIONS")...); err != nil {
+		log.Fatalf("Invalid PROMPT_SELECTABLE_VERSIONS: %v", err)
+	}
+	log.Printf("Prompt templates %v loaded; default %s, selectable %v", prompts.Versions(), prompts.DefaultVersion(), prompts.SelectableVersions())
+	return prompts
+}
+
+// newFileConfig stores uploaded files in UPLOAD_DIR and signs their download
+// URLs with FILE_URL_SECRET, valid for FILE_URL_TTL. Without a secret, one
+// is generated and URLs handed out stop working on restart.
+func newFileConfig() usecase.FileConfig {
+	dir := envString("UPLOAD_DIR", "uploads")
+	blobs, err := infrastructure.NewLocalBlobStore(dir)
+	if err != nil {
+		log.Fatalf("Failed to prepare file storage: %v", err)
+	}
+	secret := []byte(os.Getenv("FILE_URL_SECRET"))
+	if len(secret) == 0 {
+		log.Println("Warning: FILE_URL_SECRET is not set; download URLs will not survive a restart")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("Failed to generate a file URL secret: %v", err)
+		}
+	}
+	log.Printf("Storing uploaded files in %s", dir)
+	return usecase.FileConfig{
+		Blobs:     blobs,
+		URLSecret: secret,
+		URLTTL:    envDuration("FILE_URL_TTL", usecase.DefaultFileURLTTL),
+	}
+}
+
+// repositories are the persistent stores of the application.
+type repositories struct {
+	forms       usecase.FormRepository
+	apiKeys     usecase.APIKeyRepository
+	usage       usecase.UsageRepository
+	submissions usecase.SubmissionRepository
+	webhooks    usecase.WebhookRepository
+}
+
+// newRepositories selects the storage from DATA_STORE (memory or sqlite).
+func newRepositories() repositories {
+	store := envString("DATA_STORE", "memory")
+	switch store {
+	case "memory":
+		log.Println("Storing data in memory; it is lost on restart")
+		return repositories{
+			forms:       infrastructure.NewInMemoryFormRepository(),
+			apiKeys:     infrastructure.NewInMemoryAPIKeyRepository(),
+			usage:       infrastructure.NewInMemoryUsageRepository(),
+			submissions: infrastructure.NewInMemorySubmissionRepository(),
+			webhooks:    infrastructure.NewInMemoryWebhookRepository(),
+		}
+	case "sqlite":
+		path := envString("SQLITE_PATH", "better-form.db")
+		db, err := infrastructure.OpenSQLite(path)
+		if err != nil {
+			log.Fatalf("Failed to open database: %v", err)
+		}
+		forms, err := infrastructure.NewSQLiteFormRepository(db)
+		if err != nil {
+			log.Fatalf("Failed to prepare form storage: %v", err)
+		}
+		apiKeys, err := infrastructure.NewSQLiteAPIKeyRepository(db)
+		if err != nil {
+			log.Fatalf("Failed to prepare API key storage: %v", err)
+		}
+		usage, err := infrastructure.NewSQLiteUsageRepository(db)
+		if err != nil {
+			log.Fatalf("Failed to prepare usage storage: %v", err)
+		}
+		submissions, err := infrastructure.NewSQLiteSubmissionRepository(db)
+		if err != nil {
+			log.Fatalf("Failed to prepare submission storage: %v", err)
+		}
+		webhooks, err := infrastructure.NewSQLiteWebhookRepository(db)
+		if err != nil {
+			log.Fatalf("Failed to prepare webhook storage: %v", err)
+		}
+		log.Printf("Storing data in SQLite database %s", path)
+		return repositories{forms: forms, apiKeys: apiKeys, usage: usage, submissions: submissions, webhooks: webhooks}
+	default:
+		log.Fatalf("Unknown DATA_STORE %q (expected memory or sqlite)", store)
+		return repositories{}
+	}
+}
+
+// newAuthMiddleware selects the accepted credentials from AUTH_MODE: jwt,
+// apikey, both or none. It defaults to jwt when a JWT key source is
+// configured and to none otherwise.
+func newAuthMiddleware(apiKeys usecase.APIKeyUseCaseInterface) gin.HandlerFunc {
+	verifier := newJWTVerifier()
+	defaultMode := "none"
+	if verifier != nil {
+		defaultMode = "jwt"
+	}
+
+	config := infrastructure.AuthConfig{AdminUsers: envList("AUTH_ADMIN_USERS")}
+	mode := envString("AUTH_MODE", defaultMode)
+	switch mode {
+	case "none":
+		log.Println("Warning: authentication is disabled; do not use AUTH_MODE=none in production")
+		return infrastructure.NoAuthMiddleware()
+	case "jwt", "apikey", "both":
+		if mode != "apikey" {
+			if verifier == nil {
+				log.Fatalf("AUTH_MODE=%s requires AUTH_JWKS_URL, AUTH_JWKS_FILE or JWT_SECRET", mode)
+			}
+			config.JWT = verifier
+		}
+		if mode != "jwt" {
+			config.APIKeys = apiKeys
+		}
+		log.Printf("Authentication mode: %s", mode)
+		return infrastructure.AuthMiddleware(config)
+	default:
+		log.Fatalf("Unknown AUTH_MODE %q (expected jwt, apikey, both or none)", mode)
+		return nil
+	}
+}
+
+// newRateLimitMiddleware limits generation per user or API key to
+// RATE_LIMIT_RPM requests per minute and DAILY_TOKEN_BUDGET model tokens per
+// day. Counters are kept in memory, or in Redis when REDIS_URL is set.
+func newRateLimitMiddleware() gin.HandlerFunc {
+	config := infrastructure.RateLimitConfig{
+		RequestsPerMinute: envInt("RATE_LIMIT_RPM", 0),
+		DailyTokenBudget:  int64(envInt("DAILY_TOKEN_BUDGET", 0)),
+	}
+	if config.RequestsPerMinute == 0 && config.DailyTokenBudget == 0 {
+		return nil
+	}
+
+	var store infrastructure.QuotaStore = infrastructure.NewInMemoryQuotaStore()
+	if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
+		client, err := infrastructure.NewRedisClient(redisURL)
+		if err != nil {
+			log.Fatalf("Failed to configure Redis: %v", err)
+		}
+		store = infrastructure.NewRedisQuotaStore(client, "better-form:")
+		log.Println("Keeping rate limit counters in Redis")
+	}
+	log.Printf("Rate limits: %d requests/minute, %d tokens/day (0 = unlimited)", config.RequestsPerMinute, config.DailyTokenBudget)
+	return infrastructure.RateLimitMiddleware(store, config)
+}
+
+// newJWTVerifier verifies bearer tokens against a JWKS (AUTH_JWKS_URL or
+// AUTH_JWKS_FILE) or a shared JWT_SECRET. It returns nil when none of them is set.
+func newJWTVerifier() *infrastructure.JWTVerifier {
+	jwtConfig := infrastructure.JWTConfig{
+		Issuer:   os.Getenv("JWT_ISSUER"),
+		Audience: os.Getenv("JWT_AUDIENCE"),
+		Leeway:   envDuration("JWT_LEEWAY", 30*time.Second),
+	}
+	refreshInterval := envDuration("AUTH_JWKS_REFRESH_INTERVAL", time.Hour)
+
+	if url := os.Getenv("AUTH_JWKS_URL"); url != "" {
+		keys := infrastructure.NewRemoteJWKS(url, refreshInterval)
+		if err := keys.Refresh(); err != nil {
+			// The auth server may start after us; keys are fetched again on demand.
+			log.Printf("Warning: %v", err)
+		}
+		log.Printf("Verifying tokens against JWKS %s", url)
+		return infrastructure.NewJWKSVerifier(keys, jwtConfig)
+	}
+	if path := os.Getenv("AUTH_JWKS_FILE"); path != "" {
+		keys := infrastructure.NewFileJWKS(path, refreshInterval)
+		if err := keys.Refresh(); err != nil {
+			log.Fatalf("Failed to load JWKS: %v", err)
+		}
+		log.Printf("Verifying tokens against JWKS file %s", path)
+		return infrastructure.NewJWKSVerifier(keys, jwtConfig)
+	}
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		log.Println("Verifying HS256 tokens signed with JWT_SECRET")
+		return infrastructure.NewHMACVerifier([]byte(secret), jwtConfig)
+	}
+	return nil
+}
+
+// envString reads a string from the environment, or returns fallback if unset.
+func envString(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// envList reads a comma-separated list from the environment.
+func envList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// envInt reads a non-negative integer from the environment, or returns fallback if unset.
+func envInt(key string, fallback int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		log.Fatalf("%s must be a non-negative integer, got %q", key, raw)
+	}
+	return value
+}
+
+// envFloat reads an optional float from the environment.
+func envFloat(key string) *float64 {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		log.Fatalf("%s must be a number, got %q", key, raw)
+	}
+	return &value
+}
+
+// envDuration reads a duration such as "90m" from the environment, or returns fallback if unset.
+func envDuration(key string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Fatalf("%s must be a duration such as 30s or 2h, got %q", key, raw)
+	}
+	return value
+}
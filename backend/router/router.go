@@ -3,6 +3,8 @@ package router
 import (
 	"better-form-doc-backend/controller"
 	_ "better-form-doc-backend/docs"
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/infrastructure"
 	"net/http"
 
 	"github.com/gin-contrib/cors"
@@ -11,31 +13,96 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// Middleware holds the configurable middleware of the API.
+type Middleware struct {
+	// Auth authenticates the /api group and sets the granted scopes.
+	Auth gin.HandlerFunc
+	// RateLimit guards the generation endpoints; nil disables it.
+	RateLimit gin.HandlerFunc
+}
+
 // SetupRouter initializes and configures all the application routes
-func SetupRouter(chatController controller.ChatController) *gin.Engine {
+func SetupRouter(
+	chatController controller.ChatController,
+	formController controller.FormController,
+	apiKeyController controller.APIKeyController,
+	usageController controller.UsageController,
+	submissionController controller.SubmissionController,
+	webhookController controller.WebhookController,
+	fieldOptionsController controller.FieldOptionsController,
+	middleware Middleware,
+) *gin.Engine {
 	router := gin.Default()
 
 	config := cors.DefaultConfig()
 	// Allow requests from your Next.js development server
 	config.AllowOrigins = []string{"http://localhost:3000"}
 	// You must also allow the headers your frontend is sending
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "X-API-Key", infrastructure.RequestIDHeader}
+	// Let the frontend read the request ID, rate limit and cache hints
+	config.ExposeHeaders = []string{
+		infrastructure.RequestIDHeader, "Retry-After", "X-Cache",
+		"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset",
+		"X-Token-Quota-Limit", "X-Token-Quota-Remaining", "X-Token-Quota-Reset",
+	}
 	// Allow credentials (cookies, etc.)
 	config.AllowCredentials = true
 	router.Use(cors.New(config))
 
+	// Every error is rendered as a controller.ErrorResponse carrying the request ID.
+	router.Use(infrastructure.RequestIDMiddleware(), controller.ErrorMiddleware())
+
 	// --- Public Routes ---
 	router.GET("/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "pong"})
 	})
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Stored forms are filled in by anyone who knows their ID.
+	router.POST("/api/submit/:formId", submissionController.Submit)
+	// Uploaded files are served to anyone holding a signed download URL.
+	router.GET("/api/submissions/:submissionId/files/:fileId", submissionController.DownloadFile)
+	// Their remote options are loaded through the server, which holds the secrets.
+	router.GET("/api/forms/:id/fields/:name/options", fieldOptionsController.GetFieldOptions)
+
 	// --- Protected Routes ---
 	api := router.Group("/api")
-	// api.Use(infrastructure.AuthMiddleware()) // Apply auth middleware to this group
+	api.Use(middleware.Auth) // Apply auth middleware to this group
 	{
 		// Add the new chat endpoint
-		api.POST("/chat", chatController.GenerateChatResponse)
+		chat := api.Group("", infrastructure.RequireScope(domain.ScopeChat))
+		if middleware.RateLimit != nil {
+			chat.Use(middleware.RateLimit)
+		}
+		chat.POST("/chat", chatController.GenerateChatResponse)
+		chat.POST("/chat/stream", chatController.StreamChatResponse)
+
+		// Saved forms
+		forms := api.Group("/forms", infrastructure.RequireScope(domain.ScopeForms))
+		forms.POST("", formController.CreateForm)
+		forms.GET("", formController.ListForms)
+		forms.GET("/:id", formController.GetForm)
+		forms.PUT("/:id", formController.UpdateForm)
+		forms.DELETE("/:id", formController.DeleteForm)
+		forms.GET("/:id/versions", formController.ListVersions)
+		forms.GET("/:id/versions/:v/diff", formController.DiffVersions)
+		forms.POST("/:id/rollback", formController.Rollback)
+		forms.GET("/:id/submissions", submissionController.ListSubmissions)
+		forms.GET("/:id/submissions/export", submissionController.ExportSubmissions)
+		forms.POST("/:id/webhooks", webhookController.CreateWebhook)
+		forms.GET("/:id/webhooks", webhookController.ListWebhooks)
+		forms.DELETE("/:id/webhooks/:webhookId", webhookController.DeleteWebhook)
+		forms.GET("/:id/webhooks/deliveries", webhookController.ListDeliveries)
+		forms.POST("/:id/webhooks/deliveries/:deliveryId/redeliver", webhookController.Redeliver)
+
+		// Token usage reports
+		api.GET("/usage", usageController.GetUsage)
+
+		// API key management
+		admin := api.Group("/admin", infrastructure.RequireScope(domain.ScopeAdmin))
+		admin.POST("/api-keys", apiKeyController.MintAPIKey)
+		admin.GET("/api-keys", apiKeyController.ListAPIKeys)
+		admin.DELETE("/api-keys/:id", apiKeyController.RevokeAPIKey)
 	}
 
 	return router
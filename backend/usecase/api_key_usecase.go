@@ -0,0 +1,161 @@
+// usecase/api_key_usecase.go
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// bootstrapKeyID identifies the admin key configured through the environment.
+const bootstrapKeyID = "bootstrap"
+
+// ErrInvalidAPIKeyInput is returned when a key cannot be minted as requested.
+var ErrInvalidAPIKeyInput = errors.New("invalid API key request")
+
+// APIKeyRepository persists API keys.
+type APIKeyRepository interface {
+	Create(key *domain.APIKey) error
+	// GetByHash returns domain.ErrAPIKeyNotFound when no key has the hash.
+	GetByHash(hash string) (*domain.APIKey, error)
+	// List returns the keys of ownerID, or every key if ownerID is empty,
+	// newest first.
+	List(ownerID string) ([]*domain.APIKey, error)
+	// Revoke marks a key as revoked at the given time. It returns
+	// domain.ErrAPIKeyNotFound when the key does not exist.
+	Revoke(id string, at time.Time) error
+}
+
+// MintAPIKeyInput describes a key to create.
+type MintAPIKeyInput struct {
+	OwnerID string
+	Name    string
+	Scopes  []string
+	// ExpiresAt is optional; nil keys do not expire.
+	ExpiresAt *time.Time
+}
+
+// MintedAPIKey is a new key. Key is the secret and is never shown again.
+type MintedAPIKey struct {
+	Key string `json:"key"`
+	domain.APIKey
+}
+
+// APIKeyUseCaseInterface defines the contract for managing and checking API keys.
+type APIKeyUseCaseInterface interface {
+	MintKey(input MintAPIKeyInput) (*MintedAPIKey, error)
+	ListKeys(ownerID string) ([]*domain.APIKey, error)
+	RevokeKey(id string) error
+	// Authenticate returns the active key matching rawKey, or domain.ErrInvalidAPIKey.
+	Authenticate(rawKey string) (*domain.APIKey, error)
+}
+
+// APIKeyUseCase is the implementation of APIKeyUseCaseInterface.
+type APIKeyUseCase struct {
+	keys APIKeyRepository
+	// bootstrapKey is an admin key taken from the configuration, so the first
+	// keys can be minted when only API keys are accepted.
+	bootstrapKey string
+}
+
+// NewAPIKeyUseCase creates a new instance of APIKeyUseCase. bootstrapKey may
+// be empty.
+func NewAPIKeyUseCase(keys APIKeyRepository, bootstrapKey string) APIKeyUseCaseInterface {
+	return &APIKeyUseCase{keys: keys, bootstrapKey: bootstrapKey}
+}
+
+// MintKey creates a key and returns it together with its secret.
+func (uc *APIKeyUseCase) MintKey(input MintAPIKeyInput) (*MintedAPIKey, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidAPIKeyInput)
+	}
+	if len(input.Scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKeyInput)
+	}
+	for _, scope := range input.Scopes {
+		if !slices.Contains(domain.Scopes, scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q (expected one of %s)", ErrInvalidAPIKeyInput, scope, strings.Join(domain.Scopes, ", "))
+		}
+	}
+	now := time.Now().UTC()
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expiry must be in the future", ErrInvalidAPIKeyInput)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	rawKey := domain.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := domain.APIKey{
+		ID:        id,
+		OwnerID:   input.OwnerID,
+		Name:      name,
+		Prefix:    rawKey[:len(domain.APIKeyPrefix)+6],
+		Hash:      hashAPIKey(rawKey),
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+		CreatedAt: now,
+	}
+	if err := uc.keys.Create(&key); err != nil {
+		return nil, fmt.Errorf("failed to save API key: %w", err)
+	}
+	return &MintedAPIKey{Key: rawKey, APIKey: key}, nil
+}
+
+// ListKeys returns the keys of ownerID, or every key if ownerID is empty.
+func (uc *APIKeyUseCase) ListKeys(ownerID string) ([]*domain.APIKey, error) {
+	return uc.keys.List(ownerID)
+}
+
+// RevokeKey revokes a key. Revoked keys stay listed for auditing.
+func (uc *APIKeyUseCase) RevokeKey(id string) error {
+	return uc.keys.Revoke(id, time.Now().UTC())
+}
+
+// Authenticate looks the key up by its hash. Keys are long random strings,
+// so a fast unsalted hash is enough to keep them safe at rest.
+func (uc *APIKeyUseCase) Authenticate(rawKey string) (*domain.APIKey, error) {
+	if uc.bootstrapKey != "" && subtle.ConstantTimeCompare([]byte(rawKey), []byte(uc.bootstrapKey)) == 1 {
+		return &domain.APIKey{
+			ID:      bootstrapKeyID,
+			OwnerID: "admin",
+			Name:    "Bootstrap admin key",
+			Scopes:  domain.Scopes,
+		}, nil
+	}
+	if !strings.HasPrefix(rawKey, domain.APIKeyPrefix) {
+		return nil, domain.ErrInvalidAPIKey
+	}
+
+	key, err := uc.keys.GetByHash(hashAPIKey(rawKey))
+	if errors.Is(err, domain.ErrAPIKeyNotFound) {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !key.Active(time.Now()) {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	return key, nil
+}
+
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/infrastructure"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMintAndAuthenticateAPIKey(t *testing.T) {
+	keys := infrastructure.NewInMemoryAPIKeyRepository()
+	uc := NewAPIKeyUseCase(keys, "")
+
+	minted, err := uc.MintKey(MintAPIKeyInput{OwnerID: "alice", Name: " CI ", Scopes: []string{domain.ScopeChat}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(minted.Key, domain.APIKeyPrefix) || !strings.HasPrefix(minted.Key, minted.Prefix) || minted.Name != "CI" {
+		t.Errorf("minted = %+v", minted)
+	}
+	// Only the hash of the secret is stored.
+	stored, err := keys.GetByHash(hashAPIKey(minted.Key))
+	if err != nil || stored.ID != minted.ID {
+		t.Fatalf("GetByHash = %+v, %v", stored, err)
+	}
+	if stored.Hash == minted.Key || strings.Contains(stored.Hash, minted.Key[len(domain.APIKeyPrefix):]) {
+		t.Errorf("stored hash %q reveals the key", stored.Hash)
+	}
+
+	key, err := uc.Authenticate(minted.Key)
+	if err != nil || key.ID != minted.ID || key.OwnerID != "alice" || len(key.Scopes) != 1 || key.Scopes[0] != domain.ScopeChat {
+		t.Errorf("Authenticate = %+v, %v", key, err)
+	}
+
+	other, _ := uc.MintKey(MintAPIKeyInput{OwnerID: "alice", Name: "Other", Scopes: []string{domain.ScopeForms}})
+	if other.Key == minted.Key || other.Hash == minted.Hash {
+		t.Error("two keys share a secret")
+	}
+	for name, rawKey := range map[string]string{
+		"unknown key":      domain.APIKeyPrefix + "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+		"altered key":      minted.Key[:len(minted.Key)-1] + "x",
+		"without prefix":   strings.TrimPrefix(minted.Key, domain.APIKeyPrefix),
+		"the stored hash":  stored.Hash,
+		"the short prefix": minted.Prefix,
+		"empty":            "",
+	} {
+		if key, err := uc.Authenticate(rawKey); !errors.Is(err, domain.ErrInvalidAPIKey) {
+			t.Errorf("%s: Authenticate = %+v, %v; want ErrInvalidAPIKey", name, key, err)
+		}
+	}
+}
+
+func TestRevokedAndExpiredAPIKeys(t *testing.T) {
+	keys := infrastructure.NewInMemoryAPIKeyRepository()
+	uc := NewAPIKeyUseCase(keys, "")
+	minted, _ := uc.MintKey(MintAPIKeyInput{OwnerID: "alice", Name: "CI", Scopes: []string{domain.ScopeChat}})
+
+	if err := uc.RevokeKey(minted.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uc.Authenticate(minted.Key); !errors.Is(err, domain.ErrInvalidAPIKey) {
+		t.Errorf("revoked key: err = %v, want ErrInvalidAPIKey", err)
+	}
+	// Revoked keys stay listed.
+	listed, err := uc.ListKeys("alice")
+	if err != nil || len(listed) != 1 || listed[0].RevokedAt == nil {
+		t.Errorf("ListKeys = %+v, %v; want the revoked key", listed, err)
+	}
+	if err := uc.RevokeKey("missing"); !errors.Is(err, domain.ErrAPIKeyNotFound) {
+		t.Errorf("RevokeKey(missing) = %v, want ErrAPIKeyNotFound", err)
+	}
+
+	// A key past its expiry is rejected like a revoked one.
+	expiresAt := time.Now().Add(time.Hour)
+	expiring, _ := uc.MintKey(MintAPIKeyInput{OwnerID: "alice", Name: "Temp", Scopes: []string{domain.ScopeChat}, ExpiresAt: &expiresAt})
+	stored, _ := keys.GetByHash(expiring.Hash)
+	past := time.Now().Add(-time.Minute)
+	stored.ExpiresAt = &past
+	_ = keys.Create(stored)
+	if _, err := uc.Authenticate(expiring.Key); !errors.Is(err, domain.ErrInvalidAPIKey) {
+		t.Errorf("expired key: err = %v, want ErrInvalidAPIKey", err)
+	}
+}
+
+func TestBootstrapAPIKey(t *testing.T) {
+	uc := NewAPIKeyUseCase(infrastructure.NewInMemoryAPIKeyRepository(), "admin-secret")
+	key, err := uc.Authenticate("admin-secret")
+	if err != nil || key.ID != bootstrapKeyID || len(key.Scopes) != len(domain.Scopes) {
+		t.Errorf("Authenticate(bootstrap) = %+v, %v; want every scope", key, err)
+	}
+	for _, rawKey := range []string{"admin-secre", "admin-secret ", "ADMIN-SECRET"} {
+		if _, err := uc.Authenticate(rawKey); !errors.Is(err, domain.ErrInvalidAPIKey) {
+			t.Errorf("Authenticate(%q) err = %v, want ErrInvalidAPIKey", rawKey, err)
+		}
+	}
+
+	// Without a configured bootstrap key, the empty key does not match it.
+	if _, err := NewAPIKeyUseCase(infrastructure.NewInMemoryAPIKeyRepository(), "").Authenticate(""); !errors.Is(err, domain.ErrInvalidAPIKey) {
+		t.Errorf("Authenticate(\"\") err = %v, want ErrInvalidAPIKey", err)
+	}
+}
+
+func TestMintAPIKeyValidation(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	tests := map[string]MintAPIKeyInput{
+		"no name":       {Name: " ", Scopes: []string{domain.ScopeChat}},
+		"no scopes":     {Name: "CI"},
+		"unknown scope": {Name: "CI", Scopes: []string{domain.ScopeChat, "root"}},
+		"expired":       {Name: "CI", Scopes: []string{domain.ScopeChat}, ExpiresAt: &past},
+	}
+	uc := NewAPIKeyUseCase(infrastructure.NewInMemoryAPIKeyRepository(), "")
+	for name, input := range tests {
+		if _, err := uc.MintKey(input); !errors.Is(err, ErrInvalidAPIKeyInput) {
+			t.Errorf("%s: err = %v, want ErrInvalidAPIKeyInput", name, err)
+		}
+	}
+}
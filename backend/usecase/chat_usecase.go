@@ -2,130 +2,410 @@
 package usecase
 
 import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/validation"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 )
 
-// GeminiClientInterface remains the same.
-type GeminiClientInterface interface {
-	GenerateContent(prompt string) (map[string]interface{}, error)
+// LLMClient is the provider-neutral seam to a language model. Implementations
+// live in the infrastructure package (Gemini, OpenAI-compatible, Ollama).
+//
+// Transient provider failures are reported as errors wrapping
+// domain.ErrRateLimited or domain.ErrUpstreamUnavailable. A response blocked
+// by safety filters is returned together with an error wrapping
+// domain.ErrSafetyBlocked, so that its usage is still accounted; so is the
+// partial response of a stream that fails midway. Calls are abandoned when
+// ctx is done.
+type LLMClient interface {
+	Generate(ctx context.Context, request domain.GenerationRequest) (*domain.GenerationResponse, error)
+	// Stream passes text chunks to onText as they are generated and returns
+	// the complete response.
+	Stream(ctx context.Context, request domain.GenerationRequest, onText func(text string)) (*domain.GenerationResponse, error)
 }
 
 // ChatUseCaseInterface defines the contract for our form generation use case.
 type ChatUseCaseInterface interface {
-	GenerateChatResponse(userPrompt string) (map[string]interface{}, error)
+	GenerateChatResponse(ctx context.Context, input ChatInput) (*GenerationResult, error)
+	StreamChatResponse(ctx context.Context, input ChatInput, events StreamEvents) (*GenerationResult, error)
+}
+
+// StreamEvents receives progress while a form is generated in streaming mode.
+// Nil callbacks are ignored.
+type StreamEvents struct {
+	// OnToken is called with every partial chunk of model output.
+	OnToken func(text string)
+	// OnRepair is called before an invalid answer is sent back for repair.
+	OnRepair func(attempt int, issues validation.Issues)
+}
+
+// GenerationResult is a validated form together with metadata about how it
+// was produced.
+type GenerationResult struct {
+	ConversationID string             `json:"conversationId"`
+	Form           *domain.FormConfig `json:"form"`
+	// Changes is set for refinements and describes the edit to the previous form.
+	Changes *FormChanges `json:"changes,omitempty"`
+	// FormVersion is the version created when a stored form was refined.
+	FormVersion int                `json:"formVersion,omitempty"`
+	Metadata    GenerationMetadata `json:"metadata"`
+}
+
+// GenerationMetadata describes how a form was generated.
+type GenerationMetadata struct {
+	// RepairAttempts is the number of extra model calls needed to turn an
+	// invalid answer into a valid form. Zero means the first answer was valid.
+	RepairAttempts int `json:"repairAttempts"`
+	// Usage is the token consumption of every model call of the request.
+	Usage domain.TokenUsage `json:"usage"`
+	// Model is the model version that produced the form.
+	Model string `json:"model,omitempty"`
+	// FinishReason is why the model stopped, e.g. STOP or MAX_TOKENS.
+	FinishReason string `json:"finishReason,omitempty"`
+	// SafetyRatings are the provider's safety assessments of the answer.
+	SafetyRatings []domain.SafetyRating `json:"safetyRatings,omitempty"`
+	// Cache is HIT when the form was served from the cache and MISS when it
+	// was generated and cached; empty when the request is not cacheable.
+	Cache string `json:"cache,omitempty"`
+	// PromptVersion identifies the prompt templates that produced the form.
+	PromptVersion string `json:"promptVersion"`
+}
+
+// InvalidFormError is returned when the model output is not a valid FormConfig.
+// Issues carries every problem found, addressed by JSON path.
+type InvalidFormError struct {
+	Issues validation.Issues
+	// RepairAttempts is the number of repair rounds tried before giving up.
+	RepairAttempts int
+	// Usage is the token consumption of every model call of the request.
+	Usage domain.TokenUsage
+}
+
+func (e *InvalidFormError) Error() string {
+	return "invalid form config: " + e.Issues.Error()
+}
+
+// ErrIrrelevantPrompt is returned when the model decides the request is not about a form.
+var ErrIrrelevantPrompt = errors.New("irrelevant prompt: please describe the form you want to build")
+
+// ErrModelOutputInvalid is returned when the model's answer is not a usable
+// form even after the repair attempts. It wraps an *InvalidFormError when
+// the answer could be parsed.
+var ErrModelOutputInvalid = errors.New("model output invalid")
+
+// DefaultMaxRepairAttempts is used when FormGeneratorConfig.MaxRepairAttempts is negative.
+const DefaultMaxRepairAttempts = 2
+
+// FormGeneratorConfig tunes how forms are generated.
+type FormGeneratorConfig struct {
+	// MaxRepairAttempts bounds how many times an invalid answer is sent back to
+	// the model for correction; 0 disables the repair loop.
+	MaxRepairAttempts int
+
+	// Sampling parameters forwarded to the model; nil or zero use its defaults.
+	Temperature     *float64
+	TopP            *float64
+	MaxOutputTokens int
+
+	// RequestTimeout bounds a whole generation, repairs included; CallTimeout
+	// bounds a single non-streaming model call. Zero means no deadline besides
+	// the caller's context.
+	RequestTimeout time.Duration
+	CallTimeout    time.Duration
+
+	// ModelName identifies the model in cache keys.
+	ModelName string
+
+	// Prompts holds the prompt templates; nil uses the embedded ones with
+	// DefaultPromptVersion as the default.
+	Prompts *PromptRegistry
+
+	// MaxPromptLength bounds the characters of a user message; zero uses
+	// DefaultMaxPromptLength.
+	MaxPromptLength int
+	// OutputPolicy is checked on every generated form, in addition to the
+	// form validation; violations are repaired like validation issues.
+	OutputPolicy validation.OutputPolicy
 }
 
 // FormGeneratorUseCase is the new implementation.
 type FormGeneratorUseCase struct {
-	geminiClient GeminiClientInterface
+	llmClient     LLMClient
+	conversations ConversationStore
+	forms         FormUseCaseInterface
+	usage         UsageRepository
+	cache         FormCache
+	config        FormGeneratorConfig
 }
 
-// NewChatUseCase creates a new instance of FormGeneratorUseCase.
-func NewChatUseCase(geminiClient GeminiClientInterface) ChatUseCaseInterface {
+// NewChatUseCase creates a new instance of FormGeneratorUseCase. conversations
+// may be nil, in which case every request must carry its own history; forms
+// may be nil when stored forms cannot be refined; usage may be nil to skip
+// usage accounting; cache may be nil to always call the model.
+func NewChatUseCase(
+	llmClient LLMClient,
+	conversations ConversationStore,
+	forms FormUseCaseInterface,
+	usage UsageRepository,
+	cache FormCache,
+	config FormGeneratorConfig,
+) ChatUseCaseInterface {
+	if config.MaxRepairAttempts < 0 {
+		config.MaxRepairAttempts = DefaultMaxRepairAttempts
+	}
+	if config.MaxPromptLength == 0 {
+		config.MaxPromptLength = DefaultMaxPromptLength
+	}
+	if config.Prompts == nil {
+		prompts, err := NewPromptRegistry("")
+		if err != nil {
+			// The embedded templates are part of the binary.
+			panic(err)
+		}
+		config.Prompts = prompts
+	}
 	return &FormGeneratorUseCase{
-		geminiClient: geminiClient,
+		llmClient:     llmClient,
+		conversations: conversations,
+		forms:         forms,
+		usage:         usage,
+		cache:         cache,
+		config:        config,
 	}
 }
 
 // GenerateChatResponse contains the core logic for the form generation feature.
-func (uc *FormGeneratorUseCase) GenerateChatResponse(userPrompt string) (map[string]interface{}, error) {
-	// 1. Construct the full, detailed prompt using the template.
-	fullPrompt := fmt.Sprintf(formGenerationPrompt, userPrompt)
+func (uc *FormGeneratorUseCase) GenerateChatResponse(ctx context.Context, input ChatInput) (*GenerationResult, error) {
+	return uc.generate(ctx, input, func(ctx context.Context, request domain.GenerationRequest) (*domain.GenerationResponse, error) {
+		if uc.config.CallTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, uc.config.CallTimeout)
+			defer cancel()
+		}
+		// Call the infrastructure layer (LLM client) to get the AI response.
+		return uc.llmClient.Generate(ctx, request)
+	}, nil)
+}
 
-	// 2. Call the infrastructure layer (Gemini client) to get the AI response.
-	rawResponse, err := uc.geminiClient.GenerateContent(fullPrompt)
-	if err != nil {
-		return nil, fmt.Errorf("error from Gemini client: %w", err)
+// StreamChatResponse generates a form like GenerateChatResponse but reports
+// the model output chunk by chunk while it is produced.
+func (uc *FormGeneratorUseCase) StreamChatResponse(ctx context.Context, input ChatInput, events StreamEvents) (*GenerationResult, error) {
+	onToken := events.OnToken
+	if onToken == nil {
+		onToken = func(string) {}
+	}
+	return uc.generate(ctx, input, func(ctx context.Context, request domain.GenerationRequest) (*domain.GenerationResponse, error) {
+		return uc.llmClient.Stream(ctx, request, onToken)
+	}, events.OnRepair)
+}
+
+// generate runs the generate-validate-repair loop. callModel performs a single
+// model call and returns its JSON text.
+func (uc *FormGeneratorUseCase) generate(
+	ctx context.Context,
+	input ChatInput,
+	callModel func(ctx context.Context, request domain.GenerationRequest) (*domain.GenerationResponse, error),
+	onRepair func(attempt int, issues validation.Issues),
+) (result *GenerationResult, err error) {
+	if uc.config.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, uc.config.RequestTimeout)
+		defer cancel()
 	}
 
-	parsedJSON, err := extractAndParseJSON(rawResponse)
+	// 1. The master prompt goes into the system instruction, prior turns are
+	// replayed with their roles and the user's request is the last message.
+	// Structured output guarantees the answer is either a FormConfig or the
+	// IrrelevantPrompt error object. User text never becomes part of the
+	// system instruction.
+	if input, err = uc.sanitizeInput(input); err != nil {
+		return nil, err
+	}
+	turn, err := uc.startTurn(input)
+	if err != nil {
+		return nil, err
+	}
+	// A new form asked for before is served from the cache.
+	var cacheKey string
+	if uc.cacheable(turn) {
+		cacheKey = uc.cacheKey(input.Prompt, turn.prompts)
+		if entry := uc.cachedEntry(cacheKey, input.BypassCache); entry != nil {
+			if err := uc.finishTurn(turn, input.Prompt, entry.Form); err != nil {
+				return nil, err
+			}
+			return &GenerationResult{
+				ConversationID: turn.id,
+				Form:           entry.Form,
+				Metadata:       GenerationMetadata{Model: entry.Model, Cache: CacheHit, PromptVersion: turn.prompts.Version},
+			}, nil
+		}
+	}
+	request, err := uc.newGenerationRequest(turn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to process AI response: %w", err)
+		return nil, err
 	}
 
-	// --- VALIDATION LOGIC STARTS HERE ---
+	// Every model call is billed, including failed and repaired ones.
+	meter := &usageMeter{}
+	defer func() {
+		uc.recordUsage(input.UserID, meter, err)
+		if err != nil && meter.calls > 0 {
+			err = &UsageError{Err: err, Usage: meter.usage}
+		}
+	}()
 
-	// 1. Check if the AI returned our specific error object
-	if errVal, ok := parsedJSON["error"]; ok {
-		if errType, isString := errVal.(string); isString && errType == "IrrelevantPrompt" {
-			// The AI has correctly identified an irrelevant prompt.
-			// We can return a specific, user-friendly error from our API.
-			return nil, errors.New("irrelevant prompt: please describe the form you want to build")
+	for attempt := 0; ; attempt++ {
+		// 2. Ask the model for a form.
+		response, err := callModel(ctx, request)
+		if response != nil {
+			meter.add(response)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error from LLM client: %w", err)
+		}
+		// With a JSON response format the text is bare JSON.
+		jsonText := strings.TrimSpace(response.Text)
+		if jsonText == "" {
+			return nil, fmt.Errorf("%w: no text content found (finish reason %q)", ErrModelOutputInvalid, response.FinishReason)
 		}
-	}
 
-	// 2. Check for the essential fields of a valid FormConfig
-	// A valid form config MUST have 'fields' and 'submit'.
-	if _, hasFields := parsedJSON["fields"]; !hasFields {
-		return nil, errors.New("invalid form config: missing 'fields' property")
-	}
-	if _, hasSubmit := parsedJSON["submit"]; !hasSubmit {
-		return nil, errors.New("invalid form config: missing 'submit' property")
+		formConfig, err := parseGeneratedForm(jsonText, uc.config.OutputPolicy)
+		var invalidForm *InvalidFormError
+		if errors.As(err, &invalidForm) {
+			invalidForm.RepairAttempts = attempt
+			invalidForm.Usage = meter.usage
+			if attempt >= uc.config.MaxRepairAttempts {
+				return nil, fmt.Errorf("%w: %w", ErrModelOutputInvalid, invalidForm)
+			}
+			if onRepair != nil {
+				onRepair(attempt+1, invalidForm.Issues)
+			}
+			// 3. Send the bad answer back together with the problems found and
+			// ask the model for a corrected form.
+			repairPrompt, err := turn.prompts.RepairPrompt(turn.currentForm, turn.request, jsonText, formatIssues(invalidForm.Issues))
+			if err != nil {
+				return nil, err
+			}
+			request.Messages[len(request.Messages)-1].Content = repairPrompt
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := uc.finishTurn(turn, input.Prompt, formConfig); err != nil {
+			return nil, err
+		}
+
+		result := &GenerationResult{
+			ConversationID: turn.id,
+			Form:           formConfig,
+			Metadata: GenerationMetadata{
+				RepairAttempts: attempt,
+				Usage:          meter.usage,
+				Model:          meter.model,
+				FinishReason:   meter.finishReason,
+				SafetyRatings:  meter.safetyRatings,
+				PromptVersion:  turn.prompts.Version,
+			},
+		}
+		if turn.previousForm != nil {
+			if result.Changes, err = diffForms(turn.previousForm, formConfig); err != nil {
+				return nil, err
+			}
+		}
+		if cacheKey != "" {
+			uc.storeCachedForm(cacheKey, cachedForm{Form: formConfig, Model: meter.model})
+			result.Metadata.Cache = CacheMiss
+		}
+		if turn.formID != "" {
+			// 4. Every refinement of a stored form becomes a new version.
+			stored, err := uc.forms.SaveRefinement(input.UserID, turn.formID, formConfig, input.Prompt, turn.prompts.Version)
+			if err != nil {
+				return nil, err
+			}
+			result.FormVersion = stored.Version
+		}
+		return result, nil
 	}
+}
 
-	// --- VALIDATION LOGIC ENDS HERE ---
+// newGenerationRequest builds the structured-output request for a chat turn.
+func (uc *FormGeneratorUseCase) newGenerationRequest(turn *conversationTurn) (domain.GenerationRequest, error) {
+	systemPrompt, err := turn.prompts.SystemPrompt()
+	if err != nil {
+		return domain.GenerationRequest{}, err
+	}
+	messages := make([]domain.Message, 0, len(turn.history)+2)
+	messages = append(messages, domain.Message{Role: domain.RoleSystem, Content: systemPrompt})
+	messages = append(messages, turn.history...)
+	messages = append(messages, domain.Message{Role: domain.RoleUser, Content: turn.userMessage})
 
-	// If we passed the checks, it's likely a valid FormConfig
-	return parsedJSON, nil
+	return domain.GenerationRequest{
+		Messages:         messages,
+		ResponseMimeType: "application/json",
+		ResponseSchema: &domain.Schema{
+			AnyOf: []*domain.Schema{domain.FormConfigSchema(), domain.IrrelevantPromptSchema()},
+		},
+		Temperature:     uc.config.Temperature,
+		TopP:            uc.config.TopP,
+		MaxOutputTokens: uc.config.MaxOutputTokens,
+	}, nil
 }
 
-// extractAndParseJSON is a helper function to robustly find and parse the JSON
-// from the AI's potentially messy output.
-func extractAndParseJSON(rawResponse map[string]interface{}) (map[string]interface{}, error) {
-	// This logic depends heavily on the actual response structure from your GeminiClient.
-	// Let's assume the client might return something like:
-	// { "candidates": [ { "content": { "parts": [ { "text": "```json\n{...}\n```" } ] } } ] }
-	// We need to navigate this structure to get to the "text".
+// parseGeneratedForm turns the model's JSON text into a validated FormConfig
+// that complies with policy. Problems the model could fix are reported as
+// *InvalidFormError.
+func parseGeneratedForm(jsonText string, policy validation.OutputPolicy) (*domain.FormConfig, error) {
+	// 1. Check if the AI returned our specific error object
+	var errorProbe struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(jsonText), &errorProbe); err == nil && errorProbe.Error == "IrrelevantPrompt" {
+		// The AI has correctly identified an irrelevant prompt.
+		// We can return a specific, user-friendly error from our API.
+		return nil, ErrIrrelevantPrompt
+	}
 
-	text, ok := findNestedString(rawResponse, "text")
-	if !ok || text == "" {
-		return nil, errors.New("no text content found in AI response")
+	// 2. Strictly decode the output into a typed FormConfig. Unknown properties
+	// and unsupported enum values are rejected here; maps written as lists of
+	// {key, value} entries, as the response schema describes them, are
+	// accepted.
+	formConfig, err := domain.ParseGeneratedFormConfig([]byte(jsonText))
+	if err != nil {
+		return nil, &InvalidFormError{Issues: validation.Issues{validation.DecodeIssue(err)}}
 	}
 
-	// The AI might wrap the JSON in markdown code blocks (```json ... ```). Let's strip them.
-	// Trim whitespace
-	cleanedText := strings.TrimSpace(text)
-	// Trim ```json prefix
-	cleanedText = strings.TrimPrefix(cleanedText, "```json")
-	// Trim ``` suffix
-	cleanedText = strings.TrimSuffix(cleanedText, "```")
-	// Trim any remaining whitespace
-	cleanedText = strings.TrimSpace(cleanedText)
+	// Apply the mandatory JSON content type when the model left the headers
+	// out (see rule 3 of the master prompt).
+	if formConfig.Headers == nil {
+		formConfig.Headers = map[string]string{"Content-Type": "application/json"}
+	}
 
-	var formConfig map[string]interface{}
-	err := json.Unmarshal([]byte(cleanedText), &formConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse JSON from AI output: %w. Raw text: %s", err, cleanedText)
+	// 3. Run the semantic checks (references, ranges, naming, endpoint...)
+	// and the output policy, which rejects what a manipulated request could
+	// have slipped in: external endpoints and credentials in headers.
+	issues := append(validation.ValidateFormConfig(formConfig), policy.Check(formConfig)...)
+	if len(issues) > 0 {
+		return nil, &InvalidFormError{Issues: issues}
 	}
 
 	return formConfig, nil
 }
 
-// findNestedString is a utility to search for a key in a nested map.
-func findNestedString(data map[string]interface{}, key string) (string, bool) {
-	if val, ok := data[key]; ok {
-		if strVal, ok := val.(string); ok {
-			return strVal, true
-		}
-	}
-	for _, v := range data {
-		if nestedMap, ok := v.(map[string]interface{}); ok {
-			if strVal, found := findNestedString(nestedMap, key); found {
-				return strVal, true
-			}
-		}
-		if nestedSlice, ok := v.([]interface{}); ok {
-			for _, item := range nestedSlice {
-				if itemMap, ok := item.(map[string]interface{}); ok {
-					if strVal, found := findNestedString(itemMap, key); found {
-						return strVal, true
-					}
-				}
-			}
+// formatIssues renders validation issues as a bullet list for the repair prompt.
+func formatIssues(issues validation.Issues) string {
+	var sb strings.Builder
+	for _, issue := range issues {
+		sb.WriteString("- ")
+		if len(issue.Path) > 0 {
+			sb.WriteString(issue.Path.String() + ": ")
 		}
+		sb.WriteString(issue.Message + "\n")
 	}
-	return "", false
+	return sb.String()
 }
@@ -0,0 +1,258 @@
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/validation"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRepairLoop(t *testing.T) {
+	const (
+		notJSON         = `{"title":"Login",`
+		missingEndpoint = `{"title":"Login","endpoint":"","submit":{"label":"Log in"},"fields":[{"name":"email","type":"email"}]}`
+		credentials     = `{"title":"Login","endpoint":"/api/login","headers":[{"key":"Authorization","value":"Bearer abc"}],"submit":{"label":"Log in"},"fields":[{"name":"email","type":"email"}]}`
+		irrelevant      = `{"error":"IrrelevantPrompt"}`
+	)
+	tests := []struct {
+		name          string
+		answers       []string
+		maxRepairs    int
+		wantCalls     int
+		wantRepairs   int
+		wantErr       error
+		wantProblem   string
+		wantIssueCode string
+	}{
+		{"valid answer", []string{testGeneratedForm}, 2, 1, 0, nil, "", ""},
+		{"invalid JSON repaired", []string{notJSON, testGeneratedForm}, 2, 2, 1, nil, "", validation.CodeInvalidJSON},
+		{"invalid form repaired", []string{missingEndpoint, missingEndpoint, testGeneratedForm}, 2, 3, 2, nil, "endpoint: ", validation.CodeRequired},
+		{"policy violation repaired", []string{credentials, testGeneratedForm}, 2, 2, 1, nil, "headers.Authorization: ", validation.CodeCredentialHeader},
+		{"repairs used up", []string{missingEndpoint}, 2, 3, 2, ErrModelOutputInvalid, "", validation.CodeRequired},
+		{"repairs disabled", []string{missingEndpoint, testGeneratedForm}, 0, 1, 0, ErrModelOutputInvalid, "", validation.CodeRequired},
+		{"irrelevant prompt", []string{irrelevant, testGeneratedForm}, 2, 1, 0, ErrIrrelevantPrompt, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := &fakeLLM{answers: tt.answers}
+			uc := NewChatUseCase(llm, nil, nil, nil, nil, FormGeneratorConfig{MaxRepairAttempts: tt.maxRepairs})
+			var repairs []int
+			result, err := uc.StreamChatResponse(context.Background(), ChatInput{Prompt: "a login form"}, StreamEvents{
+				OnRepair: func(attempt int, issues validation.Issues) {
+					repairs = append(repairs, attempt)
+					if len(issues) == 0 || issues[0].Code != tt.wantIssueCode {
+						t.Errorf("repair %d issues = %v, want %s", attempt, issues, tt.wantIssueCode)
+					}
+				},
+			})
+			if llm.calls() != tt.wantCalls || len(repairs) != tt.wantRepairs {
+				t.Errorf("%d calls and repairs %v, want %d calls and %d repairs", llm.calls(), repairs, tt.wantCalls, tt.wantRepairs)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				var invalidForm *InvalidFormError
+				if errors.As(err, &invalidForm) && invalidForm.RepairAttempts != tt.wantRepairs {
+					t.Errorf("RepairAttempts = %d, want %d", invalidForm.RepairAttempts, tt.wantRepairs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Metadata.RepairAttempts != tt.wantRepairs {
+				t.Errorf("RepairAttempts = %d, want %d", result.Metadata.RepairAttempts, tt.wantRepairs)
+			}
+
+			// Repairs replace the user message with the bad answer and its
+			// problems; the system instruction and history stay.
+			for i := 1; i < len(llm.requests); i++ {
+				messages := llm.requests[i].Messages
+				repair := messages[len(messages)-1].Content
+				for _, want := range []string{"a login form", tt.answers[i-1], tt.wantProblem} {
+					if !strings.Contains(repair, want) {
+						t.Errorf("repair prompt %d does not mention %q:\n%s", i, want, repair)
+					}
+				}
+				if len(messages) != len(llm.requests[0].Messages) || messages[0] != llm.requests[0].Messages[0] {
+					t.Errorf("repair request %d changed the conversation", i)
+				}
+			}
+		})
+	}
+}
+
+func TestRepairPromptOfRefinement(t *testing.T) {
+	llm := &fakeLLM{answers: []string{`{"title":"Login"}`, testGeneratedForm}}
+	uc := NewChatUseCase(llm, nil, nil, nil, nil, FormGeneratorConfig{MaxRepairAttempts: 1})
+	current := mustParseForm(t, `{"title":"Contact","endpoint":"/api/contact","submit":{"label":"Send"},"fields":[{"name":"name","type":"text"}]}`)
+	if _, err := uc.GenerateChatResponse(context.Background(), ChatInput{Prompt: "rename it to Login", CurrentForm: current}); err != nil {
+		t.Fatal(err)
+	}
+	messages := llm.requests[1].Messages
+	repair := messages[len(messages)-1].Content
+	if !strings.Contains(repair, `"title": "Contact"`) || strings.Count(repair, "<user_request>") != 1 {
+		t.Errorf("repair prompt of a refinement:\n%s", repair)
+	}
+}
+
+type contextKey string
+
+// blockingLLM answers only when its context is done, with the context error.
+// It records the deadline and the "request" value of every call's context.
+type blockingLLM struct {
+	mu        sync.Mutex
+	deadlines []time.Time
+	values    []interface{}
+}
+
+func (b *blockingLLM) Generate(ctx context.Context, _ domain.GenerationRequest) (*domain.GenerationResponse, error) {
+	b.mu.Lock()
+	deadline, _ := ctx.Deadline()
+	b.deadlines = append(b.deadlines, deadline)
+	b.values = append(b.values, ctx.Value(contextKey("request")))
+	b.mu.Unlock()
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (b *blockingLLM) Stream(ctx context.Context, request domain.GenerationRequest, _ func(text string)) (*domain.GenerationResponse, error) {
+	return b.Generate(ctx, request)
+}
+
+func TestGenerationDeadlines(t *testing.T) {
+	tests := []struct {
+		name         string
+		stream       bool
+		config       FormGeneratorConfig
+		wantDeadline time.Duration
+	}{
+		{"request timeout", false, FormGeneratorConfig{RequestTimeout: 30 * time.Millisecond}, 30 * time.Millisecond},
+		{"call timeout", false, FormGeneratorConfig{RequestTimeout: time.Minute, CallTimeout: 30 * time.Millisecond}, 30 * time.Millisecond},
+		// A stream sends tokens for as long as it runs; only the whole request is bounded.
+		{"stream ignores the call timeout", true, FormGeneratorConfig{RequestTimeout: 50 * time.Millisecond, CallTimeout: 10 * time.Millisecond}, 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := &blockingLLM{}
+			uc := NewChatUseCase(llm, nil, nil, nil, nil, tt.config)
+			start := time.Now()
+			var err error
+			if tt.stream {
+				_, err = uc.StreamChatResponse(context.Background(), ChatInput{Prompt: "a login form"}, StreamEvents{})
+			} else {
+				_, err = uc.GenerateChatResponse(context.Background(), ChatInput{Prompt: "a login form"})
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+			}
+			if len(llm.deadlines) != 1 {
+				t.Fatalf("%d calls, want 1", len(llm.deadlines))
+			}
+			if got := llm.deadlines[0].Sub(start); got < tt.wantDeadline || got > tt.wantDeadline+20*time.Millisecond {
+				t.Errorf("call deadline in %v, want %v", got, tt.wantDeadline)
+			}
+		})
+	}
+}
+
+func TestGenerationWithoutDeadlines(t *testing.T) {
+	llm := &fakeLLM{answers: []string{testGeneratedForm}}
+	var deadline bool
+	uc := NewChatUseCase(&deadlineProbe{LLMClient: llm, deadline: &deadline}, nil, nil, nil, nil, FormGeneratorConfig{})
+	if _, err := uc.GenerateChatResponse(context.Background(), ChatInput{Prompt: "a login form"}); err != nil {
+		t.Fatal(err)
+	}
+	if deadline {
+		t.Error("the model call has a deadline, want none without timeouts")
+	}
+}
+
+// deadlineProbe tells whether the context of a Generate call has a deadline.
+type deadlineProbe struct {
+	LLMClient
+	deadline *bool
+}
+
+func (p *deadlineProbe) Generate(ctx context.Context, request domain.GenerationRequest) (*domain.GenerationResponse, error) {
+	_, *p.deadline = ctx.Deadline()
+	return p.LLMClient.Generate(ctx, request)
+}
+
+func TestCanceledGeneration(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%t", stream), func(t *testing.T) {
+			llm := &blockingLLM{}
+			uc := NewChatUseCase(llm, nil, nil, nil, nil, FormGeneratorConfig{RequestTimeout: time.Minute, CallTimeout: time.Minute})
+			ctx, cancel := context.WithCancel(context.WithValue(context.Background(), contextKey("request"), "req-1"))
+			time.AfterFunc(10*time.Millisecond, cancel)
+
+			var err error
+			if stream {
+				_, err = uc.StreamChatResponse(ctx, ChatInput{Prompt: "a login form"}, StreamEvents{})
+			} else {
+				_, err = uc.GenerateChatResponse(ctx, ChatInput{Prompt: "a login form"})
+			}
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("err = %v, want context.Canceled", err)
+			}
+			// The model call runs in the caller's context.
+			if len(llm.values) != 1 || llm.values[0] != "req-1" {
+				t.Errorf("model calls saw %v, want the request context once", llm.values)
+			}
+		})
+	}
+}
+
+func TestCanceledRepairIsNotInvalidOutput(t *testing.T) {
+	const missingEndpoint = `{"title":"Login","endpoint":"","submit":{"label":"Log in"},"fields":[{"name":"email","type":"email"}]}`
+	llm := &fakeLLM{answers: []string{missingEndpoint, testGeneratedForm}}
+	uc := NewChatUseCase(llm, nil, nil, nil, nil, FormGeneratorConfig{MaxRepairAttempts: 2})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The client goes away while the first answer is repaired.
+	_, err := uc.StreamChatResponse(ctx, ChatInput{Prompt: "a login form"}, StreamEvents{
+		OnRepair: func(int, validation.Issues) { cancel() },
+	})
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrModelOutputInvalid) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if llm.calls() > 2 {
+		t.Errorf("%d calls, want no call after the cancellation", llm.calls())
+	}
+}
+
+func TestStreamReportsEveryAttempt(t *testing.T) {
+	const missingEndpoint = `{"title":"Login","endpoint":"","submit":{"label":"Log in"},"fields":[{"name":"email","type":"email"}]}`
+	llm := &fakeLLM{answers: []string{missingEndpoint, testGeneratedForm}}
+	uc := NewChatUseCase(llm, nil, nil, nil, nil, FormGeneratorConfig{MaxRepairAttempts: 1})
+
+	// Tokens of the rejected answer come before the repair event.
+	var events []string
+	result, err := uc.StreamChatResponse(context.Background(), ChatInput{Prompt: "a login form"}, StreamEvents{
+		OnToken:  func(text string) { events = append(events, text) },
+		OnRepair: func(attempt int, _ validation.Issues) { events = append(events, fmt.Sprintf("repair %d", attempt)) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected, repaired := len(missingEndpoint)/2, len(testGeneratedForm)/2
+	want := []string{
+		missingEndpoint[:rejected], missingEndpoint[rejected:],
+		"repair 1",
+		testGeneratedForm[:repaired], testGeneratedForm[repaired:],
+	}
+	if strings.Join(events, "|") != strings.Join(want, "|") {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+	if result.Form == nil || result.Form.Title != "Login" || result.Metadata.RepairAttempts != 1 {
+		t.Errorf("result = %+v, want the repaired form", result)
+	}
+}
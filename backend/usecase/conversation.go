@@ -0,0 +1,205 @@
+// usecase/conversation.go
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/jsonpatch"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// maxHistoryMessages bounds how many prior turns are replayed to the model.
+const maxHistoryMessages = 20
+
+// ErrInvalidHistory is returned when client-supplied history contains a
+// message that is neither a user nor an assistant turn.
+var ErrInvalidHistory = errors.New("invalid history: only user and assistant messages are allowed")
+
+// ErrInvalidPatch is returned when a client-supplied patch cannot be applied
+// or does not produce a well-formed FormConfig.
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// ChatInput is a single turn of a form-building conversation.
+type ChatInput struct {
+	// Prompt is the user's new message.
+	Prompt string
+	// ConversationID continues a stored conversation. Empty starts a new one.
+	ConversationID string
+	// History replaces the stored turns when the client keeps its own history.
+	History []domain.Message
+	// CurrentForm is the form being refined, e.g. after manual edits.
+	CurrentForm *domain.FormConfig
+	// FormID refines a stored form: its current config is the form being
+	// refined (unless CurrentForm is set) and the result is saved as a new version.
+	FormID string
+	// UserID is the caller; stored forms are looked up on their behalf.
+	UserID string
+	// Patch holds user edits applied server-side to the form being refined
+	// (CurrentForm, or else the last form of the conversation) before the model
+	// is called.
+	Patch jsonpatch.Patch
+	// BypassCache skips the lookup of a cached form; the fresh form is
+	// still cached.
+	BypassCache bool
+	// PromptVersion selects the prompt templates; empty uses the deployment
+	// default. Only versions the registry lets requests select are accepted.
+	PromptVersion string
+}
+
+// ConversationStore keeps the turns of a conversation between requests.
+// Conversations are kept per user: the same ID used by another user names a
+// different conversation, so nobody can read or overwrite someone else's.
+type ConversationStore interface {
+	Get(userID, id string) ([]domain.Message, bool)
+	Save(userID, id string, messages []domain.Message)
+}
+
+// conversationTurn is the state a single chat turn works with.
+type conversationTurn struct {
+	id      string
+	userID  string
+	history []domain.Message
+	// request is the user's text of this turn.
+	request string
+	// currentForm is the JSON of the form being edited, if one was supplied.
+	currentForm string
+	// userMessage is the final user message sent to the model; it embeds the
+	// current form when one was supplied.
+	userMessage string
+	// previousForm is the form being refined, or nil for a new form.
+	previousForm *domain.FormConfig
+	// formID is the stored form the result is saved to, if any.
+	formID string
+	// prompts are the templates the turn is generated with.
+	prompts *PromptTemplate
+}
+
+// startTurn resolves the conversation history and builds the user message.
+func (uc *FormGeneratorUseCase) startTurn(input ChatInput) (*conversationTurn, error) {
+	prompts, err := uc.config.Prompts.Select(input.PromptVersion)
+	if err != nil {
+		return nil, err
+	}
+	turn := &conversationTurn{id: input.ConversationID, userID: input.UserID, request: input.Prompt, userMessage: input.Prompt, prompts: prompts}
+
+	switch {
+	case len(input.History) > 0:
+		for _, message := range input.History {
+			if message.Role != domain.RoleUser && message.Role != domain.RoleAssistant {
+				return nil, ErrInvalidHistory
+			}
+		}
+		turn.history = input.History
+	case turn.id != "" && uc.conversations != nil:
+		turn.history, _ = uc.conversations.Get(turn.userID, turn.id)
+	}
+	if turn.id == "" {
+		id, err := newID()
+		if err != nil {
+			return nil, err
+		}
+		turn.id = id
+	}
+	if len(turn.history) > maxHistoryMessages {
+		turn.history = turn.history[len(turn.history)-maxHistoryMessages:]
+	}
+
+	currentForm := input.CurrentForm
+	if input.FormID != "" {
+		if uc.forms == nil {
+			return nil, domain.ErrFormNotFound
+		}
+		stored, err := uc.forms.GetForm(input.UserID, input.FormID)
+		if err != nil {
+			return nil, err
+		}
+		turn.formID = stored.ID
+		if currentForm == nil {
+			currentForm = &stored.Config
+		}
+	}
+
+	turn.previousForm = currentForm
+	if turn.previousForm == nil {
+		turn.previousForm = lastGeneratedForm(turn.history)
+	}
+
+	if len(input.Patch) > 0 {
+		if turn.previousForm == nil {
+			return nil, fmt.Errorf("%w: there is no form to apply it to", ErrInvalidPatch)
+		}
+		patched, err := applyFormPatch(turn.previousForm, input.Patch)
+		if err != nil {
+			return nil, err
+		}
+		// The model has to see the edited form, so send it explicitly.
+		turn.previousForm = patched
+		currentForm = patched
+	}
+
+	if currentForm != nil {
+		currentFormJSON, err := json.MarshalIndent(currentForm, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode current form: %w", err)
+		}
+		turn.currentForm = string(currentFormJSON)
+		if turn.userMessage, err = prompts.RefinementPrompt(turn.currentForm, input.Prompt); err != nil {
+			return nil, err
+		}
+	}
+	return turn, nil
+}
+
+// lastGeneratedForm returns the most recent form produced in the history.
+func lastGeneratedForm(history []domain.Message) *domain.FormConfig {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role != domain.RoleAssistant {
+			continue
+		}
+		if form, err := domain.ParseFormConfig([]byte(history[i].Content)); err == nil {
+			return form
+		}
+	}
+	return nil
+}
+
+// applyFormPatch applies a JSON Patch to a form and strictly decodes the result.
+func applyFormPatch(form *domain.FormConfig, patch jsonpatch.Patch) (*domain.FormConfig, error) {
+	doc, err := jsonpatch.ToDocument(form)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode form: %w", err)
+	}
+	patchedDoc, err := jsonpatch.Apply(doc, patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	raw, err := json.Marshal(patchedDoc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	patched, err := domain.ParseFormConfig(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return patched, nil
+}
+
+// finishTurn records the user's prompt and the accepted form.
+func (uc *FormGeneratorUseCase) finishTurn(turn *conversationTurn, prompt string, form *domain.FormConfig) error {
+	if uc.conversations == nil {
+		return nil
+	}
+	formJSON, err := json.Marshal(form)
+	if err != nil {
+		return fmt.Errorf("failed to encode generated form: %w", err)
+	}
+	messages := make([]domain.Message, 0, len(turn.history)+2)
+	messages = append(messages, turn.history...)
+	messages = append(messages,
+		domain.Message{Role: domain.RoleUser, Content: prompt},
+		domain.Message{Role: domain.RoleAssistant, Content: string(formJSON)},
+	)
+	uc.conversations.Save(turn.userID, turn.id, messages)
+	return nil
+}
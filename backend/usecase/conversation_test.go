@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"better-form-doc-backend/jsonpatch"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestChatPatchIsApplied(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		wantErr bool
+	}{
+		{"valid", `[{"op":"replace","path":"/title","value":"Sign in"}]`, false},
+		{"add without value", `[{"op":"add","path":"/description"}]`, true},
+		{"replace without value", `[{"op":"replace","path":"/title"}]`, true},
+		{"test without value", `[{"op":"test","path":"/title"}]`, true},
+		{"failing test", `[{"op":"test","path":"/title","value":"Other"}]`, true},
+		{"result is not a form", `[{"op":"add","path":"/theme","value":"dark"}]`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patch jsonpatch.Patch
+			if err := json.Unmarshal([]byte(tt.patch), &patch); err != nil {
+				t.Fatal(err)
+			}
+			llm := &fakeLLM{answers: []string{testGeneratedForm}}
+			uc := NewChatUseCase(llm, nil, nil, nil, nil, FormGeneratorConfig{})
+			_, err := uc.GenerateChatResponse(context.Background(), ChatInput{
+				Prompt:      "add a phone field",
+				CurrentForm: mustParseForm(t, testGeneratedForm),
+				Patch:       patch,
+			})
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPatch) || llm.calls() != 0 {
+					t.Errorf("err = %v after %d calls, want ErrInvalidPatch before calling the model", err, llm.calls())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The model sees the patched form.
+			if message := llm.requests[0].Messages[len(llm.requests[0].Messages)-1].Content; !strings.Contains(message, `"title": "Sign in"`) {
+				t.Errorf("user message does not carry the patched form:\n%s", message)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+// usecase/field_options_usecase.go
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/validation"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoDataSource is returned when options are requested for a field
+	// without a remote data source.
+	ErrNoDataSource = errors.New("field has no remote data source")
+	// ErrInvalidOptionsQuery is returned when the search term or cursor of an
+	// options request is malformed.
+	ErrInvalidOptionsQuery = errors.New("invalid options query")
+	// ErrDataSourceFailed is returned when the upstream endpoint of a data
+	// source cannot be called or answers with an error.
+	ErrDataSourceFailed = errors.New("data source request failed")
+)
+
+// DefaultDataSourceTimeout bounds a call to an upstream data source.
+const DefaultDataSourceTimeout = 10 * time.Second
+
+// maxOptionsQueryLength is the longest search term passed upstream.
+const maxOptionsQueryLength = 200
+
+// DataSourceFetcher calls the upstream endpoints of data sources.
+type DataSourceFetcher interface {
+	// Fetch sends a request and returns the body of a 2xx response; any other
+	// status is an error. Redirects are not followed.
+	Fetch(ctx context.Context, method, url string, headers map[string]string, body []byte) ([]byte, error)
+}
+
+// SecretResolver looks up the secrets referenced by authTokenRef, so they
+// stay on the server.
+type SecretResolver interface {
+	// Secret returns the secret named ref, or false when none is configured.
+	Secret(ref string) (string, bool)
+}
+
+// OptionsCache stores encoded option pages, each for its own TTL.
+type OptionsCache interface {
+	// Get returns the value stored under key, or false when it is missing or expired.
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+// DataSourceConfig configures the data source proxy.
+type DataSourceConfig struct {
+	// Policy decides which endpoints are called and where secrets may go;
+	// the forms were checked against it when they were saved.
+	Policy validation.DataSourcePolicy
+	// Timeout bounds each upstream call.
+	Timeout time.Duration
+}
+
+// OptionsPage is a page of the options of a field.
+type OptionsPage struct {
+	Options []domain.StaticOption `json:"options"`
+	// NextCursor fetches the following page; empty on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+	// Cache is CacheHit or CacheMiss when the data source sets cacheTtlMs.
+	Cache string `json:"-"`
+}
+
+// FieldOptionsUseCaseInterface defines the data source proxy.
+type FieldOptionsUseCaseInterface interface {
+	// FieldOptions returns a page of the options of a field of a stored form
+	// whose options come from a remote data source, searched for query. An
+	// empty cursor starts at the first page.
+	FieldOptions(ctx context.Context, formID, fieldName, query, cursor string) (*OptionsPage, error)
+}
+
+// FieldOptionsUseCase is the implementation of FieldOptionsUseCaseInterface.
+type FieldOptionsUseCase struct {
+	forms   FormRepository
+	fetcher DataSourceFetcher
+	secrets SecretResolver
+	cache   OptionsCache
+	config  DataSourceConfig
+}
+
+// NewFieldOptionsUseCase creates a new instance of FieldOptionsUseCase. cache
+// may be nil, in which case cacheTtlMs is ignored.
+func NewFieldOptionsUseCase(forms FormRepository, fetcher DataSourceFetcher, secrets SecretResolver, cache OptionsCache, config DataSourceConfig) FieldOptionsUseCaseInterface {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultDataSourceTimeout
+	}
+	return &FieldOptionsUseCase{forms: forms, fetcher: fetcher, secrets: secrets, cache: cache, config: config}
+}
+
+// FieldOptions calls the upstream endpoint of the field's data source with
+// the search term in queryParam and the cursor in cursorParam, or the page
+// number in pageParam, and maps the items to options with labelKey and
+// valueKey. The secret named by authTokenRef is sent as a bearer token.
+func (uc *FieldOptionsUseCase) FieldOptions(ctx context.Context, formID, fieldName, query, cursor string) (*OptionsPage, error) {
+	form, err := uc.forms.Get(formID)
+	if err != nil {
+		return nil, err
+	}
+	var field *domain.FormField
+	for i := range form.Config.Fields {
+		if form.Config.Fields[i].Name == fieldName {
+			field = &form.Config.Fields[i]
+			break
+		}
+	}
+	if field == nil {
+		return nil, domain.ErrFieldNotFound
+	}
+	ds := field.DataSource
+	if ds == nil {
+		return nil, ErrNoDataSource
+	}
+	query = strings.TrimSpace(query)
+	if len(query) > maxOptionsQueryLength {
+		return nil, fmt.Errorf("%w: q cannot be longer than %d characters", ErrInvalidOptionsQuery, maxOptionsQueryLength)
+	}
+
+	var ttl time.Duration
+	if uc.cache != nil && ds.CacheTTLMs != nil && *ds.CacheTTLMs > 0 {
+		ttl = time.Duration(*ds.CacheTTLMs) * time.Millisecond
+	}
+	// The version keeps pages of an edited data source from being served.
+	key := strings.Join([]string{form.ID, strconv.Itoa(form.Version), field.Name, query, cursor}, "\x00")
+	if ttl > 0 {
+		if cached, ok := uc.cache.Get(key); ok {
+			var page OptionsPage
+			if err := json.Unmarshal(cached, &page); err == nil {
+				page.Cache = CacheHit
+				return &page, nil
+			}
+		}
+	}
+
+	page, err := uc.fetchOptions(ctx, ds, query, cursor)
+	if err != nil {
+		return nil, err
+	}
+	if ttl > 0 {
+		if encoded, err := json.Marshal(page); err == nil {
+			uc.cache.Set(key, encoded, ttl)
+		}
+		page.Cache = CacheMiss
+	}
+	return page, nil
+}
+
+// fetchOptions makes the upstream request for one page.
+func (uc *FieldOptionsUseCase) fetchOptions(ctx context.Context, ds *domain.DynamicDataSource, query, cursor string) (*OptionsPage, error) {
+	pagination := ds.Pagination
+	if pagination == nil {
+		pagination = &domain.DataSourcePagination{}
+	}
+	params := map[string]interface{}{}
+	if ds.QueryParam != "" && query != "" {
+		params[ds.QueryParam] = query
+	}
+	page := 0
+	switch {
+	case pagination.CursorParam != "":
+		if cursor != "" {
+			params[pagination.CursorParam] = cursor
+		}
+	case pagination.PageParam != "":
+		page = 1
+		if cursor != "" {
+			n, err := strconv.Atoi(cursor)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: cursor must be a page number", ErrInvalidOptionsQuery)
+			}
+			page = n
+		}
+		params[pagination.PageParam] = page
+	default:
+		if cursor != "" {
+			return nil, fmt.Errorf("%w: the data source is not paginated", ErrInvalidOptionsQuery)
+		}
+	}
+
+	endpoint, err := uc.config.Policy.Resolve(ds)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDataSourceFailed, err)
+	}
+	headers := maps.Clone(ds.Headers)
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	headers["Accept"] = "application/json"
+	if ds.AuthTokenRef != "" {
+		secret, ok := uc.secrets.Secret(ds.AuthTokenRef)
+		if !ok {
+			return nil, fmt.Errorf("%w: secret '%s' is not configured", ErrDataSourceFailed, ds.AuthTokenRef)
+		}
+		headers["Authorization"] = "Bearer " + secret
+	}
+
+	method := ds.Method
+	if method == "" {
+		method = "GET"
+	}
+	var body []byte
+	if method == "POST" {
+		payload := maps.Clone(ds.PayloadTemplate)
+		if payload == nil {
+			payload = map[string]interface{}{}
+		}
+		maps.Copy(payload, params)
+		if body, err = json.Marshal(payload); err != nil {
+			return nil, fmt.Errorf("failed to encode data source payload: %w", err)
+		}
+		headers["Content-Type"] = "application/json"
+	} else {
+		values := endpoint.Query()
+		for name, value := range params {
+			values.Set(name, fmt.Sprint(value))
+		}
+		endpoint.RawQuery = values.Encode()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, uc.config.Timeout)
+	defer cancel()
+	response, err := uc.fetcher.Fetch(ctx, method, endpoint.String(), headers, body)
+	if err != nil {
+		// %v rather than %w: a timeout is the upstream's, not this request's.
+		return nil, fmt.Errorf("%w: %v", ErrDataSourceFailed, err)
+	}
+	var payload interface{}
+	if err := json.Unmarshal(response, &payload); err != nil {
+		return nil, fmt.Errorf("%w: response is not JSON", ErrDataSourceFailed)
+	}
+	return newOptionsPage(payload, pagination, page), nil
+}
+
+// newOptionsPage maps an upstream response to options. The items are the
+// response itself when it is an array, or its data, items or results array.
+// Items without a usable label and value are skipped.
+//
+// With cursorParam, the next cursor is the response member of the same name,
+// or nextCursor. With pageParam, the next page is requested while hasMoreKey
+// is true or, without one, while pages are full (pageSize) or not empty.
+func newOptionsPage(payload interface{}, pagination *domain.DataSourcePagination, page int) *OptionsPage {
+	object, _ := payload.(map[string]interface{})
+	items, ok := payload.([]interface{})
+	if !ok {
+		for _, name := range []string{"data", "items", "results"} {
+			if items, ok = object[name].([]interface{}); ok {
+				break
+			}
+		}
+	}
+
+	labelKey, valueKey := pagination.LabelKey, pagination.ValueKey
+	if labelKey == "" {
+		labelKey = "label"
+	}
+	if valueKey == "" {
+		valueKey = "value"
+	}
+	result := &OptionsPage{Options: []domain.StaticOption{}}
+	for _, item := range items {
+		record, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		label, ok := scalarText(record[labelKey])
+		if !ok {
+			continue
+		}
+		switch value := record[valueKey].(type) {
+		case string, float64, bool:
+			result.Options = append(result.Options, domain.StaticOption{Value: domain.ScalarValue{Value: value}, Label: label})
+		}
+	}
+
+	var hasMore, known bool
+	if pagination.HasMoreKey != "" {
+		hasMore, known = object[pagination.HasMoreKey].(bool)
+	}
+	switch {
+	case pagination.CursorParam != "":
+		next, ok := scalarText(object[pagination.CursorParam])
+		if !ok {
+			next, _ = scalarText(object["nextCursor"])
+		}
+		if !known || hasMore {
+			result.NextCursor = next
+		}
+	case pagination.PageParam != "":
+		if !known {
+			if pagination.PageSize != nil {
+				hasMore = len(items) >= *pagination.PageSize
+			} else {
+				hasMore = len(items) > 0
+			}
+		}
+		if hasMore {
+			result.NextCursor = strconv.Itoa(page + 1)
+		}
+	}
+	return result
+}
+
+// scalarText renders a string or number as option text.
+func scalarText(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, v != ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
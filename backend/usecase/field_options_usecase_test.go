@@ -0,0 +1,297 @@
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/infrastructure"
+	"better-form-doc-backend/validation"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fetchedRequest is a request made through a fakeFetcher.
+type fetchedRequest struct {
+	method  string
+	url     *url.URL
+	headers map[string]string
+	body    []byte
+}
+
+// fakeFetcher answers with the responses it is given in turn, repeating the
+// last one, or with err.
+type fakeFetcher struct {
+	responses []string
+	err       error
+	requests  []fetchedRequest
+}
+
+func (f *fakeFetcher) Fetch(_ context.Context, method, rawURL string, headers map[string]string, body []byte) ([]byte, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	f.requests = append(f.requests, fetchedRequest{method: method, url: parsed, headers: headers, body: body})
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []byte(f.responses[min(len(f.requests), len(f.responses))-1]), nil
+}
+
+type mapSecrets map[string]string
+
+func (s mapSecrets) Secret(ref string) (string, bool) {
+	secret, ok := s[ref]
+	return secret, ok
+}
+
+var testDataSourcePolicy = validation.DataSourcePolicy{
+	AllowedHosts: []string{"api.example.com"},
+	SecretHosts: func(ref string) ([]string, bool) {
+		if ref == "CRM_TOKEN" {
+			return []string{"crm.example.com"}, true
+		}
+		return nil, ref == "ORPHAN_TOKEN"
+	},
+}
+
+// newOptionsFixture returns the options of the field "country" of testFormID,
+// loaded from the data source ds through a fake fetcher.
+func newOptionsFixture(t *testing.T, ds string, responses ...string) (FieldOptionsUseCaseInterface, *fakeFetcher) {
+	t.Helper()
+	forms, _ := newTestForms(t, `[{"name":"name","type":"text"},{"name":"country","type":"select","dataSource":`+ds+`}]`)
+	fetcher := &fakeFetcher{responses: responses}
+	secrets := mapSecrets{"CRM_TOKEN": "s3cret"}
+	cache := infrastructure.NewInMemoryOptionsCache(100)
+	return NewFieldOptionsUseCase(forms, fetcher, secrets, cache, DataSourceConfig{Policy: testDataSourcePolicy}), fetcher
+}
+
+// optionPairs renders options as "value=label".
+func optionPairs(options []domain.StaticOption) []string {
+	pairs := make([]string, len(options))
+	for i, option := range options {
+		pairs[i] = option.Value.String() + "=" + option.Label
+	}
+	return pairs
+}
+
+func TestFieldOptionsMapsItems(t *testing.T) {
+	tests := []struct {
+		name     string
+		ds       string
+		response string
+		want     []string
+	}{
+		{
+			name:     "array",
+			ds:       `{"type":"remote","endpoint":"https://api.example.com/countries"}`,
+			response: `[{"value":"nl","label":"Netherlands"},{"value":"be","label":"Belgium"}]`,
+			want:     []string{"nl=Netherlands", "be=Belgium"},
+		},
+		{
+			name:     "data member",
+			ds:       `{"type":"remote","endpoint":"https://api.example.com/countries"}`,
+			response: `{"data":[{"value":"nl","label":"Netherlands"}]}`,
+			want:     []string{"nl=Netherlands"},
+		},
+		{
+			name:     "items member",
+			ds:       `{"type":"remote","endpoint":"https://api.example.com/countries"}`,
+			response: `{"total":1,"items":[{"value":"nl","label":"Netherlands"}]}`,
+			want:     []string{"nl=Netherlands"},
+		},
+		{
+			name:     "results member with keys",
+			ds:       `{"type":"remote","endpoint":"https://api.example.com/countries","pagination":{"mode":"infinite","labelKey":"name","valueKey":"id"}}`,
+			response: `{"results":[{"id":31,"name":"Netherlands"},{"id":true,"name":7}]}`,
+			want:     []string{"31=Netherlands", "true=7"},
+		},
+		{
+			name:     "unusable items",
+			ds:       `{"type":"remote","endpoint":"https://api.example.com/countries"}`,
+			response: `[{"value":"nl","label":"Netherlands"},"be",{"value":"de"},{"value":"fr","label":""},{"value":{"id":1},"label":"Object"},{"value":null,"label":"Null"}]`,
+			want:     []string{"nl=Netherlands"},
+		},
+		{
+			name:     "no items",
+			ds:       `{"type":"remote","endpoint":"https://api.example.com/countries"}`,
+			response: `{"message":"nothing here"}`,
+			want:     []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase, _ := newOptionsFixture(t, tt.ds, tt.response)
+			page, err := useCase.FieldOptions(context.Background(), testFormID, "country", "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := optionPairs(page.Options); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("options = %v, want %v", got, tt.want)
+			}
+			if page.NextCursor != "" {
+				t.Errorf("NextCursor = %q on an unpaginated source", page.NextCursor)
+			}
+		})
+	}
+}
+
+func TestFieldOptionsCursorPagination(t *testing.T) {
+	tests := []struct {
+		name       string
+		pagination string
+		response   string
+		wantNext   string
+	}{
+		{"member named by cursorParam", `{"mode":"infinite","cursorParam":"after","labelKey":"label","valueKey":"value"}`, `{"data":[],"after":"c2"}`, "c2"},
+		{"nextCursor fallback", `{"mode":"infinite","cursorParam":"after","labelKey":"label","valueKey":"value"}`, `{"data":[],"nextCursor":"c2"}`, "c2"},
+		{"numeric cursor", `{"mode":"infinite","cursorParam":"offset","labelKey":"label","valueKey":"value"}`, `{"data":[],"offset":40}`, "40"},
+		{"last page", `{"mode":"infinite","cursorParam":"after","labelKey":"label","valueKey":"value"}`, `{"data":[]}`, ""},
+		{"hasMore true", `{"mode":"infinite","cursorParam":"after","labelKey":"label","valueKey":"value","hasMoreKey":"more"}`, `{"data":[],"after":"c2","more":true}`, "c2"},
+		{"hasMore false", `{"mode":"infinite","cursorParam":"after","labelKey":"label","valueKey":"value","hasMoreKey":"more"}`, `{"data":[],"after":"c2","more":false}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase, fetcher := newOptionsFixture(t, `{"type":"remote","endpoint":"https://api.example.com/countries?locale=en","queryParam":"q","pagination":`+tt.pagination+`}`, tt.response)
+			page, err := useCase.FieldOptions(context.Background(), testFormID, "country", "  ned ", "c1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.NextCursor != tt.wantNext {
+				t.Errorf("NextCursor = %q, want %q", page.NextCursor, tt.wantNext)
+			}
+			query := fetcher.requests[0].url.Query()
+			param := "after"
+			if strings.Contains(tt.pagination, `"offset"`) {
+				param = "offset"
+			}
+			if query.Get("q") != "ned" || query.Get(param) != "c1" || query.Get("locale") != "en" {
+				t.Errorf("upstream query = %v, want the trimmed term, the cursor and the endpoint's own parameters", query)
+			}
+		})
+	}
+}
+
+func TestFieldOptionsPagePagination(t *testing.T) {
+	tests := []struct {
+		name       string
+		pagination string
+		response   string
+		cursor     string
+		wantPage   string
+		wantNext   string
+	}{
+		{"first page", `{"mode":"infinite","pageParam":"page","labelKey":"label","valueKey":"value"}`, `[{"value":"a","label":"A"}]`, "", "1", "2"},
+		{"empty page", `{"mode":"infinite","pageParam":"page","labelKey":"label","valueKey":"value"}`, `[]`, "3", "3", ""},
+		{"full page", `{"mode":"infinite","pageParam":"page","pageSize":2,"labelKey":"label","valueKey":"value"}`, `[{"value":"a","label":"A"},{"value":"b","label":"B"}]`, "2", "2", "3"},
+		{"short page", `{"mode":"infinite","pageParam":"page","pageSize":2,"labelKey":"label","valueKey":"value"}`, `[{"value":"a","label":"A"}]`, "2", "2", ""},
+		{"hasMore overrides size", `{"mode":"infinite","pageParam":"page","pageSize":2,"labelKey":"label","valueKey":"value","hasMoreKey":"hasMore"}`, `{"items":[{"value":"a","label":"A"}],"hasMore":true}`, "", "1", "2"},
+		{"hasMore false", `{"mode":"infinite","pageParam":"page","labelKey":"label","valueKey":"value","hasMoreKey":"hasMore"}`, `{"items":[{"value":"a","label":"A"}],"hasMore":false}`, "", "1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase, fetcher := newOptionsFixture(t, `{"type":"remote","endpoint":"https://api.example.com/countries","pagination":`+tt.pagination+`}`, tt.response)
+			page, err := useCase.FieldOptions(context.Background(), testFormID, "country", "", tt.cursor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fetcher.requests[0].url.Query().Get("page"); got != tt.wantPage {
+				t.Errorf("requested page %q, want %q", got, tt.wantPage)
+			}
+			if page.NextCursor != tt.wantNext {
+				t.Errorf("NextCursor = %q, want %q", page.NextCursor, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestFieldOptionsPostsPayload(t *testing.T) {
+	useCase, fetcher := newOptionsFixture(t, `{"type":"remote","endpoint":"https://crm.example.com/search","method":"POST","queryParam":"term","authTokenRef":"CRM_TOKEN","headers":{"X-Tenant":"acme"},"payloadTemplate":{"kind":"country","term":"overridden"},"pagination":{"mode":"infinite","pageParam":"page","labelKey":"label","valueKey":"value"}}`, `[]`)
+	if _, err := useCase.FieldOptions(context.Background(), testFormID, "country", "ned", "2"); err != nil {
+		t.Fatal(err)
+	}
+	request := fetcher.requests[0]
+	if request.method != "POST" || request.url.RawQuery != "" {
+		t.Errorf("request = %s %s, want a POST without query", request.method, request.url)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(request.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"kind": "country", "term": "ned", "page": 2.0}; !reflect.DeepEqual(payload, want) {
+		t.Errorf("payload = %v, want %v", payload, want)
+	}
+	wantHeaders := map[string]string{"X-Tenant": "acme", "Accept": "application/json", "Content-Type": "application/json", "Authorization": "Bearer s3cret"}
+	if !reflect.DeepEqual(request.headers, wantHeaders) {
+		t.Errorf("headers = %v, want %v", request.headers, wantHeaders)
+	}
+}
+
+func TestFieldOptionsCache(t *testing.T) {
+	useCase, fetcher := newOptionsFixture(t, `{"type":"remote","endpoint":"https://api.example.com/countries","queryParam":"q","cacheTtlMs":60000}`,
+		`[{"value":"nl","label":"Netherlands"}]`, `[{"value":"be","label":"Belgium"}]`)
+
+	first, err := useCase.FieldOptions(context.Background(), testFormID, "country", "n", "")
+	if err != nil || first.Cache != CacheMiss {
+		t.Fatalf("first page = %+v, %v; want a cache miss", first, err)
+	}
+	second, err := useCase.FieldOptions(context.Background(), testFormID, "country", "n", "")
+	if err != nil || second.Cache != CacheHit || !reflect.DeepEqual(optionPairs(second.Options), optionPairs(first.Options)) {
+		t.Errorf("second page = %+v, %v; want the first page from the cache", second, err)
+	}
+	other, err := useCase.FieldOptions(context.Background(), testFormID, "country", "b", "")
+	if err != nil || other.Cache != CacheMiss || !reflect.DeepEqual(optionPairs(other.Options), []string{"be=Belgium"}) {
+		t.Errorf("page for another term = %+v, %v; want a fresh page", other, err)
+	}
+	if len(fetcher.requests) != 2 {
+		t.Errorf("upstream requests = %d, want 2", len(fetcher.requests))
+	}
+}
+
+func TestFieldOptionsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		ds       string
+		field    string
+		query    string
+		cursor   string
+		fetchErr error
+		response string
+		want     error
+	}{
+		{name: "unknown field", ds: `{"type":"remote","endpoint":"https://api.example.com/c"}`, field: "missing", want: domain.ErrFieldNotFound},
+		{name: "static field", ds: `{"type":"remote","endpoint":"https://api.example.com/c"}`, field: "name", want: ErrNoDataSource},
+		{name: "long query", ds: `{"type":"remote","endpoint":"https://api.example.com/c"}`, query: strings.Repeat("q", 201), want: ErrInvalidOptionsQuery},
+		{name: "cursor without pagination", ds: `{"type":"remote","endpoint":"https://api.example.com/c"}`, cursor: "c1", want: ErrInvalidOptionsQuery},
+		{name: "non-numeric page", ds: `{"type":"remote","endpoint":"https://api.example.com/c","pagination":{"mode":"infinite","pageParam":"page","labelKey":"label","valueKey":"value"}}`, cursor: "next", want: ErrInvalidOptionsQuery},
+		{name: "page zero", ds: `{"type":"remote","endpoint":"https://api.example.com/c","pagination":{"mode":"infinite","pageParam":"page","labelKey":"label","valueKey":"value"}}`, cursor: "0", want: ErrInvalidOptionsQuery},
+		{name: "host not allowed", ds: `{"type":"remote","endpoint":"https://evil.example.com/c"}`, want: ErrDataSourceFailed},
+		{name: "secret to another host", ds: `{"type":"remote","endpoint":"https://api.example.com/c","authTokenRef":"CRM_TOKEN"}`, want: ErrDataSourceFailed},
+		{name: "secret bound to no host", ds: `{"type":"remote","endpoint":"https://api.example.com/c","authTokenRef":"ORPHAN_TOKEN"}`, want: ErrDataSourceFailed},
+		{name: "upstream error", ds: `{"type":"remote","endpoint":"https://api.example.com/c"}`, fetchErr: context.DeadlineExceeded, want: ErrDataSourceFailed},
+		{name: "not JSON", ds: `{"type":"remote","endpoint":"https://api.example.com/c"}`, response: `<html>`, want: ErrDataSourceFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase, fetcher := newOptionsFixture(t, tt.ds, tt.response)
+			fetcher.err = tt.fetchErr
+			field := tt.field
+			if field == "" {
+				field = "country"
+			}
+			_, err := useCase.FieldOptions(context.Background(), testFormID, field, tt.query, tt.cursor)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("FieldOptions = %v, want %v", err, tt.want)
+			}
+			if tt.fetchErr != nil && errors.Is(err, tt.fetchErr) {
+				t.Errorf("FieldOptions = %v wraps the upstream timeout", err)
+			}
+			if tt.fetchErr == nil && tt.response == "" && len(fetcher.requests) > 0 {
+				t.Errorf("the upstream was called for a refused request")
+			}
+		})
+	}
+}
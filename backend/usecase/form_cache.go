@@ -0,0 +1,107 @@
+// usecase/form_cache.go
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// FormCache stores generated forms by key. InMemoryFormCache (an LRU) and
+// DiskFormCache implement it; both apply their own TTL and size limits.
+type FormCache interface {
+	// Get returns the value stored under key, or false when it is missing or expired.
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte) error
+}
+
+// Cache outcomes reported in GenerationMetadata.Cache.
+const (
+	CacheHit  = "HIT"
+	CacheMiss = "MISS"
+)
+
+// cachedForm is what is stored for a generated form.
+type cachedForm struct {
+	Form  *domain.FormConfig `json:"form"`
+	Model string             `json:"model,omitempty"`
+}
+
+// cacheable reports whether the answer depends on the prompt alone. Follow-up
+// turns and refinements depend on their context and are never cached.
+func (uc *FormGeneratorUseCase) cacheable(turn *conversationTurn) bool {
+	return uc.cache != nil && len(turn.history) == 0 && turn.previousForm == nil && turn.formID == ""
+}
+
+// cacheKey identifies the answer to prompt: the normalized prompt together
+// with everything else that shapes the model's answer, including the prompt
+// templates it is generated with.
+func (uc *FormGeneratorUseCase) cacheKey(prompt string, prompts *PromptTemplate) string {
+	parts := []string{
+		normalizePrompt(prompt),
+		uc.config.ModelName,
+		prompts.Version,
+		prompts.digest,
+		formatOptionalFloat(uc.config.Temperature),
+		formatOptionalFloat(uc.config.TopP),
+		strconv.Itoa(uc.config.MaxOutputTokens),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizePrompt folds case and whitespace and drops trailing punctuation,
+// so that "Login form." and "login   form" share an entry.
+func normalizePrompt(prompt string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(prompt)), " ")
+	return strings.TrimRight(normalized, ".!? ")
+}
+
+func formatOptionalFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'g', -1, 64)
+}
+
+// cachedEntry returns the cached answer for key, or nil when bypass is set.
+// Cache failures are logged and treated as misses: the model can always
+// answer instead.
+func (uc *FormGeneratorUseCase) cachedEntry(key string, bypass bool) *cachedForm {
+	if bypass {
+		return nil
+	}
+	value, ok, err := uc.cache.Get(key)
+	if err != nil {
+		log.Printf("Form cache lookup failed: %v", err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	var entry cachedForm
+	if err := json.Unmarshal(value, &entry); err != nil || entry.Form == nil {
+		log.Printf("Ignoring unreadable form cache entry %s", key)
+		return nil
+	}
+	if issues := uc.config.OutputPolicy.Check(entry.Form); len(issues) > 0 {
+		// Cached before the output policy was tightened.
+		return nil
+	}
+	return &entry
+}
+
+// storeCachedForm caches a freshly generated form.
+func (uc *FormGeneratorUseCase) storeCachedForm(key string, entry cachedForm) {
+	value, err := json.Marshal(entry)
+	if err == nil {
+		err = uc.cache.Set(key, value)
+	}
+	if err != nil {
+		log.Printf("Failed to cache generated form: %v", err)
+	}
+}
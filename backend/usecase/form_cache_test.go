@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/infrastructure"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testGeneratedForm = `{"title":"Login","endpoint":"/api/login","submit":{"label":"Log in"},"fields":[{"name":"email","type":"email","label":"Email"}]}`
+
+// fakeLLM answers the n-th call with answers[n], repeating the last answer,
+// and records every request. Stream sends the answer in two chunks.
+type fakeLLM struct {
+	mu       sync.Mutex
+	answers  []string
+	usage    domain.TokenUsage
+	err      error
+	requests []domain.GenerationRequest
+}
+
+func (f *fakeLLM) Generate(ctx context.Context, request domain.GenerationRequest) (*domain.GenerationResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, request)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	answer := f.answers[min(len(f.requests), len(f.answers))-1]
+	return &domain.GenerationResponse{Text: answer, Usage: f.usage, FinishReason: "STOP", Model: "fake-model"}, nil
+}
+
+func (f *fakeLLM) Stream(ctx context.Context, request domain.GenerationRequest, onText func(text string)) (*domain.GenerationResponse, error) {
+	response, err := f.Generate(ctx, request)
+	if err != nil {
+		return response, err
+	}
+	half := len(response.Text) / 2
+	onText(response.Text[:half])
+	onText(response.Text[half:])
+	return response, nil
+}
+
+func (f *fakeLLM) calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+func TestGeneratedFormsAreCached(t *testing.T) {
+	llm := &fakeLLM{answers: []string{testGeneratedForm}}
+	prompts, err := NewPromptRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = prompts.SetSelectable("v1")
+	uc := NewChatUseCase(llm, nil, nil, nil, infrastructure.NewInMemoryFormCache(10, time.Hour), FormGeneratorConfig{Prompts: prompts})
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		input     ChatInput
+		wantCache string
+		wantCalls int
+	}{
+		{"first request", ChatInput{Prompt: "Login form."}, CacheMiss, 1},
+		{"normalized prompt", ChatInput{Prompt: "  login   FORM!"}, CacheHit, 1},
+		{"bypass", ChatInput{Prompt: "Login form", BypassCache: true}, CacheMiss, 2},
+		{"other prompt version", ChatInput{Prompt: "Login form", PromptVersion: "v1"}, CacheMiss, 3},
+		{"other prompt", ChatInput{Prompt: "Signup form"}, CacheMiss, 4},
+		{"follow-up turn", ChatInput{Prompt: "Login form", History: []domain.Message{
+			{Role: domain.RoleUser, Content: "A form"}, {Role: domain.RoleAssistant, Content: testGeneratedForm},
+		}}, "", 5},
+		{"refinement", ChatInput{Prompt: "Login form", CurrentForm: mustParseForm(t, testGeneratedForm)}, "", 6},
+		{"still cached", ChatInput{Prompt: "login form"}, CacheHit, 6},
+	}
+	for _, tt := range tests {
+		result, err := uc.GenerateChatResponse(ctx, tt.input)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if result.Metadata.Cache != tt.wantCache || llm.calls() != tt.wantCalls {
+			t.Errorf("%s: cache = %q after %d calls, want %q after %d", tt.name, result.Metadata.Cache, llm.calls(), tt.wantCache, tt.wantCalls)
+		}
+		if result.Form == nil || result.Form.Title != "Login" {
+			t.Errorf("%s: form = %+v", tt.name, result.Form)
+		}
+	}
+}
+
+func TestNormalizePrompt(t *testing.T) {
+	tests := map[string]string{
+		"Login form.":              "login form",
+		"  LOGIN\n\tform ?!":       "login form",
+		"Login form with e-mail":   "login form with e-mail",
+		"What is 1.5 + 1.5?":       "what is 1.5 + 1.5",
+		"Form for product v2. OK.": "form for product v2. ok",
+	}
+	for prompt, want := range tests {
+		if got := normalizePrompt(prompt); got != want {
+			t.Errorf("normalizePrompt(%q) = %q, want %q", prompt, got, want)
+		}
+	}
+}
+
+func mustParseForm(t *testing.T, raw string) *domain.FormConfig {
+	t.Helper()
+	form, err := domain.ParseFormConfig([]byte(raw))
+	if err != nil {
+		t.Fatalf("invalid test form: %v", err)
+	}
+	return form
+}
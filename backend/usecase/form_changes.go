@@ -0,0 +1,157 @@
+// usecase/form_changes.go
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/jsonpatch"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FormChanges describes how a refinement changed the previous form.
+type FormChanges struct {
+	// Patch is the RFC 6902 JSON Patch turning the previous form into the new one.
+	Patch jsonpatch.Patch `json:"patch"`
+	// Summary lists the changes in plain language, e.g. "Added field 'phone' (text)".
+	Summary []string `json:"summary"`
+}
+
+// diffForms computes the patch and change summary between two forms.
+func diffForms(before, after *domain.FormConfig) (*FormChanges, error) {
+	beforeDoc, err := jsonpatch.ToDocument(before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode previous form: %w", err)
+	}
+	afterDoc, err := jsonpatch.ToDocument(after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode new form: %w", err)
+	}
+
+	patch := jsonpatch.Diff(beforeDoc, afterDoc)
+	if patch == nil {
+		patch = jsonpatch.Patch{}
+	}
+	return &FormChanges{
+		Patch:   patch,
+		Summary: summarizeChanges(beforeDoc.(map[string]interface{}), afterDoc.(map[string]interface{})),
+	}, nil
+}
+
+// summarizeChanges describes field, step and top-level changes between two
+// forms in generic JSON form.
+func summarizeChanges(before, after map[string]interface{}) []string {
+	summary := []string{}
+
+	summary = append(summary, summarizeList("field", "name", before["fields"], after["fields"])...)
+	summary = append(summary, summarizeList("step", "id", before["steps"], after["steps"])...)
+
+	keys := map[string]bool{}
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+	var sorted []string
+	for key := range keys {
+		if key != "fields" && key != "steps" {
+			sorted = append(sorted, key)
+		}
+	}
+	sort.Strings(sorted)
+
+	for _, key := range sorted {
+		oldValue, hadOld := before[key]
+		newValue, hasNew := after[key]
+		switch {
+		case !hadOld:
+			summary = append(summary, fmt.Sprintf("Added %s", key))
+		case !hasNew:
+			summary = append(summary, fmt.Sprintf("Removed %s", key))
+		default:
+			if paths := changedPaths(oldValue, newValue); len(paths) > 0 {
+				summary = append(summary, fmt.Sprintf("Changed %s", key))
+			}
+		}
+	}
+	return summary
+}
+
+// summarizeList matches the items of two arrays by keyName and reports added,
+// removed, changed and reordered items.
+func summarizeList(kind, keyName string, before, after interface{}) []string {
+	oldItems, _ := before.([]interface{})
+	newItems, _ := after.([]interface{})
+
+	oldByKey := map[string]map[string]interface{}{}
+	var oldOrder []string
+	for _, item := range oldItems {
+		if obj, ok := item.(map[string]interface{}); ok {
+			key, _ := obj[keyName].(string)
+			oldByKey[key] = obj
+			oldOrder = append(oldOrder, key)
+		}
+	}
+
+	var summary []string
+	newKeys := map[string]bool{}
+	var commonNewOrder []string
+	for _, item := range newItems {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key, _ := obj[keyName].(string)
+		newKeys[key] = true
+
+		oldObj, existed := oldByKey[key]
+		if !existed {
+			if fieldType, ok := obj["type"].(string); ok {
+				summary = append(summary, fmt.Sprintf("Added %s '%s' (%s)", kind, key, fieldType))
+			} else {
+				summary = append(summary, fmt.Sprintf("Added %s '%s'", kind, key))
+			}
+			continue
+		}
+		commonNewOrder = append(commonNewOrder, key)
+		if paths := changedPaths(oldObj, obj); len(paths) > 0 {
+			summary = append(summary, fmt.Sprintf("Changed %s '%s': %s", kind, key, strings.Join(paths, ", ")))
+		}
+	}
+
+	var commonOldOrder []string
+	for _, key := range oldOrder {
+		if !newKeys[key] {
+			summary = append(summary, fmt.Sprintf("Removed %s '%s'", kind, key))
+			continue
+		}
+		commonOldOrder = append(commonOldOrder, key)
+	}
+	if strings.Join(commonOldOrder, "\x00") != strings.Join(commonNewOrder, "\x00") {
+		summary = append(summary, fmt.Sprintf("Reordered %ss", kind))
+	}
+	return summary
+}
+
+// changedPaths lists the distinct properties (at most two levels deep, in dotted
+// form) that differ between two JSON values.
+func changedPaths(before, after interface{}) []string {
+	seen := map[string]bool{}
+	var paths []string
+	for _, op := range jsonpatch.Diff(before, after) {
+		tokens := strings.Split(strings.TrimPrefix(op.Path, "/"), "/")
+		if len(tokens) > 2 {
+			tokens = tokens[:2]
+		}
+		path := strings.Join(tokens, ".")
+		if path == "" {
+			path = "value"
+		}
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,268 @@
+// usecase/form_usecase.go
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/validation"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrFormNameRequired is returned when a form is saved without a usable name.
+var ErrFormNameRequired = errors.New("form name is required")
+
+// FormRepository persists stored forms and their version history.
+type FormRepository interface {
+	// Create stores a new form together with its first version.
+	Create(form *domain.StoredForm, version *domain.FormVersion) error
+	// Get returns domain.ErrFormNotFound when the form does not exist.
+	Get(id string) (*domain.StoredForm, error)
+	// ListByOwner returns the forms of a user, most recently updated first.
+	ListByOwner(ownerID string) ([]*domain.StoredForm, error)
+	// Update saves the form's metadata (name, timestamps).
+	Update(form *domain.StoredForm) error
+	// AddVersion stores a new version and makes it the form's current config
+	// in one step. It returns domain.ErrVersionConflict if the version exists.
+	AddVersion(form *domain.StoredForm, version *domain.FormVersion) error
+	// GetVersion returns domain.ErrVersionNotFound when the version does not exist.
+	GetVersion(formID string, version int) (*domain.FormVersion, error)
+	// ListVersions returns every version of a form, oldest first.
+	ListVersions(formID string) ([]*domain.FormVersion, error)
+	// Delete removes a form and its versions.
+	Delete(id string) error
+}
+
+// FormUseCaseInterface defines the contract for managing stored forms. Every
+// method is scoped to the calling user; forms of other users are reported as
+// domain.ErrFormNotFound.
+type FormUseCaseInterface interface {
+	// CreateForm saves a new form; promptVersion records the prompt templates
+	// that generated config, empty when it was not generated.
+	CreateForm(ownerID, name string, config *domain.FormConfig, promptVersion string) (*domain.StoredForm, error)
+	GetForm(ownerID, id string) (*domain.StoredForm, error)
+	ListForms(ownerID string) ([]*domain.StoredForm, error)
+	// UpdateForm renames the form and/or replaces its config; nil leaves a value
+	// unchanged. A new config creates a new version.
+	UpdateForm(ownerID, id string, name *string, config *domain.FormConfig) (*domain.StoredForm, error)
+	DeleteForm(ownerID, id string) error
+
+	// SaveRefinement stores a config produced by an AI refinement with the
+	// prompt templates of promptVersion as a new version.
+	SaveRefinement(ownerID, id string, config *domain.FormConfig, prompt, promptVersion string) (*domain.StoredForm, error)
+	ListVersions(ownerID, id string) ([]*domain.FormVersion, error)
+	// DiffVersions describes the changes from version against to version; an
+	// against of 0 compares with the preceding version.
+	DiffVersions(ownerID, id string, version, against int) (*VersionDiff, error)
+	// Rollback restores the config of an earlier version as a new version.
+	Rollback(ownerID, id string, version int) (*domain.StoredForm, error)
+}
+
+// VersionDiff is the change between two versions of a stored form.
+type VersionDiff struct {
+	FormID  string       `json:"formId"`
+	From    int          `json:"from"`
+	To      int          `json:"to"`
+	Changes *FormChanges `json:"changes"`
+}
+
+// FormUseCase is the implementation of FormUseCaseInterface.
+type FormUseCase struct {
+	forms       FormRepository
+	dataSources validation.DataSourcePolicy
+}
+
+// NewFormUseCase creates a new instance of FormUseCase. Every saved config
+// must only use data sources dataSources allows.
+func NewFormUseCase(forms FormRepository, dataSources validation.DataSourcePolicy) FormUseCaseInterface {
+	return &FormUseCase{forms: forms, dataSources: dataSources}
+}
+
+// CreateForm validates and saves a new form as version 1.
+func (uc *FormUseCase) CreateForm(ownerID, name string, config *domain.FormConfig, promptVersion string) (*domain.StoredForm, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = strings.TrimSpace(config.Title)
+	}
+	if name == "" {
+		return nil, ErrFormNameRequired
+	}
+	if issues := validation.ValidateFormConfig(config); len(issues) > 0 {
+		return nil, &InvalidFormError{Issues: issues}
+	}
+	if issues := uc.dataSources.Check(config); len(issues) > 0 {
+		return nil, &InvalidFormError{Issues: issues}
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	form := &domain.StoredForm{
+		ID:        id,
+		OwnerID:   ownerID,
+		Name:      name,
+		Version:   1,
+		Config:    *config,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	version := &domain.FormVersion{
+		FormID:        id,
+		Version:       1,
+		Source:        domain.VersionCreated,
+		Config:        *config,
+		PromptVersion: promptVersion,
+		CreatedBy:     ownerID,
+		CreatedAt:     now,
+	}
+	if err := uc.forms.Create(form, version); err != nil {
+		return nil, fmt.Errorf("failed to save form: %w", err)
+	}
+	return form, nil
+}
+
+// GetForm returns a form owned by ownerID.
+func (uc *FormUseCase) GetForm(ownerID, id string) (*domain.StoredForm, error) {
+	form, err := uc.forms.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if form.OwnerID != ownerID {
+		return nil, domain.ErrFormNotFound
+	}
+	return form, nil
+}
+
+// ListForms returns every form owned by ownerID.
+func (uc *FormUseCase) ListForms(ownerID string) ([]*domain.StoredForm, error) {
+	return uc.forms.ListByOwner(ownerID)
+}
+
+// UpdateForm renames a form and/or replaces its config.
+func (uc *FormUseCase) UpdateForm(ownerID, id string, name *string, config *domain.FormConfig) (*domain.StoredForm, error) {
+	form, err := uc.GetForm(ownerID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != nil {
+		trimmed := strings.TrimSpace(*name)
+		if trimmed == "" {
+			return nil, ErrFormNameRequired
+		}
+		form.Name = trimmed
+	}
+	if config != nil {
+		if issues := validation.ValidateFormConfig(config); len(issues) > 0 {
+			return nil, &InvalidFormError{Issues: issues}
+		}
+		return uc.addVersion(form, ownerID, config, domain.VersionEdited, "", "")
+	}
+
+	form.UpdatedAt = time.Now().UTC()
+	if err := uc.forms.Update(form); err != nil {
+		return nil, fmt.Errorf("failed to update form: %w", err)
+	}
+	return form, nil
+}
+
+// DeleteForm removes a form owned by ownerID.
+func (uc *FormUseCase) DeleteForm(ownerID, id string) error {
+	if _, err := uc.GetForm(ownerID, id); err != nil {
+		return err
+	}
+	return uc.forms.Delete(id)
+}
+
+// SaveRefinement stores an AI-refined config as a new version. The config has
+// already been validated by the generation loop.
+func (uc *FormUseCase) SaveRefinement(ownerID, id string, config *domain.FormConfig, prompt, promptVersion string) (*domain.StoredForm, error) {
+	form, err := uc.GetForm(ownerID, id)
+	if err != nil {
+		return nil, err
+	}
+	return uc.addVersion(form, ownerID, config, domain.VersionRefined, prompt, promptVersion)
+}
+
+// ListVersions returns the version history of a form owned by ownerID.
+func (uc *FormUseCase) ListVersions(ownerID, id string) ([]*domain.FormVersion, error) {
+	if _, err := uc.GetForm(ownerID, id); err != nil {
+		return nil, err
+	}
+	return uc.forms.ListVersions(id)
+}
+
+// DiffVersions compares two versions of a form owned by ownerID.
+func (uc *FormUseCase) DiffVersions(ownerID, id string, version, against int) (*VersionDiff, error) {
+	if _, err := uc.GetForm(ownerID, id); err != nil {
+		return nil, err
+	}
+	if against == 0 {
+		if version == 1 {
+			return nil, fmt.Errorf("%w: version 1 has no preceding version to compare with", domain.ErrVersionNotFound)
+		}
+		against = version - 1
+	}
+
+	to, err := uc.forms.GetVersion(id, version)
+	if err != nil {
+		return nil, err
+	}
+	from, err := uc.forms.GetVersion(id, against)
+	if err != nil {
+		return nil, fmt.Errorf("version %d to compare against: %w", against, err)
+	}
+
+	changes, err := diffForms(&from.Config, &to.Config)
+	if err != nil {
+		return nil, err
+	}
+	return &VersionDiff{FormID: id, From: against, To: version, Changes: changes}, nil
+}
+
+// Rollback restores an earlier version. History is never rewritten: the old
+// config is saved again as the newest version.
+func (uc *FormUseCase) Rollback(ownerID, id string, version int) (*domain.StoredForm, error) {
+	form, err := uc.GetForm(ownerID, id)
+	if err != nil {
+		return nil, err
+	}
+	target, err := uc.forms.GetVersion(id, version)
+	if err != nil {
+		return nil, err
+	}
+	return uc.addVersion(form, ownerID, &target.Config, domain.VersionRolledBack,
+		fmt.Sprintf("Restored version %d", version), target.PromptVersion)
+}
+
+// addVersion saves config as the next version of form. promptVersion is empty
+// unless config was generated. The data sources are checked here because
+// refinements and restored versions were not checked against the current
+// policy.
+func (uc *FormUseCase) addVersion(form *domain.StoredForm, userID string, config *domain.FormConfig, source domain.VersionSource, note, promptVersion string) (*domain.StoredForm, error) {
+	if issues := uc.dataSources.Check(config); len(issues) > 0 {
+		return nil, &InvalidFormError{Issues: issues}
+	}
+	now := time.Now().UTC()
+	form.Version++
+	form.Config = *config
+	form.UpdatedAt = now
+
+	version := &domain.FormVersion{
+		FormID:        form.ID,
+		Version:       form.Version,
+		Source:        source,
+		Note:          note,
+		Config:        *config,
+		PromptVersion: promptVersion,
+		CreatedBy:     userID,
+		CreatedAt:     now,
+	}
+	if err := uc.forms.AddVersion(form, version); err != nil {
+		return nil, fmt.Errorf("failed to save form version: %w", err)
+	}
+	return form, nil
+}
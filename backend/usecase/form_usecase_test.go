@@ -0,0 +1,396 @@
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/infrastructure"
+	"better-form-doc-backend/jsonpatch"
+	"better-form-doc-backend/validation"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func newFormUseCase() FormUseCaseInterface {
+	return NewFormUseCase(infrastructure.NewInMemoryFormRepository(), testDataSourcePolicy)
+}
+
+func TestCreateForm(t *testing.T) {
+	tests := []struct {
+		name     string
+		formName string
+		config   string
+		wantName string
+		wantErr  error
+	}{
+		{"explicit name", "  Sign-in  ", testGeneratedForm, "Sign-in", nil},
+		{"falls back to the title", " ", testGeneratedForm, "Login", nil},
+		{
+			"no name and no title", "",
+			`{"endpoint":"/api/login","submit":{"label":"Log in"},"fields":[{"name":"email","type":"email"}]}`,
+			"", ErrFormNameRequired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := newFormUseCase()
+			form, err := useCase.CreateForm("owner", tt.formName, mustParseForm(t, tt.config), "v2")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("CreateForm error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateForm failed: %v", err)
+			}
+			if form.Name != tt.wantName || form.OwnerID != "owner" || form.Version != 1 || form.ID == "" {
+				t.Errorf("CreateForm = %+v, want version 1 of owner named %q", form, tt.wantName)
+			}
+
+			versions, err := useCase.ListVersions("owner", form.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(versions) != 1 || versions[0].Source != domain.VersionCreated || versions[0].PromptVersion != "v2" {
+				t.Errorf("versions = %+v, want one created version with prompt version v2", versions)
+			}
+		})
+	}
+}
+
+func TestCreateFormRejectsInvalidConfigs(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		wantIssue string
+	}{
+		{
+			"duplicate field names",
+			`{"title":"Dup","endpoint":"/api/x","submit":{"label":"Send"},"fields":[{"name":"a","type":"text"},{"name":"a","type":"text"}]}`,
+			validation.CodeDuplicate,
+		},
+		{
+			"data source on a host that is not allowed",
+			`{"title":"Countries","endpoint":"/api/x","submit":{"label":"Send"},"fields":[{"name":"country","type":"select","dataSource":{"type":"remote","endpoint":"https://evil.example.net/countries"}}]}`,
+			validation.CodeExternalEndpoint,
+		},
+		{
+			"data source with an unknown secret",
+			`{"title":"Countries","endpoint":"/api/x","submit":{"label":"Send"},"fields":[{"name":"country","type":"select","dataSource":{"type":"remote","endpoint":"https://api.example.com/countries","authTokenRef":"MISSING"}}]}`,
+			validation.CodeUnknownSecret,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := domain.ParseFormConfig([]byte(tt.config))
+			if err != nil {
+				t.Fatalf("invalid test form: %v", err)
+			}
+			_, err = newFormUseCase().CreateForm("owner", "", config, "")
+			var invalid *InvalidFormError
+			if !errors.As(err, &invalid) || len(invalid.Issues) == 0 {
+				t.Fatalf("CreateForm error = %v, want an InvalidFormError", err)
+			}
+			for _, issue := range invalid.Issues {
+				if issue.Code == tt.wantIssue {
+					return
+				}
+			}
+			t.Errorf("issues = %+v, want %s", invalid.Issues, tt.wantIssue)
+		})
+	}
+}
+
+func TestFormCRUD(t *testing.T) {
+	useCase := newFormUseCase()
+	form, err := useCase.CreateForm("owner", "First", mustParseForm(t, testGeneratedForm), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := useCase.CreateForm("owner", "Second", mustParseForm(t, testGeneratedForm), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := useCase.GetForm("owner", form.ID)
+	if err != nil || got.Name != "First" || got.Config.Title != "Login" {
+		t.Fatalf("GetForm = %+v, %v, want the created form", got, err)
+	}
+	forms, err := useCase.ListForms("owner")
+	if err != nil || len(forms) != 2 {
+		t.Fatalf("ListForms = %d forms, %v, want 2", len(forms), err)
+	}
+
+	// A rename keeps the version, a new config adds one.
+	name := " Renamed "
+	renamed, err := useCase.UpdateForm("owner", form.ID, &name, nil)
+	if err != nil || renamed.Name != "Renamed" || renamed.Version != 1 {
+		t.Fatalf("UpdateForm(name) = %+v, %v, want version 1 named Renamed", renamed, err)
+	}
+	edited := mustParseForm(t, testGeneratedForm)
+	edited.Title = "Sign in"
+	updated, err := useCase.UpdateForm("owner", form.ID, nil, edited)
+	if err != nil || updated.Version != 2 || updated.Config.Title != "Sign in" || updated.Name != "Renamed" {
+		t.Fatalf("UpdateForm(config) = %+v, %v, want version 2 with the new title", updated, err)
+	}
+	blank := "  "
+	if _, err := useCase.UpdateForm("owner", form.ID, &blank, nil); !errors.Is(err, ErrFormNameRequired) {
+		t.Errorf("UpdateForm(blank name) error = %v, want ErrFormNameRequired", err)
+	}
+
+	if err := useCase.DeleteForm("owner", form.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := useCase.GetForm("owner", form.ID); !errors.Is(err, domain.ErrFormNotFound) {
+		t.Errorf("GetForm after delete error = %v, want ErrFormNotFound", err)
+	}
+	if forms, _ := useCase.ListForms("owner"); len(forms) != 1 {
+		t.Errorf("ListForms after delete = %d forms, want 1", len(forms))
+	}
+}
+
+func TestFormsOfAnotherOwner(t *testing.T) {
+	useCase := newFormUseCase()
+	form, err := useCase.CreateForm("owner", "", mustParseForm(t, testGeneratedForm), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := "Mine now"
+	config := mustParseForm(t, testGeneratedForm)
+
+	calls := map[string]func() error{
+		"GetForm": func() error { _, err := useCase.GetForm("intruder", form.ID); return err },
+		"UpdateForm name": func() error {
+			_, err := useCase.UpdateForm("intruder", form.ID, &name, nil)
+			return err
+		},
+		"UpdateForm config": func() error {
+			_, err := useCase.UpdateForm("intruder", form.ID, nil, config)
+			return err
+		},
+		"DeleteForm": func() error { return useCase.DeleteForm("intruder", form.ID) },
+		"SaveRefinement": func() error {
+			_, err := useCase.SaveRefinement("intruder", form.ID, config, "prompt", "v2")
+			return err
+		},
+		"ListVersions": func() error { _, err := useCase.ListVersions("intruder", form.ID); return err },
+		"DiffVersions": func() error { _, err := useCase.DiffVersions("intruder", form.ID, 1, 1); return err },
+		"Rollback":     func() error { _, err := useCase.Rollback("intruder", form.ID, 1); return err },
+	}
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, domain.ErrFormNotFound) {
+			t.Errorf("%s error = %v, want ErrFormNotFound", name, err)
+		}
+	}
+
+	if forms, err := useCase.ListForms("intruder"); err != nil || len(forms) != 0 {
+		t.Errorf("ListForms(intruder) = %d forms, %v, want none", len(forms), err)
+	}
+	got, err := useCase.GetForm("owner", form.ID)
+	if err != nil || got.Name != "Login" || got.Version != 1 {
+		t.Errorf("form after the intruder's calls = %+v, %v, want it unchanged", got, err)
+	}
+}
+
+// editedForm returns the test form with a different title.
+func editedForm(t *testing.T, title string) *domain.FormConfig {
+	t.Helper()
+	config := mustParseForm(t, testGeneratedForm)
+	config.Title = title
+	return config
+}
+
+func TestFormVersions(t *testing.T) {
+	useCase := newFormUseCase()
+	form, err := useCase.CreateForm("owner", "Form", mustParseForm(t, testGeneratedForm), "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := useCase.UpdateForm("owner", form.ID, nil, editedForm(t, "Edited")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := useCase.SaveRefinement("owner", form.ID, editedForm(t, "Refined"), "Rename it", "v2"); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := useCase.Rollback("owner", form.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Version != 4 || restored.Config.Title != "Login" {
+		t.Errorf("Rollback = version %d titled %q, want version 4 titled Login", restored.Version, restored.Config.Title)
+	}
+
+	versions, err := useCase.ListVersions("owner", form.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		source        domain.VersionSource
+		title, note   string
+		promptVersion string
+	}{
+		{domain.VersionCreated, "Login", "", "v1"},
+		{domain.VersionEdited, "Edited", "", ""},
+		{domain.VersionRefined, "Refined", "Rename it", "v2"},
+		// A rollback keeps the prompt version of the restored config.
+		{domain.VersionRolledBack, "Login", "Restored version 1", "v1"},
+	}
+	if len(versions) != len(want) {
+		t.Fatalf("ListVersions = %d versions, want %d", len(versions), len(want))
+	}
+	for i, version := range versions {
+		w := want[i]
+		if version.Version != i+1 || version.Source != w.source || version.Config.Title != w.title ||
+			version.Note != w.note || version.PromptVersion != w.promptVersion || version.CreatedBy != "owner" {
+			t.Errorf("version %d = %+v, want %+v", i+1, version, w)
+		}
+	}
+
+	if _, err := useCase.Rollback("owner", form.ID, 9); !errors.Is(err, domain.ErrVersionNotFound) {
+		t.Errorf("Rollback(9) error = %v, want ErrVersionNotFound", err)
+	}
+}
+
+func TestDiffVersions(t *testing.T) {
+	useCase := newFormUseCase()
+	form, err := useCase.CreateForm("owner", "Form", mustParseForm(t, testGeneratedForm), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := editedForm(t, "Sign in")
+	second.Fields = append(second.Fields, domain.FormField{Name: "password", Type: "password", Label: "Password"})
+	if _, err := useCase.UpdateForm("owner", form.ID, nil, second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := useCase.UpdateForm("owner", form.ID, nil, editedForm(t, "Sign in")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name             string
+		version, against int
+		wantFrom         int
+		wantSummary      []string
+	}{
+		{"preceding version", 2, 0, 1, []string{"Added field 'password' (password)", "Changed title"}},
+		{"explicit version", 3, 1, 1, []string{"Changed title"}},
+		{"backwards", 1, 2, 2, []string{"Removed field 'password'", "Changed title"}},
+		{"same version", 2, 2, 2, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := useCase.DiffVersions("owner", form.ID, tt.version, tt.against)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff.FormID != form.ID || diff.From != tt.wantFrom || diff.To != tt.version {
+				t.Errorf("DiffVersions = %s %d..%d, want %s %d..%d", diff.FormID, diff.From, diff.To, form.ID, tt.wantFrom, tt.version)
+			}
+			if !reflect.DeepEqual(diff.Changes.Summary, tt.wantSummary) {
+				t.Errorf("Summary = %q, want %q", diff.Changes.Summary, tt.wantSummary)
+			}
+
+			// The patch turns the older version into the newer one.
+			from, _ := useCase.(*FormUseCase).forms.GetVersion(form.ID, diff.From)
+			to, _ := useCase.(*FormUseCase).forms.GetVersion(form.ID, diff.To)
+			fromDoc, err := jsonpatch.ToDocument(&from.Config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			toDoc, err := jsonpatch.ToDocument(&to.Config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := jsonpatch.Apply(fromDoc, diff.Changes.Patch)
+			if err != nil {
+				t.Fatalf("Apply(patch) failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, toDoc) {
+				t.Errorf("Apply(patch) = %v, want %v", got, toDoc)
+			}
+		})
+	}
+
+	for _, versions := range [][2]int{{1, 0}, {4, 0}, {2, 7}} {
+		if _, err := useCase.DiffVersions("owner", form.ID, versions[0], versions[1]); !errors.Is(err, domain.ErrVersionNotFound) {
+			t.Errorf("DiffVersions(%d, %d) error = %v, want ErrVersionNotFound", versions[0], versions[1], err)
+		}
+	}
+}
+
+func TestRollbackChecksTheCurrentDataSourcePolicy(t *testing.T) {
+	forms := infrastructure.NewInMemoryFormRepository()
+	config, err := domain.ParseFormConfig([]byte(`{"title":"Countries","endpoint":"/api/x","submit":{"label":"Send"},"fields":[{"name":"country","type":"select","dataSource":{"type":"remote","endpoint":"https://old.example.com/countries"}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := NewFormUseCase(forms, validation.DataSourcePolicy{AllowedHosts: []string{"old.example.com"}})
+	form, err := before.CreateForm("owner", "", config, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := before.UpdateForm("owner", form.ID, nil, mustParseForm(t, testGeneratedForm)); err != nil {
+		t.Fatal(err)
+	}
+
+	// old.example.com is no longer allowed, so version 1 cannot be restored.
+	after := NewFormUseCase(forms, testDataSourcePolicy)
+	_, err = after.Rollback("owner", form.ID, 1)
+	var invalid *InvalidFormError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Rollback error = %v, want an InvalidFormError", err)
+	}
+	if versions, _ := after.ListVersions("owner", form.ID); len(versions) != 2 {
+		t.Errorf("ListVersions = %d versions, want the rejected rollback not to be saved", len(versions))
+	}
+}
+
+func TestConcurrentVersionsConflict(t *testing.T) {
+	useCase := newFormUseCase()
+	form, err := useCase.CreateForm("owner", "Form", mustParseForm(t, testGeneratedForm), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every save reads the current version and writes the next one; saves
+	// racing for the same version fail instead of overwriting each other.
+	const saves = 20
+	configs := make([]*domain.FormConfig, saves)
+	for i := range configs {
+		configs[i] = editedForm(t, fmt.Sprintf("Edit %d", i))
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, saves)
+	for _, config := range configs {
+		wg.Add(1)
+		go func(config *domain.FormConfig) {
+			defer wg.Done()
+			_, err := useCase.UpdateForm("owner", form.ID, nil, config)
+			errs <- err
+		}(config)
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, domain.ErrVersionConflict):
+			t.Errorf("UpdateForm error = %v, want nil or ErrVersionConflict", err)
+		}
+	}
+	versions, err := useCase.ListVersions("owner", form.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != succeeded+1 {
+		t.Errorf("ListVersions = %d versions, want %d: one per successful save", len(versions), succeeded+1)
+	}
+	current, _ := useCase.GetForm("owner", form.ID)
+	if last := versions[len(versions)-1]; current.Version != last.Version || current.Config.Title != last.Config.Title {
+		t.Errorf("current form = version %d %q, want the last version %d %q", current.Version, current.Config.Title, last.Version, last.Config.Title)
+	}
+}
@@ -0,0 +1,76 @@
+// usecase/prompt_input.go
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultMaxPromptLength is used when FormGeneratorConfig.MaxPromptLength is zero.
+const DefaultMaxPromptLength = 4000
+
+// Errors returned for user text that is not sent to the model.
+var (
+	ErrEmptyPrompt   = errors.New("prompt is empty")
+	ErrPromptTooLong = errors.New("prompt is too long")
+)
+
+// userRequestTags matches the delimiters the prompt templates put around
+// user text, so that the text cannot close its own block and continue as
+// instructions.
+var userRequestTags = regexp.MustCompile(`(?i)<\s*/?\s*user_request\s*>`)
+
+// sanitizeUserText cleans text typed by a user before it reaches the model:
+// control characters (except newlines and tabs) and invisible formatting
+// characters such as zero-width spaces and bidi overrides are dropped, as are
+// the template's user text delimiters. The result is trimmed and limited to
+// maxLength characters.
+func sanitizeUserText(text string, maxLength int) (string, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case r == utf8.RuneError, unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			return -1
+		}
+		return r
+	}, text)
+	text = strings.TrimSpace(userRequestTags.ReplaceAllString(text, ""))
+
+	if text == "" {
+		return "", ErrEmptyPrompt
+	}
+	if length := utf8.RuneCountInString(text); length > maxLength {
+		return "", fmt.Errorf("%w: %d characters, at most %d are allowed", ErrPromptTooLong, length, maxLength)
+	}
+	return text, nil
+}
+
+// sanitizeInput applies sanitizeUserText to the prompt and to the user turns
+// of client-supplied history.
+func (uc *FormGeneratorUseCase) sanitizeInput(input ChatInput) (ChatInput, error) {
+	var err error
+	if input.Prompt, err = sanitizeUserText(input.Prompt, uc.config.MaxPromptLength); err != nil {
+		return input, err
+	}
+	if len(input.History) == 0 {
+		return input, nil
+	}
+	history := make([]domain.Message, len(input.History))
+	for i, message := range input.History {
+		if message.Role == domain.RoleUser {
+			if message.Content, err = sanitizeUserText(message.Content, uc.config.MaxPromptLength); err != nil {
+				return input, fmt.Errorf("history message %d: %w", i, err)
+			}
+		}
+		history[i] = message
+	}
+	input.History = history
+	return input, nil
+}
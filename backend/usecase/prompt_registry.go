@@ -0,0 +1,249 @@
+// usecase/prompt_registry.go
+package usecase
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"text/template"
+)
+
+// DefaultPromptVersion is the embedded prompt version used when neither the
+// deployment nor the request selects one.
+const DefaultPromptVersion = "v2"
+
+// embeddedPrompts holds the built-in prompt templates, one directory per version.
+//
+//go:embed prompts
+var embeddedPrompts embed.FS
+
+// ErrUnknownPromptVersion is returned when a request selects a prompt version
+// that is not registered, or that requests may not select.
+var ErrUnknownPromptVersion = errors.New("unknown prompt version")
+
+// Prompt template files every version directory must contain.
+const (
+	systemPromptFile     = "system.tmpl"
+	refinementPromptFile = "refinement.tmpl"
+	repairPromptFile     = "repair.tmpl"
+)
+
+// PromptTemplate is one version of the prompts used to generate forms.
+type PromptTemplate struct {
+	Version string
+	// digest identifies the template sources, so that editing the files of a
+	// directory-loaded version invalidates cached forms even without a new id.
+	digest     string
+	system     *template.Template
+	refinement *template.Template
+	repair     *template.Template
+}
+
+// systemPromptData is available to system.tmpl.
+type systemPromptData struct {
+	Version string
+}
+
+// refinementPromptData is available to refinement.tmpl, the user message when
+// the client sends the form it is currently editing.
+type refinementPromptData struct {
+	CurrentForm string
+	Request     string
+}
+
+// repairPromptData is available to repair.tmpl, the user message that replaces
+// the request when the previous answer could not be used. CurrentForm is
+// empty unless the request was an edit.
+type repairPromptData struct {
+	CurrentForm    string
+	Request        string
+	PreviousOutput string
+	Problems       string
+}
+
+// SystemPrompt renders the system instruction.
+func (t *PromptTemplate) SystemPrompt() (string, error) {
+	return render(t.system, systemPromptData{Version: t.Version})
+}
+
+// RefinementPrompt renders the user message for an edit of currentForm.
+func (t *PromptTemplate) RefinementPrompt(currentForm, request string) (string, error) {
+	return render(t.refinement, refinementPromptData{CurrentForm: currentForm, Request: request})
+}
+
+// RepairPrompt renders the user message asking the model to fix its answer
+// to request, an edit of currentForm unless that is empty.
+func (t *PromptTemplate) RepairPrompt(currentForm, request, previousOutput, problems string) (string, error) {
+	return render(t.repair, repairPromptData{CurrentForm: currentForm, Request: request, PreviousOutput: previousOutput, Problems: problems})
+}
+
+func render(tmpl *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %s: %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+// PromptRegistry holds the available prompt templates by version.
+type PromptRegistry struct {
+	templates      map[string]*PromptTemplate
+	defaultVersion string
+	// selectable are the versions besides the default that requests may
+	// select with Select.
+	selectable map[string]bool
+}
+
+// NewPromptRegistry loads the embedded prompts and then every source in turn.
+// Each source holds one directory per version id containing system.tmpl,
+// refinement.tmpl and repair.tmpl; a later source replaces a version of the
+// same id. defaultVersion is used by requests that do not select a version;
+// empty means DefaultPromptVersion.
+func NewPromptRegistry(defaultVersion string, sources ...fs.FS) (*PromptRegistry, error) {
+	embedded, err := fs.Sub(embeddedPrompts, "prompts")
+	if err != nil {
+		return nil, err
+	}
+	registry := &PromptRegistry{templates: map[string]*PromptTemplate{}, defaultVersion: defaultVersion, selectable: map[string]bool{}}
+	if registry.defaultVersion == "" {
+		registry.defaultVersion = DefaultPromptVersion
+	}
+	for _, source := range append([]fs.FS{embedded}, sources...) {
+		if err := registry.load(source); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := registry.templates[registry.defaultVersion]; !ok {
+		return nil, fmt.Errorf("%w: default %q", ErrUnknownPromptVersion, registry.defaultVersion)
+	}
+	return registry, nil
+}
+
+// load parses every version directory of source.
+func (r *PromptRegistry) load(source fs.FS) error {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return fmt.Errorf("failed to list prompt versions: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		tmpl, err := loadPromptTemplate(source, entry.Name())
+		if err != nil {
+			return err
+		}
+		r.templates[tmpl.Version] = tmpl
+	}
+	return nil
+}
+
+func loadPromptTemplate(source fs.FS, version string) (*PromptTemplate, error) {
+	tmpl := &PromptTemplate{Version: version}
+	hash := sha256.New()
+	for _, file := range []struct {
+		name   string
+		target **template.Template
+	}{
+		{systemPromptFile, &tmpl.system},
+		{refinementPromptFile, &tmpl.refinement},
+		{repairPromptFile, &tmpl.repair},
+	} {
+		name := path.Join(version, file.name)
+		text, err := fs.ReadFile(source, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt template %s: %w", name, err)
+		}
+		parsed, err := template.New(name).Parse(string(text))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse prompt template %s: %w", name, err)
+		}
+		*file.target = parsed
+		hash.Write([]byte(name))
+		hash.Write([]byte{0})
+		hash.Write(text)
+	}
+	tmpl.digest = hex.EncodeToString(hash.Sum(nil))
+
+	// A misspelled variable only fails when the template is executed, so
+	// render every template once rather than fail a request later.
+	if _, err := tmpl.SystemPrompt(); err != nil {
+		return nil, err
+	}
+	if _, err := tmpl.RefinementPrompt("", ""); err != nil {
+		return nil, err
+	}
+	if _, err := tmpl.RepairPrompt("", "", "", ""); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// Get returns the template of version; empty selects the default version.
+func (r *PromptRegistry) Get(version string) (*PromptTemplate, error) {
+	if version == "" {
+		version = r.defaultVersion
+	}
+	tmpl, ok := r.templates[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q (available: %v)", ErrUnknownPromptVersion, version, r.Versions())
+	}
+	return tmpl, nil
+}
+
+// Select returns the template of a version chosen by a request: the default
+// version, or one allowed with SetSelectable. Other versions, such as older
+// templates kept to compare against, are only available to the deployment.
+func (r *PromptRegistry) Select(version string) (*PromptTemplate, error) {
+	if version != "" && version != r.defaultVersion && !r.selectable[version] {
+		return nil, fmt.Errorf("%w: %q (selectable: %v)", ErrUnknownPromptVersion, version, r.SelectableVersions())
+	}
+	return r.Get(version)
+}
+
+// SetSelectable lets requests select versions in addition to the default.
+// Every version must be registered.
+func (r *PromptRegistry) SetSelectable(versions ...string) error {
+	selectable := map[string]bool{}
+	for _, version := range versions {
+		if _, ok := r.templates[version]; !ok {
+			return fmt.Errorf("%w: selectable %q", ErrUnknownPromptVersion, version)
+		}
+		selectable[version] = true
+	}
+	r.selectable = selectable
+	return nil
+}
+
+// SelectableVersions returns the version ids requests may select, in order.
+func (r *PromptRegistry) SelectableVersions() []string {
+	versions := []string{r.defaultVersion}
+	for version := range r.selectable {
+		if version != r.defaultVersion {
+			versions = append(versions, version)
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// Versions returns the registered version ids in order.
+func (r *PromptRegistry) Versions() []string {
+	versions := make([]string, 0, len(r.templates))
+	for version := range r.templates {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// DefaultVersion is the version used when a request does not select one.
+func (r *PromptRegistry) DefaultVersion() string {
+	return r.defaultVersion
+}
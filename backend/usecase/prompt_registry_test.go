@@ -0,0 +1,157 @@
+package usecase
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPromptRegistrySelect(t *testing.T) {
+	registry, err := NewPromptRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []string{"", DefaultPromptVersion} {
+		if tmpl, err := registry.Select(version); err != nil || tmpl.Version != DefaultPromptVersion {
+			t.Errorf("Select(%q) = %v, %v; want the default", version, tmpl, err)
+		}
+	}
+	// Older templates are registered but requests cannot choose them.
+	if _, err := registry.Get("v1"); err != nil {
+		t.Fatalf("Get(v1): %v", err)
+	}
+	if _, err := registry.Select("v1"); !errors.Is(err, ErrUnknownPromptVersion) {
+		t.Errorf("Select(v1) err = %v, want ErrUnknownPromptVersion", err)
+	}
+
+	if err := registry.SetSelectable("v1"); err != nil {
+		t.Fatal(err)
+	}
+	if tmpl, err := registry.Select("v1"); err != nil || tmpl.Version != "v1" {
+		t.Errorf("Select(v1) after SetSelectable = %v, %v", tmpl, err)
+	}
+	if got := strings.Join(registry.SelectableVersions(), ","); got != "v1,v2" {
+		t.Errorf("SelectableVersions = %s, want v1,v2", got)
+	}
+	if err := registry.SetSelectable("v9"); !errors.Is(err, ErrUnknownPromptVersion) {
+		t.Errorf("SetSelectable(v9) err = %v, want ErrUnknownPromptVersion", err)
+	}
+}
+
+func TestRepairPromptDelimitsTheRequest(t *testing.T) {
+	registry, err := NewPromptRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, _ := registry.Get(DefaultPromptVersion)
+	for _, currentForm := range []string{"", `{"title":"Contact"}`} {
+		prompt, err := tmpl.RepairPrompt(currentForm, "add a phone field", `{"fields":[]}`, "- fields: required")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(prompt, "<user_request>\nadd a phone field\n</user_request>") {
+			t.Errorf("request is not delimited:\n%s", prompt)
+		}
+		if strings.Count(prompt, "<user_request>") != 1 {
+			t.Errorf("request is delimited more than once:\n%s", prompt)
+		}
+		if currentForm != "" && !strings.Contains(prompt, currentForm) {
+			t.Errorf("current form is missing:\n%s", prompt)
+		}
+	}
+}
+
+// promptSource is a version directory with the given templates.
+func promptSource(version, system, refinement, repair string) fstest.MapFS {
+	return fstest.MapFS{
+		version + "/" + systemPromptFile:     {Data: []byte(system)},
+		version + "/" + refinementPromptFile: {Data: []byte(refinement)},
+		version + "/" + repairPromptFile:     {Data: []byte(repair)},
+	}
+}
+
+func TestPromptRegistryGet(t *testing.T) {
+	custom := promptSource("v3", "system {{.Version}}", "{{.CurrentForm}} / {{.Request}}", "{{.Request}} {{.PreviousOutput}} {{.Problems}}")
+	registry, err := NewPromptRegistry("v3", custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(registry.Versions(), ","); got != "v1,v2,v3" {
+		t.Errorf("Versions = %s, want v1,v2,v3", got)
+	}
+	if registry.DefaultVersion() != "v3" {
+		t.Errorf("DefaultVersion = %s, want v3", registry.DefaultVersion())
+	}
+
+	tests := []struct {
+		version    string
+		want       string
+		wantSystem string
+	}{
+		{"", "v3", "system v3"},
+		{"v3", "v3", "system v3"},
+		{"v1", "v1", ""},
+		{"v2", "v2", ""},
+	}
+	for _, tt := range tests {
+		tmpl, err := registry.Get(tt.version)
+		if err != nil || tmpl.Version != tt.want {
+			t.Errorf("Get(%q) = %v, %v; want %s", tt.version, tmpl, err, tt.want)
+			continue
+		}
+		system, err := tmpl.SystemPrompt()
+		if err != nil || system == "" || (tt.wantSystem != "" && system != tt.wantSystem) {
+			t.Errorf("Get(%q).SystemPrompt() = %q, %v", tt.version, system, err)
+		}
+	}
+	tmpl, _ := registry.Get("v3")
+	if got, _ := tmpl.RefinementPrompt("{}", "add a field"); got != "{} / add a field" {
+		t.Errorf("RefinementPrompt = %q", got)
+	}
+
+	for _, version := range []string{"v9", "V2", "../v2"} {
+		if _, err := registry.Get(version); !errors.Is(err, ErrUnknownPromptVersion) {
+			t.Errorf("Get(%q) err = %v, want ErrUnknownPromptVersion", version, err)
+		}
+	}
+}
+
+func TestPromptRegistryOverride(t *testing.T) {
+	embedded, _ := NewPromptRegistry("")
+	builtIn, _ := embedded.Get("v2")
+
+	registry, err := NewPromptRegistry("", promptSource("v2", "replaced", "{{.Request}}", "{{.Request}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replaced, _ := registry.Get("v2")
+	if system, _ := replaced.SystemPrompt(); system != "replaced" {
+		t.Errorf("SystemPrompt = %q, want the replacement", system)
+	}
+	// Cached forms of the built-in templates must not be served for the
+	// replacement, although the version id is the same.
+	if replaced.digest == builtIn.digest {
+		t.Error("replaced templates have the digest of the built-in ones")
+	}
+}
+
+func TestNewPromptRegistryErrors(t *testing.T) {
+	tests := map[string]struct {
+		defaultVersion string
+		source         fstest.MapFS
+	}{
+		"unknown default":  {"v9", fstest.MapFS{}},
+		"missing template": {"", fstest.MapFS{"v3/" + systemPromptFile: {Data: []byte("system")}}},
+		"syntax error":     {"", promptSource("v3", "{{.Version", "", "")},
+		"unknown variable": {"", promptSource("v3", "", "{{.Form}}", "")},
+	}
+	for name, tt := range tests {
+		if _, err := NewPromptRegistry(tt.defaultVersion, tt.source); err == nil {
+			t.Errorf("%s: registry was created", name)
+		}
+	}
+	if _, err := NewPromptRegistry("v9"); !errors.Is(err, ErrUnknownPromptVersion) {
+		t.Errorf("unknown default: err = %v, want ErrUnknownPromptVersion", err)
+	}
+}
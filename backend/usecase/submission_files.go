@@ -0,0 +1,200 @@
+// usecase/submission_files.go
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultFileURLTTL is how long download URLs stay valid by default.
+const DefaultFileURLTTL = time.Hour
+
+// maxFileNameLength bounds the stored name of an uploaded file, in runes.
+const maxFileNameLength = 255
+
+// sniffLength is how much of a file http.DetectContentType looks at.
+const sniffLength = 512
+
+// ErrInvalidDownloadLink is returned for download URLs with a wrong signature
+// or past their expiry.
+var ErrInvalidDownloadLink = errors.New("invalid or expired download link")
+
+// BlobStore keeps the content of uploaded files. Keys are slash-separated
+// IDs.
+type BlobStore interface {
+	Put(key string, content io.Reader) error
+	// Open returns domain.ErrFileNotFound when nothing is stored under key.
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// FileUpload is a file submitted to a file field.
+type FileUpload struct {
+	// Name is the file name given by the client.
+	Name    string
+	Size    int64
+	Content io.ReadSeeker
+}
+
+// FileConfig configures the storage of uploaded files.
+type FileConfig struct {
+	Blobs BlobStore
+	// URLSecret signs download URLs. URLs signed with another secret, such
+	// as one generated by a previous run, are rejected.
+	URLSecret []byte
+	// URLTTL is how long a download URL stays valid; 0 means DefaultFileURLTTL.
+	URLTTL time.Duration
+}
+
+// sniffedUpload is an upload together with the type detected from its content.
+type sniffedUpload struct {
+	FileUpload
+	name        string
+	contentType string
+}
+
+// sniffUploads detects the content type of every upload and rewinds it.
+func sniffUploads(uploads []FileUpload) ([]sniffedUpload, error) {
+	sniffed := make([]sniffedUpload, 0, len(uploads))
+	for _, upload := range uploads {
+		head := make([]byte, sniffLength)
+		n, err := io.ReadFull(upload.Content, head)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read upload: %w", err)
+		}
+		if _, err := upload.Content.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to read upload: %w", err)
+		}
+		sniffed = append(sniffed, sniffedUpload{
+			FileUpload:  upload,
+			name:        cleanFileName(upload.Name),
+			contentType: http.DetectContentType(head[:n]),
+		})
+	}
+	return sniffed, nil
+}
+
+// cleanFileName keeps the base name of a client file name, without control
+// characters, so it is safe to echo in headers and exports.
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if runes := []rune(name); len(runes) > maxFileNameLength {
+		name = string(runes[len(runes)-maxFileNameLength:]) // keep the extension
+	}
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	return name
+}
+
+// storeUploads writes the uploads of a submission to the blob store and
+// returns their descriptions. On failure the blobs already written are removed.
+func (uc *SubmissionUseCase) storeUploads(formID string, fields []string, uploads map[string][]sniffedUpload) ([]domain.SubmissionFile, error) {
+	var files []domain.SubmissionFile
+	for _, field := range fields {
+		for _, upload := range uploads[field] {
+			id, err := newID()
+			if err == nil {
+				err = uc.files.Blobs.Put(blobKey(formID, id), upload.Content)
+			}
+			if err != nil {
+				uc.deleteBlobs(formID, files)
+				return nil, fmt.Errorf("failed to store file: %w", err)
+			}
+			files = append(files, domain.SubmissionFile{
+				ID:          id,
+				Field:       field,
+				Name:        upload.name,
+				ContentType: upload.contentType,
+				Size:        upload.Size,
+			})
+		}
+	}
+	return files, nil
+}
+
+func (uc *SubmissionUseCase) deleteBlobs(formID string, files []domain.SubmissionFile) {
+	for _, file := range files {
+		if err := uc.files.Blobs.Delete(blobKey(formID, file.ID)); err != nil {
+			log.Printf("Failed to remove file %s: %v", file.ID, err)
+		}
+	}
+}
+
+func blobKey(formID, fileID string) string {
+	return formID + "/" + fileID
+}
+
+// withDownloadURLs returns a copy of submission whose files carry signed
+// download URLs.
+func (uc *SubmissionUseCase) withDownloadURLs(submission *domain.Submission) *domain.Submission {
+	if len(submission.Files) == 0 {
+		return submission
+	}
+	signed := *submission
+	signed.Files = slices.Clone(submission.Files)
+	expires := strconv.FormatInt(time.Now().Add(uc.files.URLTTL).Unix(), 10)
+	for i := range signed.Files {
+		file := &signed.Files[i]
+		query := url.Values{"expires": {expires}, "signature": {uc.signFile(submission.ID, file.ID, expires)}}
+		file.URL = fmt.Sprintf("/api/submissions/%s/files/%s?%s", submission.ID, file.ID, query.Encode())
+	}
+	return &signed
+}
+
+func (uc *SubmissionUseCase) signFile(submissionID, fileID, expires string) string {
+	mac := hmac.New(sha256.New, uc.files.URLSecret)
+	mac.Write([]byte(submissionID + "/" + fileID + "/" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// OpenFile checks a download URL and returns the file with its content.
+func (uc *SubmissionUseCase) OpenFile(submissionID, fileID, expires, signature string) (*domain.SubmissionFile, io.ReadCloser, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return nil, nil, ErrInvalidDownloadLink
+	}
+	expected := uc.signFile(submissionID, fileID, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, nil, ErrInvalidDownloadLink
+	}
+
+	submission, err := uc.submissions.Get(submissionID)
+	if errors.Is(err, domain.ErrSubmissionNotFound) {
+		return nil, nil, domain.ErrFileNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, file := range submission.Files {
+		if file.ID == fileID {
+			content, err := uc.files.Blobs.Open(blobKey(submission.FormID, file.ID))
+			if err != nil {
+				return nil, nil, err
+			}
+			return &file, content, nil
+		}
+	}
+	return nil, nil, domain.ErrFileNotFound
+}
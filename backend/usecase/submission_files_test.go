@@ -0,0 +1,178 @@
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	pngContent = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	pdfContent = "%PDF-1.4\n%test document"
+)
+
+func upload(name, content string) FileUpload {
+	return FileUpload{Name: name, Size: int64(len(content)), Content: strings.NewReader(content)}
+}
+
+// downloadLink splits a download URL into the arguments of OpenFile.
+func downloadLink(t *testing.T, file domain.SubmissionFile) (fileID, expires, signature string) {
+	t.Helper()
+	link, err := url.Parse(file.URL)
+	if err != nil {
+		t.Fatalf("invalid download URL %q: %v", file.URL, err)
+	}
+	query := link.Query()
+	return path.Base(link.Path), query.Get("expires"), query.Get("signature")
+}
+
+func TestSubmitSniffsUploads(t *testing.T) {
+	f := newSubmissionFixture(t, `[{"name":"photo","type":"file","attributes":{"accept":"image/*"}},{"name":"cv","type":"file","attributes":{"accept":".pdf"}}]`)
+
+	submission, err := f.useCase.Submit(f.form.ID, map[string]interface{}{"photo": "ignored"}, map[string][]FileUpload{
+		"photo": {upload(`C:\Users\ada\me.jpeg`, pngContent)},
+		"cv":    {upload("../../cv.pdf", pdfContent)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(submission.Files) != 2 {
+		t.Fatalf("files = %+v, want 2", submission.Files)
+	}
+	photo, cv := submission.Files[0], submission.Files[1]
+	// The type comes from the content, not from the name.
+	if photo.ContentType != "image/png" || photo.Name != "me.jpeg" || photo.Size != int64(len(pngContent)) {
+		t.Errorf("photo = %+v", photo)
+	}
+	if cv.ContentType != "application/pdf" || cv.Name != "cv.pdf" {
+		t.Errorf("cv = %+v", cv)
+	}
+	if submission.Data["photo"] != "me.jpeg" || submission.Data["cv"] != "cv.pdf" {
+		t.Errorf("data = %v, want the file names", submission.Data)
+	}
+
+	// The stored content is the whole upload, not what was sniffed.
+	fileID, expires, signature := downloadLink(t, cv)
+	_, content, err := f.useCase.OpenFile(submission.ID, fileID, expires, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer content.Close()
+	if stored, _ := io.ReadAll(content); string(stored) != pdfContent {
+		t.Errorf("stored content = %q, want %q", stored, pdfContent)
+	}
+}
+
+func TestSubmitRejectsUploads(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		uploads []FileUpload
+		want    string
+	}{
+		{"renamed file", `{"name":"doc","type":"file","attributes":{"accept":".pdf"}}`, []FileUpload{upload("doc.pdf", pngContent)}, "invalid_file_type"},
+		{"too large", `{"name":"doc","type":"file","attributes":{"maxSize":16}}`, []FileUpload{upload("doc.pdf", pdfContent)}, "too_big"},
+		{"too many", `{"name":"doc","type":"file","attributes":{"multiple":true,"maxFiles":1}}`, []FileUpload{upload("a.pdf", pdfContent), upload("b.pdf", pdfContent)}, "too_big"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSubmissionFixture(t, "["+tt.field+"]")
+			_, err := f.useCase.Submit(f.form.ID, nil, map[string][]FileUpload{"doc": tt.uploads})
+			var invalid *InvalidSubmissionError
+			if !errors.As(err, &invalid) || len(invalid.Issues) == 0 || invalid.Issues[0].Code != tt.want {
+				t.Fatalf("Submit = %v, want a %s issue", err, tt.want)
+			}
+			page, err := f.useCase.ListSubmissions("owner", f.form.ID, SubmissionFilter{}, "", 0)
+			if err != nil || len(page.Submissions) != 0 {
+				t.Errorf("stored submissions = %+v, %v; want none", page, err)
+			}
+		})
+	}
+}
+
+func TestSubmitKeepsNoFilesOfHiddenFields(t *testing.T) {
+	f := newSubmissionFixture(t, `[{"name":"attach","type":"checkbox"},{"name":"doc","type":"file","visibleWhen":[{"field":"attach","operator":"equals","value":true}]}]`)
+	submission, err := f.useCase.Submit(f.form.ID, map[string]interface{}{"attach": false}, map[string][]FileUpload{"doc": {upload("doc.pdf", pdfContent)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(submission.Files) != 0 {
+		t.Errorf("files = %+v, want none for a hidden field", submission.Files)
+	}
+}
+
+func TestOpenFileChecksTheSignedURL(t *testing.T) {
+	f := newSubmissionFixture(t, `[{"name":"doc","type":"file"}]`)
+	submission, err := f.useCase.Submit(f.form.ID, nil, map[string][]FileUpload{"doc": {upload("doc.pdf", pdfContent)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileID, expires, signature := downloadLink(t, submission.Files[0])
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		t.Fatalf("expires = %q", expires)
+	}
+	if ttl := time.Until(time.Unix(expiresAt, 0)); ttl < DefaultFileURLTTL-time.Minute || ttl > DefaultFileURLTTL {
+		t.Errorf("the link expires in %v, want %v", ttl, DefaultFileURLTTL)
+	}
+
+	file, content, err := f.useCase.OpenFile(submission.ID, fileID, expires, signature)
+	if err != nil {
+		t.Fatalf("OpenFile with a valid link = %v", err)
+	}
+	content.Close()
+	if file.ID != fileID || file.Name != "doc.pdf" {
+		t.Errorf("file = %+v", file)
+	}
+
+	uc := f.useCase.(*SubmissionUseCase)
+	past := strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10)
+	later := strconv.FormatInt(expiresAt+3600, 10)
+	other := NewSubmissionUseCase(nil, nil, nil, FileConfig{URLSecret: []byte("another secret")}).(*SubmissionUseCase)
+	invalid := []struct {
+		name                                string
+		submissionID, fileID, expires, sign string
+	}{
+		{"expired", submission.ID, fileID, past, uc.signFile(submission.ID, fileID, past)},
+		{"extended expiry", submission.ID, fileID, later, signature},
+		{"tampered signature", submission.ID, fileID, expires, strings.Repeat("0", len(signature))},
+		{"no signature", submission.ID, fileID, expires, ""},
+		{"malformed expiry", submission.ID, fileID, "tomorrow", signature},
+		{"another file", submission.ID, "other", expires, signature},
+		{"another secret", submission.ID, fileID, expires, other.signFile(submission.ID, fileID, expires)},
+	}
+	for _, tt := range invalid {
+		if _, _, err := f.useCase.OpenFile(tt.submissionID, tt.fileID, tt.expires, tt.sign); !errors.Is(err, ErrInvalidDownloadLink) {
+			t.Errorf("%s: OpenFile = %v, want ErrInvalidDownloadLink", tt.name, err)
+		}
+	}
+
+	// A validly signed link to a file that does not exist.
+	if _, _, err := f.useCase.OpenFile(submission.ID, "missing", expires, uc.signFile(submission.ID, "missing", expires)); !errors.Is(err, domain.ErrFileNotFound) {
+		t.Errorf("OpenFile of a missing file = %v, want ErrFileNotFound", err)
+	}
+}
+
+func TestCleanFileName(t *testing.T) {
+	tests := map[string]string{
+		"report.pdf":                      "report.pdf",
+		"../../etc/passwd":                "passwd",
+		`C:\Users\ada\cv.docx`:            "cv.docx",
+		"a\"b\r\nc\u202e.txt":             "abc.txt",
+		"  ":                              "file",
+		"dir/":                            "dir",
+		"/":                               "file",
+		strings.Repeat("x", 300) + ".pdf": strings.Repeat("x", 251) + ".pdf",
+	}
+	for name, want := range tests {
+		if got := cleanFileName(name); got != want {
+			t.Errorf("cleanFileName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
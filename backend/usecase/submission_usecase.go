@@ -0,0 +1,412 @@
+// usecase/submission_usecase.go
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/validation"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SubmissionRepository persists form submissions.
+type SubmissionRepository interface {
+	Create(submission *domain.Submission) error
+	// Get returns domain.ErrSubmissionNotFound when the submission does not exist.
+	Get(id string) (*domain.Submission, error)
+	// List returns the submissions matching query, newest first.
+	List(query domain.SubmissionQuery) ([]*domain.Submission, error)
+}
+
+// Page sizes of submission listings.
+const (
+	DefaultSubmissionPageSize = 50
+	MaxSubmissionPageSize     = 200
+	// exportBatchSize is how many submissions an export reads at a time.
+	exportBatchSize = 500
+)
+
+// ErrInvalidSubmissionQuery is returned for unknown filter fields, filter
+// values that do not fit the field and malformed cursors.
+var ErrInvalidSubmissionQuery = errors.New("invalid submission query")
+
+// SubmissionFilter narrows a listing or an export of submissions.
+type SubmissionFilter struct {
+	// From and To bound the submission time to [From, To); zero values leave
+	// the range open.
+	From time.Time
+	To   time.Time
+	// Fields maps field names to the value to match, as text. Array values
+	// match when they contain the value.
+	Fields map[string]string
+}
+
+// SubmissionPage is one page of a submission listing.
+type SubmissionPage struct {
+	Submissions []*domain.Submission `json:"submissions"`
+	// NextCursor fetches the next page; empty on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// SubmissionExportWriter encodes exported submissions in one file format.
+type SubmissionExportWriter interface {
+	// WriteHeader is called once, before any row, with the column titles.
+	WriteHeader(columns []string) error
+	// WriteRow is called for every submission with its cells in column order.
+	WriteRow(submission *domain.Submission, cells []string) error
+	// Close completes the file.
+	Close() error
+}
+
+// InvalidSubmissionError is returned when submitted values break the rules
+// of the form. Issues are addressed by field name.
+type InvalidSubmissionError struct {
+	Issues validation.Issues
+}
+
+func (e *InvalidSubmissionError) Error() string {
+	return "invalid submission: " + e.Issues.Error()
+}
+
+// SubmissionUseCaseInterface defines the contract of the submission runtime.
+type SubmissionUseCaseInterface interface {
+	// Submit validates values and the files uploaded to file fields, keyed
+	// by field name, against the current config of the form and stores them.
+	// Anyone who knows the form ID may submit.
+	Submit(formID string, values map[string]interface{}, uploads map[string][]FileUpload) (*domain.Submission, error)
+	// ListSubmissions returns a page of the submissions of a form owned by
+	// ownerID, newest first. An empty cursor starts at the newest submission.
+	ListSubmissions(ownerID, formID string, filter SubmissionFilter, cursor string, limit int) (*SubmissionPage, error)
+	// ExportSubmissions writes every matching submission of a form owned by
+	// ownerID to writer, with the field labels as column titles.
+	ExportSubmissions(ownerID, formID string, filter SubmissionFilter, writer SubmissionExportWriter) error
+	// OpenFile returns an uploaded file and its content if the signature of
+	// its download URL is valid and has not expired.
+	OpenFile(submissionID, fileID, expires, signature string) (*domain.SubmissionFile, io.ReadCloser, error)
+}
+
+// SubmissionUseCase is the implementation of SubmissionUseCaseInterface.
+type SubmissionUseCase struct {
+	forms       FormRepository
+	submissions SubmissionRepository
+	notifier    SubmissionNotifier
+	files       FileConfig
+}
+
+// NewSubmissionUseCase creates a new instance of SubmissionUseCase. notifier
+// may be nil.
+func NewSubmissionUseCase(forms FormRepository, submissions SubmissionRepository, notifier SubmissionNotifier, files FileConfig) SubmissionUseCaseInterface {
+	if files.URLTTL <= 0 {
+		files.URLTTL = DefaultFileURLTTL
+	}
+	return &SubmissionUseCase{forms: forms, submissions: submissions, notifier: notifier, files: files}
+}
+
+// Submit validates, coerces and stores a submission. The value of a file
+// field is the name of the uploaded file, or the names of several; values
+// sent for file fields in place of uploads are ignored.
+func (uc *SubmissionUseCase) Submit(formID string, values map[string]interface{}, uploads map[string][]FileUpload) (*domain.Submission, error) {
+	form, err := uc.forms.Get(formID)
+	if err != nil {
+		return nil, err
+	}
+
+	values = maps.Clone(values)
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	sniffed := make(map[string][]sniffedUpload)
+	for i := range form.Config.Fields {
+		field := &form.Config.Fields[i]
+		if field.Type != domain.FieldFile {
+			continue
+		}
+		delete(values, field.Name)
+		if len(uploads[field.Name]) == 0 {
+			continue
+		}
+		if sniffed[field.Name], err = sniffUploads(uploads[field.Name]); err != nil {
+			return nil, err
+		}
+		names := make([]interface{}, len(sniffed[field.Name]))
+		for j, upload := range sniffed[field.Name] {
+			names[j] = upload.name
+		}
+		if validation.FieldDataType(field) == domain.DataArray {
+			values[field.Name] = names
+		} else {
+			values[field.Name] = names[0]
+		}
+	}
+
+	data, issues := validation.ValidateSubmission(&form.Config, values)
+	var uploaded []string
+	for i := range form.Config.Fields {
+		field := &form.Config.Fields[i]
+		if _, visible := data[field.Name]; !visible || len(sniffed[field.Name]) == 0 {
+			continue // hidden fields keep no files
+		}
+		files := make([]validation.UploadedFile, len(sniffed[field.Name]))
+		for j, upload := range sniffed[field.Name] {
+			files[j] = validation.UploadedFile{Name: upload.name, Size: upload.Size, ContentType: upload.contentType}
+		}
+		issues = append(issues, validation.ValidateUploads(field, files)...)
+		uploaded = append(uploaded, field.Name)
+	}
+	if len(issues) > 0 {
+		return nil, &InvalidSubmissionError{Issues: issues}
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	files, err := uc.storeUploads(form.ID, uploaded, sniffed)
+	if err != nil {
+		return nil, err
+	}
+	submission := &domain.Submission{
+		ID:          id,
+		FormID:      form.ID,
+		FormVersion: form.Version,
+		Data:        data,
+		Files:       files,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := uc.submissions.Create(submission); err != nil {
+		uc.deleteBlobs(form.ID, files)
+		return nil, fmt.Errorf("failed to save submission: %w", err)
+	}
+	submission = uc.withDownloadURLs(submission)
+	if uc.notifier != nil {
+		uc.notifier.SubmissionCreated(submission)
+	}
+	return submission, nil
+}
+
+// ListSubmissions returns one page of submissions.
+func (uc *SubmissionUseCase) ListSubmissions(ownerID, formID string, filter SubmissionFilter, cursor string, limit int) (*SubmissionPage, error) {
+	form, err := ownedForm(uc.forms, ownerID, formID)
+	if err != nil {
+		return nil, err
+	}
+	query, err := newSubmissionQuery(form, filter)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != "" {
+		if query.After, err = decodeSubmissionCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+	if limit <= 0 {
+		limit = DefaultSubmissionPageSize
+	}
+	query.Limit = min(limit, MaxSubmissionPageSize) + 1 // one more tells whether a next page exists
+
+	submissions, err := uc.submissions.List(query)
+	if err != nil {
+		return nil, err
+	}
+	page := &SubmissionPage{Submissions: submissions}
+	if len(submissions) == query.Limit {
+		page.Submissions = submissions[:len(submissions)-1]
+		page.NextCursor = encodeSubmissionCursor(page.Submissions[len(page.Submissions)-1])
+	}
+	for i, submission := range page.Submissions {
+		page.Submissions[i] = uc.withDownloadURLs(submission)
+	}
+	return page, nil
+}
+
+// ExportSubmissions writes every matching submission, newest first.
+func (uc *SubmissionUseCase) ExportSubmissions(ownerID, formID string, filter SubmissionFilter, writer SubmissionExportWriter) error {
+	form, err := ownedForm(uc.forms, ownerID, formID)
+	if err != nil {
+		return err
+	}
+	query, err := newSubmissionQuery(form, filter)
+	if err != nil {
+		return err
+	}
+
+	fields := form.Config.Fields
+	if err := writer.WriteHeader(exportColumns(fields)); err != nil {
+		return err
+	}
+	query.Limit = exportBatchSize
+	for {
+		submissions, err := uc.submissions.List(query)
+		if err != nil {
+			return err
+		}
+		for _, submission := range submissions {
+			cells := make([]string, 0, len(fields)+2)
+			cells = append(cells, submission.ID, submission.CreatedAt.UTC().Format(time.RFC3339))
+			for _, field := range fields {
+				cells = append(cells, exportCell(submission.Data[field.Name]))
+			}
+			if err := writer.WriteRow(uc.withDownloadURLs(submission), cells); err != nil {
+				return err
+			}
+		}
+		if len(submissions) < query.Limit {
+			return writer.Close()
+		}
+		last := submissions[len(submissions)-1]
+		query.After = &domain.SubmissionCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+}
+
+// ownedForm returns a form owned by ownerID; forms of other users are
+// reported as domain.ErrFormNotFound.
+func ownedForm(forms FormRepository, ownerID, formID string) (*domain.StoredForm, error) {
+	form, err := forms.Get(formID)
+	if err != nil {
+		return nil, err
+	}
+	if form.OwnerID != ownerID {
+		return nil, domain.ErrFormNotFound
+	}
+	return form, nil
+}
+
+// newSubmissionQuery converts filter into a repository query, typing every
+// field value like the submitted values of the field.
+func newSubmissionQuery(form *domain.StoredForm, filter SubmissionFilter) (domain.SubmissionQuery, error) {
+	query := domain.SubmissionQuery{FormID: form.ID, From: filter.From, To: filter.To}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return query, fmt.Errorf("%w: from must be before to", ErrInvalidSubmissionQuery)
+	}
+	if len(filter.Fields) == 0 {
+		return query, nil
+	}
+	query.Fields = make(map[string]interface{}, len(filter.Fields))
+	for name, raw := range filter.Fields {
+		field := findField(form.Config.Fields, name)
+		if field == nil {
+			return query, fmt.Errorf("%w: unknown field %q", ErrInvalidSubmissionQuery, name)
+		}
+		value, err := filterValue(field, raw)
+		if err != nil {
+			return query, fmt.Errorf("%w: field %q: %v", ErrInvalidSubmissionQuery, name, err)
+		}
+		query.Fields[name] = value
+	}
+	return query, nil
+}
+
+func findField(fields []domain.FormField, name string) *domain.FormField {
+	for i := range fields {
+		if fields[i].Name == name {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// filterValue types raw like the stored values of field: option values keep
+// the type of the option, numbers and booleans are parsed.
+func filterValue(field *domain.FormField, raw string) (interface{}, error) {
+	for _, option := range field.Options {
+		if fmt.Sprint(option.Value.Value) == raw {
+			return option.Value.Value, nil
+		}
+	}
+	switch validation.FieldDataType(field) {
+	case domain.DataNumber:
+		number, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return number, nil
+	case domain.DataBoolean:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return value, nil
+	}
+	return raw, nil
+}
+
+// encodeSubmissionCursor returns an opaque cursor positioned after submission.
+func encodeSubmissionCursor(submission *domain.Submission) string {
+	position := submission.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + submission.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(position))
+}
+
+func decodeSubmissionCursor(cursor string) (*domain.SubmissionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidSubmissionQuery)
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidSubmissionQuery)
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidSubmissionQuery)
+	}
+	return &domain.SubmissionCursor{CreatedAt: t, ID: id}, nil
+}
+
+// exportColumns returns the column titles of an export: the submission ID
+// and time, then the field labels. Labels shared by several fields are
+// told apart by the field name.
+func exportColumns(fields []domain.FormField) []string {
+	counts := make(map[string]int, len(fields))
+	for _, field := range fields {
+		counts[fieldTitle(&field)]++
+	}
+	columns := make([]string, 0, len(fields)+2)
+	columns = append(columns, "Submission ID", "Submitted at")
+	for _, field := range fields {
+		title := fieldTitle(&field)
+		if counts[title] > 1 {
+			title = fmt.Sprintf("%s (%s)", title, field.Name)
+		}
+		columns = append(columns, title)
+	}
+	return columns
+}
+
+func fieldTitle(field *domain.FormField) string {
+	if label := strings.TrimSpace(field.Label); label != "" {
+		return label
+	}
+	return field.Name
+}
+
+// exportCell renders a value as text. Multiselect and other array values are
+// flattened into a "; "-separated list; objects are written as JSON.
+func exportCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = exportCell(item)
+		}
+		return strings.Join(items, "; ")
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/infrastructure"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// submissionFixture is a submission runtime over in-memory repositories,
+// holding one form owned by "owner".
+type submissionFixture struct {
+	useCase     SubmissionUseCaseInterface
+	form        *domain.StoredForm
+	submissions *infrastructure.InMemorySubmissionRepository
+	blobs       *infrastructure.LocalBlobStore
+}
+
+// testFormID is the ID of the form created by newTestForms. It is hex, like
+// the IDs of newID, so that it can key blobs.
+const testFormID = "f0000000000000000000000000000001"
+
+// newTestForms returns a form repository holding the form testFormID, owned
+// by "owner", with the given fields.
+func newTestForms(t *testing.T, fields string) (*infrastructure.InMemoryFormRepository, *domain.StoredForm) {
+	t.Helper()
+	config, err := domain.ParseFormConfig([]byte(`{"endpoint":"/api/submit","submit":{"label":"Send"},"fields":` + fields + `}`))
+	if err != nil {
+		t.Fatalf("invalid test form: %v", err)
+	}
+	now := time.Now().UTC()
+	form := &domain.StoredForm{ID: testFormID, OwnerID: "owner", Name: "Form", Version: 1, Config: *config, CreatedAt: now, UpdatedAt: now}
+	forms := infrastructure.NewInMemoryFormRepository()
+	if err := forms.Create(form, &domain.FormVersion{FormID: form.ID, Version: 1, Source: domain.VersionCreated, Config: *config, CreatedBy: "owner", CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	return forms, form
+}
+
+func newSubmissionFixture(t *testing.T, fields string) *submissionFixture {
+	t.Helper()
+	forms, form := newTestForms(t, fields)
+	blobs, err := infrastructure.NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	submissions := infrastructure.NewInMemorySubmissionRepository()
+	useCase := NewSubmissionUseCase(forms, submissions, nil, FileConfig{Blobs: blobs, URLSecret: []byte("test secret")})
+	return &submissionFixture{useCase: useCase, form: form, submissions: submissions, blobs: blobs}
+}
+
+func (f *submissionFixture) store(t *testing.T, id string, createdAt time.Time, data map[string]interface{}) {
+	t.Helper()
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	submission := &domain.Submission{ID: id, FormID: f.form.ID, FormVersion: 1, Data: data, CreatedAt: createdAt}
+	if err := f.submissions.Create(submission); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListSubmissionsPagesAcrossEqualTimestamps(t *testing.T) {
+	f := newSubmissionFixture(t, `[{"name":"name","type":"text"}]`)
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, id := range []string{"s1", "s2", "s3", "s4", "s5", "s6"} {
+		createdAt := base
+		if i == 5 {
+			createdAt = base.Add(time.Millisecond)
+		}
+		f.store(t, id, createdAt, nil)
+	}
+	want := []string{"s6", "s5", "s4", "s3", "s2", "s1"}
+
+	var got []string
+	cursor := ""
+	for pages := 1; ; pages++ {
+		page, err := f.useCase.ListSubmissions("owner", f.form.ID, SubmissionFilter{}, cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Submissions) > 2 {
+			t.Fatalf("page %d holds %d submissions, want at most 2", pages, len(page.Submissions))
+		}
+		for _, submission := range page.Submissions {
+			got = append(got, submission.ID)
+		}
+		if page.NextCursor == "" {
+			if pages != 3 {
+				t.Errorf("listing ended after %d pages, want 3", pages)
+			}
+			break
+		}
+		if pages > 3 {
+			t.Fatal("paging does not end")
+		}
+		cursor = page.NextCursor
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pages = %v, want %v", got, want)
+	}
+}
+
+func TestListSubmissionsFilters(t *testing.T) {
+	f := newSubmissionFixture(t, `[{"name":"seats","type":"number"},{"name":"plan","type":"select","options":[{"value":"free","label":"Free"},{"value":"pro","label":"Pro"}]}]`)
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	f.store(t, "a", base, map[string]interface{}{"seats": 1.0, "plan": "free"})
+	f.store(t, "b", base.Add(time.Hour), map[string]interface{}{"seats": 3.0, "plan": "pro"})
+
+	page, err := f.useCase.ListSubmissions("owner", f.form.ID, SubmissionFilter{Fields: map[string]string{"seats": "3"}}, "", 0)
+	if err != nil || len(page.Submissions) != 1 || page.Submissions[0].ID != "b" {
+		t.Errorf("filter by number = %+v, %v; want b", page, err)
+	}
+	page, err = f.useCase.ListSubmissions("owner", f.form.ID, SubmissionFilter{To: base.Add(time.Minute)}, "", 0)
+	if err != nil || len(page.Submissions) != 1 || page.Submissions[0].ID != "a" {
+		t.Errorf("filter by time = %+v, %v; want a", page, err)
+	}
+
+	invalid := []SubmissionFilter{
+		{Fields: map[string]string{"unknown": "x"}},
+		{Fields: map[string]string{"seats": "many"}},
+		{From: base, To: base},
+	}
+	for _, filter := range invalid {
+		if _, err := f.useCase.ListSubmissions("owner", f.form.ID, filter, "", 0); !errors.Is(err, ErrInvalidSubmissionQuery) {
+			t.Errorf("filter %+v: error = %v, want ErrInvalidSubmissionQuery", filter, err)
+		}
+	}
+}
+
+func TestListSubmissionsRejectsMalformedCursors(t *testing.T) {
+	f := newSubmissionFixture(t, `[{"name":"name","type":"text"}]`)
+	for _, cursor := range []string{"not base64!", "bm8tc2VwYXJhdG9y", "eWVzdGVyZGF5fHMx"} {
+		if _, err := f.useCase.ListSubmissions("owner", f.form.ID, SubmissionFilter{}, cursor, 0); !errors.Is(err, ErrInvalidSubmissionQuery) {
+			t.Errorf("cursor %q: error = %v, want ErrInvalidSubmissionQuery", cursor, err)
+		}
+	}
+}
+
+func TestListSubmissionsOfAnotherOwner(t *testing.T) {
+	f := newSubmissionFixture(t, `[{"name":"name","type":"text"}]`)
+	if _, err := f.useCase.ListSubmissions("intruder", f.form.ID, SubmissionFilter{}, "", 0); !errors.Is(err, domain.ErrFormNotFound) {
+		t.Errorf("error = %v, want ErrFormNotFound", err)
+	}
+}
+
+func TestSubmissionCursorRoundTrip(t *testing.T) {
+	submission := &domain.Submission{ID: "s|1", CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.FixedZone("CEST", 2*60*60))}
+	cursor, err := decodeSubmissionCursor(encodeSubmissionCursor(submission))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cursor.CreatedAt.Equal(submission.CreatedAt) || cursor.ID != submission.ID {
+		t.Errorf("decoded cursor = %+v, want %v and %q", cursor, submission.CreatedAt, submission.ID)
+	}
+}
@@ -0,0 +1,152 @@
+// usecase/usage_usecase.go
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrInvalidDateRange is returned when a usage report covers no days or too many.
+var ErrInvalidDateRange = errors.New("invalid date range")
+
+// maxUsageReportDays bounds the range of a single usage report.
+const maxUsageReportDays = 366
+
+// UsageRepository persists usage records.
+type UsageRepository interface {
+	Record(record *domain.UsageRecord) error
+	// DailyTotals aggregates the records of userID created in [from, to) by
+	// UTC day and model, ordered by day and model.
+	DailyTotals(userID string, from, to time.Time) ([]domain.DailyUsage, error)
+}
+
+// UsageReport is the usage of one user over a range of days.
+type UsageReport struct {
+	UserID string `json:"userId"`
+	// From and To are the first and last UTC day of the report, inclusive.
+	From     string              `json:"from"`
+	To       string              `json:"to"`
+	Days     []domain.DailyUsage `json:"days"`
+	Requests int                 `json:"requests"`
+	Total    domain.TokenUsage   `json:"total"`
+}
+
+// UsageUseCaseInterface defines the contract for usage reports.
+type UsageUseCaseInterface interface {
+	// DailyUsage reports the usage of userID between the UTC days from and to,
+	// inclusive.
+	DailyUsage(userID string, from, to time.Time) (*UsageReport, error)
+}
+
+// UsageUseCase is the implementation of UsageUseCaseInterface.
+type UsageUseCase struct {
+	usage UsageRepository
+}
+
+// NewUsageUseCase creates a new instance of UsageUseCase.
+func NewUsageUseCase(usage UsageRepository) UsageUseCaseInterface {
+	return &UsageUseCase{usage: usage}
+}
+
+// DailyUsage aggregates the usage records of a user per day and model.
+func (uc *UsageUseCase) DailyUsage(userID string, from, to time.Time) (*UsageReport, error) {
+	from = from.UTC().Truncate(24 * time.Hour)
+	to = to.UTC().Truncate(24 * time.Hour)
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidDateRange)
+	}
+	if to.Sub(from) >= maxUsageReportDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: a report covers at most %d days", ErrInvalidDateRange, maxUsageReportDays)
+	}
+
+	days, err := uc.usage.DailyTotals(userID, from, to.Add(24*time.Hour))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load usage: %w", err)
+	}
+	report := &UsageReport{
+		UserID: userID,
+		From:   from.Format("2006-01-02"),
+		To:     to.Format("2006-01-02"),
+		Days:   days,
+	}
+	for _, day := range days {
+		report.Requests += day.Requests
+		report.Total.Add(day.Usage)
+	}
+	return report, nil
+}
+
+// UsageError is returned for a failed generation request that called the
+// model. Usage is what the calls consumed, which counts against quotas
+// whatever the outcome.
+type UsageError struct {
+	Err   error
+	Usage domain.TokenUsage
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// usageMeter accumulates the model calls of one generation request.
+type usageMeter struct {
+	calls         int
+	usage         domain.TokenUsage
+	model         string
+	finishReason  string
+	safetyRatings []domain.SafetyRating
+}
+
+func (m *usageMeter) add(response *domain.GenerationResponse) {
+	m.calls++
+	m.usage.Add(response.Usage)
+	m.model = response.Model
+	m.finishReason = response.FinishReason
+	m.safetyRatings = response.SafetyRatings
+}
+
+// recordUsage stores what a generation request consumed. Accounting must not
+// fail a request whose tokens are already spent, so errors are only logged.
+func (uc *FormGeneratorUseCase) recordUsage(userID string, meter *usageMeter, err error) {
+	if uc.usage == nil || meter.calls == 0 {
+		return
+	}
+	id, idErr := newID()
+	if idErr != nil {
+		log.Printf("Failed to record usage: %v", idErr)
+		return
+	}
+
+	outcome := "success"
+	var invalidForm *InvalidFormError
+	switch {
+	case err == nil:
+	case errors.As(err, &invalidForm):
+		outcome = "invalid_form"
+	case errors.Is(err, ErrIrrelevantPrompt):
+		outcome = "irrelevant_prompt"
+	default:
+		outcome = "error"
+	}
+
+	record := &domain.UsageRecord{
+		ID:           id,
+		UserID:       userID,
+		Model:        meter.model,
+		Calls:        meter.calls,
+		Usage:        meter.usage,
+		FinishReason: meter.finishReason,
+		Outcome:      outcome,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := uc.usage.Record(record); err != nil {
+		log.Printf("Failed to record usage: %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/infrastructure"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// usageRecorder keeps the usage records of generation requests.
+type usageRecorder struct {
+	mu      sync.Mutex
+	records []domain.UsageRecord
+}
+
+func (r *usageRecorder) Record(record *domain.UsageRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, *record)
+	return nil
+}
+
+func (r *usageRecorder) DailyTotals(string, time.Time, time.Time) ([]domain.DailyUsage, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestDailyUsage(t *testing.T) {
+	usage := infrastructure.NewInMemoryUsageRepository()
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i, record := range []domain.UsageRecord{
+		{Model: "gemini", Usage: domain.TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}, CreatedAt: day.Add(time.Hour)},
+		{Model: "gpt", Usage: domain.TokenUsage{PromptTokens: 2, CompletionTokens: 1, TotalTokens: 3}, CreatedAt: day.Add(2 * time.Hour)},
+		// The last day of the report is included.
+		{Model: "gemini", Usage: domain.TokenUsage{PromptTokens: 4, CompletionTokens: 4, TotalTokens: 8}, CreatedAt: day.Add(71 * time.Hour)},
+		{Model: "gemini", Usage: domain.TokenUsage{PromptTokens: 100, TotalTokens: 100}, CreatedAt: day.Add(72 * time.Hour)},
+	} {
+		record.ID = string(rune('a' + i))
+		record.UserID = "alice"
+		if err := usage.Record(&record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	useCase := NewUsageUseCase(usage)
+
+	// Times within a day select the whole day.
+	report, err := useCase.DailyUsage("alice", day.Add(13*time.Hour), day.Add(50*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.UserID != "alice" || report.From != "2024-05-01" || report.To != "2024-05-03" {
+		t.Errorf("report covers %s from %s to %s, want alice from 2024-05-01 to 2024-05-03", report.UserID, report.From, report.To)
+	}
+	if len(report.Days) != 3 || report.Days[2].Date != "2024-05-03" {
+		t.Errorf("Days = %+v, want three totals, the last of 2024-05-03", report.Days)
+	}
+	if want := (domain.TokenUsage{PromptTokens: 16, CompletionTokens: 10, TotalTokens: 26}); report.Requests != 3 || report.Total != want {
+		t.Errorf("report = %d requests using %+v, want 3 using %+v", report.Requests, report.Total, want)
+	}
+
+	empty, err := useCase.DailyUsage("bob", day, day)
+	if err != nil || empty.Requests != 0 || len(empty.Days) != 0 {
+		t.Errorf("DailyUsage(bob) = %+v, %v, want an empty report", empty, err)
+	}
+}
+
+func TestDailyUsageRange(t *testing.T) {
+	useCase := NewUsageUseCase(infrastructure.NewInMemoryUsageRepository())
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		from, to time.Time
+		wantErr  bool
+	}{
+		{"one day", day, day.Add(23 * time.Hour), false},
+		{"longest range", day, day.AddDate(0, 0, maxUsageReportDays-1), false},
+		{"too long", day, day.AddDate(0, 0, maxUsageReportDays), true},
+		{"reversed", day, day.Add(-time.Hour), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := useCase.DailyUsage("alice", tt.from, tt.to)
+			if got := errors.Is(err, ErrInvalidDateRange); got != tt.wantErr {
+				t.Errorf("DailyUsage error = %v, want invalid date range: %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerationUsageIsRecorded(t *testing.T) {
+	const (
+		missingEndpoint = `{"title":"Login","endpoint":"","submit":{"label":"Log in"},"fields":[{"name":"email","type":"email"}]}`
+		irrelevant      = `{"error":"IrrelevantPrompt"}`
+	)
+	perCall := domain.TokenUsage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120}
+	tests := []struct {
+		name        string
+		answers     []string
+		llmErr      error
+		wantOutcome string
+		wantCalls   int
+	}{
+		{"success", []string{testGeneratedForm}, nil, "success", 1},
+		{"repaired", []string{missingEndpoint, testGeneratedForm}, nil, "success", 2},
+		{"invalid form", []string{missingEndpoint}, nil, "invalid_form", 3},
+		{"irrelevant prompt", []string{irrelevant}, nil, "irrelevant_prompt", 1},
+		{"model error", nil, errors.New("model unavailable"), "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage := &usageRecorder{}
+			llm := &fakeLLM{answers: tt.answers, usage: perCall, err: tt.llmErr}
+			uc := NewChatUseCase(llm, nil, nil, usage, nil, FormGeneratorConfig{MaxRepairAttempts: 2})
+			result, err := uc.GenerateChatResponse(context.Background(), ChatInput{Prompt: "a login form", UserID: "alice"})
+
+			want := domain.TokenUsage{}
+			for i := 0; i < tt.wantCalls; i++ {
+				want.Add(perCall)
+			}
+			if tt.wantCalls == 0 {
+				// A request that never got an answer is not billed.
+				if len(usage.records) != 0 {
+					t.Errorf("records = %+v, want none", usage.records)
+				}
+				var usageErr *UsageError
+				if errors.As(err, &usageErr) {
+					t.Errorf("err = %v, want no UsageError", err)
+				}
+				return
+			}
+
+			if len(usage.records) != 1 {
+				t.Fatalf("records = %+v, want one", usage.records)
+			}
+			record := usage.records[0]
+			if record.UserID != "alice" || record.Model != "fake-model" || record.Calls != tt.wantCalls ||
+				record.Outcome != tt.wantOutcome || record.Usage != want || record.ID == "" {
+				t.Errorf("record = %+v, want %d calls of alice using %+v with outcome %s", record, tt.wantCalls, want, tt.wantOutcome)
+			}
+
+			// Failed requests report what they consumed.
+			if tt.wantOutcome == "success" {
+				if err != nil || result.Metadata.Usage != want {
+					t.Errorf("result = %+v, %v, want usage %+v", result, err, want)
+				}
+				return
+			}
+			var usageErr *UsageError
+			if !errors.As(err, &usageErr) || usageErr.Usage != want {
+				t.Errorf("err = %v, want a UsageError with usage %+v", err, want)
+			}
+		})
+	}
+}
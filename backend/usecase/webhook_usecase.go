@@ -0,0 +1,495 @@
+// usecase/webhook_usecase.go
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidWebhook is returned when a webhook cannot be created as requested.
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// Headers of a webhook request.
+const (
+	// WebhookSignatureHeader carries "t=<unix time>,v1=<hex HMAC-SHA256>",
+	// computed with the webhook secret over "<unix time>.<body>".
+	WebhookSignatureHeader = "X-BetterForm-Signature"
+	WebhookEventHeader     = "X-BetterForm-Event"
+	// WebhookDeliveryHeader carries the delivery ID, which stays the same
+	// across retries so receivers can drop duplicates.
+	WebhookDeliveryHeader = "X-BetterForm-Delivery"
+)
+
+// webhookSecretPrefix starts generated webhook secrets.
+const webhookSecretPrefix = "whsec_"
+
+// minWebhookSecretLength is the shortest secret a user may choose.
+const minWebhookSecretLength = 16
+
+// WebhookRepository persists webhooks and their deliveries.
+type WebhookRepository interface {
+	Create(webhook *domain.Webhook) error
+	// Get returns domain.ErrWebhookNotFound when the webhook does not exist.
+	Get(id string) (*domain.Webhook, error)
+	// ListByForm returns the webhooks of a form, oldest first.
+	ListByForm(formID string) ([]*domain.Webhook, error)
+	// Delete removes a webhook together with its deliveries. It returns
+	// domain.ErrWebhookNotFound when the webhook does not exist.
+	Delete(id string) error
+
+	CreateDelivery(delivery *domain.WebhookDelivery) error
+	// GetDelivery returns domain.ErrDeliveryNotFound when the delivery does
+	// not exist.
+	GetDelivery(id string) (*domain.WebhookDelivery, error)
+	// UpdateDelivery stores the status, attempt counters and schedule of a
+	// delivery. It returns domain.ErrDeliveryNotFound when the delivery does
+	// not exist.
+	UpdateDelivery(delivery *domain.WebhookDelivery) error
+	// ListDeliveries returns the deliveries matching query, newest first.
+	ListDeliveries(query domain.DeliveryQuery) ([]*domain.WebhookDelivery, error)
+	// DueDeliveries returns up to limit pending deliveries whose next attempt
+	// is due at now, earliest first.
+	DueDeliveries(now time.Time, limit int) ([]*domain.WebhookDelivery, error)
+}
+
+// WebhookSender makes the HTTP request of a delivery attempt.
+type WebhookSender interface {
+	// Send posts body to url and returns the response status code. Redirects
+	// are not followed.
+	Send(ctx context.Context, url string, headers map[string]string, body []byte) (int, error)
+}
+
+// SubmissionNotifier is told about every accepted submission.
+type SubmissionNotifier interface {
+	SubmissionCreated(submission *domain.Submission)
+}
+
+// WebhookConfig tunes webhook delivery. Zero values take the defaults of
+// DefaultWebhookConfig.
+type WebhookConfig struct {
+	// MaxAttempts is the number of attempts before a delivery is dead-lettered.
+	MaxAttempts int
+	// BaseDelay is the wait after the first failed attempt; it doubles after
+	// every further failure, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Timeout bounds a single attempt.
+	Timeout time.Duration
+	// PollInterval is how often due retries are looked for. New events are
+	// sent right away.
+	PollInterval time.Duration
+	// Concurrency is the number of attempts in flight at once.
+	Concurrency int
+}
+
+// DefaultWebhookConfig makes 8 attempts over about an hour.
+var DefaultWebhookConfig = WebhookConfig{
+	MaxAttempts:  8,
+	BaseDelay:    30 * time.Second,
+	MaxDelay:     30 * time.Minute,
+	Timeout:      10 * time.Second,
+	PollInterval: 5 * time.Second,
+	Concurrency:  4,
+}
+
+func (c WebhookConfig) withDefaults() WebhookConfig {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = DefaultWebhookConfig.MaxAttempts
+	}
+	if c.BaseDelay <= 0 {
+		c.BaseDelay = DefaultWebhookConfig.BaseDelay
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = DefaultWebhookConfig.MaxDelay
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultWebhookConfig.Timeout
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = DefaultWebhookConfig.PollInterval
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = DefaultWebhookConfig.Concurrency
+	}
+	return c
+}
+
+// backoff returns the wait after the given number of failed attempts.
+func (c WebhookConfig) backoff(attempts int) time.Duration {
+	if attempts > 30 {
+		return c.MaxDelay
+	}
+	if delay := c.BaseDelay << (attempts - 1); delay > 0 && delay < c.MaxDelay {
+		return delay
+	}
+	return c.MaxDelay
+}
+
+// CreateWebhookInput describes a webhook to create.
+type CreateWebhookInput struct {
+	URL string
+	// Secret signs the requests; one is generated when empty.
+	Secret string
+	// Events defaults to every event.
+	Events []string
+}
+
+// CreatedWebhook is a new webhook. Secret is never shown again.
+type CreatedWebhook struct {
+	Secret string `json:"secret"`
+	domain.Webhook
+}
+
+// WebhookEventPayload is the body of a webhook request.
+type WebhookEventPayload struct {
+	// ID is the delivery ID, also sent in the X-BetterForm-Delivery header.
+	ID         string    `json:"id"`
+	Event      string    `json:"event"`
+	FormID     string    `json:"formId"`
+	OccurredAt time.Time `json:"occurredAt"`
+	// Data is the object the event is about, a domain.Submission for
+	// submission events.
+	Data interface{} `json:"data"`
+}
+
+// WebhookUseCaseInterface defines the contract for managing webhooks and
+// delivering their events.
+type WebhookUseCaseInterface interface {
+	SubmissionNotifier
+	CreateWebhook(ownerID, formID string, input CreateWebhookInput) (*CreatedWebhook, error)
+	ListWebhooks(ownerID, formID string) ([]*domain.Webhook, error)
+	DeleteWebhook(ownerID, formID, id string) error
+	// ListDeliveries returns the latest deliveries of a form, newest first.
+	// webhookID and status narrow the list when set; status "dead" lists
+	// the dead letters.
+	ListDeliveries(ownerID, formID, webhookID, status string, limit int) ([]*domain.WebhookDelivery, error)
+	// Redeliver queues a delivery to be sent again, with a fresh set of attempts.
+	Redeliver(ownerID, formID, deliveryID string) (*domain.WebhookDelivery, error)
+	// Run sends due deliveries until ctx is done.
+	Run(ctx context.Context)
+}
+
+// WebhookUseCase is the implementation of WebhookUseCaseInterface.
+type WebhookUseCase struct {
+	forms    FormRepository
+	webhooks WebhookRepository
+	sender   WebhookSender
+	config   WebhookConfig
+	// wake asks Run to look for due deliveries right away.
+	wake chan struct{}
+}
+
+// NewWebhookUseCase creates a new instance of WebhookUseCase. Deliveries are
+// only sent while Run is running.
+func NewWebhookUseCase(forms FormRepository, webhooks WebhookRepository, sender WebhookSender, config WebhookConfig) WebhookUseCaseInterface {
+	return &WebhookUseCase{
+		forms:    forms,
+		webhooks: webhooks,
+		sender:   sender,
+		config:   config.withDefaults(),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// CreateWebhook validates and stores a webhook of a form owned by ownerID.
+func (uc *WebhookUseCase) CreateWebhook(ownerID, formID string, input CreateWebhookInput) (*CreatedWebhook, error) {
+	if _, err := ownedForm(uc.forms, ownerID, formID); err != nil {
+		return nil, err
+	}
+	target, err := url.Parse(strings.TrimSpace(input.URL))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	events := input.Events
+	if len(events) == 0 {
+		events = domain.WebhookEvents
+	}
+	for _, event := range events {
+		if !slices.Contains(domain.WebhookEvents, event) {
+			return nil, fmt.Errorf("%w: unknown event %q (expected one of %s)", ErrInvalidWebhook, event, strings.Join(domain.WebhookEvents, ", "))
+		}
+	}
+	secret := input.Secret
+	if secret == "" {
+		if secret, err = newWebhookSecret(); err != nil {
+			return nil, err
+		}
+	} else if len(secret) < minWebhookSecretLength {
+		return nil, fmt.Errorf("%w: secret must be at least %d characters", ErrInvalidWebhook, minWebhookSecretLength)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	webhook := &domain.Webhook{
+		ID:        id,
+		FormID:    formID,
+		URL:       target.String(),
+		Secret:    secret,
+		Events:    slices.Compact(slices.Sorted(slices.Values(events))),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := uc.webhooks.Create(webhook); err != nil {
+		return nil, fmt.Errorf("failed to save webhook: %w", err)
+	}
+	return &CreatedWebhook{Secret: secret, Webhook: *webhook}, nil
+}
+
+// ListWebhooks returns the webhooks of a form owned by ownerID.
+func (uc *WebhookUseCase) ListWebhooks(ownerID, formID string) ([]*domain.Webhook, error) {
+	if _, err := ownedForm(uc.forms, ownerID, formID); err != nil {
+		return nil, err
+	}
+	return uc.webhooks.ListByForm(formID)
+}
+
+// DeleteWebhook removes a webhook and its deliveries.
+func (uc *WebhookUseCase) DeleteWebhook(ownerID, formID, id string) error {
+	if _, err := uc.ownedWebhook(ownerID, formID, id); err != nil {
+		return err
+	}
+	return uc.webhooks.Delete(id)
+}
+
+// ListDeliveries returns the latest deliveries of a form.
+func (uc *WebhookUseCase) ListDeliveries(ownerID, formID, webhookID, status string, limit int) ([]*domain.WebhookDelivery, error) {
+	if _, err := ownedForm(uc.forms, ownerID, formID); err != nil {
+		return nil, err
+	}
+	if status != "" && !slices.Contains(domain.DeliveryStatuses, status) {
+		return nil, fmt.Errorf("%w: status must be one of %s", ErrInvalidWebhook, strings.Join(domain.DeliveryStatuses, ", "))
+	}
+	if limit <= 0 {
+		limit = DefaultSubmissionPageSize
+	}
+	return uc.webhooks.ListDeliveries(domain.DeliveryQuery{
+		FormID:    formID,
+		WebhookID: webhookID,
+		Status:    status,
+		Limit:     min(limit, MaxSubmissionPageSize),
+	})
+}
+
+// Redeliver resets a delivery to pending and sends it right away.
+func (uc *WebhookUseCase) Redeliver(ownerID, formID, deliveryID string) (*domain.WebhookDelivery, error) {
+	if _, err := ownedForm(uc.forms, ownerID, formID); err != nil {
+		return nil, err
+	}
+	delivery, err := uc.webhooks.GetDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.FormID != formID {
+		return nil, domain.ErrDeliveryNotFound
+	}
+	now := time.Now().UTC()
+	delivery.Status = domain.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+	delivery.UpdatedAt = now
+	if err := uc.webhooks.UpdateDelivery(delivery); err != nil {
+		return nil, fmt.Errorf("failed to queue delivery: %w", err)
+	}
+	uc.notify()
+	return delivery, nil
+}
+
+// SubmissionCreated queues a delivery for every webhook of the form that
+// subscribes to submissions. Failures are logged; they never fail the
+// submission itself.
+func (uc *WebhookUseCase) SubmissionCreated(submission *domain.Submission) {
+	webhooks, err := uc.webhooks.ListByForm(submission.FormID)
+	if err != nil {
+		log.Printf("Failed to look up the webhooks of form %s: %v", submission.FormID, err)
+		return
+	}
+	queued := false
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(domain.EventSubmissionCreated) {
+			continue
+		}
+		if err := uc.enqueue(webhook, domain.EventSubmissionCreated, submission); err != nil {
+			log.Printf("Failed to queue webhook %s for submission %s: %v", webhook.ID, submission.ID, err)
+			continue
+		}
+		queued = true
+	}
+	if queued {
+		uc.notify()
+	}
+}
+
+func (uc *WebhookUseCase) enqueue(webhook *domain.Webhook, event string, data interface{}) error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	payload, err := json.Marshal(WebhookEventPayload{ID: id, Event: event, FormID: webhook.FormID, OccurredAt: now, Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	return uc.webhooks.CreateDelivery(&domain.WebhookDelivery{
+		ID:            id,
+		WebhookID:     webhook.ID,
+		FormID:        webhook.FormID,
+		Event:         event,
+		Payload:       payload,
+		Status:        domain.DeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	})
+}
+
+// notify wakes Run without blocking; a pending wake-up covers any number of events.
+func (uc *WebhookUseCase) notify() {
+	select {
+	case uc.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends due deliveries whenever events are queued and every
+// PollInterval, until ctx is done.
+func (uc *WebhookUseCase) Run(ctx context.Context) {
+	ticker := time.NewTicker(uc.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		uc.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-uc.wake:
+		}
+	}
+}
+
+// deliverDue makes one attempt for a batch of due deliveries and waits for
+// all of them. A full batch schedules another round right away.
+func (uc *WebhookUseCase) deliverDue(ctx context.Context) {
+	batchSize := uc.config.Concurrency * 4
+	deliveries, err := uc.webhooks.DueDeliveries(time.Now().UTC(), batchSize)
+	if err != nil {
+		log.Printf("Failed to load due webhook deliveries: %v", err)
+		return
+	}
+
+	slots := make(chan struct{}, uc.config.Concurrency)
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-slots; wg.Done() }()
+			uc.attempt(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+	if len(deliveries) == batchSize {
+		uc.notify()
+	}
+}
+
+// attempt sends a delivery once and records the outcome: success, a retry
+// after the backoff, or the dead-letter list once the attempts are used up.
+func (uc *WebhookUseCase) attempt(ctx context.Context, delivery *domain.WebhookDelivery) {
+	webhook, err := uc.webhooks.Get(delivery.WebhookID)
+	if err != nil {
+		log.Printf("Failed to load webhook %s: %v", delivery.WebhookID, err)
+		return
+	}
+
+	sentAt := time.Now().UTC()
+	headers := map[string]string{
+		"Content-Type":         "application/json",
+		WebhookEventHeader:     delivery.Event,
+		WebhookDeliveryHeader:  delivery.ID,
+		WebhookSignatureHeader: SignWebhookPayload(webhook.Secret, sentAt, delivery.Payload),
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, uc.config.Timeout)
+	status, err := uc.sender.Send(attemptCtx, webhook.URL, headers, delivery.Payload)
+	cancel()
+
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.LastStatusCode = status
+	delivery.UpdatedAt = now
+	switch {
+	case err == nil && status >= 200 && status < 300:
+		delivery.Status = domain.DeliverySucceeded
+		delivery.LastError = ""
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= uc.config.MaxAttempts:
+		delivery.Status = domain.DeliveryDead
+		delivery.LastError = attemptError(status, err)
+		delivery.NextAttemptAt = nil
+		log.Printf("Webhook delivery %s to %s failed %d times and was dead-lettered: %s", delivery.ID, webhook.URL, delivery.Attempts, delivery.LastError)
+	default:
+		delivery.LastError = attemptError(status, err)
+		next := now.Add(uc.config.backoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+	if err := uc.webhooks.UpdateDelivery(delivery); err != nil && !errors.Is(err, domain.ErrDeliveryNotFound) {
+		log.Printf("Failed to record webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+func attemptError(status int, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "unexpected response status " + strconv.Itoa(status)
+}
+
+// SignWebhookPayload returns the X-BetterForm-Signature header of a request
+// sent at timestamp. Receivers recompute the HMAC over "<t>.<body>" with the
+// webhook secret, compare it in constant time and reject stale timestamps.
+func SignWebhookPayload(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix + "."))
+	mac.Write(body)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// ownedWebhook returns a webhook of a form owned by ownerID; webhooks of
+// other forms are reported as domain.ErrWebhookNotFound.
+func (uc *WebhookUseCase) ownedWebhook(ownerID, formID, id string) (*domain.Webhook, error) {
+	if _, err := ownedForm(uc.forms, ownerID, formID); err != nil {
+		return nil, err
+	}
+	webhook, err := uc.webhooks.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.FormID != formID {
+		return nil, domain.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
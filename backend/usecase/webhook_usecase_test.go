@@ -0,0 +1,343 @@
+package usecase
+
+import (
+	"better-form-doc-backend/domain"
+	"better-form-doc-backend/infrastructure"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "whsec_test-secret-1234"
+
+// receivedWebhook is a request received by a webhookReceiver.
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+	at     time.Time
+}
+
+// webhookReceiver is an HTTP endpoint answering with the statuses it is
+// given in turn, repeating the last one.
+type webhookReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	received []receivedWebhook
+}
+
+func newWebhookReceiver(t *testing.T, statuses ...int) *webhookReceiver {
+	t.Helper()
+	r := &webhookReceiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.received = append(r.received, receivedWebhook{header: req.Header.Clone(), body: body, at: time.Now()})
+		status := r.statuses[min(len(r.received), len(r.statuses))-1]
+		r.mu.Unlock()
+		if status >= 300 && status < 400 {
+			w.Header().Set("Location", "http://example.com/elsewhere")
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *webhookReceiver) requests() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.received...)
+}
+
+func (r *webhookReceiver) answer(statuses ...int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses = append(make([]int, len(r.received)), statuses...)
+}
+
+// webhookFixture runs a webhook use case over in-memory repositories with a
+// webhook of testFormID pointing at a receiver.
+type webhookFixture struct {
+	useCase  WebhookUseCaseInterface
+	webhooks *infrastructure.InMemoryWebhookRepository
+	receiver *webhookReceiver
+	webhook  *CreatedWebhook
+}
+
+var fastWebhookConfig = WebhookConfig{
+	MaxAttempts:  3,
+	BaseDelay:    20 * time.Millisecond,
+	MaxDelay:     50 * time.Millisecond,
+	Timeout:      time.Second,
+	PollInterval: 5 * time.Millisecond,
+	Concurrency:  2,
+}
+
+func newWebhookFixture(t *testing.T, statuses ...int) *webhookFixture {
+	t.Helper()
+	forms, form := newTestForms(t, `[{"name":"email","type":"email"}]`)
+	webhooks := infrastructure.NewInMemoryWebhookRepository()
+	useCase := NewWebhookUseCase(forms, webhooks, infrastructure.NewHTTPWebhookSender(), fastWebhookConfig)
+	receiver := newWebhookReceiver(t, statuses...)
+	webhook, err := useCase.CreateWebhook("owner", form.ID, CreateWebhookInput{URL: receiver.URL + "/hook", Secret: testWebhookSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		useCase.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return &webhookFixture{useCase: useCase, webhooks: webhooks, receiver: receiver, webhook: webhook}
+}
+
+func (f *webhookFixture) submit(id string) *domain.Submission {
+	submission := &domain.Submission{ID: id, FormID: testFormID, FormVersion: 1, Data: map[string]interface{}{"email": "ada@example.com"}, CreatedAt: time.Now().UTC()}
+	f.useCase.SubmissionCreated(submission)
+	return submission
+}
+
+// waitForDelivery waits until the only delivery of the form is in status.
+func (f *webhookFixture) waitForDelivery(t *testing.T, status string) *domain.WebhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deliveries, err := f.useCase.ListDeliveries("owner", testFormID, "", "", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) == 1 && deliveries[0].Status == status {
+			return deliveries[0]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no delivery reached status %q: %+v", status, deliveries)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// verifySignature checks a request the way a receiver should.
+func verifySignature(request receivedWebhook) error {
+	header := request.header.Get(WebhookSignatureHeader)
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("signature header %q has no timestamp", header)
+	}
+	if age := time.Since(time.Unix(unix, 0)); age < -time.Second || age > time.Minute {
+		return fmt.Errorf("signature timestamp is %v old", age)
+	}
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(request.body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return fmt.Errorf("signature %q does not match the body, want %q", signature, expected)
+	}
+	return nil
+}
+
+func TestWebhookDeliverySignature(t *testing.T) {
+	f := newWebhookFixture(t, http.StatusNoContent)
+	submission := f.submit("sub-1")
+	delivery := f.waitForDelivery(t, domain.DeliverySucceeded)
+
+	requests := f.receiver.requests()
+	if len(requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(requests))
+	}
+	request := requests[0]
+	if err := verifySignature(request); err != nil {
+		t.Error(err)
+	}
+	if got := request.header.Get(WebhookEventHeader); got != domain.EventSubmissionCreated {
+		t.Errorf("%s = %q, want %s", WebhookEventHeader, got, domain.EventSubmissionCreated)
+	}
+	if got := request.header.Get(WebhookDeliveryHeader); got != delivery.ID {
+		t.Errorf("%s = %q, want the delivery ID %q", WebhookDeliveryHeader, got, delivery.ID)
+	}
+	if got := request.header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	var payload struct {
+		ID     string            `json:"id"`
+		Event  string            `json:"event"`
+		FormID string            `json:"formId"`
+		Data   domain.Submission `json:"data"`
+	}
+	if err := json.Unmarshal(request.body, &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if payload.ID != delivery.ID || payload.Event != domain.EventSubmissionCreated || payload.FormID != testFormID {
+		t.Errorf("payload = %+v", payload)
+	}
+	if payload.Data.ID != submission.ID || payload.Data.Data["email"] != "ada@example.com" {
+		t.Errorf("payload data = %+v, want the submission", payload.Data)
+	}
+
+	// A tampered body or another secret does not verify.
+	tampered := request
+	tampered.body = []byte(strings.Replace(string(request.body), "ada@", "eve@", 1))
+	if verifySignature(tampered) == nil {
+		t.Error("a tampered body passed verification")
+	}
+	if SignWebhookPayload("another secret", time.Now(), request.body) == request.header.Get(WebhookSignatureHeader) {
+		t.Error("another secret produced the same signature")
+	}
+
+	if delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusNoContent || delivery.NextAttemptAt != nil {
+		t.Errorf("delivery = %+v, want one successful attempt", delivery)
+	}
+}
+
+func TestWebhookDeliveryRetriesWithBackoff(t *testing.T) {
+	f := newWebhookFixture(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK)
+	f.submit("sub-1")
+	delivery := f.waitForDelivery(t, domain.DeliverySucceeded)
+
+	requests := f.receiver.requests()
+	if len(requests) != 3 || delivery.Attempts != 3 {
+		t.Fatalf("receiver got %d requests and the delivery records %d attempts, want 3", len(requests), delivery.Attempts)
+	}
+	// The delivery ID stays the same so receivers can drop duplicates, and
+	// every attempt is signed afresh.
+	for _, request := range requests {
+		if got := request.header.Get(WebhookDeliveryHeader); got != delivery.ID {
+			t.Errorf("%s = %q, want %q on every attempt", WebhookDeliveryHeader, got, delivery.ID)
+		}
+		if err := verifySignature(request); err != nil {
+			t.Error(err)
+		}
+	}
+	for i, wait := range []time.Duration{fastWebhookConfig.BaseDelay, 2 * fastWebhookConfig.BaseDelay} {
+		if gap := requests[i+1].at.Sub(requests[i].at); gap < wait {
+			t.Errorf("attempt %d came %v after the previous one, want at least %v", i+2, gap, wait)
+		}
+	}
+	if delivery.LastError != "" || delivery.LastStatusCode != http.StatusOK {
+		t.Errorf("delivery = %+v, want the error cleared by the successful attempt", delivery)
+	}
+}
+
+func TestWebhookDeliveryDeadLetterAndRedelivery(t *testing.T) {
+	f := newWebhookFixture(t, http.StatusServiceUnavailable)
+	f.submit("sub-1")
+	dead := f.waitForDelivery(t, domain.DeliveryDead)
+
+	if dead.Attempts != fastWebhookConfig.MaxAttempts || dead.LastStatusCode != http.StatusServiceUnavailable {
+		t.Errorf("dead delivery = %+v, want %d attempts ending in 503", dead, fastWebhookConfig.MaxAttempts)
+	}
+	if !strings.Contains(dead.LastError, "503") || dead.NextAttemptAt != nil {
+		t.Errorf("dead delivery = %+v, want the last error and no next attempt", dead)
+	}
+	time.Sleep(3 * fastWebhookConfig.MaxDelay)
+	if n := len(f.receiver.requests()); n != fastWebhookConfig.MaxAttempts {
+		t.Errorf("receiver got %d requests, want no attempts after dead-lettering", n)
+	}
+	letters, err := f.useCase.ListDeliveries("owner", testFormID, f.webhook.ID, domain.DeliveryDead, 0)
+	if err != nil || len(letters) != 1 || letters[0].ID != dead.ID {
+		t.Errorf("dead letters = %+v, %v; want the delivery", letters, err)
+	}
+
+	// Redelivery starts a fresh set of attempts with the same payload.
+	f.receiver.answer(http.StatusOK)
+	queued, err := f.useCase.Redeliver("owner", testFormID, dead.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queued.Status != domain.DeliveryPending || queued.Attempts != 0 {
+		t.Errorf("redelivered = %+v, want pending with no attempts", queued)
+	}
+	delivered := f.waitForDelivery(t, domain.DeliverySucceeded)
+	if delivered.ID != dead.ID || delivered.Attempts != 1 {
+		t.Errorf("delivery = %+v, want the same delivery after one attempt", delivered)
+	}
+	requests := f.receiver.requests()
+	if first, last := requests[0], requests[len(requests)-1]; string(first.body) != string(last.body) {
+		t.Errorf("redelivered payload %s differs from %s", last.body, first.body)
+	}
+
+	if _, err := f.useCase.Redeliver("intruder", testFormID, dead.ID); !errors.Is(err, domain.ErrFormNotFound) {
+		t.Errorf("Redeliver by another user = %v, want ErrFormNotFound", err)
+	}
+	if _, err := f.useCase.Redeliver("owner", testFormID, "missing"); !errors.Is(err, domain.ErrDeliveryNotFound) {
+		t.Errorf("Redeliver of an unknown delivery = %v, want ErrDeliveryNotFound", err)
+	}
+}
+
+func TestWebhookDeliveryDoesNotFollowRedirects(t *testing.T) {
+	f := newWebhookFixture(t, http.StatusFound)
+	f.submit("sub-1")
+	dead := f.waitForDelivery(t, domain.DeliveryDead)
+	if dead.LastStatusCode != http.StatusFound {
+		t.Errorf("LastStatusCode = %d, want the redirect itself", dead.LastStatusCode)
+	}
+}
+
+func TestWebhookConfigBackoff(t *testing.T) {
+	config := WebhookConfig{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 8 * time.Second, 5: 10 * time.Second, 100: 10 * time.Second} {
+		if got := config.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestCreateWebhook(t *testing.T) {
+	forms, form := newTestForms(t, `[{"name":"email","type":"email"}]`)
+	useCase := NewWebhookUseCase(forms, infrastructure.NewInMemoryWebhookRepository(), infrastructure.NewHTTPWebhookSender(), WebhookConfig{})
+
+	created, err := useCase.CreateWebhook("owner", form.ID, CreateWebhookInput{URL: "https://example.com/hook"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(created.Secret, webhookSecretPrefix) || len(created.Secret) < minWebhookSecretLength {
+		t.Errorf("generated secret %q", created.Secret)
+	}
+	if len(created.Events) != len(domain.WebhookEvents) {
+		t.Errorf("events = %v, want every event by default", created.Events)
+	}
+
+	invalid := []CreateWebhookInput{
+		{URL: "ftp://example.com/hook"},
+		{URL: "/relative"},
+		{URL: "https://example.com/hook", Events: []string{"form.deleted"}},
+		{URL: "https://example.com/hook", Secret: "short"},
+	}
+	for _, input := range invalid {
+		if _, err := useCase.CreateWebhook("owner", form.ID, input); !errors.Is(err, ErrInvalidWebhook) {
+			t.Errorf("CreateWebhook(%+v) = %v, want ErrInvalidWebhook", input, err)
+		}
+	}
+	if _, err := useCase.CreateWebhook("intruder", form.ID, CreateWebhookInput{URL: "https://example.com/hook"}); !errors.Is(err, domain.ErrFormNotFound) {
+		t.Errorf("CreateWebhook by another user = %v, want ErrFormNotFound", err)
+	}
+}
@@ -0,0 +1,125 @@
+// validation/data_source_policy.go
+package validation
+
+import (
+	"better-form-doc-backend/domain"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// CodeUnknownSecret is reported by DataSourcePolicy.Check for an authTokenRef
+// that names no configured secret.
+const CodeUnknownSecret = "unknown_secret"
+
+// DataSourcePolicy decides which endpoints the server calls on behalf of a
+// data source and where the secret named by its authTokenRef may be sent.
+// Nothing is allowed unless configured: an absolute endpoint needs an allowed
+// host, and a secret only goes to the hosts it is bound to.
+type DataSourcePolicy struct {
+	// BaseURL resolves relative endpoints, which point to the application's
+	// own origin. Its host is allowed. Without it, relative endpoints are
+	// accepted in forms but cannot be called by the server.
+	BaseURL *url.URL
+	// AllowedHosts may be called without a secret.
+	AllowedHosts []string
+	// SecretHosts returns the hosts the secret named ref may be sent to, and
+	// false when no such secret is configured.
+	SecretHosts func(ref string) ([]string, bool)
+}
+
+// Check returns every data source of config the server would refuse to
+// call, so that forms are rejected when saved rather than when filled in.
+func (p DataSourcePolicy) Check(config *domain.FormConfig) Issues {
+	v := &validator{}
+	for i, field := range config.Fields {
+		ds := field.DataSource
+		if ds == nil {
+			continue
+		}
+		dsPath := Path{}.with("fields", i, "dataSource")
+		endpoint, err := url.Parse(strings.TrimSpace(ds.Endpoint))
+		if err != nil {
+			v.add(CodeExternalEndpoint, dsPath.with("endpoint"), "'%s' is not a valid URL", ds.Endpoint)
+			continue
+		}
+		if p.BaseURL == nil && !endpoint.IsAbs() && endpoint.Host == "" && ds.AuthTokenRef == "" {
+			continue // left to the browser's own origin
+		}
+		if _, err := p.Resolve(ds); err != nil {
+			var refused *refusedDataSource
+			if errors.As(err, &refused) {
+				v.add(refused.code, dsPath.with(refused.member), "%s", refused.message)
+			}
+		}
+	}
+	return v.issues
+}
+
+// Resolve returns the absolute URL of the endpoint of ds, or an error when
+// the server may not call it with the secret ds references.
+func (p DataSourcePolicy) Resolve(ds *domain.DynamicDataSource) (*url.URL, error) {
+	endpoint, err := url.Parse(strings.TrimSpace(ds.Endpoint))
+	if err != nil {
+		return nil, refuse(CodeExternalEndpoint, "endpoint", "'%s' is not a valid URL", ds.Endpoint)
+	}
+	if p.BaseURL != nil {
+		endpoint = p.BaseURL.ResolveReference(endpoint)
+	}
+	if !endpoint.IsAbs() {
+		return nil, refuse(CodeExternalEndpoint, "endpoint", "Relative endpoints cannot be called without a base URL")
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, refuse(CodeExternalEndpoint, "endpoint", "URL scheme '%s' is not allowed", endpoint.Scheme)
+	}
+	host := endpoint.Hostname()
+
+	if ds.AuthTokenRef != "" {
+		var secretHosts []string
+		ok := p.SecretHosts != nil
+		if ok {
+			secretHosts, ok = p.SecretHosts(ds.AuthTokenRef)
+		}
+		if !ok {
+			return nil, refuse(CodeUnknownSecret, "authTokenRef", "No secret named '%s' is configured", ds.AuthTokenRef)
+		}
+		if !containsHost(secretHosts, host) {
+			return nil, refuse(CodeExternalEndpoint, "endpoint", "Secret '%s' may not be sent to '%s'", ds.AuthTokenRef, host)
+		}
+		return endpoint, nil
+	}
+	if p.BaseURL != nil && strings.EqualFold(host, p.BaseURL.Hostname()) {
+		return endpoint, nil
+	}
+	if !containsHost(p.AllowedHosts, host) {
+		return nil, refuse(CodeExternalEndpoint, "endpoint", "Data sources may not call '%s'", host)
+	}
+	return endpoint, nil
+}
+
+func containsHost(hosts []string, host string) bool {
+	if host == "" {
+		return false
+	}
+	for _, allowed := range hosts {
+		if strings.EqualFold(host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// refusedDataSource is the error Resolve returns for a data source the
+// policy does not allow.
+type refusedDataSource struct {
+	code, member, message string
+}
+
+func refuse(code, member, format string, args ...interface{}) error {
+	return &refusedDataSource{code: code, member: member, message: fmt.Sprintf(format, args...)}
+}
+
+func (e *refusedDataSource) Error() string {
+	return e.message
+}
@@ -0,0 +1,75 @@
+package validation
+
+import (
+	"better-form-doc-backend/domain"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func testPolicy(baseURL string) DataSourcePolicy {
+	policy := DataSourcePolicy{
+		AllowedHosts: []string{"api.example.com"},
+		SecretHosts: func(ref string) ([]string, bool) {
+			if ref == "CRM_TOKEN" {
+				return []string{"crm.example.com"}, true
+			}
+			return nil, false
+		},
+	}
+	if baseURL != "" {
+		policy.BaseURL, _ = url.Parse(baseURL)
+	}
+	return policy
+}
+
+func TestDataSourcePolicyResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		ds      domain.DynamicDataSource
+		want    string
+	}{
+		{"allowed host", "", domain.DynamicDataSource{Endpoint: "https://API.example.com/c?x=1"}, "https://API.example.com/c?x=1"},
+		{"relative to the base URL", "https://forms.example.org/app/", domain.DynamicDataSource{Endpoint: "/api/countries"}, "https://forms.example.org/api/countries"},
+		{"secret to its host", "", domain.DynamicDataSource{Endpoint: "https://crm.example.com/search", AuthTokenRef: "CRM_TOKEN"}, "https://crm.example.com/search"},
+		{"host not allowed", "", domain.DynamicDataSource{Endpoint: "https://evil.example.com/c"}, ""},
+		{"relative without base URL", "", domain.DynamicDataSource{Endpoint: "/api/countries"}, ""},
+		{"scheme", "", domain.DynamicDataSource{Endpoint: "file:///etc/passwd"}, ""},
+		{"lookalike host", "", domain.DynamicDataSource{Endpoint: "https://api.example.com.evil.net/c"}, ""},
+		{"credentials before the host", "", domain.DynamicDataSource{Endpoint: "https://api.example.com@evil.net/c"}, ""},
+		{"secret to an allowed host", "", domain.DynamicDataSource{Endpoint: "https://api.example.com/c", AuthTokenRef: "CRM_TOKEN"}, ""},
+		{"secret to the base URL", "https://forms.example.org", domain.DynamicDataSource{Endpoint: "/api/c", AuthTokenRef: "CRM_TOKEN"}, ""},
+		{"unknown secret", "", domain.DynamicDataSource{Endpoint: "https://crm.example.com/c", AuthTokenRef: "OTHER"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint, err := testPolicy(tt.baseURL).Resolve(&tt.ds)
+			switch {
+			case tt.want == "" && err == nil:
+				t.Errorf("Resolve = %s, want a refusal", endpoint)
+			case tt.want != "" && (err != nil || endpoint.String() != tt.want):
+				t.Errorf("Resolve = %v, %v; want %s", endpoint, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDataSourcePolicyCheck(t *testing.T) {
+	config := mustFormConfig(t, `[
+		{"name":"a","type":"select","dataSource":{"type":"remote","endpoint":"https://api.example.com/a"}},
+		{"name":"b","type":"select","dataSource":{"type":"remote","endpoint":"https://evil.example.com/b"}},
+		{"name":"c","type":"select","dataSource":{"type":"remote","endpoint":"/api/c"}},
+		{"name":"d","type":"select","dataSource":{"type":"remote","endpoint":"https://crm.example.com/d","authTokenRef":"OTHER"}},
+		{"name":"e","type":"select","dataSource":{"type":"remote","endpoint":"/api/e","authTokenRef":"CRM_TOKEN"}}
+	]`)
+	want := map[string]string{
+		"fields[1].dataSource.endpoint":     CodeExternalEndpoint,
+		"fields[3].dataSource.authTokenRef": CodeUnknownSecret,
+		"fields[4].dataSource.endpoint":     CodeExternalEndpoint,
+	}
+	// Without a base URL, relative endpoints are left to the browser.
+	if got := issueCodes(testPolicy("").Check(config)); !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,159 @@
+// validation/file_validator.go
+package validation
+
+import (
+	"better-form-doc-backend/domain"
+	"fmt"
+	"mime"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// CodeInvalidFileType is reported for uploads that match none of the types a
+// file field accepts.
+const CodeInvalidFileType = "invalid_file_type"
+
+// DefaultMaxFileSize bounds uploads to file fields without a maxSize attribute.
+const DefaultMaxFileSize = 10 << 20
+
+// UploadedFile describes an upload for ValidateUploads. ContentType is the
+// sniffed type of the content, not the type the client claimed.
+type UploadedFile struct {
+	Name        string
+	Size        int64
+	ContentType string
+}
+
+// FileLimits are the restrictions of a file field, read from its attributes:
+// accept (as on <input type="file">, e.g. "image/*,.pdf"), maxSize (bytes,
+// or a size such as "5MB"), multiple and maxFiles.
+type FileLimits struct {
+	Accept   []string
+	MaxSize  int64
+	MaxFiles int
+}
+
+// FieldFileLimits returns the limits of a file field.
+func FieldFileLimits(field *domain.FormField) (FileLimits, error) {
+	limits := FileLimits{MaxSize: DefaultMaxFileSize, MaxFiles: 1}
+	if accept, ok := field.Attributes["accept"]; ok {
+		for _, entry := range strings.Split(accept.String(), ",") {
+			if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+				limits.Accept = append(limits.Accept, entry)
+			}
+		}
+	}
+	if raw, ok := field.Attributes["maxSize"]; ok {
+		size, err := parseByteSize(raw.Value)
+		if err != nil {
+			return limits, err
+		}
+		limits.MaxSize = size
+	}
+	if multiple, ok := field.Attributes["multiple"].Value.(bool); ok && multiple {
+		limits.MaxFiles = 0
+	}
+	if raw, ok := field.Attributes["maxFiles"]; ok {
+		count, ok := raw.Value.(float64)
+		if !ok || count < 1 || count != float64(int(count)) {
+			return limits, fmt.Errorf("maxFiles must be a positive integer")
+		}
+		limits.MaxFiles = int(count)
+	}
+	return limits, nil
+}
+
+var byteUnits = map[string]int64{"": 1, "b": 1, "kb": 1 << 10, "mb": 1 << 20, "gb": 1 << 30}
+
+// parseByteSize accepts a number of bytes or a string such as "512KB" or "5 MB".
+func parseByteSize(raw interface{}) (int64, error) {
+	switch value := raw.(type) {
+	case float64:
+		if value >= 1 {
+			return int64(value), nil
+		}
+	case string:
+		text := strings.ToLower(strings.TrimSpace(value))
+		digits := strings.TrimRight(text, "abcdefghijklmnopqrstuvwxyz ")
+		unit, ok := byteUnits[strings.TrimSpace(text[len(digits):])]
+		number, err := strconv.ParseFloat(digits, 64)
+		if ok && err == nil && number > 0 && number*float64(unit) >= 1 {
+			return int64(number * float64(unit)), nil
+		}
+	}
+	return 0, fmt.Errorf("maxSize must be a number of bytes or a size such as \"5MB\"")
+}
+
+// ValidateUploads checks the files uploaded to a field against its limits.
+// Issues are addressed by field name, and by index for a single file.
+func ValidateUploads(field *domain.FormField, files []UploadedFile) Issues {
+	v := &validator{}
+	fieldPath := Path{field.Name}
+	limits, err := FieldFileLimits(field)
+	if err != nil {
+		// Reported when the form is saved; fall back to the defaults.
+		limits = FileLimits{MaxSize: DefaultMaxFileSize, MaxFiles: 1}
+	}
+
+	if limits.MaxFiles == 1 && len(files) > 1 {
+		v.add(CodeTooBig, fieldPath, "Only one file can be uploaded")
+	} else if limits.MaxFiles > 1 && len(files) > limits.MaxFiles {
+		v.add(CodeTooBig, fieldPath, "Upload at most %d files", limits.MaxFiles)
+	}
+	for i, file := range files {
+		path := fieldPath.with(i)
+		if file.Size > limits.MaxSize {
+			v.add(CodeTooBig, path, "'%s' is larger than %s", file.Name, formatByteSize(limits.MaxSize))
+		}
+		if len(limits.Accept) > 0 && !acceptsFile(limits.Accept, file) {
+			v.add(CodeInvalidFileType, path, "'%s' is not an accepted file type (%s)", file.Name, strings.Join(limits.Accept, ", "))
+		}
+	}
+	return v.issues
+}
+
+// acceptsFile matches the sniffed type against the accept entries. Extension
+// entries are matched by the type registered for the extension, so a renamed
+// file does not pass; extensions without a known type match by name.
+func acceptsFile(accept []string, file UploadedFile) bool {
+	actual, _, _ := mime.ParseMediaType(file.ContentType)
+	for _, entry := range accept {
+		expected := entry
+		if strings.HasPrefix(entry, ".") {
+			if !strings.EqualFold(path.Ext(file.Name), entry) {
+				continue
+			}
+			byExtension, _, err := mime.ParseMediaType(mime.TypeByExtension(entry))
+			if err != nil {
+				return true
+			}
+			expected = byExtension
+		}
+		if mediaTypeMatches(expected, actual) {
+			return true
+		}
+	}
+	return false
+}
+
+func mediaTypeMatches(expected, actual string) bool {
+	if expected == "*/*" || expected == actual {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(expected, "/*"); ok {
+		return strings.HasPrefix(actual, prefix+"/")
+	}
+	// Sniffing cannot tell plain text formats apart; CSV and JSON read as text/plain.
+	return actual == "text/plain" && (strings.HasPrefix(expected, "text/") || expected == "application/json")
+}
+
+func formatByteSize(size int64) string {
+	switch {
+	case size >= 1<<20 && size%(1<<20) == 0:
+		return fmt.Sprintf("%dMB", size>>20)
+	case size >= 1<<10 && size%(1<<10) == 0:
+		return fmt.Sprintf("%dKB", size>>10)
+	}
+	return fmt.Sprintf("%d bytes", size)
+}
@@ -0,0 +1,119 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFieldFileLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		want    FileLimits
+		wantErr bool
+	}{
+		{"defaults", `{"name":"f","type":"file"}`, FileLimits{MaxSize: DefaultMaxFileSize, MaxFiles: 1}, false},
+		{"accept list", `{"name":"f","type":"file","attributes":{"accept":" Image/* , .PDF ,"}}`, FileLimits{Accept: []string{"image/*", ".pdf"}, MaxSize: DefaultMaxFileSize, MaxFiles: 1}, false},
+		{"size in bytes", `{"name":"f","type":"file","attributes":{"maxSize":2048}}`, FileLimits{MaxSize: 2048, MaxFiles: 1}, false},
+		{"size with unit", `{"name":"f","type":"file","attributes":{"maxSize":"1.5 MB"}}`, FileLimits{MaxSize: 3 << 19, MaxFiles: 1}, false},
+		{"multiple", `{"name":"f","type":"file","attributes":{"multiple":true}}`, FileLimits{MaxSize: DefaultMaxFileSize}, false},
+		{"max files", `{"name":"f","type":"file","attributes":{"multiple":true,"maxFiles":3}}`, FileLimits{MaxSize: DefaultMaxFileSize, MaxFiles: 3}, false},
+		{"unknown unit", `{"name":"f","type":"file","attributes":{"maxSize":"5 parsecs"}}`, FileLimits{}, true},
+		{"zero size", `{"name":"f","type":"file","attributes":{"maxSize":0}}`, FileLimits{}, true},
+		{"fractional max files", `{"name":"f","type":"file","attributes":{"maxFiles":1.5}}`, FileLimits{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := mustFormConfig(t, "["+tt.field+"]")
+			got, err := FieldFileLimits(&config.Fields[0])
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("FieldFileLimits = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FieldFileLimits = %+v, %v; want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateUploads(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes string
+		files      []UploadedFile
+		wantIssues map[string]string
+	}{
+		{
+			name:       "within limits",
+			attributes: `{"accept":"image/*","maxSize":"1KB"}`,
+			files:      []UploadedFile{{Name: "a.png", Size: 1024, ContentType: "image/png"}},
+			wantIssues: map[string]string{},
+		},
+		{
+			name:       "too large",
+			attributes: `{"maxSize":"1KB"}`,
+			files:      []UploadedFile{{Name: "a.png", Size: 1025, ContentType: "image/png"}},
+			wantIssues: map[string]string{"doc[0]": CodeTooBig},
+		},
+		{
+			name:       "default size limit",
+			attributes: `{}`,
+			files:      []UploadedFile{{Name: "a.bin", Size: DefaultMaxFileSize + 1, ContentType: "application/octet-stream"}},
+			wantIssues: map[string]string{"doc[0]": CodeTooBig},
+		},
+		{
+			name:       "wrong media type",
+			attributes: `{"accept":"image/*"}`,
+			files:      []UploadedFile{{Name: "a.png", Size: 10, ContentType: "application/pdf"}},
+			wantIssues: map[string]string{"doc[0]": CodeInvalidFileType},
+		},
+		{
+			name:       "extension matched by sniffed type",
+			attributes: `{"accept":".pdf"}`,
+			files:      []UploadedFile{{Name: "report.PDF", Size: 10, ContentType: "application/pdf"}},
+			wantIssues: map[string]string{},
+		},
+		{
+			name:       "renamed file",
+			attributes: `{"accept":".pdf"}`,
+			files:      []UploadedFile{{Name: "payload.pdf", Size: 10, ContentType: "application/x-msdownload"}},
+			wantIssues: map[string]string{"doc[0]": CodeInvalidFileType},
+		},
+		{
+			name:       "csv sniffed as text",
+			attributes: `{"accept":"text/csv"}`,
+			files:      []UploadedFile{{Name: "data.csv", Size: 10, ContentType: "text/plain; charset=utf-8"}},
+			wantIssues: map[string]string{},
+		},
+		{
+			name:       "single file field",
+			attributes: `{}`,
+			files:      []UploadedFile{{Name: "a.txt", Size: 1}, {Name: "b.txt", Size: 1}},
+			wantIssues: map[string]string{"doc": CodeTooBig},
+		},
+		{
+			name:       "too many files",
+			attributes: `{"multiple":true,"maxFiles":2}`,
+			files:      []UploadedFile{{Name: "a.txt", Size: 1}, {Name: "b.txt", Size: 1}, {Name: "c.txt", Size: 1}},
+			wantIssues: map[string]string{"doc": CodeTooBig},
+		},
+		{
+			name:       "unbounded multiple",
+			attributes: `{"multiple":true}`,
+			files:      []UploadedFile{{Name: "a.txt", Size: 1}, {Name: "b.txt", Size: 1}, {Name: "c.txt", Size: 1}},
+			wantIssues: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := mustFormConfig(t, `[{"name":"doc","type":"file","attributes":`+tt.attributes+`}]`)
+			issues := ValidateUploads(&config.Fields[0], tt.files)
+			if got := issueCodes(issues); !reflect.DeepEqual(got, tt.wantIssues) {
+				t.Errorf("issues = %v, want %v", issues, tt.wantIssues)
+			}
+		})
+	}
+}
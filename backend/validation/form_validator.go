@@ -0,0 +1,346 @@
+// validation/form_validator.go
+package validation
+
+import (
+	"better-form-doc-backend/domain"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dlclark/regexp2"
+)
+
+// Issue codes reported by ValidateFormConfig. They are stable so the frontend
+// can switch on them.
+const (
+	CodeInvalidJSON        = "invalid_json"
+	CodeRequired           = "required"
+	CodeDuplicate          = "duplicate"
+	CodeUnknownReference   = "unknown_reference"
+	CodeMissingOptions     = "missing_options"
+	CodeInvalidPattern     = "invalid_pattern"
+	CodeInvalidRange       = "invalid_range"
+	CodeInvalidName        = "invalid_name"
+	CodeInvalidEndpoint    = "invalid_endpoint"
+	CodeInvalidCombination = "invalid_combination"
+)
+
+// Path addresses a value inside a FormConfig. Segments are property names
+// (string) or array indexes (int), matching the `path` of a Zod issue.
+type Path []interface{}
+
+// String renders the path in dotted form, e.g. "fields[2].validation.sameAs".
+func (p Path) String() string {
+	var sb strings.Builder
+	for _, segment := range p {
+		switch s := segment.(type) {
+		case int:
+			sb.WriteString("[" + strconv.Itoa(s) + "]")
+		default:
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(fmt.Sprint(s))
+		}
+	}
+	return sb.String()
+}
+
+// with returns a copy of the path extended by the given segments.
+func (p Path) with(segments ...interface{}) Path {
+	next := make(Path, 0, len(p)+len(segments))
+	next = append(next, p...)
+	return append(next, segments...)
+}
+
+// Issue is a single problem found in a FormConfig. It has the same shape as
+// the ZodIssue entries carried by the frontend's FormConfigError.
+type Issue struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Path    Path   `json:"path"`
+}
+
+// Issues is the full list of problems found in a FormConfig.
+type Issues []Issue
+
+// Error joins every issue into a single line so Issues can be used as an error.
+func (is Issues) Error() string {
+	messages := make([]string, 0, len(is))
+	for _, issue := range is {
+		if len(issue.Path) == 0 {
+			messages = append(messages, issue.Message)
+			continue
+		}
+		messages = append(messages, issue.Path.String()+": "+issue.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+var camelCasePattern = regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`)
+
+// patternTimeout bounds matching a value against a field pattern: unlike
+// RE2, the ECMAScript engine backtracks, so a hostile pattern or value could
+// otherwise take exponential time.
+const patternTimeout = 100 * time.Millisecond
+
+// compilePattern compiles the pattern of a field with the ECMAScript
+// semantics of the frontend's `new RegExp(pattern)`, so lookarounds and
+// backreferences are accepted.
+func compilePattern(pattern string) (*regexp2.Regexp, error) {
+	compiled, err := regexp2.Compile(pattern, regexp2.ECMAScript)
+	if err != nil {
+		return nil, err
+	}
+	compiled.MatchTimeout = patternTimeout
+	return compiled, nil
+}
+
+// ValidateFormConfig runs every semantic check on a decoded FormConfig and
+// returns all problems found, or nil if the config is valid.
+func ValidateFormConfig(config *domain.FormConfig) Issues {
+	v := &validator{}
+	v.validateConfig(config)
+	return v.issues
+}
+
+// DecodeIssue wraps a decoding error from domain.ParseFormConfig into an
+// issue addressed at the document root.
+func DecodeIssue(err error) Issue {
+	return Issue{Code: CodeInvalidJSON, Message: err.Error(), Path: Path{}}
+}
+
+type validator struct {
+	issues Issues
+}
+
+func (v *validator) add(code string, path Path, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{Code: code, Message: fmt.Sprintf(format, args...), Path: path})
+}
+
+func (v *validator) validateConfig(config *domain.FormConfig) {
+	root := Path{}
+
+	if config.Endpoint == "" {
+		v.add(CodeRequired, root.with("endpoint"), "Form endpoint is required")
+	} else if config.Endpoint != "/api" && !strings.HasPrefix(config.Endpoint, "/api/") {
+		v.add(CodeInvalidEndpoint, root.with("endpoint"), "Endpoint '%s' must be prefixed with /api", config.Endpoint)
+	}
+	if config.Submit.Label == "" {
+		v.add(CodeRequired, root.with("submit", "label"), "Submit button requires a label")
+	}
+	if dialog := config.Submit.ConfirmDialog; dialog != nil {
+		if dialog.Title == "" {
+			v.add(CodeRequired, root.with("submit", "confirmDialog", "title"), "Confirm dialog requires a title")
+		}
+		if dialog.Message == "" {
+			v.add(CodeRequired, root.with("submit", "confirmDialog", "message"), "Confirm dialog requires a message")
+		}
+	}
+	if config.Draft != nil && config.Draft.IntervalMs != nil && *config.Draft.IntervalMs <= 0 {
+		v.add(CodeInvalidRange, root.with("draft", "intervalMs"), "intervalMs must be positive")
+	}
+
+	if len(config.Fields) == 0 {
+		v.add(CodeRequired, root.with("fields"), "At least one field is required")
+	}
+
+	fieldNames := make(map[string]bool, len(config.Fields))
+	for i, field := range config.Fields {
+		path := root.with("fields", i, "name")
+		if field.Name == "" {
+			v.add(CodeRequired, path, "Field name is required")
+			continue
+		}
+		if fieldNames[field.Name] {
+			v.add(CodeDuplicate, path, "Duplicate field name: %s", field.Name)
+		}
+		if !camelCasePattern.MatchString(field.Name) {
+			v.add(CodeInvalidName, path, "Field name '%s' must be camelCase", field.Name)
+		}
+		fieldNames[field.Name] = true
+	}
+
+	for i := range config.Fields {
+		v.validateField(&config.Fields[i], root.with("fields", i), fieldNames)
+	}
+
+	v.validateSteps(config.Steps, root.with("steps"), fieldNames)
+}
+
+func (v *validator) validateField(field *domain.FormField, path Path, fieldNames map[string]bool) {
+	if field.Type == "" {
+		v.add(CodeRequired, path.with("type"), "Field type is required")
+	}
+
+	isPassword := field.IsPassword
+	if field.Type == domain.FieldPassword && isPassword != nil && !*isPassword {
+		v.add(CodeInvalidCombination, path.with("isPassword"), "Password fields should not explicitly set isPassword to false")
+	}
+	if field.Type != domain.FieldPassword && isPassword != nil && *isPassword {
+		v.add(CodeInvalidCombination, path.with("isPassword"), "Only password fields can set isPassword")
+	}
+
+	switch field.Type {
+	case domain.FieldSelect, domain.FieldMultiselect, domain.FieldRadio:
+		if len(field.Options) == 0 && field.DataSource == nil {
+			v.add(CodeMissingOptions, path.with("options"), "Select-like fields require options or a dataSource")
+		}
+	}
+	if field.Type == domain.FieldMultiselect && field.MaxSelections != nil && *field.MaxSelections == 1 {
+		v.add(CodeInvalidCombination, path.with("maxSelections"), "Use type 'select' instead of limiting multiselect to one option")
+	}
+	if field.MaxSelections != nil && *field.MaxSelections <= 0 {
+		v.add(CodeInvalidRange, path.with("maxSelections"), "maxSelections must be positive")
+	}
+	if field.Rows != nil && *field.Rows <= 0 {
+		v.add(CodeInvalidRange, path.with("rows"), "rows must be positive")
+	}
+	if field.Type == domain.FieldFile {
+		if _, err := FieldFileLimits(field); err != nil {
+			v.add(CodeInvalidRange, path.with("attributes"), "%v", err)
+		}
+	}
+
+	for i, option := range field.Options {
+		if option.Label == "" {
+			v.add(CodeRequired, path.with("options", i, "label"), "Option label is required")
+		}
+	}
+
+	if field.Min != nil && field.Max != nil && field.Min.Number != nil && field.Max.Number != nil &&
+		*field.Min.Number > *field.Max.Number {
+		v.add(CodeInvalidRange, path.with("max"), "min cannot be greater than max")
+	}
+	if field.Min != nil && field.Max != nil && field.Min.String != "" && field.Max.String != "" &&
+		field.Min.String > field.Max.String {
+		v.add(CodeInvalidRange, path.with("max"), "min cannot be after max")
+	}
+
+	if rules := field.Validation; rules != nil {
+		v.validateRules(rules, path.with("validation"), fieldNames)
+	}
+
+	for i, rule := range field.VisibleWhen {
+		rulePath := path.with("visibleWhen", i)
+		if rule.Field == "" {
+			v.add(CodeRequired, rulePath.with("field"), "Visibility rule requires a field")
+		} else if !fieldNames[rule.Field] {
+			v.add(CodeUnknownReference, rulePath.with("field"), "visibleWhen field '%s' does not exist", rule.Field)
+		} else if rule.Field == field.Name {
+			v.add(CodeInvalidCombination, rulePath.with("field"), "A field cannot depend on its own visibility")
+		}
+		if rule.Operator == "" {
+			v.add(CodeRequired, rulePath.with("operator"), "Visibility rule requires an operator")
+		}
+		if (rule.Operator == domain.OpIn || rule.Operator == domain.OpNotIn) && rule.Value != nil {
+			if _, ok := rule.Value.([]interface{}); !ok {
+				v.add(CodeInvalidCombination, rulePath.with("value"), "Operator '%s' requires an array value", rule.Operator)
+			}
+		}
+	}
+
+	if layout := field.Layout; layout != nil {
+		if layout.ColSpan != nil && (*layout.ColSpan < 1 || *layout.ColSpan > 12) {
+			v.add(CodeInvalidRange, path.with("layout", "colSpan"), "colSpan must be between 1 and 12")
+		}
+		if layout.RowSpan != nil && *layout.RowSpan < 1 {
+			v.add(CodeInvalidRange, path.with("layout", "rowSpan"), "rowSpan must be positive")
+		}
+		if layout.Order != nil && *layout.Order < 0 {
+			v.add(CodeInvalidRange, path.with("layout", "order"), "order cannot be negative")
+		}
+	}
+
+	if ds := field.DataSource; ds != nil {
+		dsPath := path.with("dataSource")
+		if ds.Endpoint == "" {
+			v.add(CodeRequired, dsPath.with("endpoint"), "Endpoint is required")
+		}
+		if p := ds.Pagination; p != nil {
+			if p.LabelKey == "" {
+				v.add(CodeRequired, dsPath.with("pagination", "labelKey"), "labelKey is required")
+			}
+			if p.ValueKey == "" {
+				v.add(CodeRequired, dsPath.with("pagination", "valueKey"), "valueKey is required")
+			}
+			if p.PageSize != nil && *p.PageSize <= 0 {
+				v.add(CodeInvalidRange, dsPath.with("pagination", "pageSize"), "pageSize must be positive")
+			}
+		}
+		if ds.DebounceMs != nil && *ds.DebounceMs < 0 {
+			v.add(CodeInvalidRange, dsPath.with("debounceMs"), "debounceMs cannot be negative")
+		}
+		if ds.CacheTTLMs != nil && *ds.CacheTTLMs < 0 {
+			v.add(CodeInvalidRange, dsPath.with("cacheTtlMs"), "cacheTtlMs cannot be negative")
+		}
+	}
+}
+
+func (v *validator) validateRules(rules *domain.FormFieldValidation, path Path, fieldNames map[string]bool) {
+	if rules.MinLength != nil && *rules.MinLength < 0 {
+		v.add(CodeInvalidRange, path.with("minLength"), "minLength cannot be negative")
+	}
+	if rules.MaxLength != nil && *rules.MaxLength < 0 {
+		v.add(CodeInvalidRange, path.with("maxLength"), "maxLength cannot be negative")
+	}
+	if rules.MinLength != nil && rules.MaxLength != nil && *rules.MinLength > *rules.MaxLength {
+		v.add(CodeInvalidRange, path.with("maxLength"), "minLength cannot be greater than maxLength")
+	}
+	if rules.Min != nil && rules.Max != nil && *rules.Min > *rules.Max {
+		v.add(CodeInvalidRange, path.with("max"), "min cannot be greater than max")
+	}
+	if rules.Pattern != "" {
+		if _, err := compilePattern(rules.Pattern); err != nil {
+			v.add(CodeInvalidPattern, path.with("pattern"), "Invalid regex pattern: %v", err)
+		}
+	}
+	if rules.SameAs != "" && !fieldNames[rules.SameAs] {
+		v.add(CodeUnknownReference, path.with("sameAs"), "sameAs target '%s' does not exist", rules.SameAs)
+	}
+}
+
+func (v *validator) validateSteps(steps []domain.FormStep, path Path, fieldNames map[string]bool) {
+	if len(steps) == 0 {
+		return
+	}
+
+	stepIDs := make(map[string]bool, len(steps))
+	referenced := make(map[string]bool)
+
+	for i, step := range steps {
+		stepPath := path.with(i)
+		if step.ID == "" {
+			v.add(CodeRequired, stepPath.with("id"), "Step id is required")
+		} else if stepIDs[step.ID] {
+			v.add(CodeDuplicate, stepPath.with("id"), "Duplicate step id: %s", step.ID)
+		}
+		stepIDs[step.ID] = true
+
+		if len(step.Fields) == 0 {
+			v.add(CodeRequired, stepPath.with("fields"), "Step must reference at least one field")
+		}
+
+		local := make(map[string]bool, len(step.Fields))
+		for j, name := range step.Fields {
+			fieldPath := stepPath.with("fields", j)
+			if !fieldNames[name] {
+				v.add(CodeUnknownReference, fieldPath, "Step references unknown field '%s'", name)
+				continue
+			}
+			if local[name] {
+				v.add(CodeDuplicate, fieldPath, "Field '%s' is listed more than once in step '%s'", name, step.ID)
+			} else if referenced[name] {
+				v.add(CodeDuplicate, fieldPath, "Field '%s' is already assigned to a previous step", name)
+			}
+			local[name] = true
+			referenced[name] = true
+		}
+	}
+
+	if len(referenced) == 0 {
+		v.add(CodeRequired, path, "Steps must reference at least one defined field")
+	}
+}
@@ -0,0 +1,207 @@
+package validation
+
+import (
+	"better-form-doc-backend/domain"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateFormConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		wantIssues map[string]string
+	}{
+		{
+			name:       "valid",
+			config:     `{"endpoint":"/api/signup","submit":{"label":"Sign up"},"fields":[{"name":"email","type":"email"},{"name":"plan","type":"select","options":[{"value":"free","label":"Free"}]}]}`,
+			wantIssues: map[string]string{},
+		},
+		{
+			name:   "root",
+			config: `{"endpoint":"https://evil.example.com/collect","submit":{"label":"","confirmDialog":{"title":"","message":""}},"draft":{"intervalMs":0},"fields":[]}`,
+			wantIssues: map[string]string{
+				"endpoint":                     CodeInvalidEndpoint,
+				"submit.label":                 CodeRequired,
+				"submit.confirmDialog.title":   CodeRequired,
+				"submit.confirmDialog.message": CodeRequired,
+				"draft.intervalMs":             CodeInvalidRange,
+				"fields":                       CodeRequired,
+			},
+		},
+		{
+			name:       "missing endpoint",
+			config:     `{"endpoint":"","submit":{"label":"Send"},"fields":[{"name":"a","type":"text"}]}`,
+			wantIssues: map[string]string{"endpoint": CodeRequired},
+		},
+		{
+			name:       "endpoint sharing the prefix",
+			config:     `{"endpoint":"/apis/contact","submit":{"label":"Send"},"fields":[{"name":"a","type":"text"}]}`,
+			wantIssues: map[string]string{"endpoint": CodeInvalidEndpoint},
+		},
+		{
+			name:   "field names",
+			config: `{"endpoint":"/api","submit":{"label":"Send"},"fields":[{"name":"","type":"text"},{"name":"first_name","type":"text"},{"name":"email","type":"email"},{"name":"email","type":"text"}]}`,
+			wantIssues: map[string]string{
+				"fields[0].name": CodeRequired,
+				"fields[1].name": CodeInvalidName,
+				"fields[3].name": CodeDuplicate,
+			},
+		},
+		{
+			name: "field attributes",
+			config: `{"endpoint":"/api/x","submit":{"label":"Send"},"fields":[
+				{"name":"choice","type":"radio"},
+				{"name":"tags","type":"multiselect","options":[{"value":"a","label":""}],"maxSelections":1},
+				{"name":"bio","type":"textarea","rows":0},
+				{"name":"secret","type":"text","isPassword":true},
+				{"name":"age","type":"number","min":10,"max":5},
+				{"name":"day","type":"date","min":"2024-12-31","max":"2024-01-01"},
+				{"name":"cv","type":"file","attributes":{"maxSize":"huge"}},
+				{"name":"wide","type":"text","layout":{"colSpan":13,"rowSpan":0,"order":-1}}
+			]}`,
+			wantIssues: map[string]string{
+				"fields[0].options":          CodeMissingOptions,
+				"fields[1].options[0].label": CodeRequired,
+				"fields[1].maxSelections":    CodeInvalidCombination,
+				"fields[2].rows":             CodeInvalidRange,
+				"fields[3].isPassword":       CodeInvalidCombination,
+				"fields[4].max":              CodeInvalidRange,
+				"fields[5].max":              CodeInvalidRange,
+				"fields[6].attributes":       CodeInvalidRange,
+				"fields[7].layout.colSpan":   CodeInvalidRange,
+				"fields[7].layout.rowSpan":   CodeInvalidRange,
+				"fields[7].layout.order":     CodeInvalidRange,
+			},
+		},
+		{
+			name: "validation rules",
+			config: `{"endpoint":"/api/x","submit":{"label":"Send"},"fields":[
+				{"name":"code","type":"text","validation":{"minLength":5,"maxLength":2,"pattern":"[a-z"}},
+				{"name":"count","type":"number","validation":{"min":3,"max":1}},
+				{"name":"confirm","type":"password","validation":{"sameAs":"password"}}
+			]}`,
+			wantIssues: map[string]string{
+				"fields[0].validation.maxLength": CodeInvalidRange,
+				"fields[0].validation.pattern":   CodeInvalidPattern,
+				"fields[1].validation.max":       CodeInvalidRange,
+				"fields[2].validation.sameAs":    CodeUnknownReference,
+			},
+		},
+		{
+			name: "visibility rules",
+			config: `{"endpoint":"/api/x","submit":{"label":"Send"},"fields":[
+				{"name":"a","type":"text","visibleWhen":[{"field":"a","operator":"exists"}]},
+				{"name":"b","type":"text","visibleWhen":[{"field":"missing","operator":"equals","value":1},{"field":"a","operator":"in","value":"x"}]},
+				{"name":"c","type":"text","visibleWhen":[{"field":"","operator":"exists"}]}
+			]}`,
+			wantIssues: map[string]string{
+				"fields[0].visibleWhen[0].field": CodeInvalidCombination,
+				"fields[1].visibleWhen[0].field": CodeUnknownReference,
+				"fields[1].visibleWhen[1].value": CodeInvalidCombination,
+				"fields[2].visibleWhen[0].field": CodeRequired,
+			},
+		},
+		{
+			name: "steps",
+			config: `{"endpoint":"/api/x","submit":{"label":"Send"},"fields":[{"name":"a","type":"text"},{"name":"b","type":"text"}],"steps":[
+				{"id":"one","fields":["a","a","ghost"]},
+				{"id":"one","fields":["a"]},
+				{"id":"","fields":[]}
+			]}`,
+			wantIssues: map[string]string{
+				"steps[0].fields[1]": CodeDuplicate,
+				"steps[0].fields[2]": CodeUnknownReference,
+				"steps[1].id":        CodeDuplicate,
+				"steps[1].fields[0]": CodeDuplicate,
+				"steps[2].id":        CodeRequired,
+				"steps[2].fields":    CodeRequired,
+			},
+		},
+		{
+			name:   "steps without known fields",
+			config: `{"endpoint":"/api/x","submit":{"label":"Send"},"fields":[{"name":"a","type":"text"}],"steps":[{"id":"one","fields":["ghost"]}]}`,
+			wantIssues: map[string]string{
+				"steps[0].fields[0]": CodeUnknownReference,
+				"steps":              CodeRequired,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := domain.ParseFormConfig([]byte(tt.config))
+			if err != nil {
+				t.Fatalf("invalid test form: %v", err)
+			}
+			issues := ValidateFormConfig(config)
+			if got := issueCodes(issues); !reflect.DeepEqual(got, tt.wantIssues) {
+				t.Errorf("issues = %v\nwant %v", issues, tt.wantIssues)
+			}
+			for _, issue := range issues {
+				if issue.Message == "" {
+					t.Errorf("issue at %s has no message", issue.Path)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFormConfigPatterns(t *testing.T) {
+	tests := []struct {
+		pattern string
+		valid   bool
+	}{
+		{`^\d{5}$`, true},
+		// ECMAScript constructs that RE2 rejects.
+		{`^(?=.*[A-Z])(?=.*\d).{8,}$`, true},
+		{`^(?!admin$)[a-z]+$`, true},
+		{`(?<=@)example\.com$`, true},
+		{`^(\w)\1$`, true},
+		{`^(?<year>\d{4})-\k<year>$`, true},
+		{`[a-z`, false},
+		{`a{3,1}`, false},
+		{`(?P<name>x)`, false},
+		{`*`, false},
+	}
+	for _, tt := range tests {
+		config := mustFormConfig(t, `[{"name":"code","type":"text","validation":{"pattern":`+quote(tt.pattern)+`}}]`)
+		issues := ValidateFormConfig(config)
+		if valid := len(issues) == 0; valid != tt.valid {
+			t.Errorf("pattern %s: issues = %v, want valid = %v", tt.pattern, issues, tt.valid)
+		}
+	}
+}
+
+func TestPathString(t *testing.T) {
+	tests := map[string]Path{
+		"":                              {},
+		"fields[2].validation.sameAs":   {"fields", 2, "validation", "sameAs"},
+		"steps[0].fields[1]":            {"steps", 0, "fields", 1},
+		"[3]":                           {3},
+		"fields[0].dataSource.endpoint": Path{}.with("fields", 0).with("dataSource", "endpoint"),
+	}
+	for want, path := range tests {
+		if got := path.String(); got != want {
+			t.Errorf("%#v.String() = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestIssuesError(t *testing.T) {
+	issues := Issues{
+		{Code: CodeRequired, Message: "Form endpoint is required", Path: Path{"endpoint"}},
+		{Code: CodeDuplicate, Message: "Duplicate field name: a", Path: Path{"fields", 1, "name"}},
+	}
+	got := issues.Error()
+	for _, want := range []string{"endpoint", "Form endpoint is required", "fields[1].name", "Duplicate field name: a"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Error() = %q, want it to mention %q", got, want)
+		}
+	}
+}
+
+// quote renders s as a JSON string.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
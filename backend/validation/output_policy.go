@@ -0,0 +1,128 @@
+// validation/output_policy.go
+package validation
+
+import (
+	"better-form-doc-backend/domain"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Issue codes reported by OutputPolicy.Check.
+const (
+	CodeExternalEndpoint = "external_endpoint"
+	CodeCredentialHeader = "credential_header"
+)
+
+// OutputPolicy restricts what a generated form may do, so that a request
+// smuggling instructions to the model cannot produce a form that sends user
+// input elsewhere or carries secrets. Forms written by hand are not checked.
+type OutputPolicy struct {
+	// AllowedHosts are the hosts absolute URLs may point to. Relative URLs
+	// stay on the application's own origin and are always allowed.
+	AllowedHosts []string
+}
+
+// credentialHeaders are header names that carry credentials. Secrets belong
+// in authTokenRef, which is resolved when the request is made.
+var credentialHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+}
+
+// credentialNameParts mark custom credential headers such as X-API-Key or
+// X-Auth-Token.
+var credentialNameParts = []string{"token", "secret", "password", "apikey", "api-key", "api_key", "session", "credential"}
+
+// credentialValuePrefixes mark credentials whatever the header is called.
+var credentialValuePrefixes = []string{"bearer ", "basic ", "token ", "digest "}
+
+// Check returns every violation of the policy, or nil.
+func (p OutputPolicy) Check(config *domain.FormConfig) Issues {
+	v := &validator{}
+	root := Path{}
+
+	p.checkURL(v, root.with("endpoint"), config.Endpoint)
+	p.checkURL(v, root.with("onSuccessRedirect"), config.OnSuccessRedirect)
+	checkHeaders(v, root.with("headers"), config.Headers)
+	for i, field := range config.Fields {
+		if ds := field.DataSource; ds != nil {
+			dsPath := root.with("fields", i, "dataSource")
+			p.checkURL(v, dsPath.with("endpoint"), ds.Endpoint)
+			checkHeaders(v, dsPath.with("headers"), ds.Headers)
+		}
+	}
+	return v.issues
+}
+
+// checkURL rejects absolute and protocol-relative URLs to hosts that are not
+// allowed, and URLs with any scheme but http(s).
+func (p OutputPolicy) checkURL(v *validator, path Path, raw string) {
+	if raw == "" {
+		return
+	}
+	trimmed := strings.TrimSpace(raw)
+	u, err := url.Parse(trimmed)
+	if err != nil {
+		v.add(CodeExternalEndpoint, path, "'%s' is not a valid URL", raw)
+		return
+	}
+	// Browsers treat backslashes like slashes, so "/\evil.example" leaves the site.
+	if u.Scheme == "" && u.Host == "" && !strings.HasPrefix(strings.ReplaceAll(trimmed, `\`, "/"), "//") {
+		return
+	}
+	if u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+		v.add(CodeExternalEndpoint, path, "URL scheme '%s' is not allowed", u.Scheme)
+		return
+	}
+	if !p.allowsHost(u.Hostname()) {
+		v.add(CodeExternalEndpoint, path, "'%s' points to an external host; use a relative URL", raw)
+	}
+}
+
+func (p OutputPolicy) allowsHost(host string) bool {
+	if host == "" {
+		return false
+	}
+	for _, allowed := range p.AllowedHosts {
+		if strings.EqualFold(host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHeaders rejects headers that carry credentials, by name or by value.
+func checkHeaders(v *validator, path Path, headers map[string]string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names) // stable issue order
+	for _, name := range names {
+		if isCredentialHeader(name, headers[name]) {
+			v.add(CodeCredentialHeader, path.with(name), "Header '%s' carries credentials; reference them with authTokenRef instead", name)
+		}
+	}
+}
+
+func isCredentialHeader(name, value string) bool {
+	lowerName := strings.ToLower(strings.TrimSpace(name))
+	if credentialHeaders[lowerName] {
+		return true
+	}
+	for _, part := range credentialNameParts {
+		if strings.Contains(lowerName, part) {
+			return true
+		}
+	}
+	lowerValue := strings.ToLower(strings.TrimSpace(value))
+	for _, prefix := range credentialValuePrefixes {
+		if strings.HasPrefix(lowerValue, prefix) {
+			return true
+		}
+	}
+	return false
+}
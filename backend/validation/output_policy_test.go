@@ -0,0 +1,110 @@
+package validation
+
+import (
+	"better-form-doc-backend/domain"
+	"reflect"
+	"testing"
+)
+
+func TestOutputPolicyURLs(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"/api/contact", true},
+		{"api/contact?x=1", true},
+		{"#done", true},
+		{"https://api.example.com/contact", true},
+		{"HTTP://API.EXAMPLE.COM/contact", true},
+		{" https://api.example.com/contact ", true},
+		{"https://evil.example.com/collect", false},
+		{"https://api.example.com.evil.net/collect", false},
+		{"https://api.example.com@evil.net/collect", false},
+		{"//evil.example.com/collect", false},
+		{`/\evil.example.com/collect`, false},
+		{`\\evil.example.com/collect`, false},
+		{" //evil.example.com/collect", false},
+		{"javascript:alert(1)", false},
+		{"ftp://api.example.com/x", false},
+		{"data:text/html,<script>", false},
+		{"https://%zz", false},
+	}
+	policy := OutputPolicy{AllowedHosts: []string{"api.example.com"}}
+	for _, tt := range tests {
+		for _, key := range []string{"endpoint", "onSuccessRedirect"} {
+			config := &domain.FormConfig{Endpoint: "/api/submit"}
+			if key == "endpoint" {
+				config.Endpoint = tt.url
+			} else {
+				config.OnSuccessRedirect = tt.url
+			}
+			issues := policy.Check(config)
+			if allowed := len(issues) == 0; allowed != tt.want {
+				t.Errorf("%s %q: issues = %v, want allowed = %v", key, tt.url, issues, tt.want)
+			}
+		}
+	}
+}
+
+func TestOutputPolicyHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   bool
+	}{
+		{"plain header", "Accept-Language", "en", true},
+		{"content type", "Content-Type", "application/json", true},
+		{"authorization", "Authorization", "x", false},
+		{"authorization in another case", " authorization ", "x", false},
+		{"proxy authorization", "Proxy-Authorization", "x", false},
+		{"cookie", "Cookie", "sid=1", false},
+		{"set-cookie", "Set-Cookie", "sid=1", false},
+		{"api key", "X-API-Key", "abc", false},
+		{"api key with underscore", "x_api_key", "abc", false},
+		{"auth token", "X-Auth-Token", "abc", false},
+		{"session", "X-Session-Id", "abc", false},
+		{"client secret", "Client-Secret", "abc", false},
+		{"bearer value", "X-Forward", "Bearer abc", false},
+		{"basic value", "X-Forward", "  basic dXNlcjpwYXNz", false},
+		{"token value", "X-Forward", "Token abc", false},
+		{"digest value", "X-Forward", "Digest username=x", false},
+		{"word in the value", "X-Note", "bearer of news", false},
+		{"prefix without separator", "X-Note", "Bearers", true},
+	}
+	policy := OutputPolicy{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{tt.header: tt.value}
+			config := &domain.FormConfig{
+				Endpoint: "/api/submit",
+				Headers:  headers,
+				Fields: []domain.FormField{{Name: "country", Type: "select", DataSource: &domain.DynamicDataSource{
+					Type: "remote", Endpoint: "/api/countries", Headers: headers,
+				}}},
+			}
+			want := map[string]string{}
+			if !tt.want {
+				want = map[string]string{
+					"headers." + tt.header:                      CodeCredentialHeader,
+					"fields[0].dataSource.headers." + tt.header: CodeCredentialHeader,
+				}
+			}
+			if got := issueCodes(policy.Check(config)); !reflect.DeepEqual(got, want) {
+				t.Errorf("issues = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestOutputPolicyDataSourceEndpoint(t *testing.T) {
+	config := mustFormConfig(t, `[
+		{"name":"a","type":"select","dataSource":{"type":"remote","endpoint":"/api/a"}},
+		{"name":"b","type":"select","dataSource":{"type":"remote","endpoint":"https://evil.example.com/b"}},
+		{"name":"c","type":"select","dataSource":{"type":"remote","endpoint":"https://api.example.com/c"}}
+	]`)
+	want := map[string]string{"fields[1].dataSource.endpoint": CodeExternalEndpoint}
+	if got := issueCodes(OutputPolicy{AllowedHosts: []string{"api.example.com"}}.Check(config)); !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,481 @@
+// validation/submission_validator.go
+package validation
+
+import (
+	"better-form-doc-backend/domain"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Issue codes reported by ValidateSubmission, named after their Zod
+// counterparts.
+const (
+	CodeInvalidType   = "invalid_type"
+	CodeInvalidOption = "invalid_enum_value"
+	CodeInvalidString = "invalid_string"
+	CodeTooSmall      = "too_small"
+	CodeTooBig        = "too_big"
+	CodeNotMultipleOf = "not_multiple_of"
+	CodeMismatch      = "mismatch"
+)
+
+var emailPattern = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
+
+// FieldDataType returns the type a field's value is coerced to: its dataType,
+// or else the type implied by the widget and the options.
+func FieldDataType(field *domain.FormField) domain.BackendDataType {
+	if field.DataType != "" {
+		return field.DataType
+	}
+	switch field.Type {
+	case domain.FieldNumber:
+		return domain.DataNumber
+	case domain.FieldSelect, domain.FieldRadio:
+		if len(field.Options) > 0 {
+			switch field.Options[0].Value.Value.(type) {
+			case float64:
+				return domain.DataNumber
+			case bool:
+				return domain.DataBoolean
+			}
+		}
+		return domain.DataString
+	case domain.FieldMultiselect:
+		return domain.DataArray
+	case domain.FieldCheckbox, domain.FieldToggle:
+		return domain.DataBoolean
+	case domain.FieldDate:
+		return domain.DataDate
+	case domain.FieldDatetime:
+		return domain.DataDatetime
+	case domain.FieldFile:
+		// The value is the name of the uploaded file, or the names of several.
+		if limits, err := FieldFileLimits(field); err == nil && limits.MaxFiles != 1 {
+			return domain.DataArray
+		}
+		return domain.DataString
+	case domain.FieldText, domain.FieldEmail, domain.FieldPassword, domain.FieldTextarea:
+		return domain.DataString
+	}
+	return domain.DataJSON
+}
+
+// ValidateSubmission checks submitted values against the fields of config
+// with the rules the frontend applies (webapp_betterhack/lib/formParser.ts)
+// and returns the values coerced by dataType. Fields hidden by their
+// visibleWhen rules are neither required nor kept, and unknown keys are
+// dropped. Issues are addressed by field name.
+func ValidateSubmission(config *domain.FormConfig, values map[string]interface{}) (map[string]interface{}, Issues) {
+	v := &validator{}
+
+	// Coerce first: visibility rules compare against the coerced values.
+	type coercedField struct {
+		value   interface{}
+		present bool
+		problem *coercionProblem
+	}
+	coerced := make(map[string]coercedField, len(config.Fields))
+	current := make(map[string]interface{}, len(config.Fields))
+	for i := range config.Fields {
+		field := &config.Fields[i]
+		raw, present := submittedValue(field, values)
+		entry := coercedField{value: raw, present: present}
+		if present {
+			entry.value, entry.problem = coerceValue(field, raw)
+		}
+		coerced[field.Name] = entry
+		if entry.problem == nil {
+			current[field.Name] = entry.value
+		} else {
+			current[field.Name] = raw
+		}
+	}
+
+	data := make(map[string]interface{}, len(config.Fields))
+	for i := range config.Fields {
+		field := &config.Fields[i]
+		if !IsFieldVisible(field.VisibleWhen, current) {
+			continue
+		}
+		path := Path{field.Name}
+		entry := coerced[field.Name]
+		if !entry.present || isEmptyString(entry.value) {
+			if rule := requiredRule(field); rule.Required {
+				message := rule.Message
+				if message == "" {
+					message = "This field is required"
+				}
+				v.add(CodeRequired, path, "%s", message)
+			} else if entry.present {
+				data[field.Name] = entry.value
+			}
+			continue
+		}
+		if entry.problem != nil {
+			v.add(entry.problem.code, path, "%s", entry.problem.message)
+			continue
+		}
+		v.validateValue(field, entry.value, path)
+		data[field.Name] = entry.value
+	}
+
+	for i := range config.Fields {
+		field := &config.Fields[i]
+		if field.Validation == nil || field.Validation.SameAs == "" {
+			continue
+		}
+		if _, visible := data[field.Name]; !visible {
+			continue
+		}
+		if !valuesEqual(data[field.Name], data[field.Validation.SameAs]) {
+			label := field.Label
+			if label == "" {
+				label = field.Name
+			}
+			v.add(CodeMismatch, Path{field.Name}, "%s must match %s", label, field.Validation.SameAs)
+		}
+	}
+	return data, v.issues
+}
+
+// submittedValue returns the value sent for field, falling back to the
+// defaults the frontend starts with. Empty strings of non-text fields count
+// as missing, as HTML forms send them for untouched inputs.
+func submittedValue(field *domain.FormField, values map[string]interface{}) (interface{}, bool) {
+	raw, present := values[field.Name]
+	if present && raw == nil {
+		present = false
+	}
+	if present && isEmptyString(raw) && FieldDataType(field) != domain.DataString {
+		present = false
+	}
+	if present {
+		return raw, true
+	}
+	switch {
+	case field.DefaultValue != nil:
+		return field.DefaultValue, true
+	case field.Type == domain.FieldCheckbox || field.Type == domain.FieldToggle:
+		return false, true
+	case field.Type == domain.FieldMultiselect:
+		return []interface{}{}, true
+	}
+	return nil, false
+}
+
+// requiredRule returns the field's required rule. Like the frontend, a field
+// without one is required.
+func requiredRule(field *domain.FormField) domain.RequiredRule {
+	if field.Validation == nil || field.Validation.Required == nil {
+		return domain.RequiredRule{Required: true}
+	}
+	return *field.Validation.Required
+}
+
+// coercionProblem is why a value could not be coerced.
+type coercionProblem struct {
+	code    string
+	message string
+}
+
+func invalidType(message string) *coercionProblem {
+	return &coercionProblem{code: CodeInvalidType, message: message}
+}
+
+// coerceValue converts raw to the field's data type. The problem is nil on
+// success.
+func coerceValue(field *domain.FormField, raw interface{}) (interface{}, *coercionProblem) {
+	if field.Type == domain.FieldCheckbox || field.Type == domain.FieldToggle {
+		return coerceBoolean(raw)
+	}
+	if len(field.Options) > 0 {
+		switch field.Type {
+		case domain.FieldSelect, domain.FieldRadio:
+			if value, ok := matchOption(field.Options, raw); ok {
+				return value, nil
+			}
+			return raw, &coercionProblem{code: CodeInvalidOption, message: "Invalid option"}
+		case domain.FieldMultiselect:
+			items, problem := coerceArray(raw)
+			if problem != nil {
+				return raw, problem
+			}
+			for i, item := range items {
+				if value, ok := matchOption(field.Options, item); ok {
+					items[i] = value
+				}
+			}
+			return items, nil
+		}
+	}
+
+	switch FieldDataType(field) {
+	case domain.DataString:
+		switch value := raw.(type) {
+		case string:
+			return value, nil
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(value), nil
+		}
+		return raw, invalidType("Must be a string")
+	case domain.DataNumber:
+		return coerceNumber(raw)
+	case domain.DataBoolean:
+		return coerceBoolean(raw)
+	case domain.DataDate:
+		if value, ok := raw.(string); ok {
+			value = strings.TrimSpace(value)
+			if t, err := time.Parse("2006-01-02", value); err == nil {
+				return t.Format("2006-01-02"), nil
+			}
+			if t, err := time.Parse(time.RFC3339, value); err == nil {
+				return t.Format("2006-01-02"), nil
+			}
+		}
+		return raw, invalidType("Must be a valid ISO date string")
+	case domain.DataDatetime:
+		if value, ok := raw.(string); ok {
+			if t, err := time.Parse(time.RFC3339, strings.TrimSpace(value)); err == nil {
+				return t.UTC().Format(time.RFC3339Nano), nil
+			}
+		}
+		return raw, invalidType("Must be a valid ISO date-time")
+	case domain.DataEnum:
+		switch raw.(type) {
+		case string, float64, bool:
+			return raw, nil
+		}
+		return raw, invalidType("Must be a string, number or boolean")
+	case domain.DataArray:
+		items, problem := coerceArray(raw)
+		return items, problem
+	case domain.DataObject:
+		if _, ok := raw.(map[string]interface{}); ok {
+			return raw, nil
+		}
+		return raw, invalidType("Must be an object")
+	}
+	return raw, nil
+}
+
+func coerceNumber(raw interface{}) (interface{}, *coercionProblem) {
+	switch value := raw.(type) {
+	case float64:
+		return value, nil
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err == nil && !math.IsInf(number, 0) && !math.IsNaN(number) {
+			return number, nil
+		}
+	}
+	return raw, invalidType("Must be a number")
+}
+
+func coerceBoolean(raw interface{}) (interface{}, *coercionProblem) {
+	switch value := raw.(type) {
+	case bool:
+		return value, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "true", "on", "1", "yes":
+			return true, nil
+		case "false", "off", "0", "no":
+			return false, nil
+		}
+	case float64:
+		if value == 0 || value == 1 {
+			return value == 1, nil
+		}
+	}
+	return raw, invalidType("Must be a boolean")
+}
+
+// coerceArray accepts an array, or a single value as sent by a form with one
+// box checked.
+func coerceArray(raw interface{}) ([]interface{}, *coercionProblem) {
+	switch value := raw.(type) {
+	case []interface{}:
+		return append([]interface{}(nil), value...), nil
+	case string, float64, bool:
+		return []interface{}{value}, nil
+	}
+	return nil, invalidType("Must be an array")
+}
+
+// matchOption returns the option value equal to raw, comparing by string
+// form so that "2" selects the option 2.
+func matchOption(options []domain.StaticOption, raw interface{}) (interface{}, bool) {
+	text := scalarString(raw)
+	if text == "" {
+		return nil, false
+	}
+	for _, option := range options {
+		if option.Value.Value == raw {
+			return raw, true
+		}
+	}
+	for _, option := range options {
+		if scalarString(option.Value.Value) == text {
+			return option.Value.Value, true
+		}
+	}
+	return nil, false
+}
+
+func scalarString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// validateValue applies the validation rules of field to a coerced value.
+func (v *validator) validateValue(field *domain.FormField, value interface{}, path Path) {
+	rules := field.Validation
+	if rules == nil {
+		rules = &domain.FormFieldValidation{}
+	}
+
+	switch value := value.(type) {
+	case string:
+		if FieldDataType(field) != domain.DataString {
+			return
+		}
+		length := utf8.RuneCountInString(value)
+		if rules.MinLength != nil && length < *rules.MinLength {
+			v.add(CodeTooSmall, path, "Must be at least %d characters", *rules.MinLength)
+		}
+		if rules.MaxLength != nil && length > *rules.MaxLength {
+			v.add(CodeTooBig, path, "Must be at most %d characters", *rules.MaxLength)
+		}
+		// Invalid patterns are reported when the form is saved. A match
+		// that times out is treated as a mismatch.
+		if rules.Pattern != "" {
+			if pattern, err := compilePattern(rules.Pattern); err == nil {
+				if matched, err := pattern.MatchString(value); err != nil || !matched {
+					v.add(CodeInvalidString, path, "Value does not match required pattern")
+				}
+			}
+		}
+		if (rules.Email != nil && *rules.Email) || field.Type == domain.FieldEmail {
+			if !emailPattern.MatchString(value) {
+				v.add(CodeInvalidString, path, "Invalid email")
+			}
+		}
+		if rules.URL != nil && *rules.URL {
+			if u, err := url.ParseRequestURI(value); err != nil || u.Scheme == "" || u.Host == "" {
+				v.add(CodeInvalidString, path, "Invalid url")
+			}
+		}
+
+	case float64:
+		if rules.Min != nil && value < *rules.Min {
+			v.add(CodeTooSmall, path, "Must be greater than or equal to %v", *rules.Min)
+		}
+		if rules.Max != nil && value > *rules.Max {
+			v.add(CodeTooBig, path, "Must be less than or equal to %v", *rules.Max)
+		}
+		if field.Step != nil && *field.Step > 0 {
+			reference := 0.0
+			if field.Min != nil && field.Min.Number != nil {
+				reference = *field.Min.Number
+			}
+			steps := (value - reference) / *field.Step
+			if math.Abs(steps-math.Round(steps)) > 1e-9 {
+				v.add(CodeNotMultipleOf, path, "Must align with step %v", *field.Step)
+			}
+		}
+
+	case []interface{}:
+		if rules.MinLength != nil && len(value) < *rules.MinLength {
+			message := fmt.Sprintf("Select at least %d options", *rules.MinLength)
+			if rules.Required != nil && rules.Required.Message != "" {
+				message = rules.Required.Message
+			}
+			v.add(CodeTooSmall, path, "%s", message)
+		}
+		if rules.MaxLength != nil && len(value) > *rules.MaxLength {
+			v.add(CodeTooBig, path, "Select at most %d options", *rules.MaxLength)
+		}
+		if field.MaxSelections != nil && len(value) > *field.MaxSelections {
+			v.add(CodeTooBig, path, "Select no more than %d options", *field.MaxSelections)
+		}
+		if field.Type == domain.FieldMultiselect && len(field.Options) > 0 {
+			for i, item := range value {
+				if _, ok := matchOption(field.Options, item); !ok {
+					v.add(CodeInvalidOption, path.with(i), "Invalid option")
+				}
+			}
+		}
+	}
+}
+
+// IsFieldVisible evaluates visibleWhen rules against the current values the
+// way the frontend does: every rule must hold.
+func IsFieldVisible(rules []domain.VisibilityRule, values map[string]interface{}) bool {
+	for _, rule := range rules {
+		target := values[rule.Field]
+		var visible bool
+		switch rule.Operator {
+		case domain.OpEquals:
+			visible = valuesEqual(target, rule.Value)
+		case domain.OpNotEquals:
+			visible = !valuesEqual(target, rule.Value)
+		case domain.OpIn:
+			visible = containsValue(rule.Value, target)
+		case domain.OpNotIn:
+			list, ok := rule.Value.([]interface{})
+			visible = !ok || !containsValue(list, target)
+		case domain.OpExists:
+			visible = target != nil && !isEmptyString(target)
+		case domain.OpGreaterThan, domain.OpLessThan:
+			left, leftOK := target.(float64)
+			right, rightOK := rule.Value.(float64)
+			visible = leftOK && rightOK && (rule.Operator == domain.OpGreaterThan && left > right ||
+				rule.Operator == domain.OpLessThan && left < right)
+		default:
+			visible = true
+		}
+		if !visible {
+			return false
+		}
+	}
+	return true
+}
+
+func containsValue(list interface{}, value interface{}) bool {
+	items, ok := list.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, item := range items {
+		if valuesEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// valuesEqual compares JSON values strictly, like === for scalars.
+func valuesEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func isEmptyString(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.TrimSpace(s) == ""
+}
@@ -0,0 +1,300 @@
+package validation
+
+import (
+	"better-form-doc-backend/domain"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func mustFormConfig(t *testing.T, fields string) *domain.FormConfig {
+	t.Helper()
+	config, err := domain.ParseFormConfig([]byte(`{"endpoint":"/api/submit","submit":{"label":"Send"},"fields":` + fields + `}`))
+	if err != nil {
+		t.Fatalf("invalid test form: %v", err)
+	}
+	return config
+}
+
+func mustValues(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		t.Fatalf("invalid test values %s: %v", raw, err)
+	}
+	return values
+}
+
+// issueCodes maps the path of every issue to its code.
+func issueCodes(issues Issues) map[string]string {
+	codes := make(map[string]string, len(issues))
+	for _, issue := range issues {
+		codes[issue.Path.String()] = issue.Code
+	}
+	return codes
+}
+
+func TestValidateSubmission(t *testing.T) {
+	tests := []struct {
+		name       string
+		fields     string
+		values     string
+		wantData   string
+		wantIssues map[string]string
+	}{
+		{
+			name:     "text and email",
+			fields:   `[{"name":"name","type":"text"},{"name":"email","type":"email"}]`,
+			values:   `{"name":"Ada","email":"ada@example.com","extra":"dropped"}`,
+			wantData: `{"name":"Ada","email":"ada@example.com"}`,
+		},
+		{
+			name:       "fields are required by default",
+			fields:     `[{"name":"name","type":"text"},{"name":"nick","type":"text","validation":{"required":false}}]`,
+			values:     `{"name":"  "}`,
+			wantData:   `{}`,
+			wantIssues: map[string]string{"name": CodeRequired},
+		},
+		{
+			name:       "invalid email",
+			fields:     `[{"name":"email","type":"email"}]`,
+			values:     `{"email":"not-an-email"}`,
+			wantData:   `{"email":"not-an-email"}`,
+			wantIssues: map[string]string{"email": CodeInvalidString},
+		},
+		{
+			name:     "numbers are coerced from strings",
+			fields:   `[{"name":"age","type":"number","validation":{"min":18,"max":120}}]`,
+			values:   `{"age":" 42 "}`,
+			wantData: `{"age":42}`,
+		},
+		{
+			name:       "number bounds",
+			fields:     `[{"name":"low","type":"number","validation":{"min":18}},{"name":"high","type":"number","validation":{"max":10}}]`,
+			values:     `{"low":17,"high":"11"}`,
+			wantData:   `{"low":17,"high":11}`,
+			wantIssues: map[string]string{"low": CodeTooSmall, "high": CodeTooBig},
+		},
+		{
+			name:       "not a number",
+			fields:     `[{"name":"age","type":"number"}]`,
+			values:     `{"age":"forty"}`,
+			wantData:   `{}`,
+			wantIssues: map[string]string{"age": CodeInvalidType},
+		},
+		{
+			name:       "number step",
+			fields:     `[{"name":"even","type":"number","step":2,"min":1},{"name":"price","type":"number","step":0.01}]`,
+			values:     `{"even":4,"price":"19.99"}`,
+			wantData:   `{"even":4,"price":19.99}`,
+			wantIssues: map[string]string{"even": CodeNotMultipleOf},
+		},
+		{
+			name:     "booleans are coerced",
+			fields:   `[{"name":"terms","type":"checkbox"},{"name":"news","type":"toggle"},{"name":"flag","type":"text","dataType":"boolean"}]`,
+			values:   `{"terms":"on","flag":"No"}`,
+			wantData: `{"terms":true,"news":false,"flag":false}`,
+		},
+		{
+			name:       "not a boolean",
+			fields:     `[{"name":"terms","type":"checkbox"}]`,
+			values:     `{"terms":"maybe"}`,
+			wantData:   `{}`,
+			wantIssues: map[string]string{"terms": CodeInvalidType},
+		},
+		{
+			name:     "option values keep their type",
+			fields:   `[{"name":"size","type":"select","options":[{"value":1,"label":"S"},{"value":2,"label":"M"}]},{"name":"color","type":"radio","options":[{"value":"red","label":"Red"}]}]`,
+			values:   `{"size":"2","color":"red"}`,
+			wantData: `{"size":2,"color":"red"}`,
+		},
+		{
+			name:       "unknown option",
+			fields:     `[{"name":"color","type":"select","options":[{"value":"red","label":"Red"}]}]`,
+			values:     `{"color":"blue"}`,
+			wantData:   `{}`,
+			wantIssues: map[string]string{"color": CodeInvalidOption},
+		},
+		{
+			name:       "multiselect",
+			fields:     `[{"name":"tags","type":"multiselect","maxSelections":2,"options":[{"value":"a","label":"A"},{"value":"b","label":"B"},{"value":"c","label":"C"}]}]`,
+			values:     `{"tags":["a","x","c"]}`,
+			wantData:   `{"tags":["a","x","c"]}`,
+			wantIssues: map[string]string{"tags": CodeTooBig, "tags[1]": CodeInvalidOption},
+		},
+		{
+			name:     "single box of a multiselect",
+			fields:   `[{"name":"tags","type":"multiselect","options":[{"value":1,"label":"One"}]}]`,
+			values:   `{"tags":"1"}`,
+			wantData: `{"tags":[1]}`,
+		},
+		{
+			name:     "dates",
+			fields:   `[{"name":"day","type":"date"},{"name":"at","type":"datetime"}]`,
+			values:   `{"day":"2024-03-01T10:00:00Z","at":"2024-03-01T12:30:00+02:00"}`,
+			wantData: `{"day":"2024-03-01","at":"2024-03-01T10:30:00Z"}`,
+		},
+		{
+			name:       "invalid dates",
+			fields:     `[{"name":"day","type":"date"},{"name":"at","type":"datetime"}]`,
+			values:     `{"day":"01/03/2024","at":"2024-03-01"}`,
+			wantData:   `{}`,
+			wantIssues: map[string]string{"day": CodeInvalidType, "at": CodeInvalidType},
+		},
+		{
+			name:       "string length and pattern",
+			fields:     `[{"name":"code","type":"text","validation":{"minLength":3,"pattern":"^[A-Z]+$"}},{"name":"bio","type":"textarea","validation":{"maxLength":4}}]`,
+			values:     `{"code":"ab","bio":"héllo"}`,
+			wantData:   `{"code":"ab","bio":"héllo"}`,
+			wantIssues: map[string]string{"code": CodeInvalidString, "bio": CodeTooBig},
+		},
+		{
+			name:       "ECMAScript pattern with lookaheads",
+			fields:     `[{"name":"password","type":"password","validation":{"pattern":"^(?=.*[A-Z])(?=.*\\d).{8,}$"}},{"name":"repeat","type":"password","validation":{"pattern":"^(?=.*[A-Z])(?=.*\\d).{8,}$"}}]`,
+			values:     `{"password":"Secret123","repeat":"secret123"}`,
+			wantData:   `{"password":"Secret123","repeat":"secret123"}`,
+			wantIssues: map[string]string{"repeat": CodeInvalidString},
+		},
+		{
+			name:       "catastrophic backtracking times out as a mismatch",
+			fields:     `[{"name":"code","type":"text","validation":{"pattern":"^(a+)+$"}}]`,
+			values:     `{"code":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa!"}`,
+			wantData:   `{"code":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa!"}`,
+			wantIssues: map[string]string{"code": CodeInvalidString},
+		},
+		{
+			name:       "url",
+			fields:     `[{"name":"site","type":"text","validation":{"url":true}},{"name":"other","type":"text","validation":{"url":true}}]`,
+			values:     `{"site":"https://example.com/a","other":"example.com"}`,
+			wantData:   `{"site":"https://example.com/a","other":"example.com"}`,
+			wantIssues: map[string]string{"other": CodeInvalidString},
+		},
+		{
+			name:     "numbers are accepted as text",
+			fields:   `[{"name":"zip","type":"text"}]`,
+			values:   `{"zip":1234}`,
+			wantData: `{"zip":"1234"}`,
+		},
+		{
+			name:     "defaults fill in missing values",
+			fields:   `[{"name":"country","type":"text","defaultValue":"FR"},{"name":"count","type":"number","defaultValue":3}]`,
+			values:   `{"count":""}`,
+			wantData: `{"country":"FR","count":3}`,
+		},
+		{
+			name:       "custom required message",
+			fields:     `[{"name":"name","type":"text","validation":{"required":"Tell us your name"}}]`,
+			values:     `{}`,
+			wantData:   `{}`,
+			wantIssues: map[string]string{"name": CodeRequired},
+		},
+		{
+			name:       "sameAs",
+			fields:     `[{"name":"password","type":"password"},{"name":"confirm","type":"password","validation":{"sameAs":"password"}}]`,
+			values:     `{"password":"secret","confirm":"secrte"}`,
+			wantData:   `{"password":"secret","confirm":"secrte"}`,
+			wantIssues: map[string]string{"confirm": CodeMismatch},
+		},
+		{
+			name: "hidden fields are neither required nor kept",
+			fields: `[{"name":"contact","type":"select","options":[{"value":"email","label":"Email"},{"value":"phone","label":"Phone"}]},
+				{"name":"phone","type":"text","visibleWhen":[{"field":"contact","operator":"equals","value":"phone"}]},
+				{"name":"email","type":"email","visibleWhen":[{"field":"contact","operator":"equals","value":"email"}]}]`,
+			values:   `{"contact":"email","email":"ada@example.com","phone":"not kept"}`,
+			wantData: `{"contact":"email","email":"ada@example.com"}`,
+		},
+		{
+			name: "visibility compares coerced values",
+			fields: `[{"name":"age","type":"number"},
+				{"name":"guardian","type":"text","visibleWhen":[{"field":"age","operator":"lessThan","value":18}]}]`,
+			values:     `{"age":"16"}`,
+			wantData:   `{"age":16}`,
+			wantIssues: map[string]string{"guardian": CodeRequired},
+		},
+		{
+			name:       "object and array data types",
+			fields:     `[{"name":"meta","type":"text","dataType":"object"},{"name":"list","type":"text","dataType":"array"}]`,
+			values:     `{"meta":"x","list":{"a":1}}`,
+			wantData:   `{}`,
+			wantIssues: map[string]string{"meta": CodeInvalidType, "list": CodeInvalidType},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, issues := ValidateSubmission(mustFormConfig(t, tt.fields), mustValues(t, tt.values))
+			if want := mustValues(t, tt.wantData); !reflect.DeepEqual(data, want) {
+				t.Errorf("data = %v, want %v", data, want)
+			}
+			wantIssues := tt.wantIssues
+			if wantIssues == nil {
+				wantIssues = map[string]string{}
+			}
+			if got := issueCodes(issues); !reflect.DeepEqual(got, wantIssues) {
+				t.Errorf("issues = %v, want %v\n%v", got, wantIssues, issues)
+			}
+		})
+	}
+}
+
+func TestValidateSubmissionRequiredMessage(t *testing.T) {
+	config := mustFormConfig(t, `[{"name":"name","type":"text","validation":{"required":"Tell us your name"}}]`)
+	_, issues := ValidateSubmission(config, map[string]interface{}{})
+	if len(issues) != 1 || issues[0].Message != "Tell us your name" {
+		t.Errorf("issues = %v, want the custom message", issues)
+	}
+}
+
+func TestFieldDataType(t *testing.T) {
+	tests := []struct {
+		field string
+		want  domain.BackendDataType
+	}{
+		{`{"name":"a","type":"text"}`, domain.DataString},
+		{`{"name":"a","type":"text","dataType":"number"}`, domain.DataNumber},
+		{`{"name":"a","type":"number"}`, domain.DataNumber},
+		{`{"name":"a","type":"select","options":[{"value":1,"label":"One"}]}`, domain.DataNumber},
+		{`{"name":"a","type":"radio","options":[{"value":true,"label":"Yes"}]}`, domain.DataBoolean},
+		{`{"name":"a","type":"select"}`, domain.DataString},
+		{`{"name":"a","type":"multiselect"}`, domain.DataArray},
+		{`{"name":"a","type":"toggle"}`, domain.DataBoolean},
+		{`{"name":"a","type":"date"}`, domain.DataDate},
+		{`{"name":"a","type":"datetime"}`, domain.DataDatetime},
+	}
+	for _, tt := range tests {
+		var field domain.FormField
+		if err := json.Unmarshal([]byte(tt.field), &field); err != nil {
+			t.Fatal(err)
+		}
+		if got := FieldDataType(&field); got != tt.want {
+			t.Errorf("FieldDataType(%s) = %s, want %s", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestIsFieldVisible(t *testing.T) {
+	values := map[string]interface{}{"plan": "pro", "seats": 5.0, "empty": ""}
+	tests := []struct {
+		rules string
+		want  bool
+	}{
+		{`[]`, true},
+		{`[{"field":"plan","operator":"equals","value":"pro"}]`, true},
+		{`[{"field":"plan","operator":"notEquals","value":"pro"}]`, false},
+		{`[{"field":"plan","operator":"in","value":["free","pro"]}]`, true},
+		{`[{"field":"plan","operator":"notIn","value":["free","pro"]}]`, false},
+		{`[{"field":"seats","operator":"greaterThan","value":4}]`, true},
+		{`[{"field":"seats","operator":"lessThan","value":5}]`, false},
+		{`[{"field":"empty","operator":"exists"}]`, false},
+		{`[{"field":"missing","operator":"exists"}]`, false},
+		{`[{"field":"plan","operator":"exists"},{"field":"seats","operator":"equals","value":"5"}]`, false},
+	}
+	for _, tt := range tests {
+		var rules []domain.VisibilityRule
+		if err := json.Unmarshal([]byte(tt.rules), &rules); err != nil {
+			t.Fatal(err)
+		}
+		if got := IsFieldVisible(rules, values); got != tt.want {
+			t.Errorf("IsFieldVisible(%s) = %v, want %v", tt.rules, got, tt.want)
+		}
+	}
+}
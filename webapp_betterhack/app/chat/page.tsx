@@ -18,6 +18,8 @@ export default function AIChat() {
     },
   ]);
   const [input, setInput] = useState("");
+  // Lets follow-up messages refine the previously generated form
+  const [conversationId, setConversationId] = useState<string | null>(null);
   const [loading, setLoading] = useState(false);
 
   useEffect(() => {
@@ -58,19 +60,21 @@ export default function AIChat() {
           // ADD THE BEARER TOKEN HERE
           "Authorization": `Bearer ${apiToken}`,
         },
-        body: JSON.stringify({ prompt: input }),
+        body: JSON.stringify({ prompt: input, conversationId }),
       });
 
       const data = await res.json();
 
-      if (!res.ok || data.error) {
+      if (!res.ok) {
+        // Errors use the API envelope { code, message, details, requestId }
         setMessages([
           ...newMessages,
-          { role: "bot", text: `❌ Error: ${data.error || "Something went wrong"}` },
+          { role: "bot", text: `❌ Error: ${data.message || "Something went wrong"}` },
         ]);
       } else {
-        // Assuming the Go backend now returns the JSON directly
-        const formattedJson = JSON.stringify(data, null, 2);
+        // The Go backend returns the validated form plus generation metadata
+        setConversationId(data.conversationId);
+        const formattedJson = JSON.stringify(data.form, null, 2);
         setMessages([
           ...newMessages,
           { role: "bot", text: "```json\n" + formattedJson + "\n```" },